@@ -0,0 +1,227 @@
+// Package bundle assembles agent context, for 'pearls context' and the
+// server's /v1/context: the pearls named or matched by a request, each
+// with why it was included and its content, cut to the sections asked for
+// and with embeds expanded.
+package bundle
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/markdown"
+	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/query"
+	"github.com/justrnr500/pearls/internal/render"
+	"github.com/justrnr500/pearls/internal/storage"
+)
+
+// MaxEmbedDepth is how deeply embeds may nest.
+const MaxEmbedDepth = 5
+
+// Request selects the pearls of a bundle and their content.
+type Request struct {
+	// IDs are pearl IDs, each optionally with #section,section to include
+	// only those sections, unless the whole pearl is also named.
+	IDs []string
+	// Paths are files, relative to the project root, matched against
+	// pearl globs.
+	Paths []string
+	// Scopes, if set, is a scope filter as config.Scopes.Filter returns.
+	Scopes [][]string
+	// Where, if set, is a compiled query matching pearls to include.
+	Where *query.Clause
+	// WithRefs includes the pearls that the named IDs reference.
+	WithRefs bool
+	// Brief leaves out content, except the sections set for each pearl's
+	// type in Config.
+	Brief bool
+	// Sections cuts the content of pearls not named with a fragment to
+	// these sections.
+	Sections []string
+	Config   config.ContextConfig
+	// Stale, if set, says why a pearl is overdue for review, or returns "".
+	Stale func(p *pearl.Pearl) string
+}
+
+// Bundle is an assembled request.
+type Bundle struct {
+	Documents []render.Document
+	// Missing are IDs that name no pearl.
+	Missing []string
+	// Warnings describe content that could not be read or expanded.
+	Warnings []string
+}
+
+// Assemble collects the pearls a request names or matches, in order: IDs,
+// then paths, scopes, the query, and references. A pearl matched more
+// than once is included once, with every reason in its Source.
+func Assemble(store *storage.Store, req Request) (*Bundle, error) {
+	var ids []string
+	sources := make(map[string][]string)
+	include := func(id, source string) {
+		if _, ok := sources[id]; !ok {
+			ids = append(ids, id)
+		}
+		for _, s := range sources[id] {
+			if s == source {
+				return
+			}
+		}
+		sources[id] = append(sources[id], source)
+	}
+
+	// An ID#fragment selects sections, unless the whole pearl is asked for
+	requested := make([]string, 0, len(req.IDs))
+	fragments := make(map[string][]string)
+	whole := make(map[string]bool)
+	for _, arg := range req.IDs {
+		id, fragment, ok := strings.Cut(arg, "#")
+		if ok {
+			fragments[id] = append(fragments[id], SplitList(fragment)...)
+		} else {
+			whole[id] = true
+		}
+		requested = append(requested, id)
+		include(id, "id")
+	}
+	for id := range whole {
+		delete(fragments, id)
+	}
+
+	// Paths in the order given
+	if len(req.Paths) > 0 {
+		matches, err := store.FindByGlobs(req.Paths)
+		if err != nil {
+			return nil, fmt.Errorf("find by glob: %w", err)
+		}
+		for _, path := range req.Paths {
+			for _, p := range matches[path] {
+				include(p.ID, "glob:"+pearl.MatchingGlob(path, p.Globs))
+			}
+		}
+	}
+
+	if len(req.Scopes) > 0 {
+		matched, err := store.FindByScopes(req.Scopes)
+		if err != nil {
+			return nil, fmt.Errorf("find by scope: %w", err)
+		}
+		wanted := make(map[string]bool)
+		for _, group := range req.Scopes {
+			for _, s := range group {
+				wanted[s] = true
+			}
+		}
+		for _, p := range matched {
+			for _, s := range p.Scopes {
+				if wanted[s] {
+					include(p.ID, "scope:"+s)
+				}
+			}
+		}
+	}
+
+	if req.Where != nil {
+		matched, err := store.List(storage.ListOptions{Where: req.Where})
+		if err != nil {
+			return nil, fmt.Errorf("query pearls: %w", err)
+		}
+		for _, p := range matched {
+			include(p.ID, "query")
+		}
+	}
+
+	if req.WithRefs {
+		for _, id := range requested {
+			p, err := store.Get(id)
+			if err != nil || p == nil {
+				continue
+			}
+			for _, ref := range p.References {
+				include(ref, "ref:"+id)
+			}
+		}
+	}
+
+	b := &Bundle{Documents: make([]render.Document, 0, len(ids)), Missing: []string{}, Warnings: []string{}}
+	for _, id := range ids {
+		p, err := store.Get(id)
+		if err != nil {
+			return nil, fmt.Errorf("get pearl %s: %w", id, err)
+		}
+		if p == nil {
+			b.Missing = append(b.Missing, id)
+			continue
+		}
+
+		d := render.Document{Pearl: p, Source: sources[id]}
+		if req.Stale != nil {
+			d.Stale = req.Stale(p)
+		}
+		var warnings []string
+		if fragment, ok := fragments[id]; ok {
+			d.Content, warnings = Content(store, p, fragment, true)
+		} else {
+			d.Content, warnings = Select(store, p, req.Brief, req.Sections, req.Config)
+		}
+		b.Warnings = append(b.Warnings, warnings...)
+		b.Documents = append(b.Documents, d)
+	}
+	return b, nil
+}
+
+// Select returns the content to include for a pearl: the sections given,
+// else in brief mode the sections configured for its type, else all of it.
+func Select(store *storage.Store, p *pearl.Pearl, brief bool, sections []string, cfg config.ContextConfig) (string, []string) {
+	if len(sections) == 0 && brief {
+		sections = cfg.SectionsFor(string(p.Type))
+		if len(sections) == 0 {
+			return "", nil
+		}
+	}
+	return Content(store, p, sections, false)
+}
+
+// Content returns a pearl's content, cut to the given sections if any,
+// with embeds expanded, and warnings about what could not be read or
+// expanded and, if warnMissing, about sections the content lacks.
+func Content(store *storage.Store, p *pearl.Pearl, sections []string, warnMissing bool) (string, []string) {
+	content, err := store.GetContent(p)
+	if err != nil {
+		return "", []string{fmt.Sprintf("could not read content for %s: %v", p.ID, err)}
+	}
+	var warnings []string
+	if len(sections) > 0 {
+		var missing []string
+		content, missing = markdown.Extract(content, sections)
+		if warnMissing {
+			for _, m := range missing {
+				warnings = append(warnings, fmt.Sprintf("%s has no section %q", p.ID, m))
+			}
+		}
+	}
+	content, embeds := Transclude(store, p.ID, content)
+	return content, append(warnings, embeds...)
+}
+
+// Transclude expands the embeds in a pearl's content, with warnings,
+// prefixed by the pearl's ID, about any it cannot expand.
+func Transclude(store *storage.Store, id, content string) (string, []string) {
+	content, warnings := store.Transclude(id, content, MaxEmbedDepth)
+	for i, w := range warnings {
+		warnings[i] = id + ": " + w
+	}
+	return content, warnings
+}
+
+// SplitList splits a comma-separated list, dropping empty items.
+func SplitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
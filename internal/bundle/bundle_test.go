@@ -0,0 +1,178 @@
+package bundle
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/storage"
+)
+
+func setupBundleTestStore(t *testing.T) *storage.Store {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := storage.NewStore(filepath.Join(dir, "pearls.db"), filepath.Join(dir, "pearls.jsonl"), filepath.Join(dir, "content"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+func TestAssemble(t *testing.T) {
+	store := setupBundleTestStore(t)
+
+	now := time.Now()
+	users := &pearl.Pearl{
+		ID: "db.users", Name: "users", Namespace: "db",
+		Type: pearl.TypeTable, Status: pearl.StatusActive,
+		Scopes: []string{"payments"}, References: []string{"db.orders"},
+		CreatedAt: now, UpdatedAt: now,
+	}
+	if err := store.Create(users, "# users\n\n## Columns\n\n- id\n\n## Notes\n\nSoft-deleted. ![[db.orders#missing]]\n"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	orders := &pearl.Pearl{
+		ID: "db.orders", Name: "orders", Namespace: "db",
+		Type: pearl.TypeTable, Status: pearl.StatusActive,
+		Globs: []string{"src/orders/**"}, Scopes: []string{"pci"},
+		CreatedAt: now, UpdatedAt: now,
+	}
+	if err := store.Create(orders, "# orders\n"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	b, err := Assemble(store, Request{
+		IDs:      []string{"db.users#notes,nope", "missing"},
+		Paths:    []string{"src/orders/model.go"},
+		Scopes:   [][]string{{"payments", "pci"}},
+		WithRefs: true,
+		Stale: func(p *pearl.Pearl) string {
+			if p.ID == "db.orders" {
+				return "overdue"
+			}
+			return ""
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got := make(map[string][]string)
+	var order []string
+	for _, d := range b.Documents {
+		order = append(order, d.ID)
+		got[d.ID] = d.Source
+	}
+	if want := []string{"db.users", "db.orders"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("documents = %v, want %v", order, want)
+	}
+	want := map[string][]string{
+		"db.users":  {"id", "scope:payments"},
+		"db.orders": {"glob:src/orders/**", "scope:pci", "ref:db.users"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sources = %v, want %v", got, want)
+	}
+	if d := b.Documents[0]; !strings.HasPrefix(d.Content, "## Notes\n\nSoft-deleted.") || d.Stale != "" {
+		t.Errorf("db.users = content %q, stale %q; want only notes, not stale", d.Content, d.Stale)
+	}
+	if d := b.Documents[1]; d.Stale != "overdue" {
+		t.Errorf("db.orders stale = %q, want overdue", d.Stale)
+	}
+	if want := []string{"missing"}; !reflect.DeepEqual(b.Missing, want) {
+		t.Errorf("missing = %v, want %v", b.Missing, want)
+	}
+	if len(b.Warnings) != 2 {
+		t.Errorf("warnings = %q, want the missing section and the broken embed", b.Warnings)
+	}
+}
+
+func TestAssembleWholePearlOverridesFragment(t *testing.T) {
+	store := setupBundleTestStore(t)
+
+	now := time.Now()
+	users := &pearl.Pearl{
+		ID: "db.users", Name: "users", Namespace: "db",
+		Type: pearl.TypeTable, Status: pearl.StatusActive,
+		CreatedAt: now, UpdatedAt: now,
+	}
+	if err := store.Create(users, "# users\n\n## Notes\n\nSoft-deleted.\n"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	b, err := Assemble(store, Request{IDs: []string{"db.users#notes", "db.users"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b.Documents) != 1 || b.Documents[0].Content == "" || len(b.Documents[0].Source) != 1 {
+		t.Fatalf("documents = %+v, want db.users once, whole", b.Documents)
+	}
+	if b.Missing == nil || len(b.Missing) != 0 {
+		t.Errorf("missing = %#v, want empty", b.Missing)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	store := setupBundleTestStore(t)
+
+	now := time.Now()
+	users := &pearl.Pearl{
+		ID: "db.users", Name: "users", Namespace: "db",
+		Type: pearl.TypeTable, Status: pearl.StatusActive,
+		CreatedAt: now, UpdatedAt: now,
+	}
+	content := "# users\n\n## Columns\n\n| Column | Type |\n|---|---|\n| id | int |\n\n## Access Patterns\n\nBy email.\n\n## Notes\n\nSoft-deleted.\n"
+	if err := store.Create(users, content); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	auth := &pearl.Pearl{
+		ID: "api.auth", Name: "auth", Namespace: "api",
+		Type: pearl.TypeAPI, Status: pearl.StatusActive,
+		CreatedAt: now, UpdatedAt: now,
+	}
+	if err := store.Create(auth, "# auth\n"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	tests := []struct {
+		name     string
+		p        *pearl.Pearl
+		brief    bool
+		sections []string
+		cfg      config.ContextConfig
+		want     string
+	}{
+		{name: "full", p: users, want: content},
+		{name: "sections", p: users, sections: []string{"Access Patterns", "columns"}, want: "## Columns\n\n| Column | Type |\n|---|---|\n| id | int |\n\n## Access Patterns\n\nBy email.\n"},
+		{name: "brief table defaults", p: users, brief: true, want: "## Columns\n\n| Column | Type |\n|---|---|\n| id | int |\n"},
+		{name: "brief sections override defaults", p: users, brief: true, sections: []string{"access-patterns"}, want: "## Access Patterns\n\nBy email.\n"},
+		{name: "brief configured off", p: users, brief: true, cfg: config.ContextConfig{BriefSections: map[string][]string{"table": {}}}, want: ""},
+		{name: "brief without the sections", p: auth, brief: true, want: ""},
+		{name: "no matching sections", p: users, sections: []string{"endpoints"}, want: ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, warnings := Select(store, tt.p, tt.brief, tt.sections, tt.cfg)
+			if got != tt.want {
+				t.Errorf("Select = %q, want %q", got, tt.want)
+			}
+			if len(warnings) != 0 {
+				t.Errorf("warnings = %q, want none", warnings)
+			}
+		})
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	if got, want := SplitList(" a, ,b,"), []string{"a", "b"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SplitList = %q, want %q", got, want)
+	}
+	if got := SplitList(""); got != nil {
+		t.Errorf("SplitList(\"\") = %q, want nil", got)
+	}
+}
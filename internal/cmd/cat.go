@@ -12,15 +12,22 @@ var catCmd = &cobra.Command{
 	Short: "Display pearl content",
 	Long: `Display the markdown content of a pearl.
 
+Embeds like ![[shared.auth#tokens]] are replaced with the content of the
+pearl or section they name; --raw shows the file as written.
+
 Examples:
   pearls cat db.postgres.users
-  pearls cat api.stripe.customers`,
+  pearls cat api.stripe.customers
+  pearls cat api.stripe.customers --raw`,
 	Args: cobra.ExactArgs(1),
 	RunE: runCat,
 }
 
+var catRaw bool
+
 func init() {
 	rootCmd.AddCommand(catCmd)
+	catCmd.Flags().BoolVar(&catRaw, "raw", false, "Do not expand embedded pearls")
 }
 
 func runCat(cmd *cobra.Command, args []string) error {
@@ -48,6 +55,9 @@ func runCat(cmd *cobra.Command, args []string) error {
 	if err != nil {
 		return fmt.Errorf("read content: %w", err)
 	}
+	if !catRaw {
+		content = transclude(store, p.ID, content)
+	}
 
 	fmt.Fprint(os.Stdout, content)
 	return nil
@@ -1,13 +1,13 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
-	"strings"
 
 	"github.com/spf13/cobra"
 
+	"github.com/justrnr500/pearls/internal/bundle"
+	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/render"
 	"github.com/justrnr500/pearls/internal/storage"
 )
 
@@ -17,27 +17,38 @@ var clutchCmd = &cobra.Command{
 	Long: `Output concatenated content of all required pearls sorted by priority.
 
 This command outputs pearls that have been marked as required, ordered
-by priority (highest first). The output format matches the context command.
+by priority (highest first). The output formats match the context command.
 
 Examples:
   pearls clutch
   pearls clutch --brief
-  pearls clutch --json`,
+  pearls clutch --sections overview
+  pearls clutch --json
+  pearls clutch --format xml --toc
+  pearls clutch --template '{{.ID}} (priority {{.Priority}})'`,
 	RunE: runClutch,
 }
 
 var (
-	clutchBrief bool
-	clutchJSON  bool
+	clutchBrief    bool
+	clutchSections string
+	clutchTOC      bool
+	clutchOutput   outputFlags
 )
 
 func init() {
 	rootCmd.AddCommand(clutchCmd)
 	clutchCmd.Flags().BoolVar(&clutchBrief, "brief", false, "Only include metadata, not full content")
-	clutchCmd.Flags().BoolVar(&clutchJSON, "json", false, "Output as JSON")
+	clutchCmd.Flags().StringVar(&clutchSections, "sections", "", "Only include these comma-separated content sections, e.g. columns,notes")
+	clutchCmd.Flags().BoolVar(&clutchTOC, "toc", false, "Start with a table of contents")
+	addOutputFlags(clutchCmd, &clutchOutput)
 }
 
 func runClutch(cmd *cobra.Command, args []string) error {
+	if err := clutchOutput.validate(); err != nil {
+		return err
+	}
+
 	store, _, err := getStore()
 	if err != nil {
 		return err
@@ -50,62 +61,18 @@ func runClutch(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("list required pearls: %w", err)
 	}
 
-	if clutchJSON {
-		enc := json.NewEncoder(os.Stdout)
-		enc.SetIndent("", "  ")
-		return enc.Encode(map[string]interface{}{
-			"pearls": pearls,
-			"count":  len(pearls),
-		})
+	var contextConfig config.ContextConfig
+	if cfg, err := getConfig(); err == nil {
+		contextConfig = cfg.Context
 	}
+	sections := bundle.SplitList(clutchSections)
 
-	if len(pearls) == 0 {
-		return nil
-	}
-
-	var output strings.Builder
-
-	for i, p := range pearls {
-		if i > 0 {
-			output.WriteString("\n---\n\n")
-		}
-
-		if clutchBrief {
-			output.WriteString(fmt.Sprintf("## %s\n\n", p.ID))
-			output.WriteString(fmt.Sprintf("- **Type:** %s\n", p.Type))
-			output.WriteString(fmt.Sprintf("- **Status:** %s\n", p.Status))
-			if p.Description != "" {
-				output.WriteString(fmt.Sprintf("- **Description:** %s\n", p.Description))
-			}
-			if len(p.Tags) > 0 {
-				output.WriteString(fmt.Sprintf("- **Tags:** %s\n", strings.Join(p.Tags, ", ")))
-			}
-			if p.Connection != nil {
-				output.WriteString(fmt.Sprintf("- **Connection:** %s", p.Connection.Type))
-				if p.Connection.Host != "" {
-					output.WriteString(fmt.Sprintf(" @ %s", p.Connection.Host))
-				}
-				if p.Connection.Database != "" {
-					output.WriteString(fmt.Sprintf("/%s", p.Connection.Database))
-				}
-				output.WriteString("\n")
-			}
-			output.WriteString("\n")
-		} else {
-			content, err := store.GetContent(p)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: could not read content for %s: %v\n", p.ID, err)
-				output.WriteString(fmt.Sprintf("## %s\n\n", p.ID))
-				output.WriteString(fmt.Sprintf("%s\n\n", p.Description))
-			} else {
-				output.WriteString(content)
-				if !strings.HasSuffix(content, "\n") {
-					output.WriteString("\n")
-				}
-			}
-		}
+	docs := make([]render.Document, 0, len(pearls))
+	for _, p := range pearls {
+		d := render.Document{Pearl: p, Source: []string{"required"}}
+		d.Content = selectContent(store, p, clutchBrief, sections, contextConfig)
+		docs = append(docs, d)
 	}
 
-	fmt.Print(output.String())
-	return nil
+	return clutchOutput.render(documentsResult(docs, render.DocumentOptions{Brief: clutchBrief, TOC: clutchTOC}))
 }
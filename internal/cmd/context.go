@@ -3,9 +3,15 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+
+	"github.com/justrnr500/pearls/internal/bundle"
+	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/render"
+	"github.com/justrnr500/pearls/internal/storage"
 )
 
 var contextCmd = &cobra.Command{
@@ -20,30 +26,75 @@ Examples:
   pearls context db.postgres.users
   pearls context db.postgres.users db.postgres.orders
   pearls context db.postgres.users --with-refs
+  pearls context db.postgres.users#columns
+  pearls context db.postgres.users#columns,indexes db.postgres.orders
+  pearls context --scope payments --sections access-patterns,notes
   pearls context --for src/api/handler.go
+  pearls context --for src/api/handler.go --for src/api/routes.go
   pearls context --scope backend
-  pearls context --for src/api/handler.go --scope backend`,
+  pearls context --scope payments,auth
+  pearls context --scope payments,pci --all-scopes
+  pearls context --for src/api/handler.go --scope backend
+  pearls context --scope backend --flag-stale
+  pearls context --query 'type:convention tag:api'
+  pearls context --query 'required:true OR priority:>=5'
+  pearls context --scope payments --json
+  pearls context --for src/api/handler.go --format xml --toc
+
+--query takes the query language described in 'pearls list --help'.
+
+Sections are named by their heading's anchor, as GitHub renders it
+("## Access Patterns" is access-patterns), or by the heading text. An
+ID#fragment includes only those sections of that pearl; --sections does
+the same for every pearl, which falls back to its description when it has
+none of them. --brief includes the sections set for the pearl's type in
+context.brief_sections in config: by default columns or schema for tables,
+endpoints for APIs, and tables for databases and schemas.
+
+Embeds like ![[shared.auth#tokens]] in content are replaced with the
+content of the pearl or section they name.
+
+Text output joins pearls with rules. For prompts, --format xml wraps each
+pearl in a <pearl> block, and --format markdown between comments, that
+carry its ID, type, status, and other metadata, plus a source attribute
+saying why it was included: "id", "glob:<pattern>", "scope:<name>",
+"query", or "ref:<id>". JSON output carries the same reasons in "source".`,
 	RunE: runContext,
 }
 
 var (
 	contextWithRefs bool
 	contextBrief    bool
-	contextFor      string
+	contextSections string
+	contextTOC      bool
+	contextFor      []string
 	contextScope    string
+	contextAllScope bool
+	contextQuery    string
+	contextOutput   outputFlags
+	contextStale    bool
 )
 
 func init() {
 	rootCmd.AddCommand(contextCmd)
 	contextCmd.Flags().BoolVar(&contextWithRefs, "with-refs", false, "Include referenced pearls")
 	contextCmd.Flags().BoolVar(&contextBrief, "brief", false, "Only include metadata, not full content")
-	contextCmd.Flags().StringVar(&contextFor, "for", "", "File path (relative to repo root) to match pearls by glob")
-	contextCmd.Flags().StringVar(&contextScope, "scope", "", "Scope name to match pearls")
+	contextCmd.Flags().StringVar(&contextSections, "sections", "", "Only include these comma-separated content sections, e.g. columns,access-patterns")
+	contextCmd.Flags().BoolVar(&contextTOC, "toc", false, "Start with a table of contents")
+	contextCmd.Flags().StringArrayVar(&contextFor, "for", nil, "File path (relative to repo root) to match pearls by glob; repeat for several files")
+	contextCmd.Flags().StringVar(&contextScope, "scope", "", "Comma-separated scopes to match pearls, including child scopes (any by default)")
+	contextCmd.Flags().BoolVar(&contextAllScope, "all-scopes", false, "With --scope, require every scope instead of any")
+	contextCmd.Flags().StringVarP(&contextQuery, "query", "q", "", "Include pearls matching a query, e.g. 'type:convention tag:api'")
+	contextCmd.Flags().BoolVar(&contextStale, "flag-stale", false, "Warn about pearls overdue for review (see 'pearls stale')")
+	addOutputFlags(contextCmd, &contextOutput)
 }
 
 func runContext(cmd *cobra.Command, args []string) error {
-	if len(args) == 0 && contextFor == "" && contextScope == "" {
-		return fmt.Errorf("at least one pearl ID, --for, or --scope must be provided")
+	if len(args) == 0 && len(contextFor) == 0 && contextScope == "" && contextQuery == "" {
+		return fmt.Errorf("at least one pearl ID, --for, --scope, or --query must be provided")
+	}
+	if err := contextOutput.validate(); err != nil {
+		return err
 	}
 
 	store, _, err := getStore()
@@ -52,118 +103,65 @@ func runContext(cmd *cobra.Command, args []string) error {
 	}
 	defer store.Close()
 
-	// Collect all IDs to include
-	ids := make([]string, 0, len(args))
-	seen := make(map[string]bool)
-
-	// Add requested IDs
-	for _, id := range args {
-		if !seen[id] {
-			ids = append(ids, id)
-			seen[id] = true
-		}
+	req := bundle.Request{
+		IDs:      args,
+		Paths:    contextFor,
+		WithRefs: contextWithRefs,
+		Brief:    contextBrief,
+		Sections: bundle.SplitList(contextSections),
 	}
-
-	// Add pearls matched by --for flag
-	if contextFor != "" {
-		matched, err := store.FindByGlob(contextFor)
-		if err != nil {
-			return fmt.Errorf("find by glob: %w", err)
-		}
-		for _, p := range matched {
-			if !seen[p.ID] {
-				ids = append(ids, p.ID)
-				seen[p.ID] = true
-			}
-		}
+	if req.Scopes, err = scopeFilter(configuredScopes(), contextScope, contextAllScope); err != nil {
+		return fmt.Errorf("invalid --scope: %w", err)
 	}
-
-	// Add pearls matched by --scope flag
-	if contextScope != "" {
-		matched, err := store.FindByScope(contextScope)
-		if err != nil {
-			return fmt.Errorf("find by scope: %w", err)
-		}
-		for _, p := range matched {
-			if !seen[p.ID] {
-				ids = append(ids, p.ID)
-				seen[p.ID] = true
-			}
-		}
+	if req.Where, err = compileQuery(contextQuery); err != nil {
+		return err
 	}
 
-	// Add referenced IDs if requested
-	if contextWithRefs {
-		for _, id := range args {
-			p, err := store.Get(id)
-			if err != nil || p == nil {
-				continue
-			}
-			for _, refID := range p.References {
-				if !seen[refID] {
-					ids = append(ids, refID)
-					seen[refID] = true
+	// Stale warnings: on by flag or by freshness.flag_context in config
+	if cfg, err := getConfig(); err == nil {
+		if contextStale || cfg.Freshness.FlagContext {
+			freshness, now := cfg.Freshness, time.Now()
+			req.Stale = func(p *pearl.Pearl) string {
+				if stale, _ := checkStale(freshness, p, now); stale != nil {
+					return stale.describe()
 				}
+				return ""
 			}
 		}
+		req.Config = cfg.Context
 	}
 
-	// Generate output
-	var output strings.Builder
+	b, err := bundle.Assemble(store, req)
+	if err != nil {
+		return err
+	}
+	for _, id := range b.Missing {
+		fmt.Fprintf(os.Stderr, "Warning: pearl not found: %s\n", id)
+	}
+	warn(b.Warnings)
 
-	for i, id := range ids {
-		p, err := store.Get(id)
-		if err != nil {
-			return fmt.Errorf("get pearl %s: %w", id, err)
-		}
-		if p == nil {
-			fmt.Fprintf(os.Stderr, "Warning: pearl not found: %s\n", id)
-			continue
-		}
+	return contextOutput.render(documentsResult(b.Documents, render.DocumentOptions{Brief: contextBrief, TOC: contextTOC}))
+}
 
-		if i > 0 {
-			output.WriteString("\n---\n\n")
-		}
+// selectContent returns the content to show for a pearl, as
+// bundle.Select does, warning on stderr about embeds it cannot expand.
+func selectContent(store *storage.Store, p *pearl.Pearl, brief bool, sections []string, cfg config.ContextConfig) string {
+	content, warnings := bundle.Select(store, p, brief, sections, cfg)
+	warn(warnings)
+	return content
+}
 
-		if contextBrief {
-			// Brief mode: just metadata
-			output.WriteString(fmt.Sprintf("## %s\n\n", p.ID))
-			output.WriteString(fmt.Sprintf("- **Type:** %s\n", p.Type))
-			output.WriteString(fmt.Sprintf("- **Status:** %s\n", p.Status))
-			if p.Description != "" {
-				output.WriteString(fmt.Sprintf("- **Description:** %s\n", p.Description))
-			}
-			if len(p.Tags) > 0 {
-				output.WriteString(fmt.Sprintf("- **Tags:** %s\n", strings.Join(p.Tags, ", ")))
-			}
-			if p.Connection != nil {
-				output.WriteString(fmt.Sprintf("- **Connection:** %s", p.Connection.Type))
-				if p.Connection.Host != "" {
-					output.WriteString(fmt.Sprintf(" @ %s", p.Connection.Host))
-				}
-				if p.Connection.Database != "" {
-					output.WriteString(fmt.Sprintf("/%s", p.Connection.Database))
-				}
-				output.WriteString("\n")
-			}
-			output.WriteString("\n")
-		} else {
-			// Full mode: include content
-			content, err := store.GetContent(p)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Warning: could not read content for %s: %v\n", id, err)
-				// Fall back to brief
-				output.WriteString(fmt.Sprintf("## %s\n\n", p.ID))
-				output.WriteString(fmt.Sprintf("%s\n\n", p.Description))
-			} else {
-				output.WriteString(content)
-				if !strings.HasSuffix(content, "\n") {
-					output.WriteString("\n")
-				}
-			}
-		}
-	}
+// transclude expands the embeds in a pearl's content, warning on stderr
+// about any it cannot expand.
+func transclude(store *storage.Store, id, content string) string {
+	content, warnings := bundle.Transclude(store, id, content)
+	warn(warnings)
+	return content
+}
 
-	fmt.Print(output.String())
-	return nil
+// warn prints each warning on stderr.
+func warn(warnings []string) {
+	for _, w := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", w)
+	}
 }
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/coverage"
+	"github.com/justrnr500/pearls/internal/storage"
+)
+
+var coverageCmd = &cobra.Command{
+	Use:   "coverage [path]",
+	Short: "Show which files get context from pearl globs",
+	Long: `Match every git-tracked file against every pearl's globs and report
+how much of the codebase gets injected context.
+
+The report shows a directory tree with coverage percentages, the highest
+directories with no covered files, files matched by many pearls along with
+the context they pull in, and how many files each pearl matches. Pass a path
+to limit the report to files under it.
+
+Context sizes are estimates: the content of every matching pearl, at about
+4 bytes per token.
+
+Examples:
+  pearls coverage
+  pearls coverage src/api
+  pearls coverage --depth 3
+  pearls coverage --over 2      # Flag files matched by 2+ pearls
+  pearls coverage --json`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: runCoverage,
+}
+
+var (
+	coverageJSON  bool
+	coverageDepth int
+	coverageOver  int
+)
+
+func init() {
+	rootCmd.AddCommand(coverageCmd)
+	coverageCmd.Flags().BoolVar(&coverageJSON, "json", false, "Output as JSON")
+	coverageCmd.Flags().IntVar(&coverageDepth, "depth", 2, "Directory levels to show in the tree (0 for all)")
+	coverageCmd.Flags().IntVar(&coverageOver, "over", 3, "Report files matched by at least this many pearls (0 to disable)")
+}
+
+func runCoverage(cmd *cobra.Command, args []string) error {
+	store, paths, err := getStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	// Globs are relative to the project root, the directory holding .pearls
+	root := filepath.Dir(paths.Root)
+	files, err := projectFiles(root)
+	if err != nil {
+		return fmt.Errorf("list project files: %w", err)
+	}
+	files = coverage.Within(withoutPearlsDir(files), coveragePath(root, args))
+
+	pearls, err := store.List(storage.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("list pearls: %w", err)
+	}
+
+	sizes := make(map[string]int64)
+	for _, p := range pearls {
+		if len(p.Globs) == 0 || p.ContentPath == "" {
+			continue
+		}
+		if info, err := os.Stat(store.Content().FullPath(p.ContentPath)); err == nil {
+			sizes[p.ID] = info.Size()
+		}
+	}
+
+	report := coverage.Analyze(files, pearls, coverage.Options{Sizes: sizes, OverCovered: coverageOver})
+
+	if coverageJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+
+	if report.Files == 0 {
+		fmt.Println("No files to check")
+		return nil
+	}
+
+	fmt.Printf("Coverage: %d of %d files (%.0f%%) matched by %d pearls with globs\n\n",
+		report.Covered, report.Files, report.Percent, len(report.Pearls))
+	printCoverageTree(report.Tree, 0)
+
+	if len(report.Uncovered) > 0 {
+		fmt.Printf("\nUncovered directories:\n")
+		for _, d := range report.Uncovered {
+			fmt.Printf("  %s/ (%d files)\n", d.Path, d.Files)
+		}
+	}
+
+	if len(report.OverCovered) > 0 {
+		fmt.Printf("\nOver-covered files (%d+ pearls):\n", coverageOver)
+		for _, f := range report.OverCovered {
+			fmt.Printf("  %s: %d pearls, ~%s tokens\n", f.Path, len(f.Pearls), formatTokens(f.Tokens))
+			fmt.Printf("    %s\n", strings.Join(f.Pearls, ", "))
+		}
+	}
+
+	if len(report.Pearls) > 0 {
+		fmt.Printf("\nMatches per pearl:\n")
+		width := 0
+		for _, pm := range report.Pearls {
+			width = max(width, len(pm.ID))
+		}
+		for _, pm := range report.Pearls {
+			fmt.Printf("  %-*s %5d files\n", width, pm.ID, pm.Matches)
+		}
+	}
+
+	return nil
+}
+
+// coveragePath resolves the optional path argument against the project
+// root, so it works from any directory.
+func coveragePath(root string, args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	path := args[0]
+	if abs, err := filepath.Abs(path); err == nil {
+		if rel, err := filepath.Rel(root, abs); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+	}
+	return filepath.ToSlash(path)
+}
+
+// withoutPearlsDir drops the catalog's own files.
+func withoutPearlsDir(files []string) []string {
+	var kept []string
+	for _, f := range files {
+		if f != config.DirName && !strings.HasPrefix(f, config.DirName+"/") {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+func printCoverageTree(d *coverage.Dir, depth int) {
+	name := d.Path
+	if depth > 0 {
+		name = strings.Repeat("  ", depth-1) + filepath.Base(d.Path) + "/"
+	}
+	marker := ""
+	if d.Covered == 0 {
+		marker = "  ✗"
+	}
+	fmt.Printf("  %-40s %5d/%-5d %4.0f%%%s\n", name, d.Covered, d.Files, d.Percent(), marker)
+
+	// Everything under an uncovered directory is uncovered too
+	if d.Covered == 0 || (coverageDepth > 0 && depth >= coverageDepth) {
+		return
+	}
+	for _, c := range d.Dirs {
+		printCoverageTree(c, depth+1)
+	}
+}
+
+// formatTokens abbreviates thousands, e.g. 3200 as "3.2k".
+func formatTokens(n int64) string {
+	if n < 1000 {
+		return fmt.Sprintf("%d", n)
+	}
+	return fmt.Sprintf("%.1fk", float64(n)/1000)
+}
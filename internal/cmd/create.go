@@ -27,7 +27,8 @@ Examples:
   pearls create db.postgres.users --type table
   pearls create api.stripe.customers --type api -d "Stripe customer records"
   pearls create db.postgres.orders --type table --tag pii --tag core
-  pearls create db.postgres.users --type table --required --priority 10`,
+  pearls create db.postgres.users --type table --required --priority 10
+  pearls create db.postgres.users --type table --owners @alice,@org/data`,
 	Args: cobra.ExactArgs(1),
 	RunE: runCreate,
 }
@@ -38,6 +39,7 @@ var (
 	createTags        []string
 	createGlobs       string
 	createScopes      string
+	createOwners      string
 	createContent     string
 	createJSON        bool
 	createRequired    bool
@@ -49,8 +51,9 @@ func init() {
 	createCmd.Flags().StringVarP(&createType, "type", "t", "table", "Asset type (table, schema, database, api, endpoint, file, bucket, pipeline, dashboard, query, custom)")
 	createCmd.Flags().StringVarP(&createDescription, "description", "d", "", "Brief description")
 	createCmd.Flags().StringSliceVar(&createTags, "tag", nil, "Tags (can be repeated)")
-	createCmd.Flags().StringVar(&createGlobs, "globs", "", "Comma-separated file glob patterns for push-based context injection")
+	createCmd.Flags().StringVar(&createGlobs, "globs", "", "Comma-separated file glob patterns for push-based context injection (prefix with ! to exclude)")
 	createCmd.Flags().StringVar(&createScopes, "scopes", "", "Comma-separated scope names for scope-based injection")
+	createCmd.Flags().StringVar(&createOwners, "owners", "", "Comma-separated owners (people or teams, e.g. @alice,@org/data)")
 	createCmd.Flags().StringVar(&createContent, "content", "", `Inline content (use "-" to read from stdin)`)
 	createCmd.Flags().BoolVar(&createJSON, "json", false, "Output as JSON")
 	createCmd.Flags().BoolVar(&createRequired, "required", false, "Mark pearl as required context")
@@ -96,13 +99,22 @@ func runCreate(cmd *cobra.Command, args []string) error {
 		scopes = strings.Split(createScopes, ",")
 	}
 
-	// Validate globs and scopes
+	var owners []string
+	if createOwners != "" {
+		owners = strings.Split(createOwners, ",")
+	}
+
+	// Validate globs, scopes, and owners
 	if err := pearl.ValidateGlobs(globs); err != nil {
 		return fmt.Errorf("invalid --globs: %w", err)
 	}
 	if err := pearl.ValidateScopes(scopes); err != nil {
 		return fmt.Errorf("invalid --scopes: %w", err)
 	}
+	warnUndeclaredScopes(scopes)
+	if err := pearl.ValidateOwners(owners); err != nil {
+		return fmt.Errorf("invalid --owners: %w", err)
+	}
 
 	// Parse namespace and name from ID
 	namespace := pearl.ParentNamespace(id)
@@ -122,6 +134,7 @@ func runCreate(cmd *cobra.Command, args []string) error {
 		Tags:        createTags,
 		Globs:       globs,
 		Scopes:      scopes,
+		Owners:      owners,
 		Description: createDescription,
 		Required:    createRequired,
 		Priority:    createPriority,
@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/justrnr500/pearls/internal/history"
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+var diffCmd = &cobra.Command{
+	Use:   "diff <id> [rev]",
+	Short: "Show changes to a pearl since a revision",
+	Long: `Show metadata and content changes between a revision and the pearl's
+current state. Without a revision, shows what the latest change did.
+
+Revisions are numbers from 'pearls history' (r3 or 3), or commit hashes for
+history reconstructed from git.
+
+Examples:
+  pearls diff db.postgres.users        # Latest change
+  pearls diff db.postgres.users 2      # Everything since r2
+  pearls diff db.postgres.users --json`,
+	Args: cobra.RangeArgs(1, 2),
+	RunE: runDiff,
+}
+
+var diffJSON bool
+
+func init() {
+	rootCmd.AddCommand(diffCmd)
+	diffCmd.Flags().BoolVar(&diffJSON, "json", false, "Output as JSON")
+}
+
+// diffResult is the JSON output of pearls diff.
+type diffResult struct {
+	ID      string                `json:"id"`
+	From    string                `json:"from"`
+	To      string                `json:"to"`
+	Fields  []history.FieldChange `json:"fields"`
+	Content string                `json:"content_diff,omitempty"`
+}
+
+func runDiff(cmd *cobra.Command, args []string) error {
+	id := args[0]
+
+	store, paths, err := getStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	source, revs, err := loadRevisions(store, paths, id)
+	if err != nil {
+		return err
+	}
+
+	// Default: the revision before the latest, so the diff shows the last change
+	var from *history.Revision
+	switch {
+	case len(args) == 2:
+		if from, err = history.Find(revs, args[1]); err != nil {
+			return err
+		}
+	case len(revs) >= 2:
+		from = revs[len(revs)-2]
+	case len(revs) == 0:
+		return fmt.Errorf("no history for %s", id)
+	}
+
+	// Old side: nothing before the first revision; a deletion leaves nothing
+	var oldPearl *pearl.Pearl
+	var oldContent string
+	fromName := "(none)"
+	if from != nil {
+		fromName = fmt.Sprintf("r%d", from.Rev)
+		if !from.Deleted() {
+			oldPearl = from.Pearl
+			oldContent, _, err = source.Content(from)
+			if err != nil {
+				return err
+			}
+		}
+	}
+
+	// New side: the current state
+	current, err := store.Get(id)
+	if err != nil {
+		return fmt.Errorf("get pearl: %w", err)
+	}
+	var newContent string
+	if current != nil && current.ContentPath != "" && store.Content().Exists(current.ContentPath) {
+		if newContent, err = store.Content().Read(current.ContentPath); err != nil {
+			return fmt.Errorf("read content: %w", err)
+		}
+	}
+
+	result := diffResult{
+		ID:      id,
+		From:    fromName,
+		To:      "current",
+		Fields:  history.Changes(oldPearl, current),
+		Content: history.UnifiedDiff(oldContent, newContent, id+"@"+fromName, id+"@current"),
+	}
+
+	if diffJSON {
+		if result.Fields == nil {
+			result.Fields = []history.FieldChange{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+
+	if len(result.Fields) == 0 && result.Content == "" {
+		fmt.Printf("No changes to %s since %s\n", id, fromName)
+		return nil
+	}
+
+	fmt.Printf("%s: %s → current\n", id, fromName)
+	if current == nil {
+		fmt.Println("(deleted)")
+	}
+	if len(result.Fields) > 0 {
+		fmt.Println("\nMetadata:")
+		for _, c := range result.Fields {
+			fmt.Printf("  %s\n", c.Field)
+			if len(c.Before) > 0 {
+				fmt.Printf("    - %s\n", c.Before)
+			}
+			if len(c.After) > 0 {
+				fmt.Printf("    + %s\n", c.After)
+			}
+		}
+	}
+	if result.Content != "" {
+		fmt.Println("\nContent:")
+		fmt.Print(result.Content)
+	}
+
+	return nil
+}
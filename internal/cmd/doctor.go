@@ -1,14 +1,25 @@
 package cmd
 
 import (
-	"encoding/json"
+	"bufio"
 	"fmt"
+	"io"
+	"io/fs"
 	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
 
 	"github.com/spf13/cobra"
 
 	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/markdown"
+	"github.com/justrnr500/pearls/internal/owners"
+	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/render"
 	"github.com/justrnr500/pearls/internal/storage"
+	"github.com/justrnr500/pearls/internal/validate"
 )
 
 var doctorCmd = &cobra.Command{
@@ -16,68 +27,310 @@ var doctorCmd = &cobra.Command{
 	Short: "Check catalog health",
 	Long: `Run health checks on the pearls catalog to diagnose common issues.
 
-Checks:
-  - JSONL/SQLite sync (pearl counts and IDs match)
-  - Orphaned content (markdown files with no pearl)
-  - Missing content (pearls with content_path that doesn't exist)
-  - Broken references (pearls referencing IDs that don't exist)
-  - Config validity (config.yaml parses without errors)`,
+Checks, by severity:
+  error
+    - JSONL/SQLite sync (pearl counts and IDs match)
+    - Duplicate IDs (an ID appears more than once in the JSONL)
+    - Missing content (pearls with content_path that doesn't exist)
+    - Shared content (two pearls with the same content_path)
+    - Broken references (pearls referencing IDs that don't exist)
+    - Wiki-links ([[id]] links and ![[id#section]] embeds in content name
+      pearls and sections that exist)
+    - Parents (parent IDs exist and don't form a cycle)
+    - IDs match names (ID is namespace + "." + name)
+    - Config validity (config.yaml parses without errors)
+  warning
+    - Orphaned content (markdown files with no pearl)
+    - Glob matches (every glob matches at least one file in the project)
+    - Declared scopes (pearls only use scopes declared in config.yaml, once
+      any are declared)
+    - Frontmatter agreement (content frontmatter matches JSONL and SQLite)
+    - Schema version (database migrated, JSONL records in the current format)
+    - Required pearls owned (every required pearl has an owner, directly,
+      inherited, or from CODEOWNERS)
+  info
+    - Content hashes (content edited since pearls last recorded its hash)
+    - Reference cycles (pearls that reference each other in a loop)
+
+Only failed errors make doctor exit non-zero; use --fail-on warning or
+--fail-on info to be stricter.
+
+With --fix, doctor offers a repair for each issue and asks before applying
+it:
+  - JSONL/SQLite drift: resync toward whichever side changed last
+  - Orphaned content: adopt the file as a pearl, or delete it
+  - Missing content: regenerate it from the type's template
+  - Broken references: remap to the closest existing ID, or remove them
+  - Outdated content hashes: refresh them
+
+Examples:
+  pearls doctor
+  pearls doctor --fail-on warning # Fail CI on warnings too
+  pearls doctor --fix             # Confirm each repair
+  pearls doctor --fix --dry-run   # List repairs without applying them
+  pearls doctor --fix --yes       # Apply the default repair for everything`,
 	RunE: runDoctor,
 }
 
-var doctorJSON bool
+var (
+	doctorOutput outputFlags
+	doctorFix    bool
+	doctorDryRun bool
+	doctorYes    bool
+	doctorFailOn string
+)
 
 func init() {
 	rootCmd.AddCommand(doctorCmd)
-	doctorCmd.Flags().BoolVar(&doctorJSON, "json", false, "Output as JSON")
+	addOutputFlags(doctorCmd, &doctorOutput)
+	doctorCmd.Flags().BoolVar(&doctorFix, "fix", false, "Repair issues, confirming each one")
+	doctorCmd.Flags().BoolVar(&doctorDryRun, "dry-run", false, "With --fix, list repairs without applying them")
+	doctorCmd.Flags().BoolVarP(&doctorYes, "yes", "y", false, "With --fix, apply default repairs without prompting")
+	doctorCmd.Flags().StringVar(&doctorFailOn, "fail-on", severityError, "Exit non-zero on failed checks at this severity or above: error, warning, info")
 }
 
 // CheckResult represents the result of a single health check.
 type CheckResult struct {
-	Name   string   `json:"name"`
-	Passed bool     `json:"passed"`
-	Issues []string `json:"issues,omitempty"`
+	Name     string   `json:"name"`
+	Severity string   `json:"severity"` // error, warning, or info; applies if the check fails
+	Passed   bool     `json:"passed"`
+	Issues   []string `json:"issues,omitempty"`
 }
 
 func runDoctor(cmd *cobra.Command, args []string) error {
-	store, paths, err := getStore()
+	if _, ok := severityRank[doctorFailOn]; !ok {
+		return fmt.Errorf("invalid --fail-on %q: must be error, warning, or info", doctorFailOn)
+	}
+	if (doctorDryRun || doctorYes) && !doctorFix {
+		return fmt.Errorf("--dry-run and --yes require --fix")
+	}
+	if err := doctorOutput.validate(); err != nil {
+		return err
+	}
+	if doctorFix && !doctorOutput.isText() && !doctorDryRun && !doctorYes {
+		return fmt.Errorf("--fix cannot prompt with --json, --format, or --template: add --yes or --dry-run")
+	}
+	cmd.SilenceUsage = true
+
+	open := getStore
+	if doctorFix {
+		// A rebuild here would hide which side changed from planSyncFix
+		open = openStore
+	}
+	store, paths, err := open()
 	if err != nil {
 		return err
 	}
 	defer store.Close()
 
-	checks := []CheckResult{
-		checkJSONLSync(store),
-		checkOrphanedContent(store),
-		checkMissingContent(store),
-		checkBrokenReferences(store),
-		checkConfigValidity(paths.Config),
+	checks := runChecks(store, paths)
+	if doctorFix {
+		return runDoctorFix(store, paths, checks)
+	}
+
+	if err := doctorOutput.render(checksResult(checks)); err != nil {
+		return err
+	}
+	return failedChecks(checks, doctorFailOn)
+}
+
+// Check severities, from most to least serious. A failed check exits
+// non-zero when its severity is at or above --fail-on.
+const (
+	severityError   = "error"
+	severityWarning = "warning"
+	severityInfo    = "info"
+)
+
+var severityRank = map[string]int{severityError: 3, severityWarning: 2, severityInfo: 1}
+
+// runChecks runs every health check.
+func runChecks(store *storage.Store, paths *config.Paths) []CheckResult {
+	checks := []struct {
+		severity string
+		result   CheckResult
+	}{
+		{severityError, checkJSONLSync(store)},
+		{severityError, checkDuplicateJSONLIDs(store)},
+		{severityWarning, checkOrphanedContent(store)},
+		{severityError, checkMissingContent(store)},
+		{severityError, checkSharedContentPaths(store)},
+		{severityInfo, checkContentHashes(store)},
+		{severityError, checkBrokenReferences(store)},
+		{severityError, checkWikiLinks(store)},
+		{severityInfo, checkReferenceCycles(store)},
+		{severityError, checkParents(store)},
+		{severityError, checkIDsMatchNames(store)},
+		{severityWarning, checkGlobMatches(store, paths)},
+		{severityWarning, checkDeclaredScopes(store, paths.Config)},
+		{severityError, checkConfigValidity(paths.Config)},
+		{severityWarning, checkFrontmatterAgreement(store)},
+		{severityWarning, checkSchemaVersion(store)},
+		{severityWarning, checkRequiredOwners(store, paths)},
+	}
+
+	results := make([]CheckResult, len(checks))
+	for i, c := range checks {
+		results[i] = c.result
+		results[i].Severity = c.severity
 	}
+	return results
+}
 
-	if doctorJSON {
-		enc := json.NewEncoder(os.Stdout)
-		enc.SetIndent("", "  ")
-		return enc.Encode(checks)
+// checksResult is the output of a doctor run.
+func checksResult(checks []CheckResult) *render.Result {
+	return &render.Result{
+		Kind:  "check",
+		Items: checks,
+		Columns: []render.Column{
+			{Name: "name", Value: func(v interface{}) string { return v.(CheckResult).Name }},
+			{Name: "severity", Value: func(v interface{}) string { return v.(CheckResult).Severity }},
+			{Name: "passed", Value: func(v interface{}) string { return strconv.FormatBool(v.(CheckResult).Passed) }},
+			{Name: "issues", Value: func(v interface{}) string { return strings.Join(v.(CheckResult).Issues, "; ") }},
+		},
+		Text: func(w io.Writer) error {
+			printChecks(w, checks)
+			return nil
+		},
 	}
+}
 
-	allPassed := true
+// printChecks prints check results.
+func printChecks(w io.Writer, checks []CheckResult) {
 	for _, c := range checks {
 		if c.Passed {
-			fmt.Printf("✓ %s\n", c.Name)
-		} else {
-			allPassed = false
-			fmt.Printf("✗ %s\n", c.Name)
-			for _, issue := range c.Issues {
-				fmt.Printf("    %s\n", issue)
+			fmt.Fprintf(w, "✓ %s\n", c.Name)
+			continue
+		}
+		switch c.Severity {
+		case severityWarning:
+			fmt.Fprintf(w, "⚠ %s (warning)\n", c.Name)
+		case severityInfo:
+			fmt.Fprintf(w, "ℹ %s (info)\n", c.Name)
+		default:
+			fmt.Fprintf(w, "✗ %s\n", c.Name)
+		}
+		for _, issue := range c.Issues {
+			fmt.Fprintf(w, "    %s\n", issue)
+		}
+	}
+}
+
+// failedChecks returns an error if any check at or above the failOn
+// severity failed.
+func failedChecks(checks []CheckResult, failOn string) error {
+	for _, c := range checks {
+		if !c.Passed && severityRank[c.Severity] >= severityRank[failOn] {
+			return fmt.Errorf("some checks failed")
+		}
+	}
+	return nil
+}
+
+func runDoctorFix(store *storage.Store, paths *config.Paths, checks []CheckResult) error {
+	fixes := []*fixAction{}
+	if doctorOutput.isText() {
+		printChecks(os.Stdout, checks)
+	}
+
+	prompter := &fixPrompter{in: bufio.NewReader(os.Stdin), out: os.Stdout, all: doctorYes}
+	quit := false
+	process := func(actions []*fixAction) {
+		for _, a := range actions {
+			fixes = append(fixes, a)
+			if doctorDryRun || quit {
+				continue
+			}
+			var opt *fixOption
+			opt, quit = prompter.choose(a)
+			if opt == nil {
+				continue
+			}
+			if err := applyFix(a, opt); err != nil {
+				if doctorOutput.isText() {
+					fmt.Printf("✗ %s: %v\n", a.Issue, err)
+				}
+				continue
+			}
+			if doctorOutput.isText() {
+				fmt.Printf("✓ %s: %s\n", a.Issue, opt.Label)
 			}
 		}
 	}
 
-	if !allPassed {
-		return fmt.Errorf("some checks failed")
+	// Resync first: the other repairs read and write through the database
+	sync, err := planSyncFix(store)
+	if err != nil {
+		return err
+	}
+	if sync != nil {
+		process([]*fixAction{sync})
 	}
 
-	return nil
+	actions, err := planFixes(store)
+	if err != nil {
+		return err
+	}
+	process(actions)
+
+	if doctorDryRun {
+		return doctorOutput.render(fixesResult(fixes, checks, nil, func(w io.Writer) error {
+			if len(fixes) == 0 {
+				fmt.Fprintln(w, "\nNothing to fix")
+				return nil
+			}
+			fmt.Fprintf(w, "\nWould fix %d issues:\n", len(fixes))
+			for _, a := range fixes {
+				fmt.Fprintf(w, "  %-18s %s\n", a.Kind, a.Issue)
+				for i, o := range a.Options {
+					marker := " "
+					if i == 0 {
+						marker = "→"
+					}
+					fmt.Fprintf(w, "  %18s %s %s\n", "", marker, o.Label)
+				}
+			}
+			return nil
+		}))
+	}
+
+	after := runChecks(store, paths)
+	err = doctorOutput.render(fixesResult(fixes, checks, after, func(w io.Writer) error {
+		applied := 0
+		for _, a := range fixes {
+			if a.Applied != "" {
+				applied++
+			}
+		}
+		fmt.Fprintf(w, "\nApplied %d of %d fixes. Checking again:\n", applied, len(fixes))
+		printChecks(w, after)
+		return nil
+	}))
+	if err != nil {
+		return err
+	}
+	return failedChecks(after, doctorFailOn)
+}
+
+// fixesResult is the output of doctor --fix: the repairs, with the checks
+// before and, unless dry-running, after them.
+func fixesResult(fixes []*fixAction, before, after []CheckResult, text func(io.Writer) error) *render.Result {
+	meta := map[string]interface{}{"checks": before}
+	if after != nil {
+		meta["after"] = after
+	}
+	return &render.Result{
+		Kind:  "fix",
+		Items: fixes,
+		Meta:  meta,
+		Columns: []render.Column{
+			{Name: "kind", Value: func(v interface{}) string { return v.(*fixAction).Kind }},
+			{Name: "issue", Value: func(v interface{}) string { return v.(*fixAction).Issue }},
+			{Name: "applied", Value: func(v interface{}) string { return v.(*fixAction).Applied }},
+			{Name: "error", Value: func(v interface{}) string { return v.(*fixAction).Error }},
+		},
+		Text: text,
+	}
 }
 
 func checkJSONLSync(store *storage.Store) CheckResult {
@@ -129,17 +382,58 @@ func checkJSONLSync(store *storage.Store) CheckResult {
 	return CheckResult{Name: fmt.Sprintf("JSONL/SQLite in sync (%d pearls)", dbCount), Passed: true}
 }
 
+func checkSchemaVersion(store *storage.Store) CheckResult {
+	name := "Schema up to date"
+
+	version, err := store.DB().SchemaVersion()
+	if err != nil {
+		return CheckResult{Name: name, Passed: false, Issues: []string{err.Error()}}
+	}
+
+	var issues []string
+	if latest := storage.LatestSchemaVersion(); version != latest {
+		issues = append(issues, fmt.Sprintf("database schema is v%d, expected v%d", version, latest))
+	}
+
+	outdated, err := outdatedJSONLRecords(store)
+	if err != nil {
+		issues = append(issues, err.Error())
+	} else if outdated > 0 {
+		issues = append(issues, fmt.Sprintf("%d JSONL records use an older format: run 'pearls migrate'", outdated))
+	}
+
+	if len(issues) > 0 {
+		return CheckResult{Name: name, Passed: false, Issues: issues}
+	}
+
+	return CheckResult{Name: fmt.Sprintf("Schema up to date (v%d, JSONL format %d)", version, storage.JSONLFormat), Passed: true}
+}
+
 func checkOrphanedContent(store *storage.Store) CheckResult {
 	name := "No orphaned content files"
 
+	orphans, err := orphanedContent(store)
+	if err != nil {
+		return CheckResult{Name: name, Passed: false, Issues: []string{err.Error()}}
+	}
+
+	if len(orphans) > 0 {
+		return CheckResult{Name: name, Passed: false, Issues: orphans}
+	}
+
+	return CheckResult{Name: name, Passed: true}
+}
+
+// orphanedContent lists content files that no pearl points to.
+func orphanedContent(store *storage.Store) ([]string, error) {
 	files, err := store.Content().ListFiles()
 	if err != nil {
-		return CheckResult{Name: name, Passed: false, Issues: []string{fmt.Sprintf("list files: %v", err)}}
+		return nil, fmt.Errorf("list files: %w", err)
 	}
 
 	pearls, err := store.DB().All()
 	if err != nil {
-		return CheckResult{Name: name, Passed: false, Issues: []string{fmt.Sprintf("list pearls: %v", err)}}
+		return nil, fmt.Errorf("list pearls: %w", err)
 	}
 
 	pearlPaths := make(map[string]bool)
@@ -155,27 +449,20 @@ func checkOrphanedContent(store *storage.Store) CheckResult {
 			orphans = append(orphans, f)
 		}
 	}
-
-	if len(orphans) > 0 {
-		return CheckResult{Name: name, Passed: false, Issues: orphans}
-	}
-
-	return CheckResult{Name: name, Passed: true}
+	return orphans, nil
 }
 
 func checkMissingContent(store *storage.Store) CheckResult {
 	name := "No missing content files"
 
-	pearls, err := store.DB().All()
+	pearls, err := missingContent(store)
 	if err != nil {
-		return CheckResult{Name: name, Passed: false, Issues: []string{fmt.Sprintf("list pearls: %v", err)}}
+		return CheckResult{Name: name, Passed: false, Issues: []string{err.Error()}}
 	}
 
 	var missing []string
 	for _, p := range pearls {
-		if p.ContentPath != "" && !store.Content().Exists(p.ContentPath) {
-			missing = append(missing, p.ID)
-		}
+		missing = append(missing, p.ID)
 	}
 
 	if len(missing) > 0 {
@@ -189,12 +476,53 @@ func checkMissingContent(store *storage.Store) CheckResult {
 	return CheckResult{Name: name, Passed: true}
 }
 
+// missingContent lists pearls whose content file does not exist.
+func missingContent(store *storage.Store) ([]*pearl.Pearl, error) {
+	pearls, err := store.DB().All()
+	if err != nil {
+		return nil, fmt.Errorf("list pearls: %w", err)
+	}
+
+	var missing []*pearl.Pearl
+	for _, p := range pearls {
+		if p.ContentPath != "" && !store.Content().Exists(p.ContentPath) {
+			missing = append(missing, p)
+		}
+	}
+	return missing, nil
+}
+
 func checkBrokenReferences(store *storage.Store) CheckResult {
 	name := "All references valid"
 
+	refs, err := brokenReferences(store)
+	if err != nil {
+		return CheckResult{Name: name, Passed: false, Issues: []string{err.Error()}}
+	}
+
+	var broken []string
+	for _, r := range refs {
+		broken = append(broken, fmt.Sprintf("%s -> %s", r.from.ID, r.to))
+	}
+
+	if len(broken) > 0 {
+		return CheckResult{Name: name, Passed: false, Issues: broken}
+	}
+
+	return CheckResult{Name: name, Passed: true}
+}
+
+// brokenRef is a reference to a pearl that does not exist.
+type brokenRef struct {
+	from *pearl.Pearl
+	to   string
+}
+
+// brokenReferences lists references to pearls that do not exist.
+func brokenReferences(store *storage.Store) ([]brokenRef, error) {
 	pearls, err := store.DB().All()
 	if err != nil {
-		return CheckResult{Name: name, Passed: false, Issues: []string{fmt.Sprintf("list pearls: %v", err)}}
+		return nil, fmt.Errorf("list pearls: %w", err)
 	}
 
 	ids := make(map[string]bool)
@@ -202,17 +530,61 @@ func checkBrokenReferences(store *storage.Store) CheckResult {
 		ids[p.ID] = true
 	}
 
-	var broken []string
+	var broken []brokenRef
 	for _, p := range pearls {
 		for _, ref := range p.References {
 			if !ids[ref] {
-				broken = append(broken, fmt.Sprintf("%s -> %s", p.ID, ref))
+				broken = append(broken, brokenRef{from: p, to: ref})
 			}
 		}
 	}
+	return broken, nil
+}
 
-	if len(broken) > 0 {
-		return CheckResult{Name: name, Passed: false, Issues: broken}
+func checkWikiLinks(store *storage.Store) CheckResult {
+	name := "All wiki-links valid"
+
+	graph, err := store.LinkGraph()
+	if err != nil {
+		return CheckResult{Name: name, Passed: false, Issues: []string{err.Error()}}
+	}
+
+	from := make([]string, 0, len(graph))
+	for id := range graph {
+		from = append(from, id)
+	}
+	sort.Strings(from)
+
+	// Parsed content of linked pearls, for section links
+	docs := make(map[string]*markdown.Document)
+	var issues []string
+	for _, id := range from {
+		for _, l := range graph[id] {
+			target, err := store.Get(l.Target)
+			if err != nil {
+				return CheckResult{Name: name, Passed: false, Issues: []string{fmt.Sprintf("get pearl: %v", err)}}
+			}
+			if target == nil {
+				issues = append(issues, fmt.Sprintf("%s:%d -> %s: pearl not found", id, l.Line, l))
+				continue
+			}
+			if l.Section == "" {
+				continue
+			}
+			doc, ok := docs[l.Target]
+			if !ok {
+				content, _ := store.GetContent(target)
+				doc = markdown.Parse(content, 1)
+				docs[l.Target] = doc
+			}
+			if doc.FindAnchor(l.Section) == nil {
+				issues = append(issues, fmt.Sprintf("%s:%d -> %s: no such section", id, l.Line, l))
+			}
+		}
+	}
+
+	if len(issues) > 0 {
+		return CheckResult{Name: name, Passed: false, Issues: issues}
 	}
 
 	return CheckResult{Name: name, Passed: true}
@@ -221,10 +593,445 @@ func checkBrokenReferences(store *storage.Store) CheckResult {
 func checkConfigValidity(configPath string) CheckResult {
 	name := "Config valid"
 
-	_, err := config.Load(configPath)
+	cfg, err := config.Load(configPath)
+	if err != nil {
+		return CheckResult{Name: name, Passed: false, Issues: []string{err.Error()}}
+	}
+	if err := cfg.Validate(); err != nil {
+		return CheckResult{Name: name, Passed: false, Issues: []string{err.Error()}}
+	}
+	if _, err := validate.New(cfg.Validation); err != nil {
+		return CheckResult{Name: name, Passed: false, Issues: []string{err.Error()}}
+	}
+
+	return CheckResult{Name: name, Passed: true}
+}
+
+func checkFrontmatterAgreement(store *storage.Store) CheckResult {
+	name := "Frontmatter agrees with metadata"
+
+	jsonlPearls, err := store.JSONL().ReadAll()
+	if err != nil {
+		return CheckResult{Name: name, Passed: false, Issues: []string{fmt.Sprintf("read JSONL: %v", err)}}
+	}
+	jsonlByID := make(map[string]*pearl.Pearl)
+	for _, p := range jsonlPearls {
+		jsonlByID[p.ID] = p
+	}
+
+	pearls, err := store.DB().All()
+	if err != nil {
+		return CheckResult{Name: name, Passed: false, Issues: []string{fmt.Sprintf("list pearls: %v", err)}}
+	}
+
+	var issues []string
+	for _, p := range pearls {
+		if p.ContentPath == "" || !store.Content().Exists(p.ContentPath) {
+			continue
+		}
+		raw, err := store.Content().Read(p.ContentPath)
+		if err != nil {
+			issues = append(issues, fmt.Sprintf("%s: %v", p.ID, err))
+			continue
+		}
+		fm, _, err := storage.ParseFrontmatter(raw)
+		if err != nil {
+			issues = append(issues, fmt.Sprintf("%s: %v", p.ID, err))
+			continue
+		}
+		if fm == nil {
+			continue
+		}
+
+		for _, d := range fm.Diff(p) {
+			issues = append(issues, fmt.Sprintf("%s: %s (SQLite)", p.ID, d))
+		}
+		if jp := jsonlByID[p.ID]; jp != nil {
+			for _, d := range fm.Diff(jp) {
+				issues = append(issues, fmt.Sprintf("%s: %s (JSONL)", p.ID, d))
+			}
+		}
+	}
+
+	if len(issues) > 0 {
+		return CheckResult{Name: name, Passed: false, Issues: issues}
+	}
+
+	return CheckResult{Name: name, Passed: true}
+}
+
+func checkRequiredOwners(store *storage.Store, paths *config.Paths) CheckResult {
+	name := "Required pearls have owners"
+
+	pearls, err := store.DB().All()
+	if err != nil {
+		return CheckResult{Name: name, Passed: false, Issues: []string{fmt.Sprintf("list pearls: %v", err)}}
+	}
+
+	resolver, err := ownerResolver(store, paths)
+	if err != nil {
+		return CheckResult{Name: name, Passed: false, Issues: []string{err.Error()}}
+	}
+
+	var unowned []string
+	for _, p := range pearls {
+		if !p.Required || p.Status != pearl.StatusActive {
+			continue
+		}
+		res, err := resolver.Resolve(p)
+		if err != nil {
+			return CheckResult{Name: name, Passed: false, Issues: []string{fmt.Sprintf("resolve owners: %v", err)}}
+		}
+		if len(res.Owners) == 0 {
+			unowned = append(unowned, p.ID)
+		}
+	}
+
+	if len(unowned) > 0 {
+		return CheckResult{
+			Name:   name,
+			Passed: false,
+			Issues: []string{fmt.Sprintf("%d required pearls have no owner: %v (set with 'pearls update <id> --owners')", len(unowned), unowned)},
+		}
+	}
+
+	return CheckResult{Name: name, Passed: true}
+}
+
+func checkDuplicateJSONLIDs(store *storage.Store) CheckResult {
+	name := "No duplicate IDs in JSONL"
+
+	jsonlPearls, err := store.JSONL().ReadAll()
+	if err != nil {
+		return CheckResult{Name: name, Passed: false, Issues: []string{fmt.Sprintf("read JSONL: %v", err)}}
+	}
+
+	lines := make(map[string][]int)
+	var order []string
+	for i, p := range jsonlPearls {
+		if lines[p.ID] == nil {
+			order = append(order, p.ID)
+		}
+		lines[p.ID] = append(lines[p.ID], i+1)
+	}
+
+	var issues []string
+	for _, id := range order {
+		if n := len(lines[id]); n > 1 {
+			issues = append(issues, fmt.Sprintf("%s appears %d times (records %v)", id, n, lines[id]))
+		}
+	}
+
+	if len(issues) > 0 {
+		return CheckResult{Name: name, Passed: false, Issues: issues}
+	}
+
+	return CheckResult{Name: name, Passed: true}
+}
+
+func checkSharedContentPaths(store *storage.Store) CheckResult {
+	name := "Content files not shared"
+
+	pearls, err := store.DB().All()
+	if err != nil {
+		return CheckResult{Name: name, Passed: false, Issues: []string{fmt.Sprintf("list pearls: %v", err)}}
+	}
+
+	users := make(map[string][]string)
+	var order []string
+	for _, p := range pearls {
+		if p.ContentPath == "" {
+			continue
+		}
+		if users[p.ContentPath] == nil {
+			order = append(order, p.ContentPath)
+		}
+		users[p.ContentPath] = append(users[p.ContentPath], p.ID)
+	}
+
+	var issues []string
+	for _, path := range order {
+		if ids := users[path]; len(ids) > 1 {
+			issues = append(issues, fmt.Sprintf("%s is used by %s", path, strings.Join(ids, ", ")))
+		}
+	}
+
+	if len(issues) > 0 {
+		return CheckResult{Name: name, Passed: false, Issues: issues}
+	}
+
+	return CheckResult{Name: name, Passed: true}
+}
+
+func checkContentHashes(store *storage.Store) CheckResult {
+	name := "Content hashes current"
+
+	stale, err := staleContentHashes(store)
 	if err != nil {
 		return CheckResult{Name: name, Passed: false, Issues: []string{err.Error()}}
 	}
 
+	if len(stale) > 0 {
+		return CheckResult{
+			Name:   name,
+			Passed: false,
+			Issues: []string{fmt.Sprintf("%d pearls edited outside pearls: %v (refresh with 'pearls doctor --fix')", len(stale), stale)},
+		}
+	}
+
+	return CheckResult{Name: name, Passed: true}
+}
+
+func checkReferenceCycles(store *storage.Store) CheckResult {
+	name := "No reference cycles"
+
+	pearls, err := store.DB().All()
+	if err != nil {
+		return CheckResult{Name: name, Passed: false, Issues: []string{fmt.Sprintf("list pearls: %v", err)}}
+	}
+
+	edges := make(map[string][]string)
+	for _, p := range pearls {
+		edges[p.ID] = p.References
+	}
+
+	var issues []string
+	for _, cycle := range findCycles(pearls, edges) {
+		issues = append(issues, strings.Join(cycle, " -> "))
+	}
+
+	if len(issues) > 0 {
+		return CheckResult{Name: name, Passed: false, Issues: issues}
+	}
+
+	return CheckResult{Name: name, Passed: true}
+}
+
+func checkParents(store *storage.Store) CheckResult {
+	name := "Parents exist"
+
+	pearls, err := store.DB().All()
+	if err != nil {
+		return CheckResult{Name: name, Passed: false, Issues: []string{fmt.Sprintf("list pearls: %v", err)}}
+	}
+
+	ids := make(map[string]bool)
+	edges := make(map[string][]string)
+	for _, p := range pearls {
+		ids[p.ID] = true
+		if p.Parent != "" {
+			edges[p.ID] = []string{p.Parent}
+		}
+	}
+
+	var issues []string
+	for _, p := range pearls {
+		if p.Parent != "" && !ids[p.Parent] {
+			issues = append(issues, fmt.Sprintf("%s: parent %s does not exist", p.ID, p.Parent))
+		}
+	}
+	for _, cycle := range findCycles(pearls, edges) {
+		issues = append(issues, fmt.Sprintf("parent cycle: %s", strings.Join(cycle, " -> ")))
+	}
+
+	if len(issues) > 0 {
+		return CheckResult{Name: name, Passed: false, Issues: issues}
+	}
+
+	return CheckResult{Name: name, Passed: true}
+}
+
+// findCycles returns each cycle in the graph once, as a path that starts
+// and ends at the same ID. Edges to unknown IDs are ignored.
+func findCycles(pearls []*pearl.Pearl, edges map[string][]string) [][]string {
+	const (
+		unvisited = iota
+		visiting
+		done
+	)
+	state := make(map[string]int)
+	seen := make(map[string]bool)
+	var stack []string
+	var cycles [][]string
+
+	var visit func(id string)
+	visit = func(id string) {
+		state[id] = visiting
+		stack = append(stack, id)
+		for _, next := range edges[id] {
+			if _, ok := edges[next]; !ok {
+				continue
+			}
+			switch state[next] {
+			case unvisited:
+				visit(next)
+			case visiting:
+				// The cycle is the stack from next onward
+				start := len(stack) - 1
+				for stack[start] != next {
+					start--
+				}
+				cycle := append(append([]string{}, stack[start:]...), next)
+				if key := cycleKey(cycle); !seen[key] {
+					seen[key] = true
+					cycles = append(cycles, cycle)
+				}
+			}
+		}
+		stack = stack[:len(stack)-1]
+		state[id] = done
+	}
+
+	for _, p := range pearls {
+		if state[p.ID] == unvisited {
+			if _, ok := edges[p.ID]; ok {
+				visit(p.ID)
+			}
+		}
+	}
+	return cycles
+}
+
+// cycleKey identifies a cycle regardless of where it was entered.
+func cycleKey(cycle []string) string {
+	ids := append([]string{}, cycle[:len(cycle)-1]...)
+	sort.Strings(ids)
+	return strings.Join(ids, "\x00")
+}
+
+func checkIDsMatchNames(store *storage.Store) CheckResult {
+	name := "IDs match namespace and name"
+
+	pearls, err := store.DB().All()
+	if err != nil {
+		return CheckResult{Name: name, Passed: false, Issues: []string{fmt.Sprintf("list pearls: %v", err)}}
+	}
+
+	var issues []string
+	for _, p := range pearls {
+		if full := p.FullID(); full != p.ID {
+			issues = append(issues, fmt.Sprintf("%s: namespace %q and name %q give %s", p.ID, p.Namespace, p.Name, full))
+		}
+	}
+
+	if len(issues) > 0 {
+		return CheckResult{Name: name, Passed: false, Issues: issues}
+	}
+
+	return CheckResult{Name: name, Passed: true}
+}
+
+func checkGlobMatches(store *storage.Store, paths *config.Paths) CheckResult {
+	name := "Globs match files"
+
+	pearls, err := store.DB().All()
+	if err != nil {
+		return CheckResult{Name: name, Passed: false, Issues: []string{fmt.Sprintf("list pearls: %v", err)}}
+	}
+
+	files, err := projectFiles(filepath.Dir(paths.Root))
+	if err != nil {
+		return CheckResult{Name: name, Passed: false, Issues: []string{fmt.Sprintf("list project files: %v", err)}}
+	}
+
+	var issues []string
+	for _, p := range pearls {
+		if p.Status == pearl.StatusArchived {
+			continue
+		}
+		for _, g := range p.Globs {
+			pattern, negated := pearl.SplitNegation(g)
+			switch {
+			case anyMatch(files, pattern):
+			case negated:
+				issues = append(issues, fmt.Sprintf("%s: %s excludes no files", p.ID, g))
+			default:
+				issues = append(issues, fmt.Sprintf("%s: %s matches no files", p.ID, g))
+			}
+		}
+	}
+
+	if len(issues) > 0 {
+		return CheckResult{Name: name, Passed: false, Issues: issues}
+	}
+
+	return CheckResult{Name: name, Passed: true}
+}
+
+func anyMatch(files []string, glob string) bool {
+	for _, f := range files {
+		if pearl.MatchPath(f, []string{glob}) {
+			return true
+		}
+	}
+	return false
+}
+
+// projectFiles lists the project's files relative to root: the files git
+// tracks, or outside a git repository, everything but dot-directories.
+func projectFiles(root string) ([]string, error) {
+	if files := owners.RepoFiles(root); files != nil {
+		return files, nil
+	}
+
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		files = append(files, filepath.ToSlash(rel))
+		return nil
+	})
+	return files, err
+}
+
+func checkDeclaredScopes(store *storage.Store, configPath string) CheckResult {
+	name := "Scopes declared"
+
+	cfg, err := config.Load(configPath)
+	if err != nil || len(cfg.Scopes) == 0 {
+		// Without a registry every scope is free-form
+		return CheckResult{Name: name, Passed: true}
+	}
+
+	pearls, err := store.DB().All()
+	if err != nil {
+		return CheckResult{Name: name, Passed: false, Issues: []string{fmt.Sprintf("list pearls: %v", err)}}
+	}
+
+	users := make(map[string][]string)
+	var undeclared []string
+	for _, p := range pearls {
+		for _, s := range p.Scopes {
+			if cfg.Scopes.Declared(s) {
+				continue
+			}
+			if users[s] == nil {
+				undeclared = append(undeclared, s)
+			}
+			users[s] = append(users[s], p.ID)
+		}
+	}
+	sort.Strings(undeclared)
+
+	var issues []string
+	for _, s := range undeclared {
+		issues = append(issues, fmt.Sprintf("%s is not declared in config.yaml (used by %s)", s, strings.Join(users[s], ", ")))
+	}
+
+	if len(issues) > 0 {
+		return CheckResult{Name: name, Passed: false, Issues: issues}
+	}
+
 	return CheckResult{Name: name, Passed: true}
 }
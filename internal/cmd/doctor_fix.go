@@ -0,0 +1,362 @@
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/history"
+	"github.com/justrnr500/pearls/internal/markdown"
+	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/storage"
+)
+
+// fixAction is a repair for one doctor issue. Options are alternative
+// resolutions; the first is the default.
+type fixAction struct {
+	Kind    string      `json:"kind"`
+	Issue   string      `json:"issue"`
+	Options []fixOption `json:"options"`
+	// Applied is the label of the option applied, if any.
+	Applied string `json:"applied,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// fixOption is one way to resolve an issue.
+type fixOption struct {
+	Key   string `json:"key"`
+	Label string `json:"label"`
+	apply func() error
+}
+
+// Fix kinds, in the order they are planned and applied.
+const (
+	fixSync      = "sync"
+	fixOrphan    = "orphaned-content"
+	fixMissing   = "missing-content"
+	fixReference = "broken-reference"
+	fixHash      = "content-hash"
+)
+
+// planSyncFix returns a resync if JSONL and SQLite disagree, in the
+// direction of whichever changed last: the JSONL if it changed since the
+// database last synced with it, otherwise the database.
+func planSyncFix(store *storage.Store) (*fixAction, error) {
+	jsonlPearls, err := store.JSONL().ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read JSONL: %w", err)
+	}
+	dbPearls, err := store.DB().All()
+	if err != nil {
+		return nil, fmt.Errorf("list DB: %w", err)
+	}
+	if sameIDs(jsonlPearls, dbPearls) {
+		return nil, nil
+	}
+
+	stale, err := store.IsStale()
+	if err != nil {
+		return nil, fmt.Errorf("check JSONL: %w", err)
+	}
+	issue := fmt.Sprintf("JSONL has %d pearls, SQLite has %d", len(jsonlPearls), len(dbPearls))
+	if stale {
+		return &fixAction{Kind: fixSync, Issue: issue, Options: []fixOption{
+			{Key: "r", Label: "rebuild SQLite from JSONL (JSONL changed since last sync)", apply: store.SyncFromJSONL},
+		}}, nil
+	}
+	return &fixAction{Kind: fixSync, Issue: issue, Options: []fixOption{
+		{Key: "w", Label: "rewrite JSONL from SQLite (SQLite changed since last sync)", apply: store.SyncToJSONL},
+	}}, nil
+}
+
+func sameIDs(a, b []*pearl.Pearl) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	ids := make(map[string]bool, len(a))
+	for _, p := range a {
+		ids[p.ID] = true
+	}
+	for _, p := range b {
+		if !ids[p.ID] {
+			return false
+		}
+	}
+	return true
+}
+
+// planFixes returns repairs for orphaned and missing content, broken
+// references, and stale content hashes.
+func planFixes(store *storage.Store) ([]*fixAction, error) {
+	var actions []*fixAction
+
+	orphans, err := orphanedContent(store)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range orphans {
+		actions = append(actions, planOrphanFix(store, path))
+	}
+
+	missing, err := missingContent(store)
+	if err != nil {
+		return nil, err
+	}
+	for _, p := range missing {
+		actions = append(actions, &fixAction{Kind: fixMissing, Issue: fmt.Sprintf("%s: content file %s is missing", p.ID, p.ContentPath), Options: []fixOption{
+			{Key: "t", Label: "regenerate from the " + string(p.Type) + " template", apply: func() error {
+				content := store.Content().Template(p)
+				p.UpdatedAt = time.Now()
+				return store.Update(p, &content)
+			}},
+		}})
+	}
+
+	refs, err := brokenReferences(store)
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) > 0 {
+		all, err := store.DB().All()
+		if err != nil {
+			return nil, fmt.Errorf("list pearls: %w", err)
+		}
+		ids := make([]string, len(all))
+		for i, p := range all {
+			ids[i] = p.ID
+		}
+		for _, r := range refs {
+			actions = append(actions, planReferenceFix(store, r, ids))
+		}
+	}
+
+	stale, err := staleContentHashes(store)
+	if err != nil {
+		return nil, err
+	}
+	if len(stale) > 0 {
+		actions = append(actions, &fixAction{Kind: fixHash, Issue: fmt.Sprintf("%d pearls have outdated content hashes: %v", len(stale), stale), Options: []fixOption{
+			{Key: "r", Label: "refresh content hashes", apply: store.RefreshContentHashes},
+		}})
+	}
+
+	return actions, nil
+}
+
+// planOrphanFix offers to adopt an orphaned content file as a pearl, with
+// the ID its path implies, or to delete it.
+func planOrphanFix(store *storage.Store, path string) *fixAction {
+	action := &fixAction{Kind: fixOrphan, Issue: fmt.Sprintf("%s has no pearl", path)}
+
+	id := strings.ReplaceAll(strings.TrimSuffix(filepath.ToSlash(path), ".md"), "/", ".")
+	if err := pearl.ValidateNamespace(id); err == nil {
+		action.Options = append(action.Options, fixOption{Key: "a", Label: "adopt as pearl " + id, apply: func() error {
+			return adoptContent(store, id, path)
+		}})
+	}
+	action.Options = append(action.Options, fixOption{Key: "d", Label: "delete the file", apply: func() error {
+		return store.Content().Delete(path)
+	}})
+	return action
+}
+
+// adoptContent creates a pearl for an existing content file. Frontmatter in
+// the file supplies metadata; otherwise the type is custom and the
+// description is the first paragraph.
+func adoptContent(store *storage.Store, id, path string) error {
+	raw, err := store.Content().Read(path)
+	if err != nil {
+		return err
+	}
+	fm, body, err := storage.ParseFrontmatter(raw)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	p := &pearl.Pearl{
+		ID:          id,
+		Name:        pearl.LastSegment(id),
+		Namespace:   pearl.ParentNamespace(id),
+		Type:        pearl.TypeCustom,
+		Description: firstParagraph(body),
+		ContentPath: path,
+		Status:      pearl.StatusActive,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		CreatedBy:   history.Author(),
+	}
+	if fm != nil {
+		fm.Apply(p)
+	}
+	return store.Create(p, raw)
+}
+
+// firstParagraph returns the first paragraph of markdown as one line.
+func firstParagraph(body string) string {
+	for _, b := range markdown.Parse(body, 1).Blocks {
+		if b.Kind == markdown.KindParagraph {
+			return strings.Join(strings.Fields(b.Text), " ")
+		}
+	}
+	return ""
+}
+
+// planReferenceFix offers to point a broken reference at the closest
+// existing ID, or to remove it.
+func planReferenceFix(store *storage.Store, r brokenRef, ids []string) *fixAction {
+	action := &fixAction{Kind: fixReference, Issue: fmt.Sprintf("%s references missing %s", r.from.ID, r.to)}
+
+	update := func(replacement string) func() error {
+		return func() error {
+			p, err := store.Get(r.from.ID)
+			if err != nil || p == nil {
+				return fmt.Errorf("get pearl %s: %v", r.from.ID, err)
+			}
+			var refs []string
+			for _, ref := range p.References {
+				switch {
+				case ref != r.to:
+					refs = append(refs, ref)
+				case replacement != "" && !contains(p.References, replacement):
+					refs = append(refs, replacement)
+				}
+			}
+			p.References = refs
+			p.UpdatedAt = time.Now()
+			return store.Update(p, nil)
+		}
+	}
+
+	if match := closestID(r.to, ids); match != "" && match != r.from.ID {
+		action.Options = append(action.Options, fixOption{Key: "m", Label: "remap to " + match, apply: update(match)})
+	}
+	action.Options = append(action.Options, fixOption{Key: "r", Label: "remove the reference", apply: update("")})
+	return action
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// closestID returns the ID nearest to id by edit distance, if it is close
+// enough to be a plausible typo or rename: within a third of id's length.
+func closestID(id string, ids []string) string {
+	best, bestDist := "", len(id)/3+1
+	for _, candidate := range ids {
+		if d := levenshtein(id, candidate); d < bestDist {
+			best, bestDist = candidate, d
+		}
+	}
+	return best
+}
+
+// levenshtein returns the edit distance between two strings.
+func levenshtein(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev, cur = cur, prev
+	}
+	return prev[len(rb)]
+}
+
+// staleContentHashes lists pearls whose content_hash does not match their
+// content file.
+func staleContentHashes(store *storage.Store) ([]string, error) {
+	pearls, err := store.DB().All()
+	if err != nil {
+		return nil, fmt.Errorf("list pearls: %w", err)
+	}
+
+	var stale []string
+	for _, p := range pearls {
+		if p.ContentPath == "" || !store.Content().Exists(p.ContentPath) {
+			continue
+		}
+		hash, err := store.Content().Hash(p.ContentPath)
+		if err != nil {
+			return nil, fmt.Errorf("hash %s: %w", p.ContentPath, err)
+		}
+		if hash != p.ContentHash {
+			stale = append(stale, p.ID)
+		}
+	}
+	return stale, nil
+}
+
+// fixPrompter asks which option to apply for each action.
+type fixPrompter struct {
+	in  *bufio.Reader
+	out io.Writer
+	all bool // apply defaults without asking from now on
+}
+
+// choose returns the option to apply, or nil to skip. quit reports that
+// the user asked to stop.
+func (fp *fixPrompter) choose(a *fixAction) (opt *fixOption, quit bool) {
+	if fp.all {
+		return &a.Options[0], false
+	}
+
+	var keys []string
+	for _, o := range a.Options {
+		keys = append(keys, fmt.Sprintf("[%s] %s", o.Key, o.Label))
+	}
+	fmt.Fprintf(fp.out, "\n%s: %s\n  %s\n  [s] skip  [A] apply defaults to all  [q] quit\nChoice [%s]: ",
+		a.Kind, a.Issue, strings.Join(keys, "  "), a.Options[0].Key)
+
+	response, err := fp.in.ReadString('\n')
+	response = strings.TrimSpace(response)
+	if err != nil && response == "" {
+		return nil, true // EOF: stop rather than guess
+	}
+	switch response {
+	case "":
+		return &a.Options[0], false
+	case "s", "n":
+		return nil, false
+	case "q":
+		return nil, true
+	case "A":
+		fp.all = true
+		return &a.Options[0], false
+	}
+	for i := range a.Options {
+		if strings.EqualFold(response, a.Options[i].Key) {
+			return &a.Options[i], false
+		}
+	}
+	fmt.Fprintf(fp.out, "Unrecognized choice %q; skipping\n", response)
+	return nil, false
+}
+
+// applyFix runs the chosen option and records the outcome on the action.
+func applyFix(a *fixAction, opt *fixOption) error {
+	if err := opt.apply(); err != nil {
+		a.Error = err.Error()
+		return err
+	}
+	a.Applied = opt.Label
+	return nil
+}
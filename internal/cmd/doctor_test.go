@@ -1,11 +1,16 @@
 package cmd
 
 import (
+	"bufio"
+	"encoding/json"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 	"time"
 
+	"github.com/justrnr500/pearls/internal/config"
 	"github.com/justrnr500/pearls/internal/pearl"
 	"github.com/justrnr500/pearls/internal/storage"
 )
@@ -180,6 +185,33 @@ func TestCheckBrokenReferences_Clean(t *testing.T) {
 	}
 }
 
+func TestCheckWikiLinks(t *testing.T) {
+	store, _ := setupDoctorTestStore(t)
+	defer store.Close()
+
+	now := time.Now()
+	for id, content := range map[string]string{
+		"test.auth":   "# Auth\n\n## Tokens\n\nBearer.\n",
+		"test.orders": "# Orders\n\n![[test.auth#tokens]]\n\nSee [[test.users]] and [[test.auth#scopes]].\n",
+	} {
+		p := &pearl.Pearl{
+			ID: id, Name: pearl.LastSegment(id), Namespace: "test",
+			Type: pearl.TypeAPI, Status: pearl.StatusActive,
+			CreatedAt: now, UpdatedAt: now,
+		}
+		store.Create(p, content)
+	}
+
+	result := checkWikiLinks(store)
+	want := []string{
+		"test.orders:5 -> [[test.users]]: pearl not found",
+		"test.orders:5 -> [[test.auth#scopes]]: no such section",
+	}
+	if result.Passed || strings.Join(result.Issues, "\n") != strings.Join(want, "\n") {
+		t.Errorf("issues = %q, want %q", result.Issues, want)
+	}
+}
+
 func TestCheckConfigValidity(t *testing.T) {
 	tmpDir := t.TempDir()
 	configPath := filepath.Join(tmpDir, "config.yaml")
@@ -201,3 +233,522 @@ func TestCheckConfigValidity_BadYAML(t *testing.T) {
 		t.Error("expected fail for invalid YAML")
 	}
 }
+
+func TestCheckConfigValidity_Scopes(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		passed bool
+	}{
+		{"hierarchy", "scopes:\n  backend: {}\n  payments:\n    parent: backend\n", true},
+		{"undeclared parent", "scopes:\n  payments:\n    parent: backend\n", false},
+		{"cycle", "scopes:\n  a:\n    parent: b\n  b:\n    parent: a\n", false},
+		{"bad name", "scopes:\n  Payments: {}\n", false},
+	}
+	for _, tt := range tests {
+		configPath := filepath.Join(t.TempDir(), "config.yaml")
+		os.WriteFile(configPath, []byte(tt.config), 0644)
+		if result := checkConfigValidity(configPath); result.Passed != tt.passed {
+			t.Errorf("%s: passed = %v, issues %v", tt.name, result.Passed, result.Issues)
+		}
+	}
+}
+
+func TestCheckFrontmatterAgreement(t *testing.T) {
+	store, _ := setupDoctorTestStore(t)
+	defer store.Close()
+	store.SetFrontmatter(true)
+
+	now := time.Now()
+	p := &pearl.Pearl{
+		ID: "test.a", Name: "a", Namespace: "test",
+		Type: pearl.TypeTable, Status: pearl.StatusActive,
+		CreatedAt: now, UpdatedAt: now,
+	}
+	store.Create(p, "# A")
+
+	result := checkFrontmatterAgreement(store)
+	if !result.Passed {
+		t.Errorf("expected pass, got issues: %v", result.Issues)
+	}
+
+	// Edit the file without syncing
+	path := store.Content().FullPath(p.ContentPath)
+	data, _ := os.ReadFile(path)
+	os.WriteFile(path, []byte(strings.Replace(string(data), "type: table", "type: view", 1)), 0644)
+
+	result = checkFrontmatterAgreement(store)
+	if result.Passed {
+		t.Error("expected fail due to frontmatter disagreement")
+	}
+	if len(result.Issues) != 2 {
+		t.Errorf("expected SQLite and JSONL disagreements, got %v", result.Issues)
+	}
+}
+
+func TestCheckRequiredOwners(t *testing.T) {
+	store, tmpDir := setupDoctorTestStore(t)
+	defer store.Close()
+	paths := &config.Paths{Root: filepath.Join(tmpDir, config.DirName)}
+
+	now := time.Now()
+	for _, p := range []*pearl.Pearl{
+		{ID: "db", Name: "db", Owners: []string{"@org/data"}},
+		{ID: "db.users", Name: "users", Namespace: "db", Required: true},
+		{ID: "api.orders", Name: "orders", Namespace: "api", Required: true},
+		{ID: "api.notes", Name: "notes", Namespace: "api"},
+	} {
+		p.Type, p.Status, p.CreatedAt, p.UpdatedAt = pearl.TypeTable, pearl.StatusActive, now, now
+		store.Create(p, "# "+p.Name)
+	}
+
+	result := checkRequiredOwners(store, paths)
+	if result.Passed {
+		t.Fatal("expected fail for unowned required pearl")
+	}
+	if len(result.Issues) != 1 || !strings.Contains(result.Issues[0], "api.orders") || strings.Contains(result.Issues[0], "db.users") {
+		t.Errorf("issues = %v", result.Issues)
+	}
+
+	// CODEOWNERS covering the pearl's globs is enough
+	os.WriteFile(filepath.Join(tmpDir, "CODEOWNERS"), []byte("/src/orders/ @bob\n"), 0644)
+	orders, _ := store.Get("api.orders")
+	orders.Globs = []string{"src/orders/**"}
+	store.Update(orders, nil)
+
+	if result := checkRequiredOwners(store, paths); !result.Passed {
+		t.Errorf("expected pass, got issues: %v", result.Issues)
+	}
+}
+
+func TestClosestID(t *testing.T) {
+	ids := []string{"db.postgres.users", "db.postgres.orders", "api.stripe"}
+	tests := []struct {
+		id, want string
+	}{
+		{"db.postgres.user", "db.postgres.users"},
+		{"db.postgre.order", "db.postgres.orders"},
+		{"api.strip", "api.stripe"},
+		{"completely.different", ""},
+	}
+	for _, tt := range tests {
+		if got := closestID(tt.id, ids); got != tt.want {
+			t.Errorf("closestID(%q) = %q, want %q", tt.id, got, tt.want)
+		}
+	}
+	if d := levenshtein("kitten", "sitting"); d != 3 {
+		t.Errorf("levenshtein = %d, want 3", d)
+	}
+}
+
+func TestDoctorFixes(t *testing.T) {
+	store, tmpDir := setupDoctorTestStore(t)
+	defer store.Close()
+
+	now := time.Now()
+	for _, p := range []*pearl.Pearl{
+		{ID: "db.users", Name: "users", Namespace: "db"},
+		{ID: "db.orders", Name: "orders", Namespace: "db", References: []string{"db.user", "gone.thing"}},
+	} {
+		p.Type, p.Status, p.CreatedAt, p.UpdatedAt = pearl.TypeTable, pearl.StatusActive, now, now
+		if err := store.Create(p, "# "+p.Name+"\n"); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	content := filepath.Join(tmpDir, "content")
+	os.WriteFile(filepath.Join(content, "db", "notes.md"), []byte("---\ntype: runbook\n---\n# Notes\n\nLoose notes\nabout the db.\n"), 0644)
+	os.WriteFile(filepath.Join(content, "db", "Bad Name.md"), []byte("# x\n"), 0644)
+	os.Remove(filepath.Join(content, "db", "users.md"))
+	os.WriteFile(filepath.Join(content, "db", "orders.md"), []byte("# orders\n\nedited by hand\n"), 0644)
+
+	actions, err := planFixes(store)
+	if err != nil {
+		t.Fatalf("plan: %v", err)
+	}
+	kinds := map[string]int{}
+	for _, a := range actions {
+		kinds[a.Kind]++
+	}
+	want := map[string]int{fixOrphan: 2, fixMissing: 1, fixReference: 2, fixHash: 1}
+	if !reflect.DeepEqual(kinds, want) {
+		t.Fatalf("planned %v, want %v", kinds, want)
+	}
+
+	// Apply every default; an unadoptable name can only be deleted
+	for _, a := range actions {
+		if err := applyFix(a, &a.Options[0]); err != nil {
+			t.Fatalf("%s: %v", a.Issue, err)
+		}
+	}
+
+	notes, _ := store.Get("db.notes")
+	if notes == nil || notes.Type != "runbook" || notes.Description != "Loose notes about the db." {
+		t.Errorf("adopted pearl = %+v", notes)
+	}
+	if _, err := os.Stat(filepath.Join(content, "db", "Bad Name.md")); !os.IsNotExist(err) {
+		t.Error("unadoptable orphan should be deleted")
+	}
+	if !store.Content().Exists("db/users.md") {
+		t.Error("missing content should be regenerated")
+	}
+	orders, _ := store.Get("db.orders")
+	if !reflect.DeepEqual(orders.References, []string{"db.users"}) {
+		t.Errorf("references = %v, want remapped db.users and removed gone.thing", orders.References)
+	}
+
+	for _, c := range runChecks(store, &config.Paths{Root: filepath.Join(tmpDir, config.DirName), Config: filepath.Join(tmpDir, "none.yaml")}) {
+		if !c.Passed && c.Name != "Config valid" {
+			t.Errorf("after fixes: %s: %v", c.Name, c.Issues)
+		}
+	}
+	if stale, _ := staleContentHashes(store); len(stale) != 0 {
+		t.Errorf("stale hashes after refresh: %v", stale)
+	}
+}
+
+func TestPlanSyncFix(t *testing.T) {
+	store, _ := setupDoctorTestStore(t)
+	defer store.Close()
+
+	now := time.Now()
+	p := &pearl.Pearl{
+		ID: "test.a", Name: "a", Namespace: "test",
+		Type: pearl.TypeTable, Status: pearl.StatusActive,
+		CreatedAt: now, UpdatedAt: now,
+	}
+	store.Create(p, "# A")
+
+	if a, _ := planSyncFix(store); a != nil {
+		t.Fatalf("in sync, got %+v", a)
+	}
+
+	// A row only in SQLite: JSONL is unchanged, so SQLite is newer
+	orphan := *p
+	orphan.ID, orphan.Name = "test.b", "b"
+	store.DB().Insert(&orphan)
+
+	a, err := planSyncFix(store)
+	if err != nil || a == nil || a.Options[0].Key != "w" {
+		t.Fatalf("plan = %+v, %v", a, err)
+	}
+	if err := applyFix(a, &a.Options[0]); err != nil {
+		t.Fatalf("apply: %v", err)
+	}
+	if result := checkJSONLSync(store); !result.Passed {
+		t.Errorf("after sync: %v", result.Issues)
+	}
+}
+
+// TestDoctorFixSyncDirection goes through doctor as the CLI runs it: a
+// pearl dropped from pearls.jsonl by hand must be synced from JSONL, not
+// hidden by a rebuild when the store is opened.
+func TestDoctorFixSyncDirection(t *testing.T) {
+	root := t.TempDir()
+	paths := config.ResolvePaths(root)
+	store, err := storage.NewStore(paths.DB, paths.JSONL, paths.Content)
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	for _, p := range []*pearl.Pearl{
+		{ID: "db.users", Name: "users", Namespace: "db"},
+		{ID: "db.orders", Name: "orders", Namespace: "db"},
+	} {
+		p.Type, p.Status, p.CreatedAt, p.UpdatedAt = pearl.TypeTable, pearl.StatusActive, now, now
+		if err := store.Create(p, "# "+p.Name+"\n"); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+	store.Close()
+
+	data, err := os.ReadFile(paths.JSONL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var kept []string
+	for _, line := range strings.SplitAfter(string(data), "\n") {
+		if !strings.Contains(line, `"db.orders"`) {
+			kept = append(kept, line)
+		}
+	}
+	if err := os.WriteFile(paths.JSONL, []byte(strings.Join(kept, "")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	t.Chdir(root)
+	defer func() { doctorFix, doctorDryRun, doctorYes, doctorOutput = false, false, false, outputFlags{} }()
+	doctor := func(dryRun bool) []map[string]interface{} {
+		t.Helper()
+		doctorFix, doctorDryRun, doctorYes, doctorOutput = true, dryRun, !dryRun, outputFlags{json: true}
+		out, err := os.CreateTemp(t.TempDir(), "out")
+		if err != nil {
+			t.Fatal(err)
+		}
+		stdout := os.Stdout
+		os.Stdout = out
+		runErr := runDoctor(doctorCmd, nil)
+		os.Stdout = stdout
+		if dryRun && runErr != nil {
+			t.Fatalf("doctor: %v", runErr)
+		}
+		var result struct {
+			Items []map[string]interface{} `json:"items"`
+		}
+		data, _ := os.ReadFile(out.Name())
+		if err := json.Unmarshal(data, &result); err != nil {
+			t.Fatalf("parse %q: %v", data, err)
+		}
+		return result.Items
+	}
+
+	fixes := doctor(true)
+	if len(fixes) == 0 || fixes[0]["kind"] != fixSync {
+		t.Fatalf("fixes = %v, want a sync first", fixes)
+	}
+	options := fixes[0]["options"].([]interface{})
+	if key := options[0].(map[string]interface{})["key"]; key != "r" {
+		t.Errorf("sync option = %v, want rebuild from JSONL", options[0])
+	}
+
+	fixes = doctor(false)
+	if label, _ := fixes[0]["applied"].(string); !strings.HasPrefix(label, "rebuild SQLite from JSONL") {
+		t.Errorf("applied = %v", fixes[0])
+	}
+}
+
+func TestFixPrompter(t *testing.T) {
+	action := func() *fixAction {
+		return &fixAction{Kind: fixOrphan, Issue: "x", Options: []fixOption{{Key: "a", Label: "adopt"}, {Key: "d", Label: "delete"}}}
+	}
+	var out strings.Builder
+	fp := &fixPrompter{in: bufio.NewReader(strings.NewReader("\nd\ns\nzz\nA\n")), out: &out}
+
+	for i, want := range []string{"adopt", "delete", "", ""} {
+		opt, quit := fp.choose(action())
+		got := ""
+		if opt != nil {
+			got = opt.Label
+		}
+		if got != want || quit {
+			t.Errorf("choice %d = %q (quit %v), want %q", i, got, quit, want)
+		}
+	}
+
+	// "A" applies the default and stops prompting
+	if opt, _ := fp.choose(action()); opt == nil || opt.Label != "adopt" || !fp.all {
+		t.Error("A should apply the default to all")
+	}
+	if opt, quit := fp.choose(action()); opt == nil || quit {
+		t.Error("after A, choose should not prompt")
+	}
+
+	// End of input stops
+	fp = &fixPrompter{in: bufio.NewReader(strings.NewReader("")), out: &out}
+	if _, quit := fp.choose(action()); !quit {
+		t.Error("EOF should quit")
+	}
+}
+
+func TestCheckDuplicateJSONLIDs(t *testing.T) {
+	store, _ := setupDoctorTestStore(t)
+	defer store.Close()
+
+	now := time.Now()
+	p := &pearl.Pearl{
+		ID: "test.a", Name: "a", Namespace: "test",
+		Type: pearl.TypeTable, Status: pearl.StatusActive,
+		CreatedAt: now, UpdatedAt: now,
+	}
+	store.Create(p, "# A")
+
+	if result := checkDuplicateJSONLIDs(store); !result.Passed {
+		t.Fatalf("expected pass, got issues: %v", result.Issues)
+	}
+
+	store.JSONL().Append(p)
+	result := checkDuplicateJSONLIDs(store)
+	if result.Passed || len(result.Issues) != 1 || !strings.Contains(result.Issues[0], "test.a appears 2 times") {
+		t.Errorf("issues = %v", result.Issues)
+	}
+}
+
+func TestCheckSharedContentPaths(t *testing.T) {
+	store, _ := setupDoctorTestStore(t)
+	defer store.Close()
+
+	now := time.Now()
+	a := &pearl.Pearl{
+		ID: "test.a", Name: "a", Namespace: "test",
+		Type: pearl.TypeTable, Status: pearl.StatusActive,
+		CreatedAt: now, UpdatedAt: now,
+	}
+	store.Create(a, "# A")
+
+	b := *a
+	b.ID, b.Name = "test.b", "b"
+	store.DB().Insert(&b)
+
+	result := checkSharedContentPaths(store)
+	if result.Passed || len(result.Issues) != 1 || !strings.Contains(result.Issues[0], "test.a, test.b") {
+		t.Errorf("issues = %v", result.Issues)
+	}
+}
+
+func TestCheckContentHashes(t *testing.T) {
+	store, tmpDir := setupDoctorTestStore(t)
+	defer store.Close()
+
+	now := time.Now()
+	p := &pearl.Pearl{
+		ID: "test.a", Name: "a", Namespace: "test",
+		Type: pearl.TypeTable, Status: pearl.StatusActive,
+		CreatedAt: now, UpdatedAt: now,
+	}
+	store.Create(p, "# A")
+
+	if result := checkContentHashes(store); !result.Passed {
+		t.Fatalf("expected pass, got issues: %v", result.Issues)
+	}
+
+	os.WriteFile(filepath.Join(tmpDir, "content", "test", "a.md"), []byte("# A\n\nEdited\n"), 0644)
+	if result := checkContentHashes(store); result.Passed {
+		t.Error("expected fail after editing content")
+	}
+}
+
+func TestCheckReferenceCycles(t *testing.T) {
+	store, _ := setupDoctorTestStore(t)
+	defer store.Close()
+
+	now := time.Now()
+	for _, p := range []*pearl.Pearl{
+		{ID: "test.a", Name: "a", References: []string{"test.b"}},
+		{ID: "test.b", Name: "b", References: []string{"test.c", "missing.x"}},
+		{ID: "test.c", Name: "c", References: []string{"test.a"}},
+		{ID: "test.d", Name: "d", References: []string{"test.a", "test.d"}},
+	} {
+		p.Namespace, p.Type, p.Status, p.CreatedAt, p.UpdatedAt = "test", pearl.TypeTable, pearl.StatusActive, now, now
+		store.Create(p, "# "+p.Name)
+	}
+
+	result := checkReferenceCycles(store)
+	want := []string{"test.a -> test.b -> test.c -> test.a", "test.d -> test.d"}
+	if result.Passed || !reflect.DeepEqual(result.Issues, want) {
+		t.Errorf("issues = %v, want %v", result.Issues, want)
+	}
+}
+
+func TestCheckParents(t *testing.T) {
+	store, _ := setupDoctorTestStore(t)
+	defer store.Close()
+
+	now := time.Now()
+	for _, p := range []*pearl.Pearl{
+		{ID: "test.a", Name: "a"},
+		{ID: "test.b", Name: "b", Parent: "test.a"},
+	} {
+		p.Namespace, p.Type, p.Status, p.CreatedAt, p.UpdatedAt = "test", pearl.TypeTable, pearl.StatusActive, now, now
+		store.Create(p, "# "+p.Name)
+	}
+
+	if result := checkParents(store); !result.Passed {
+		t.Fatalf("expected pass, got issues: %v", result.Issues)
+	}
+
+	for _, p := range []*pearl.Pearl{
+		{ID: "test.c", Name: "c", Parent: "test.gone"},
+		{ID: "test.x", Name: "x", Parent: "test.y"},
+		{ID: "test.y", Name: "y", Parent: "test.x"},
+	} {
+		p.Namespace, p.Type, p.Status, p.CreatedAt, p.UpdatedAt = "test", pearl.TypeTable, pearl.StatusActive, now, now
+		store.Create(p, "# "+p.Name)
+	}
+
+	result := checkParents(store)
+	want := []string{
+		"test.c: parent test.gone does not exist",
+		"parent cycle: test.x -> test.y -> test.x",
+	}
+	if !reflect.DeepEqual(result.Issues, want) {
+		t.Errorf("issues = %v, want %v", result.Issues, want)
+	}
+}
+
+func TestCheckIDsMatchNames(t *testing.T) {
+	store, _ := setupDoctorTestStore(t)
+	defer store.Close()
+
+	now := time.Now()
+	for _, p := range []*pearl.Pearl{
+		{ID: "test.a", Name: "a", Namespace: "test"},
+		{ID: "top", Name: "top"},
+		{ID: "test.b", Name: "renamed", Namespace: "test"},
+	} {
+		p.Type, p.Status, p.CreatedAt, p.UpdatedAt = pearl.TypeTable, pearl.StatusActive, now, now
+		store.Create(p, "# "+p.Name)
+	}
+
+	result := checkIDsMatchNames(store)
+	if result.Passed || len(result.Issues) != 1 || !strings.HasPrefix(result.Issues[0], "test.b:") {
+		t.Errorf("issues = %v", result.Issues)
+	}
+}
+
+func TestCheckGlobMatches(t *testing.T) {
+	store, tmpDir := setupDoctorTestStore(t)
+	defer store.Close()
+
+	os.MkdirAll(filepath.Join(tmpDir, "src", "api"), 0755)
+	os.WriteFile(filepath.Join(tmpDir, "src", "api", "handler.go"), []byte("package api\n"), 0644)
+
+	now := time.Now()
+	for _, p := range []*pearl.Pearl{
+		{ID: "test.a", Name: "a", Globs: []string{"src/**/*.go"}},
+		{ID: "test.b", Name: "b", Globs: []string{"src/api/*.go", "lib/**"}},
+		{ID: "test.d", Name: "d", Globs: []string{"src/**", "!src/**/*_test.go"}},
+		{ID: "test.c", Name: "c", Globs: []string{"old/**"}, Status: pearl.StatusArchived},
+	} {
+		p.Namespace, p.Type, p.CreatedAt, p.UpdatedAt = "test", pearl.AssetType("convention"), now, now
+		if p.Status == "" {
+			p.Status = pearl.StatusActive
+		}
+		store.Create(p, "# "+p.Name)
+	}
+
+	paths := &config.Paths{Root: filepath.Join(tmpDir, config.DirName)}
+	result := checkGlobMatches(store, paths)
+	want := []string{
+		"test.b: lib/** matches no files",
+		"test.d: !src/**/*_test.go excludes no files",
+	}
+	if !reflect.DeepEqual(result.Issues, want) {
+		t.Errorf("issues = %v, want %v", result.Issues, want)
+	}
+}
+
+func TestFailedChecks(t *testing.T) {
+	checks := []CheckResult{
+		{Name: "a", Severity: severityError, Passed: true},
+		{Name: "b", Severity: severityWarning, Passed: false},
+		{Name: "c", Severity: severityInfo, Passed: false},
+	}
+
+	tests := []struct {
+		failOn string
+		failed bool
+	}{
+		{severityError, false},
+		{severityWarning, true},
+		{severityInfo, true},
+	}
+	for _, tt := range tests {
+		if err := failedChecks(checks, tt.failOn); (err != nil) != tt.failed {
+			t.Errorf("failOn %s: err = %v", tt.failOn, err)
+		}
+	}
+
+	checks[0].Passed = false
+	if err := failedChecks(checks, severityError); err == nil {
+		t.Error("expected a failed error check to fail")
+	}
+}
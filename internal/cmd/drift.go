@@ -0,0 +1,173 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/drift"
+	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/storage"
+)
+
+var driftCmd = &cobra.Command{
+	Use:   "drift [id...]",
+	Short: "Rank pearls by how much their code changed since they were updated",
+	Long: `Compare each pearl with globs against the git history of the files its
+globs match, and rank pearls by how many commits touched that code since the
+pearl was last revised.
+
+A pearl's baseline is the last commit to its content file, or its updated_at
+if the content was never committed. A later 'pearls verify' moves the
+baseline forward.
+
+Examples:
+  pearls drift
+  pearls drift api.payments
+  pearls drift --namespace api --files 10
+  pearls drift --json           # For CI, e.g. to comment on PRs`,
+	RunE: runDrift,
+}
+
+var (
+	driftJSON       bool
+	driftNamespace  string
+	driftFiles      int
+	driftMinCommits int
+)
+
+func init() {
+	rootCmd.AddCommand(driftCmd)
+	driftCmd.Flags().BoolVar(&driftJSON, "json", false, "Output as JSON")
+	driftCmd.Flags().StringVarP(&driftNamespace, "namespace", "n", "", "Filter by namespace prefix")
+	driftCmd.Flags().IntVar(&driftFiles, "files", 5, "Changed files to show per pearl (0 for all)")
+	driftCmd.Flags().IntVar(&driftMinCommits, "min-commits", 1, "Only report pearls with at least this many commits")
+}
+
+func runDrift(cmd *cobra.Command, args []string) error {
+	store, paths, err := getStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	var pearls []*pearl.Pearl
+	if len(args) > 0 {
+		for _, id := range args {
+			p, err := store.Get(id)
+			if err != nil {
+				return fmt.Errorf("get pearl: %w", err)
+			}
+			if p == nil {
+				return fmt.Errorf("pearl not found: %s", id)
+			}
+			pearls = append(pearls, p)
+		}
+	} else {
+		pearls, err = store.List(storage.ListOptions{
+			Namespace: driftNamespace,
+			Status:    string(pearl.StatusActive),
+		})
+		if err != nil {
+			return fmt.Errorf("list pearls: %w", err)
+		}
+	}
+
+	// Globs are relative to the project root, the directory holding .pearls
+	repo := &drift.Repo{Dir: filepath.Dir(paths.Root)}
+
+	baselines := make(map[string]time.Time)
+	commits := make(map[string]string)
+	var earliest time.Time
+	for _, p := range pearls {
+		if len(p.Globs) == 0 {
+			continue
+		}
+		at, commit, err := driftBaseline(repo, paths, p)
+		if err != nil {
+			return err
+		}
+		baselines[p.ID], commits[p.ID] = at, commit
+		if earliest.IsZero() || at.Before(earliest) {
+			earliest = at
+		}
+	}
+
+	reports := []*drift.Report{}
+	if len(baselines) > 0 {
+		log, err := repo.Commits(earliest)
+		if err != nil {
+			return err
+		}
+		for _, p := range pearls {
+			at, ok := baselines[p.ID]
+			if !ok {
+				continue
+			}
+			r := drift.Analyze(p, at, log, driftFiles)
+			if r == nil || r.Commits < driftMinCommits {
+				continue
+			}
+			r.BaselineCommit = commits[p.ID]
+			reports = append(reports, r)
+		}
+		drift.Rank(reports)
+	}
+
+	if driftJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(reports)
+	}
+
+	if len(reports) == 0 {
+		fmt.Println("No drift: code covered by pearls is unchanged since they were updated")
+		return nil
+	}
+
+	fmt.Printf("%d pearls have drifted from their code:\n", len(reports))
+	for _, r := range reports {
+		since := r.Baseline.Local().Format("2006-01-02")
+		if r.BaselineCommit != "" {
+			since += " (" + r.BaselineCommit[:7] + ")"
+		}
+		fmt.Printf("\n  %s  %d commits, %d files since %s\n", r.PearlID, r.Commits, r.FileCount, since)
+		for _, f := range r.Files {
+			fmt.Printf("    %4d  %s\n", f.Commits, f.Path)
+		}
+		if more := r.FileCount - len(r.Files); more > 0 {
+			fmt.Printf("          ... and %d more\n", more)
+		}
+	}
+
+	return nil
+}
+
+// driftBaseline returns when p was last revised: the last commit to its
+// content file (or updated_at if never committed), moved forward by a
+// later verification. The commit is empty unless it set the baseline.
+func driftBaseline(repo *drift.Repo, paths *config.Paths, p *pearl.Pearl) (time.Time, string, error) {
+	at, commit := p.UpdatedAt, ""
+	if p.ContentPath != "" {
+		rel, err := filepath.Rel(repo.Dir, filepath.Join(paths.Content, p.ContentPath))
+		if err != nil {
+			return time.Time{}, "", fmt.Errorf("resolve content path: %w", err)
+		}
+		hash, changed, err := repo.LastChange(rel)
+		if err != nil {
+			return time.Time{}, "", err
+		}
+		if hash != "" {
+			at, commit = changed, hash
+		}
+	}
+	if p.LastVerifiedAt != nil && p.LastVerifiedAt.After(at) {
+		at, commit = *p.LastVerifiedAt, ""
+	}
+	return at, commit, nil
+}
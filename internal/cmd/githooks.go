@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+//go:embed templates/git-hook.sh
+var gitHookSnippet string
+
+// gitHookMarker identifies the pearls block inside a git hook script.
+const gitHookMarker = "# >>> pearls >>>"
+
+// gitHookNames are the git hooks that fire after pearls.jsonl may have changed.
+var gitHookNames = []string{"post-merge", "post-checkout"}
+
+// installGitHooks adds a "pearls sync" block to the repository's post-merge
+// and post-checkout hooks. Existing hooks are appended to, not replaced, and
+// hooks that already contain the pearls block are left alone.
+func installGitHooks(root string) ([]string, error) {
+	out, err := exec.Command("git", "-C", root, "rev-parse", "--git-path", "hooks").Output()
+	if err != nil {
+		return nil, fmt.Errorf("not a git repository: %s", root)
+	}
+
+	hooksDir := strings.TrimSpace(string(out))
+	if !filepath.IsAbs(hooksDir) {
+		hooksDir = filepath.Join(root, hooksDir)
+	}
+	if err := os.MkdirAll(hooksDir, 0755); err != nil {
+		return nil, fmt.Errorf("create hooks directory: %w", err)
+	}
+
+	var installed []string
+	for _, name := range gitHookNames {
+		path := filepath.Join(hooksDir, name)
+
+		existing, err := os.ReadFile(path)
+		if err != nil && !os.IsNotExist(err) {
+			return installed, fmt.Errorf("read %s hook: %w", name, err)
+		}
+		if strings.Contains(string(existing), gitHookMarker) {
+			continue
+		}
+
+		script := string(existing)
+		if script == "" {
+			script = "#!/bin/sh\n"
+		} else if !strings.HasSuffix(script, "\n") {
+			script += "\n"
+		}
+		script += "\n" + gitHookSnippet
+
+		if err := os.WriteFile(path, []byte(script), 0755); err != nil {
+			return installed, fmt.Errorf("write %s hook: %w", name, err)
+		}
+		installed = append(installed, path)
+	}
+
+	return installed, nil
+}
@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstallGitHooks(t *testing.T) {
+	tmpDir := t.TempDir()
+	if err := exec.Command("git", "init", "-q", tmpDir).Run(); err != nil {
+		t.Skipf("git not available: %v", err)
+	}
+
+	// Pre-existing hook must be preserved
+	existing := filepath.Join(tmpDir, ".git", "hooks", "post-merge")
+	os.MkdirAll(filepath.Dir(existing), 0755)
+	os.WriteFile(existing, []byte("#!/bin/sh\necho custom\n"), 0755)
+
+	installed, err := installGitHooks(tmpDir)
+	if err != nil {
+		t.Fatalf("install: %v", err)
+	}
+	if len(installed) != 2 {
+		t.Errorf("installed %d hooks, want 2", len(installed))
+	}
+
+	data, _ := os.ReadFile(existing)
+	if !strings.Contains(string(data), "echo custom") {
+		t.Error("existing hook content was lost")
+	}
+	if !strings.Contains(string(data), "pearls sync") {
+		t.Error("pearls block not appended to existing hook")
+	}
+
+	checkout, _ := os.ReadFile(filepath.Join(tmpDir, ".git", "hooks", "post-checkout"))
+	if !strings.HasPrefix(string(checkout), "#!/bin/sh\n") {
+		t.Errorf("new hook missing shebang: %q", checkout)
+	}
+
+	// Idempotent
+	installed, err = installGitHooks(tmpDir)
+	if err != nil {
+		t.Fatalf("reinstall: %v", err)
+	}
+	if len(installed) != 0 {
+		t.Errorf("reinstall touched %d hooks, want 0", len(installed))
+	}
+	data, _ = os.ReadFile(existing)
+	if strings.Count(string(data), gitHookMarker) != 1 {
+		t.Error("pearls block duplicated")
+	}
+}
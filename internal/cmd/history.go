@@ -0,0 +1,127 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/history"
+	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/storage"
+)
+
+var historyCmd = &cobra.Command{
+	Use:   "history <id>",
+	Short: "List a pearl's revisions",
+	Long: `List the recorded changes to a pearl, newest first.
+
+Every create, update, delete, and restore is appended to
+.pearls/history.jsonl with who made it, when, which fields changed, and the
+content hash before and after. Pearls with no recorded changes fall back to
+git history of pearls.jsonl and their content file.
+
+Examples:
+  pearls history db.postgres.users
+  pearls history db.postgres.users --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runHistory,
+}
+
+var historyJSON bool
+
+func init() {
+	rootCmd.AddCommand(historyCmd)
+	historyCmd.Flags().BoolVar(&historyJSON, "json", false, "Output as JSON")
+}
+
+func runHistory(cmd *cobra.Command, args []string) error {
+	id := args[0]
+
+	store, paths, err := getStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	_, revs, err := loadRevisions(store, paths, id)
+	if err != nil {
+		return err
+	}
+
+	if historyJSON {
+		if revs == nil {
+			revs = []*history.Revision{}
+		}
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(revs)
+	}
+
+	if len(revs) == 0 {
+		fmt.Printf("No history for %s\n", id)
+		return nil
+	}
+
+	source := ""
+	if revs[0].Commit != "" {
+		source = ", from git"
+	}
+	fmt.Printf("%s (%d revisions%s)\n", id, len(revs), source)
+	for i := len(revs) - 1; i >= 0; i-- {
+		r := revs[i]
+		label := fmt.Sprintf("r%d", r.Rev)
+		if r.Commit != "" {
+			label += " " + r.Commit[:7]
+		}
+		line := fmt.Sprintf("  %-5s %s  %-10s %-8s", label, r.At.Local().Format("2006-01-02 15:04"), r.By, r.Op)
+		if len(r.Fields) > 0 {
+			line += " " + strings.Join(r.Fields, ", ")
+		}
+		fmt.Println(strings.TrimRight(line, " "))
+	}
+
+	return nil
+}
+
+// historySource returns the history source for a pearls directory.
+func historySource(store *storage.Store, paths *config.Paths) *history.Source {
+	contentDir, err := filepath.Rel(paths.Root, paths.Content)
+	if err != nil {
+		contentDir = config.ContentDir
+	}
+	return &history.Source{
+		Log:        store.History(),
+		Dir:        paths.Root,
+		JSONLFile:  config.JSONLFile,
+		ContentDir: contentDir,
+	}
+}
+
+// loadRevisions returns the history source and revisions for id, which
+// need not exist any more.
+func loadRevisions(store *storage.Store, paths *config.Paths, id string) (*history.Source, []*history.Revision, error) {
+	if err := pearl.ValidateNamespace(id); err != nil {
+		return nil, nil, err
+	}
+
+	contentPath := store.Content().PathForPearl(pearl.ParentNamespace(id), pearl.LastSegment(id))
+	p, err := store.Get(id)
+	if err != nil {
+		return nil, nil, fmt.Errorf("get pearl: %w", err)
+	}
+	if p != nil && p.ContentPath != "" {
+		contentPath = p.ContentPath
+	}
+
+	source := historySource(store, paths)
+	revs, err := source.Revisions(id, contentPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("load history: %w", err)
+	}
+	return source, revs, nil
+}
@@ -22,19 +22,28 @@ This creates a .pearls/ directory with:
   - pearls.jsonl  Metadata (git-tracked)
   - pearls.db     SQLite cache (gitignored)
   - content/      Markdown content files
-  - .gitignore    Ignores the database file`,
+  - .gitignore    Ignores the database file
+
+Inside a git repository, it also registers a merge driver so concurrent
+edits to pearls.jsonl merge by pearl ID instead of conflicting.
+
+Use --git-hooks to install post-merge/post-checkout hooks that rebuild the
+database after a pull or checkout. (Commands also rebuild a stale database
+on demand; the hooks just do it eagerly.)`,
 	RunE: runInit,
 }
 
 var (
-	initQuiet bool
-	initName  string
+	initQuiet    bool
+	initName     string
+	initGitHooks bool
 )
 
 func init() {
 	rootCmd.AddCommand(initCmd)
 	initCmd.Flags().BoolVarP(&initQuiet, "quiet", "q", false, "Suppress output (for agents)")
 	initCmd.Flags().StringVarP(&initName, "name", "n", "", "Project name")
+	initCmd.Flags().BoolVar(&initGitHooks, "git-hooks", false, "Install git hooks that rebuild the database after pull/checkout")
 }
 
 func runInit(cmd *cobra.Command, args []string) error {
@@ -110,6 +119,10 @@ func runInit(cmd *cobra.Command, args []string) error {
 pearls.db
 pearls.db-shm
 pearls.db-wal
+
+# Writer lock and interrupted atomic writes
+pearls.lock
+*.tmp
 `
 	gitignorePath := filepath.Join(paths.Root, config.GitIgnoreFile)
 	if err := os.WriteFile(gitignorePath, []byte(gitignore), 0644); err != nil {
@@ -124,6 +137,23 @@ pearls.db-wal
 	rootGitignore := filepath.Join(cwd, ".gitignore")
 	ensureGitignoreEntry(rootGitignore, ".env")
 
+	// Register the pearls.jsonl merge driver (best effort — may not be a git repo)
+	if err := installMergeDriver(cwd); err == nil && !initQuiet {
+		fmt.Println("✓ Registered git merge driver for pearls.jsonl")
+	}
+
+	if initGitHooks {
+		installed, err := installGitHooks(cwd)
+		if err != nil {
+			return fmt.Errorf("install git hooks: %w", err)
+		}
+		if !initQuiet {
+			for _, path := range installed {
+				fmt.Println("✓ Installed git hook", path)
+			}
+		}
+	}
+
 	if !initQuiet {
 		fmt.Println("\nReady to track data assets. Try:")
 		fmt.Println("  pearls create db.postgres.users --type table")
@@ -132,9 +162,10 @@ pearls.db-wal
 	return nil
 }
 
-// ensureGitignoreEntry ensures that the given entry exists in the gitignore file
-// at path. If the file does not exist, it is created. If the entry already
-// exists (compared after trimming whitespace), no changes are made.
+// ensureGitignoreEntry ensures that the given entry exists in the
+// gitignore-style file (.gitignore, .gitattributes) at path. If the file
+// does not exist, it is created. If the entry already exists (compared
+// after trimming whitespace), no changes are made.
 func ensureGitignoreEntry(path, entry string) {
 	data, err := os.ReadFile(path)
 	if err != nil && !os.IsNotExist(err) {
@@ -1,14 +1,15 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
+	"io"
 	"strings"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 
+	"github.com/justrnr500/pearls/internal/query"
+	"github.com/justrnr500/pearls/internal/render"
 	"github.com/justrnr500/pearls/internal/storage"
 )
 
@@ -24,8 +25,38 @@ Examples:
   pearls list --tag pii
   pearls list --status active
   pearls list --scope backend
+  pearls list --scope payments,auth
+  pearls list --scope payments,pci --all-scopes
   pearls list --required
-  pearls list --json`,
+  pearls list --owner @org/data
+  pearls list --query 'type:table (tag:pii OR tag:sensitive) -status:archived'
+  pearls list --query 'ns:db.* updated:>30d'
+  pearls list --sort updated:desc --limit 20
+  pearls list --limit 20 --cursor <next_cursor>
+  pearls list --json
+  pearls list --format csv
+  pearls list --template '{{.ID}} {{.Type}}'
+
+Sort by id, name, namespace, type, status, parent, priority, required,
+created, updated, or verified, each optionally followed by :asc or :desc.
+The default is priority:desc,namespace,name. With --limit, the output ends
+with a cursor for the next page; pass it back with the same --sort.
+
+Queries combine field:value terms, all of which must match. OR, parentheses,
+and a leading - (or NOT) work as expected; bare words search the ID, name,
+namespace, description, and tags. Fields:
+
+  id, name, type, status, parent   Exact match; * and ? are wildcards
+  ns                               Namespace and everything beneath it
+  desc                             Description contains the text
+  tag, scope, glob, owner, ref     Any element matches; scope includes children
+  required                         true or false
+  priority                         Number, with >, >=, <, <=
+  created, updated, verified       Date (2025-01-31) or age (30d, 2w, 36h),
+                                   with >, >=, <, <=; updated:>30d means in
+                                   the last 30 days
+  has                              tags, globs, scopes, owners, refs, parent,
+                                   connection, or verified`,
 	Aliases: []string{"ls"},
 	RunE:    runList,
 }
@@ -36,8 +67,14 @@ var (
 	listStatus    string
 	listTag       string
 	listScope     string
-	listJSON      bool
+	listAllScopes bool
+	listOwner     string
+	listQuery     string
+	listOutput    outputFlags
 	listLimit     int
+	listOffset    int
+	listCursor    string
+	listSort      string
 	listRequired  bool
 )
 
@@ -47,70 +84,146 @@ func init() {
 	listCmd.Flags().StringVarP(&listType, "type", "t", "", "Filter by type")
 	listCmd.Flags().StringVarP(&listStatus, "status", "s", "", "Filter by status")
 	listCmd.Flags().StringVar(&listTag, "tag", "", "Filter by tag")
-	listCmd.Flags().StringVar(&listScope, "scope", "", "Filter by scope")
-	listCmd.Flags().BoolVar(&listJSON, "json", false, "Output as JSON")
+	listCmd.Flags().StringVar(&listScope, "scope", "", "Filter by comma-separated scopes, including child scopes (any by default)")
+	listCmd.Flags().BoolVar(&listAllScopes, "all-scopes", false, "With --scope, require every scope instead of any")
+	listCmd.Flags().StringVar(&listOwner, "owner", "", "Filter by owner, including inherited and CODEOWNERS owners")
+	listCmd.Flags().StringVarP(&listQuery, "query", "q", "", "Filter with a query, e.g. 'type:table tag:pii -status:archived'")
+	addOutputFlags(listCmd, &listOutput)
 	listCmd.Flags().IntVar(&listLimit, "limit", 0, "Limit number of results")
+	listCmd.Flags().IntVar(&listOffset, "offset", 0, "Skip this many results")
+	listCmd.Flags().StringVar(&listCursor, "cursor", "", "Continue after a previous page (its next_cursor)")
+	listCmd.Flags().StringVar(&listSort, "sort", "", "Sort by comma-separated fields, each with optional :asc or :desc (e.g. updated:desc,name)")
 	listCmd.Flags().BoolVar(&listRequired, "required", false, "Filter to required pearls only")
 }
 
 func runList(cmd *cobra.Command, args []string) error {
-	store, _, err := getStore()
+	if err := listOutput.validate(); err != nil {
+		return err
+	}
+
+	store, paths, err := getStore()
 	if err != nil {
 		return err
 	}
 	defer store.Close()
 
+	scopes, err := scopeFilter(configuredScopes(), listScope, listAllScopes)
+	if err != nil {
+		return fmt.Errorf("invalid --scope: %w", err)
+	}
+	where, err := compileQuery(listQuery)
+	if err != nil {
+		return err
+	}
+	sortBy, err := storage.ParseSort(listSort)
+	if err != nil {
+		return fmt.Errorf("invalid --sort: %w", err)
+	}
+	if listOwner != "" && listCursor != "" {
+		return fmt.Errorf("--cursor cannot be combined with --owner; use --offset")
+	}
+
 	opts := storage.ListOptions{
 		Namespace: listNamespace,
 		Type:      listType,
 		Status:    listStatus,
 		Tag:       listTag,
-		Scope:     listScope,
-		Limit:     listLimit,
+		Scopes:    scopes,
+		Where:     where,
+		Sort:      sortBy,
+	}
+	// Ownership is resolved in Go, so paging applies after filtering
+	if listOwner == "" {
+		opts.Limit = listLimit
+		opts.Offset = listOffset
+		opts.Cursor = listCursor
 	}
 	if listRequired {
 		req := true
 		opts.Required = &req
 	}
 
-	pearls, err := store.List(opts)
+	page, err := store.ListPage(opts)
 	if err != nil {
 		return fmt.Errorf("list pearls: %w", err)
 	}
+	pearls := page.Pearls
 
-	if listJSON {
-		enc := json.NewEncoder(os.Stdout)
-		enc.SetIndent("", "  ")
-		return enc.Encode(map[string]interface{}{
-			"pearls": pearls,
-			"count":  len(pearls),
-		})
+	if listOwner != "" {
+		resolver, err := ownerResolver(store, paths)
+		if err != nil {
+			return err
+		}
+		if pearls, err = filterByOwner(resolver, pearls, listOwner); err != nil {
+			return err
+		}
+		page.Total = len(pearls)
+		pearls = pearls[min(listOffset, len(pearls)):]
+		if listLimit > 0 && len(pearls) > listLimit {
+			pearls = pearls[:listLimit]
+		}
 	}
 
-	if len(pearls) == 0 {
-		fmt.Println("No pearls found.")
-		return nil
+	meta := map[string]interface{}{"total": page.Total}
+	if page.NextCursor != "" {
+		meta["next_cursor"] = page.NextCursor
 	}
+	return listOutput.render(&render.Result{
+		Kind:    "pearl",
+		Items:   pearls,
+		Meta:    meta,
+		Columns: pearlTableColumns,
+		Text: func(w io.Writer) error {
+			if len(pearls) == 0 {
+				fmt.Fprintln(w, "No pearls found.")
+				return nil
+			}
+
+			tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			fmt.Fprintln(tw, "ID\tTYPE\tSTATUS\tDESCRIPTION")
+			fmt.Fprintln(tw, "──\t────\t──────\t───────────")
+
+			for _, p := range pearls {
+				desc := p.Description
+				if len(desc) > 50 {
+					desc = desc[:47] + "..."
+				}
+				marker := " "
+				if p.Required {
+					marker = "*"
+				}
+				fmt.Fprintf(tw, "%s%s\t%s\t%s\t%s\n", marker, p.ID, p.Type, p.Status, desc)
+			}
+			tw.Flush()
+
+			if page.Total > len(pearls) {
+				fmt.Fprintf(w, "\n%d of %d pearl(s)\n", len(pearls), page.Total)
+			} else {
+				fmt.Fprintf(w, "\n%d pearl(s)\n", len(pearls))
+			}
+			if page.NextCursor != "" {
+				fmt.Fprintf(w, "Next page: --cursor %s\n", page.NextCursor)
+			}
+			return nil
+		},
+	})
+}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "ID\tTYPE\tSTATUS\tDESCRIPTION")
-	fmt.Fprintln(w, "──\t────\t──────\t───────────")
-
-	for _, p := range pearls {
-		desc := p.Description
-		if len(desc) > 50 {
-			desc = desc[:47] + "..."
-		}
-		marker := " "
-		if p.Required {
-			marker = "*"
-		}
-		fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\n", marker, p.ID, p.Type, p.Status, desc)
+// compileQuery compiles a --query value, expanding scopes through the
+// registry in config.yaml. An empty query compiles to nil.
+func compileQuery(q string) (*query.Clause, error) {
+	if strings.TrimSpace(q) == "" {
+		return nil, nil
 	}
-	w.Flush()
+	clause, err := query.ParseAndCompile(q, queryOptions())
+	if err != nil {
+		return nil, fmt.Errorf("invalid --query: %w", err)
+	}
+	return clause, nil
+}
 
-	fmt.Printf("\n%d pearl(s)\n", len(pearls))
-	return nil
+func queryOptions() query.Options {
+	return query.Options{ExpandScope: configuredScopes().Expand}
 }
 
 // truncate truncates a string to max length with ellipsis
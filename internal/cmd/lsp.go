@@ -0,0 +1,61 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/justrnr500/pearls/internal/lsp"
+)
+
+var lspCmd = &cobra.Command{
+	Use:   "lsp",
+	Short: "Run a language server for editing pearl content",
+	Long: `Run a language server that speaks the Language Server Protocol over
+stdin and stdout. Configure your editor to start 'pearls lsp' for the
+markdown files under .pearls/content.
+
+While you edit a content file it:
+  - completes pearl IDs inside [[...]] links and in the frontmatter
+    references list, declared scopes in the scopes list, and section
+    anchors after [[id#
+  - completes column names after a table pearl's name or ID and a dot,
+    e.g. users.em, from the table's Columns section
+  - shows a linked or referenced pearl's type, status, description, and
+    tags on hover
+  - jumps to a linked pearl's content file, at the linked section
+  - reports broken wiki-links and references, undeclared scopes, content
+    files no pearl points to, and violations of the validation rules in
+    config.yaml
+
+Log messages go to stderr.
+
+Examples:
+  pearls lsp`,
+	Args: cobra.NoArgs,
+	RunE: runLSP,
+}
+
+func init() {
+	rootCmd.AddCommand(lspCmd)
+}
+
+func runLSP(cmd *cobra.Command, args []string) error {
+	store, _, err := getStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	opts := lsp.Options{Log: log.New(os.Stderr, "pearls lsp: ", log.LstdFlags)}
+	if cfg, err := getConfig(); err == nil {
+		opts.Config = cfg
+	}
+
+	if err := lsp.New(store, opts).Serve(os.Stdin, os.Stdout); err != nil {
+		return fmt.Errorf("serve: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,126 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/storage"
+)
+
+// mergeDriverAttribute is the .gitattributes line that routes pearls.jsonl
+// merges through the pearls merge driver.
+const mergeDriverAttribute = config.DirName + "/" + config.JSONLFile + " merge=pearls"
+
+// historyMergeAttribute lets git union-merge the append-only history log.
+const historyMergeAttribute = config.DirName + "/" + config.HistoryFile + " merge=union"
+
+var mergeDriverCmd = &cobra.Command{
+	Use:   "merge-driver <base> <ours> <theirs>",
+	Short: "Git merge driver for pearls.jsonl",
+	Long: `Three-way merge of pearls.jsonl, keyed by pearl ID.
+
+Git invokes this with the common ancestor (%O), our version (%A), and their
+version (%B). The merged result is written back to the "ours" file.
+
+Scalar fields take whichever side changed; if both changed, the side with
+the newer updated_at wins. Tags, globs, scopes, and references are merged
+as sets. Exits non-zero only for true conflicts: a field changed on both
+sides with the same updated_at, or a pearl deleted on one side and modified
+on the other.
+
+'pearls init' registers the driver automatically. For an existing catalog:
+  pearls merge-driver --install
+
+Manual setup:
+  git config merge.pearls.driver "pearls merge-driver %O %A %B"
+  echo '.pearls/pearls.jsonl merge=pearls' >> .gitattributes`,
+	Args: func(cmd *cobra.Command, args []string) error {
+		if mergeDriverInstall {
+			return cobra.NoArgs(cmd, args)
+		}
+		return cobra.ExactArgs(3)(cmd, args)
+	},
+	RunE: runMergeDriver,
+}
+
+var mergeDriverInstall bool
+
+func init() {
+	rootCmd.AddCommand(mergeDriverCmd)
+	mergeDriverCmd.Flags().BoolVar(&mergeDriverInstall, "install", false, "Register the merge driver in git config and .gitattributes")
+}
+
+func runMergeDriver(cmd *cobra.Command, args []string) error {
+	if mergeDriverInstall {
+		cwd, err := os.Getwd()
+		if err != nil {
+			return fmt.Errorf("get working directory: %w", err)
+		}
+		root, err := config.FindRoot(cwd)
+		if err != nil {
+			return fmt.Errorf("not in a pearls directory: run 'pearls init' first")
+		}
+		if err := installMergeDriver(root); err != nil {
+			return err
+		}
+		fmt.Println("✓ Registered git merge driver for", mergeDriverAttribute)
+		return nil
+	}
+
+	base, err := storage.NewJSONL(args[0]).ReadAll()
+	if err != nil {
+		return fmt.Errorf("read base: %w", err)
+	}
+	ours, err := storage.NewJSONL(args[1]).ReadAll()
+	if err != nil {
+		return fmt.Errorf("read ours: %w", err)
+	}
+	theirs, err := storage.NewJSONL(args[2]).ReadAll()
+	if err != nil {
+		return fmt.Errorf("read theirs: %w", err)
+	}
+
+	merged, conflicts := storage.MergePearls(base, ours, theirs)
+
+	if err := storage.NewJSONL(args[1]).WriteAll(merged); err != nil {
+		return fmt.Errorf("write merged result: %w", err)
+	}
+
+	if len(conflicts) > 0 {
+		for _, c := range conflicts {
+			fmt.Fprintf(os.Stderr, "pearls merge conflict: %s\n", c)
+		}
+		// Git prints our output; conflicts are not a usage mistake
+		cmd.SilenceUsage = true
+		return fmt.Errorf("%d unresolved pearl conflict(s)", len(conflicts))
+	}
+
+	return nil
+}
+
+// installMergeDriver registers the pearls merge driver for the repository at
+// root: the driver command in git config and the attributes in .gitattributes.
+func installMergeDriver(root string) error {
+	if err := exec.Command("git", "-C", root, "rev-parse", "--git-dir").Run(); err != nil {
+		return fmt.Errorf("not a git repository: %s", root)
+	}
+
+	gitConfig := [][]string{
+		{"merge.pearls.name", "pearls JSONL merge driver"},
+		{"merge.pearls.driver", "pearls merge-driver %O %A %B"},
+	}
+	for _, kv := range gitConfig {
+		if out, err := exec.Command("git", "-C", root, "config", kv[0], kv[1]).CombinedOutput(); err != nil {
+			return fmt.Errorf("git config %s: %v: %s", kv[0], err, out)
+		}
+	}
+
+	ensureGitignoreEntry(filepath.Join(root, ".gitattributes"), mergeDriverAttribute)
+	ensureGitignoreEntry(filepath.Join(root, ".gitattributes"), historyMergeAttribute)
+	return nil
+}
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/justrnr500/pearls/internal/storage"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate",
+	Short: "Show schema version and upgrade stored data",
+	Long: `Report the SQLite schema version and JSONL record format, and bring
+both up to date.
+
+Pending database migrations are applied automatically whenever pearls opens
+the database; this command lists the migrations that have been applied. JSONL
+records written in an older format are rewritten in the current format.
+
+Examples:
+  pearls migrate          # Show history, rewrite outdated JSONL records
+  pearls migrate --json   # Machine-readable status`,
+	RunE: runMigrate,
+}
+
+var migrateJSON bool
+
+func init() {
+	rootCmd.AddCommand(migrateCmd)
+	migrateCmd.Flags().BoolVar(&migrateJSON, "json", false, "Output as JSON")
+}
+
+// migrateStatus is the JSON output of pearls migrate.
+type migrateStatus struct {
+	SchemaVersion  int                        `json:"schema_version"`
+	LatestVersion  int                        `json:"latest_version"`
+	History        []storage.AppliedMigration `json:"history"`
+	JSONLFormat    int                        `json:"jsonl_format"`
+	JSONLRewritten int                        `json:"jsonl_rewritten"`
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	store, _, err := getStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	version, err := store.DB().SchemaVersion()
+	if err != nil {
+		return err
+	}
+	history, err := store.DB().MigrationHistory()
+	if err != nil {
+		return err
+	}
+
+	outdated, err := outdatedJSONLRecords(store)
+	if err != nil {
+		return err
+	}
+	if outdated > 0 {
+		if err := store.SyncToJSONL(); err != nil {
+			return fmt.Errorf("rewrite jsonl: %w", err)
+		}
+	}
+
+	status := migrateStatus{
+		SchemaVersion:  version,
+		LatestVersion:  storage.LatestSchemaVersion(),
+		History:        history,
+		JSONLFormat:    storage.JSONLFormat,
+		JSONLRewritten: outdated,
+	}
+
+	if migrateJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(status)
+	}
+
+	fmt.Printf("Schema version: %d (latest %d)\n", status.SchemaVersion, status.LatestVersion)
+	for _, m := range history {
+		fmt.Printf("  v%-3d %-40s %s\n", m.Version, m.Description, m.AppliedAt.Local().Format("2006-01-02 15:04"))
+	}
+	fmt.Printf("JSONL format: %d\n", status.JSONLFormat)
+	if outdated > 0 {
+		fmt.Printf("✓ Rewrote %d JSONL records in format %d\n", outdated, storage.JSONLFormat)
+	} else {
+		fmt.Println("✓ Up to date")
+	}
+
+	return nil
+}
+
+// outdatedJSONLRecords counts JSONL records written in an older format,
+// including unversioned records from before formats were recorded.
+func outdatedJSONLRecords(store *storage.Store) (int, error) {
+	formats, err := store.JSONL().Formats()
+	if err != nil {
+		return 0, fmt.Errorf("read jsonl formats: %w", err)
+	}
+
+	outdated := 0
+	for v, n := range formats {
+		if v < storage.JSONLFormat {
+			outdated += n
+		}
+	}
+	return outdated, nil
+}
@@ -35,12 +35,16 @@ to CLAUDE.md, agents.md, or both.
 Use --hooks to install a Claude Code hook script that automatically injects
 relevant pearl context based on your current git changes.
 
+Use --git-hooks to install git post-merge/post-checkout hooks that rebuild
+the pearls database whenever a pull or checkout changes pearls.jsonl.
+
 Examples:
   pearls onboard                    # Update CLAUDE.md (default)
   pearls onboard --target agents    # Update agents.md
   pearls onboard --target all       # Update both
   pearls onboard --force            # Overwrite existing pearls section
-  pearls onboard --hooks            # Set up Claude Code context hook`,
+  pearls onboard --hooks            # Set up Claude Code context hook
+  pearls onboard --git-hooks        # Rebuild database after git pull/checkout`,
 	RunE: runOnboard,
 }
 
@@ -49,6 +53,7 @@ var (
 	onboardForce  bool
 	onboardHooks  bool
 	onboardSeeds  bool
+	onboardGit    bool
 )
 
 func init() {
@@ -57,6 +62,7 @@ func init() {
 	onboardCmd.Flags().BoolVar(&onboardForce, "force", false, "Overwrite existing pearls section")
 	onboardCmd.Flags().BoolVar(&onboardHooks, "hooks", false, "Set up Claude Code hooks for automatic context injection")
 	onboardCmd.Flags().BoolVar(&onboardSeeds, "seeds", false, "Create seed pearls (sys.triggers and sys.reference)")
+	onboardCmd.Flags().BoolVar(&onboardGit, "git-hooks", false, "Install git hooks that rebuild the database after pull/checkout")
 }
 
 func runOnboard(cmd *cobra.Command, args []string) error {
@@ -96,6 +102,16 @@ func runOnboard(cmd *cobra.Command, args []string) error {
 		}
 	}
 
+	if onboardGit {
+		installed, err := installGitHooks(cwd)
+		if err != nil {
+			return fmt.Errorf("install git hooks: %w", err)
+		}
+		for _, path := range installed {
+			fmt.Printf("✓ Installed git hook: %s\n", path)
+		}
+	}
+
 	if onboardSeeds {
 		if err := createSeedPearls(); err != nil {
 			return fmt.Errorf("create seed pearls: %w", err)
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/render"
+)
+
+// outputFlags are the output options shared by read commands.
+type outputFlags struct {
+	json     bool
+	format   string
+	template string
+}
+
+func addOutputFlags(cmd *cobra.Command, o *outputFlags) {
+	cmd.Flags().BoolVar(&o.json, "json", false, "Output as JSON (same as --format json)")
+	cmd.Flags().StringVarP(&o.format, "format", "o", "", "Output format: text, json, ndjson, yaml, csv, markdown, or xml")
+	cmd.Flags().StringVar(&o.template, "template", "", "Render each result with a Go template, e.g. '{{.ID}} {{.Type}}'")
+}
+
+// options resolves the flags into render options.
+func (o *outputFlags) options() (render.Options, error) {
+	format, err := render.ParseFormat(o.format)
+	if err != nil {
+		return render.Options{}, fmt.Errorf("invalid --format: %w", err)
+	}
+	if o.json {
+		if o.format != "" && format != render.JSON {
+			return render.Options{}, fmt.Errorf("--json conflicts with --format %s", o.format)
+		}
+		format = render.JSON
+	}
+	if o.template != "" && (o.json || o.format != "") {
+		return render.Options{}, fmt.Errorf("--template cannot be combined with --json or --format")
+	}
+	return render.Options{Format: format, Template: o.template}, nil
+}
+
+// validate checks the flags before a command does any work.
+func (o *outputFlags) validate() error {
+	_, err := o.options()
+	return err
+}
+
+// isText reports whether output is for a person: the text format with no
+// template. Prompts and progress are only shown then.
+func (o *outputFlags) isText() bool {
+	opts, err := o.options()
+	return err == nil && opts.Format == render.Text && opts.Template == ""
+}
+
+// render writes r to stdout.
+func (o *outputFlags) render(r *render.Result) error {
+	opts, err := o.options()
+	if err != nil {
+		return err
+	}
+	return render.Render(os.Stdout, r, opts)
+}
+
+// pearlTableColumns are the csv and markdown columns for pearl lists.
+var pearlTableColumns = []render.Column{
+	{Name: "id", Value: func(v interface{}) string { return asPearl(v).ID }},
+	{Name: "type", Value: func(v interface{}) string { return string(asPearl(v).Type) }},
+	{Name: "status", Value: func(v interface{}) string { return string(asPearl(v).Status) }},
+	{Name: "priority", Value: func(v interface{}) string { return strconv.Itoa(asPearl(v).Priority) }},
+	{Name: "required", Value: func(v interface{}) string { return strconv.FormatBool(asPearl(v).Required) }},
+	{Name: "tags", Value: func(v interface{}) string { return strings.Join(asPearl(v).Tags, ",") }},
+	{Name: "description", Value: func(v interface{}) string { return asPearl(v).Description }},
+}
+
+// asPearl returns the pearl in a pearl or document result item.
+func asPearl(v interface{}) *pearl.Pearl {
+	if d, ok := v.(render.Document); ok {
+		return d.Pearl
+	}
+	return v.(*pearl.Pearl)
+}
+
+// documentsResult is the result for pearls as agent context, shared by
+// context and clutch.
+func documentsResult(docs []render.Document, opts render.DocumentOptions) *render.Result {
+	return &render.Result{
+		Kind:    "pearl",
+		Items:   docs,
+		Columns: pearlTableColumns,
+		Text: func(w io.Writer) error {
+			return render.WriteDocuments(w, docs, opts)
+		},
+		Markdown: func(w io.Writer) error {
+			return render.WriteMarkdown(w, docs, opts)
+		},
+		XML: func(w io.Writer) error {
+			return render.WriteXML(w, docs, opts)
+		},
+	}
+}
@@ -0,0 +1,45 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/justrnr500/pearls/internal/render"
+)
+
+func TestOutputFlags(t *testing.T) {
+	tests := []struct {
+		flags    outputFlags
+		want     render.Options
+		wantText bool
+		wantErr  string
+	}{
+		{flags: outputFlags{}, want: render.Options{Format: render.Text}, wantText: true},
+		{flags: outputFlags{json: true}, want: render.Options{Format: render.JSON}},
+		{flags: outputFlags{json: true, format: "json"}, want: render.Options{Format: render.JSON}},
+		{flags: outputFlags{format: "yml"}, want: render.Options{Format: render.YAML}},
+		{flags: outputFlags{template: "{{.ID}}"}, want: render.Options{Format: render.Text, Template: "{{.ID}}"}},
+		{flags: outputFlags{json: true, format: "csv"}, wantErr: "--json conflicts with --format csv"},
+		{flags: outputFlags{format: "csv", template: "{{.ID}}"}, wantErr: "--template cannot be combined"},
+		{flags: outputFlags{format: "toml"}, wantErr: "invalid --format"},
+	}
+	for _, tt := range tests {
+		got, err := tt.flags.options()
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%+v: error = %v, want %q", tt.flags, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%+v: %v", tt.flags, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("%+v: options = %+v, want %+v", tt.flags, got, tt.want)
+		}
+		if text := tt.flags.isText(); text != tt.wantText {
+			t.Errorf("%+v: isText = %v, want %v", tt.flags, text, tt.wantText)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/owners"
+	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/storage"
+)
+
+var ownersCmd = &cobra.Command{
+	Use:   "owners <id>",
+	Short: "Show who owns a pearl",
+	Long: `Show a pearl's effective owners and where they come from.
+
+Owners are resolved in order:
+  1. owners set on the pearl (pearls update <id> --owners @alice)
+  2. owners of the nearest ancestor namespace pearl (db.postgres for
+     db.postgres.users)
+  3. CODEOWNERS rules for the files the pearl's globs match
+
+Examples:
+  pearls owners db.postgres.users
+  pearls owners db.postgres.users --json`,
+	Args: cobra.ExactArgs(1),
+	RunE: runOwners,
+}
+
+var ownersJSON bool
+
+func init() {
+	rootCmd.AddCommand(ownersCmd)
+	ownersCmd.Flags().BoolVar(&ownersJSON, "json", false, "Output as JSON")
+}
+
+func runOwners(cmd *cobra.Command, args []string) error {
+	id := args[0]
+
+	store, paths, err := getStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	p, err := store.Get(id)
+	if err != nil {
+		return fmt.Errorf("get pearl: %w", err)
+	}
+	if p == nil {
+		return fmt.Errorf("pearl not found: %s", id)
+	}
+
+	resolver, err := ownerResolver(store, paths)
+	if err != nil {
+		return err
+	}
+	res, err := resolver.Resolve(p)
+	if err != nil {
+		return fmt.Errorf("resolve owners: %w", err)
+	}
+
+	if ownersJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(struct {
+			ID string `json:"id"`
+			*owners.Resolution
+		}{id, res})
+	}
+
+	if len(res.Owners) == 0 {
+		fmt.Printf("%s has no owners\n", id)
+		return nil
+	}
+
+	fmt.Printf("%s\n", strings.Join(res.Owners, " "))
+	fmt.Printf("  (%s)\n", describeOwnerSource(res))
+	return nil
+}
+
+// ownerResolver builds a resolver over the store and the project's
+// CODEOWNERS file, if any.
+func ownerResolver(store *storage.Store, paths *config.Paths) (*owners.Resolver, error) {
+	root := filepath.Dir(paths.Root)
+	co, err := owners.FindCodeowners(root)
+	if err != nil {
+		return nil, err
+	}
+	r := &owners.Resolver{
+		Lookup:     store.Get,
+		Codeowners: co,
+	}
+	if co != nil {
+		r.Files = owners.RepoFiles(root)
+	}
+	return r, nil
+}
+
+// describeOwnerSource explains where resolved owners came from.
+func describeOwnerSource(res *owners.Resolution) string {
+	switch res.Source {
+	case owners.SourceInherited:
+		return "inherited from " + res.From
+	case owners.SourceCodeowners:
+		return "from CODEOWNERS: " + res.From
+	default:
+		return "set on pearl"
+	}
+}
+
+// filterByOwner keeps the pearls whose effective owners include owner.
+func filterByOwner(resolver *owners.Resolver, pearls []*pearl.Pearl, owner string) ([]*pearl.Pearl, error) {
+	kept := []*pearl.Pearl{}
+	for _, p := range pearls {
+		res, err := resolver.Resolve(p)
+		if err != nil {
+			return nil, fmt.Errorf("resolve owners: %w", err)
+		}
+		if res.Has(owner) {
+			kept = append(kept, p)
+		}
+	}
+	return kept, nil
+}
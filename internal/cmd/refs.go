@@ -1,12 +1,15 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
+	"io"
+	"sort"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
+
+	"github.com/justrnr500/pearls/internal/render"
 )
 
 var refsCmd = &cobra.Command{
@@ -18,22 +21,30 @@ Displays bidirectional relationships:
   - Outgoing: pearls that this pearl references
   - Incoming: pearls that reference this pearl
 
+Wiki-links in content, [[id]], and embeds, ![[id#section]], count as
+references too: outgoing from the pearl that contains them, and as
+backlinks on the pearls they name.
+
 Examples:
   pearls refs db.postgres.orders
-  pearls refs db.postgres.users --json`,
+  pearls refs db.postgres.users --json
+  pearls refs db.postgres.users --format csv`,
 	Args: cobra.ExactArgs(1),
 	RunE: runRefs,
 }
 
-var refsJSON bool
+var refsOutput outputFlags
 
 func init() {
 	rootCmd.AddCommand(refsCmd)
-	refsCmd.Flags().BoolVar(&refsJSON, "json", false, "Output as JSON")
+	addOutputFlags(refsCmd, &refsOutput)
 }
 
 func runRefs(cmd *cobra.Command, args []string) error {
 	id := args[0]
+	if err := refsOutput.validate(); err != nil {
+		return err
+	}
 
 	store, _, err := getStore()
 	if err != nil {
@@ -50,76 +61,149 @@ func runRefs(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("pearl not found: %s", id)
 	}
 
-	// Outgoing references (what this pearl references)
-	outgoing := p.References
-	if outgoing == nil {
-		outgoing = []string{}
+	// Outgoing references (what this pearl references or links to)
+	outgoing := newRefSet()
+	for _, ref := range p.References {
+		outgoing.add(ref, "reference")
+	}
+	links, err := store.Links(p)
+	if err != nil {
+		return fmt.Errorf("read links: %w", err)
+	}
+	for _, l := range links {
+		outgoing.add(l.Target, l.Via())
 	}
 
-	// Incoming references (what references this pearl)
-	incoming, err := store.DB().FindReferencingPearls(id)
+	// Incoming references (what references or links to this pearl)
+	incoming := newRefSet()
+	referencing, err := store.DB().FindReferencingPearls(id)
 	if err != nil {
 		return fmt.Errorf("find referencing pearls: %w", err)
 	}
-
-	if refsJSON {
-		enc := json.NewEncoder(os.Stdout)
-		enc.SetIndent("", "  ")
-		return enc.Encode(map[string]interface{}{
-			"id":            id,
-			"references":    outgoing,
-			"referenced_by": incoming,
-		})
+	for _, ref := range referencing {
+		incoming.add(ref, "reference")
 	}
-
-	// Table output
-	fmt.Printf("%s\n", id)
-	fmt.Printf("\n")
-
-	if len(outgoing) == 0 && len(incoming) == 0 {
-		fmt.Printf("No references.\n")
-		return nil
+	graph, err := store.LinkGraph()
+	if err != nil {
+		return fmt.Errorf("find backlinks: %w", err)
 	}
-
-	if len(outgoing) > 0 {
-		fmt.Printf("References (outgoing):\n")
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		for _, ref := range outgoing {
-			// Try to get the referenced pearl for more info
-			refPearl, _ := store.Get(ref)
-			if refPearl != nil {
-				desc := refPearl.Description
-				if len(desc) > 40 {
-					desc = desc[:37] + "..."
-				}
-				fmt.Fprintf(w, "  → %s\t%s\t%s\n", ref, refPearl.Type, desc)
-			} else {
-				fmt.Fprintf(w, "  → %s\t(not found)\t\n", ref)
+	backlinks := make([]string, 0, len(graph))
+	for from := range graph {
+		backlinks = append(backlinks, from)
+	}
+	sort.Strings(backlinks)
+	for _, from := range backlinks {
+		for _, l := range graph[from] {
+			if l.Target == id {
+				incoming.add(from, l.Via())
 			}
 		}
-		w.Flush()
 	}
 
-	if len(incoming) > 0 {
-		if len(outgoing) > 0 {
-			fmt.Printf("\n")
+	var entries []refEntry
+	for _, dir := range []struct {
+		name string
+		refs *refSet
+	}{{"outgoing", outgoing}, {"incoming", incoming}} {
+		for _, ref := range dir.refs.ids {
+			e := refEntry{Direction: dir.name, ID: ref, Via: dir.refs.via[ref]}
+			if refPearl, _ := store.Get(ref); refPearl != nil {
+				e.Found, e.Type, e.Description = true, string(refPearl.Type), refPearl.Description
+			}
+			entries = append(entries, e)
 		}
-		fmt.Printf("Referenced by (incoming):\n")
-		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-		for _, ref := range incoming {
-			refPearl, _ := store.Get(ref)
-			if refPearl != nil {
-				desc := refPearl.Description
-				if len(desc) > 40 {
-					desc = desc[:37] + "..."
+	}
+
+	return refsOutput.render(&render.Result{
+		Kind:  "reference",
+		Items: entries,
+		Meta:  map[string]interface{}{"id": id},
+		Columns: []render.Column{
+			{Name: "direction", Value: func(v interface{}) string { return v.(refEntry).Direction }},
+			{Name: "id", Value: func(v interface{}) string { return v.(refEntry).ID }},
+			{Name: "type", Value: func(v interface{}) string { return v.(refEntry).Type }},
+			{Name: "description", Value: func(v interface{}) string { return v.(refEntry).Description }},
+			{Name: "via", Value: func(v interface{}) string { return strings.Join(v.(refEntry).Via, ",") }},
+		},
+		Text: func(w io.Writer) error {
+			fmt.Fprintf(w, "%s\n", id)
+			fmt.Fprintf(w, "\n")
+
+			if len(entries) == 0 {
+				fmt.Fprintf(w, "No references.\n")
+				return nil
+			}
+
+			if len(outgoing.ids) > 0 {
+				fmt.Fprintf(w, "References (outgoing):\n")
+				printRefs(w, entries, "outgoing", "→")
+			}
+			if len(incoming.ids) > 0 {
+				if len(outgoing.ids) > 0 {
+					fmt.Fprintf(w, "\n")
 				}
-				fmt.Fprintf(w, "  ← %s\t%s\t%s\n", ref, refPearl.Type, desc)
-			} else {
-				fmt.Fprintf(w, "  ← %s\t\t\n", ref)
+				fmt.Fprintf(w, "Referenced by (incoming):\n")
+				printRefs(w, entries, "incoming", "←")
 			}
+			return nil
+		},
+	})
+}
+
+// refEntry is one reference to or from a pearl.
+type refEntry struct {
+	Direction   string `json:"direction"` // outgoing or incoming
+	ID          string `json:"id"`
+	Found       bool   `json:"found"`
+	Type        string `json:"type,omitempty"`
+	Description string `json:"description,omitempty"`
+	// Via lists how the pearls are connected: reference, link, or embed.
+	Via []string `json:"via"`
+}
+
+// refSet collects referenced IDs in order, with how each is connected.
+type refSet struct {
+	ids []string
+	via map[string][]string
+}
+
+func newRefSet() *refSet {
+	return &refSet{via: make(map[string][]string)}
+}
+
+func (r *refSet) add(id, via string) {
+	if _, ok := r.via[id]; !ok {
+		r.ids = append(r.ids, id)
+	}
+	for _, v := range r.via[id] {
+		if v == via {
+			return
 		}
-		w.Flush()
 	}
+	r.via[id] = append(r.via[id], via)
+}
 
-	return nil
+// printRefs prints the entries in one direction as a table.
+func printRefs(w io.Writer, entries []refEntry, direction, arrow string) {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, e := range entries {
+		if e.Direction != direction {
+			continue
+		}
+		// Plain references are unmarked; links and embeds are labeled
+		var via string
+		if len(e.Via) != 1 || e.Via[0] != "reference" {
+			via = " (" + strings.Join(e.Via, ", ") + ")"
+		}
+		if !e.Found {
+			fmt.Fprintf(tw, "  %s %s\t(not found)\t%s\n", arrow, e.ID, via)
+			continue
+		}
+		desc := e.Description
+		if len(desc) > 40 {
+			desc = desc[:37] + "..."
+		}
+		fmt.Fprintf(tw, "  %s %s\t%s\t%s%s\n", arrow, e.ID, e.Type, desc, via)
+	}
+	tw.Flush()
 }
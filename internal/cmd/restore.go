@@ -0,0 +1,82 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/justrnr500/pearls/internal/history"
+)
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <id> --rev <rev>",
+	Short: "Roll a pearl back to a past revision",
+	Long: `Restore a pearl's metadata and content to a revision from 'pearls history'.
+
+Restoring a deleted pearl recreates it. Restoring a deletion revision
+restores the state just before the pearl was deleted. The restore is itself
+recorded in history, so it can be undone.
+
+Examples:
+  pearls restore db.postgres.users --rev 2
+  pearls restore db.postgres.users --rev 3f2a9c1   # commit, for git history`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRestore,
+}
+
+var restoreRev string
+
+func init() {
+	rootCmd.AddCommand(restoreCmd)
+	restoreCmd.Flags().StringVar(&restoreRev, "rev", "", "Revision to restore (required)")
+	restoreCmd.MarkFlagRequired("rev")
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	id := args[0]
+
+	store, paths, err := getStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	source, revs, err := loadRevisions(store, paths, id)
+	if err != nil {
+		return err
+	}
+	if len(revs) == 0 {
+		return fmt.Errorf("no history for %s", id)
+	}
+
+	rev, err := history.Find(revs, restoreRev)
+	if err != nil {
+		return err
+	}
+	if rev.Pearl == nil {
+		return fmt.Errorf("revision r%d has no metadata to restore", rev.Rev)
+	}
+
+	p := *rev.Pearl
+	p.UpdatedAt = time.Now()
+
+	var content *string
+	text, ok, err := source.Content(rev)
+	if err != nil {
+		return err
+	}
+	if ok {
+		content = &text
+	} else if p.ContentPath != "" {
+		fmt.Fprintf(os.Stderr, "Warning: content for r%d is not available; keeping current content\n", rev.Rev)
+	}
+
+	if err := store.Restore(&p, content); err != nil {
+		return fmt.Errorf("restore pearl: %w", err)
+	}
+
+	fmt.Printf("✓ Restored %s to r%d\n", id, rev.Rev)
+	return nil
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/storage"
+)
+
+var scopesCmd = &cobra.Command{
+	Use:   "scopes",
+	Short: "List scopes and how many pearls use them",
+	Long: `List the scopes declared in config.yaml as a tree, with the number of
+pearls in each, followed by any scopes pearls use that are not declared.
+
+Declare scopes under 'scopes' in config.yaml. A scope with a parent is part
+of it: '--scope backend' also matches pearls scoped to its children.
+
+  scopes:
+    backend:
+      description: Server-side services
+    payments:
+      description: Checkout, billing, and refunds
+      parent: backend
+
+Counts show pearls with the scope itself, then the total including child
+scopes.
+
+Examples:
+  pearls scopes
+  pearls scopes --json`,
+	RunE: runScopes,
+}
+
+var scopesJSON bool
+
+func init() {
+	rootCmd.AddCommand(scopesCmd)
+	scopesCmd.Flags().BoolVar(&scopesJSON, "json", false, "Output as JSON")
+}
+
+// scopeSummary is one scope in 'pearls scopes' output.
+type scopeSummary struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Parent      string   `json:"parent,omitempty"`
+	Declared    bool     `json:"declared"`
+	Pearls      int      `json:"pearls"`
+	Total       int      `json:"total"`
+	Children    []string `json:"children,omitempty"`
+}
+
+func runScopes(cmd *cobra.Command, args []string) error {
+	store, _, err := getStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	registry := configuredScopes()
+
+	pearls, err := store.List(storage.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("list pearls: %w", err)
+	}
+
+	summaries := summarizeScopes(registry, pearls)
+
+	if scopesJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(summaries)
+	}
+
+	if len(summaries) == 0 {
+		fmt.Println("No scopes declared or used.")
+		return nil
+	}
+
+	byName := make(map[string]*scopeSummary)
+	for i := range summaries {
+		byName[summaries[i].Name] = &summaries[i]
+	}
+
+	var show func(name string, depth int)
+	show = func(name string, depth int) {
+		s := byName[name]
+		label := strings.Repeat("  ", depth) + s.Name
+		count := fmt.Sprintf("%d", s.Pearls)
+		if s.Total != s.Pearls {
+			count = fmt.Sprintf("%d (%d with children)", s.Pearls, s.Total)
+		}
+		fmt.Printf("  %-24s %-20s %s\n", label, count, s.Description)
+		for _, child := range s.Children {
+			show(child, depth+1)
+		}
+	}
+	for _, root := range registry.Roots() {
+		show(root, 0)
+	}
+
+	var undeclared []string
+	for _, s := range summaries {
+		if !s.Declared {
+			undeclared = append(undeclared, fmt.Sprintf("%s (%d)", s.Name, s.Pearls))
+		}
+	}
+	if len(undeclared) > 0 {
+		if len(registry) > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("Undeclared: %s\n", strings.Join(undeclared, ", "))
+	}
+
+	return nil
+}
+
+// summarizeScopes counts pearls per scope: declared scopes in registry
+// order (parents before children), then undeclared scopes by name.
+func summarizeScopes(registry config.Scopes, pearls []*pearl.Pearl) []scopeSummary {
+	direct := make(map[string]int)
+	for _, p := range pearls {
+		for _, s := range p.Scopes {
+			direct[s]++
+		}
+	}
+
+	var summaries []scopeSummary
+	var add func(name string)
+	add = func(name string) {
+		sc := registry[name]
+		expanded := toSet(registry.Expand(name))
+		total := 0
+		for _, p := range pearls {
+			for _, s := range p.Scopes {
+				if expanded[s] {
+					total++
+					break
+				}
+			}
+		}
+		children := registry.Children(name)
+		summaries = append(summaries, scopeSummary{
+			Name:        name,
+			Description: sc.Description,
+			Parent:      sc.Parent,
+			Declared:    true,
+			Pearls:      direct[name],
+			Total:       total,
+			Children:    children,
+		})
+		for _, child := range children {
+			add(child)
+		}
+	}
+	for _, root := range registry.Roots() {
+		add(root)
+	}
+
+	var undeclared []string
+	for name := range direct {
+		if !registry.Declared(name) {
+			undeclared = append(undeclared, name)
+		}
+	}
+	sort.Strings(undeclared)
+	for _, name := range undeclared {
+		summaries = append(summaries, scopeSummary{Name: name, Pearls: direct[name], Total: direct[name]})
+	}
+
+	return summaries
+}
+
+// configuredScopes returns the scope registry from config.yaml, or an empty
+// one if the config cannot be read.
+func configuredScopes() config.Scopes {
+	cfg, err := getConfig()
+	if err != nil {
+		return nil
+	}
+	return cfg.Scopes
+}
+
+// scopeFilter turns a --scope value such as "payments,auth" into a storage
+// filter, as config.Scopes.Filter does.
+func scopeFilter(registry config.Scopes, spec string, all bool) ([][]string, error) {
+	if spec == "" {
+		return nil, nil
+	}
+
+	var names []string
+	for _, s := range strings.Split(spec, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			names = append(names, s)
+		}
+	}
+	if len(names) == 0 {
+		return nil, fmt.Errorf("no scopes in %q", spec)
+	}
+	return registry.Filter(names, all)
+}
+
+// warnUndeclaredScopes prints a warning for scopes missing from a
+// non-empty registry.
+func warnUndeclaredScopes(scopes []string) {
+	registry := configuredScopes()
+	if len(registry) == 0 {
+		return
+	}
+	for _, s := range scopes {
+		if !registry.Declared(s) {
+			fmt.Fprintf(os.Stderr, "Warning: scope %q is not declared in config.yaml (see 'pearls scopes')\n", s)
+		}
+	}
+}
+
+func toSet(list []string) map[string]bool {
+	set := make(map[string]bool, len(list))
+	for _, v := range list {
+		set[v] = true
+	}
+	return set
+}
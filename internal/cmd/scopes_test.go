@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+var testScopes = config.Scopes{
+	"backend":  {Description: "Server-side services"},
+	"payments": {Description: "Checkout and billing", Parent: "backend"},
+	"pci":      {Parent: "payments"},
+	"auth":     {Parent: "backend"},
+	"frontend": {},
+}
+
+func TestScopeFilter(t *testing.T) {
+	tests := []struct {
+		spec string
+		all  bool
+		want [][]string
+	}{
+		{"", false, nil},
+		{"frontend", false, [][]string{{"frontend"}}},
+		{"backend", false, [][]string{{"backend", "auth", "payments", "pci"}}},
+		{"payments, frontend", false, [][]string{{"payments", "pci", "frontend"}}},
+		{"payments,frontend", true, [][]string{{"payments", "pci"}, {"frontend"}}},
+		{"undeclared", false, [][]string{{"undeclared"}}},
+	}
+	for _, tt := range tests {
+		got, err := scopeFilter(testScopes, tt.spec, tt.all)
+		if err != nil {
+			t.Errorf("scopeFilter(%q): %v", tt.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("scopeFilter(%q, %v) = %v, want %v", tt.spec, tt.all, got, tt.want)
+		}
+	}
+
+	for _, spec := range []string{",", "Bad Scope"} {
+		if _, err := scopeFilter(testScopes, spec, false); err == nil {
+			t.Errorf("scopeFilter(%q): expected error", spec)
+		}
+	}
+}
+
+func TestSummarizeScopes(t *testing.T) {
+	pearls := []*pearl.Pearl{
+		{ID: "a", Scopes: []string{"payments"}},
+		{ID: "b", Scopes: []string{"backend", "pci"}},
+		{ID: "c", Scopes: []string{"frontend", "legacy"}},
+	}
+
+	var got []string
+	totals := make(map[string][2]int)
+	for _, s := range summarizeScopes(testScopes, pearls) {
+		got = append(got, s.Name)
+		totals[s.Name] = [2]int{s.Pearls, s.Total}
+		if s.Declared != testScopes.Declared(s.Name) {
+			t.Errorf("%s: declared = %v", s.Name, s.Declared)
+		}
+	}
+
+	want := []string{"backend", "auth", "payments", "pci", "frontend", "legacy"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("order = %v, want %v", got, want)
+	}
+	wantTotals := map[string][2]int{
+		"backend":  {1, 2}, // b directly, a through payments; b counted once
+		"auth":     {0, 0},
+		"payments": {1, 2},
+		"pci":      {1, 1},
+		"frontend": {1, 1},
+		"legacy":   {1, 1},
+	}
+	if !reflect.DeepEqual(totals, wantTotals) {
+		t.Errorf("counts = %v, want %v", totals, wantTotals)
+	}
+}
+
+func TestCheckDeclaredScopes(t *testing.T) {
+	store, tmpDir := setupDoctorTestStore(t)
+	defer store.Close()
+
+	now := time.Now()
+	for _, p := range []*pearl.Pearl{
+		{ID: "test.a", Name: "a", Scopes: []string{"payments"}},
+		{ID: "test.b", Name: "b", Scopes: []string{"payments", "legacy"}},
+		{ID: "test.c", Name: "c", Scopes: []string{"legacy"}},
+	} {
+		p.Namespace, p.Type, p.Status, p.CreatedAt, p.UpdatedAt = "test", pearl.TypeTable, pearl.StatusActive, now, now
+		store.Create(p, "# "+p.Name)
+	}
+
+	// No registry: scopes are free-form
+	configPath := filepath.Join(tmpDir, "config.yaml")
+	os.WriteFile(configPath, []byte("project:\n  name: test\n"), 0644)
+	if result := checkDeclaredScopes(store, configPath); !result.Passed {
+		t.Errorf("without registry: %v", result.Issues)
+	}
+
+	os.WriteFile(configPath, []byte("scopes:\n  payments:\n    description: Billing\n"), 0644)
+	result := checkDeclaredScopes(store, configPath)
+	want := []string{"legacy is not declared in config.yaml (used by test.b, test.c)"}
+	if result.Passed || !reflect.DeepEqual(result.Issues, want) {
+		t.Errorf("issues = %v, want %v", result.Issues, want)
+	}
+}
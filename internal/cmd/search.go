@@ -1,38 +1,54 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
+	"io"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/spf13/cobra"
 
+	"github.com/justrnr500/pearls/internal/markdown"
 	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/query"
+	"github.com/justrnr500/pearls/internal/render"
+	"github.com/justrnr500/pearls/internal/storage"
 )
 
 var searchCmd = &cobra.Command{
-	Use:   "search <query>",
+	Use:   "search [keyword]",
 	Short: "Search pearls",
 	Long: `Search for pearls by keyword.
 
-Searches across ID, name, namespace, description, and tags.
+Searches across ID, name, namespace, description, and tags. Use --query
+to filter with the query language described in 'pearls list --help',
+with or without a keyword.
 
 Examples:
   pearls search customer
   pearls search "user email"
   pearls search orders --type table
-  pearls search analytics --json`,
-	Args: cobra.ExactArgs(1),
+  pearls search orders --query 'tag:pii OR tag:sensitive'
+  pearls search --query 'type:table updated:>30d'
+  pearls search analytics --json
+  pearls search analytics --format yaml
+  pearls search email --sections
+
+--sections also searches each pearl's content, and reports the anchors of
+the sections that mention the keyword. Pass one to 'pearls context' as
+ID#anchor to include only that section.`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: runSearch,
 }
 
 var (
-	searchType   string
-	searchStatus string
-	searchTag    string
-	searchJSON   bool
-	searchLimit  int
+	searchType     string
+	searchStatus   string
+	searchTag      string
+	searchQuery    string
+	searchSections bool
+	searchOutput   outputFlags
+	searchLimit    int
 )
 
 func init() {
@@ -40,12 +56,22 @@ func init() {
 	searchCmd.Flags().StringVarP(&searchType, "type", "t", "", "Filter by type")
 	searchCmd.Flags().StringVarP(&searchStatus, "status", "s", "", "Filter by status")
 	searchCmd.Flags().StringVar(&searchTag, "tag", "", "Filter by tag")
-	searchCmd.Flags().BoolVar(&searchJSON, "json", false, "Output as JSON")
+	searchCmd.Flags().StringVarP(&searchQuery, "query", "q", "", "Filter with a query, e.g. 'type:table tag:pii'")
+	searchCmd.Flags().BoolVar(&searchSections, "sections", false, "Also search content, and report the sections that mention the keyword")
+	addOutputFlags(searchCmd, &searchOutput)
 	searchCmd.Flags().IntVar(&searchLimit, "limit", 50, "Maximum results")
 }
 
 func runSearch(cmd *cobra.Command, args []string) error {
-	query := args[0]
+	if len(args) == 0 && searchQuery == "" {
+		return fmt.Errorf("a keyword or --query must be provided")
+	}
+	if searchSections && len(args) == 0 {
+		return fmt.Errorf("--sections needs a keyword")
+	}
+	if err := searchOutput.validate(); err != nil {
+		return err
+	}
 
 	store, _, err := getStore()
 	if err != nil {
@@ -53,51 +79,153 @@ func runSearch(cmd *cobra.Command, args []string) error {
 	}
 	defer store.Close()
 
-	return runKeywordSearch(store, query)
+	var results []*pearl.Pearl
+	var label string
+	var filter *query.Clause
+	if searchQuery == "" {
+		label = args[0]
+		results, err = store.Search(args[0], searchLimit)
+		if err != nil {
+			return fmt.Errorf("search: %w", err)
+		}
+	} else {
+		n, err := query.Parse(searchQuery)
+		if err != nil {
+			return fmt.Errorf("invalid --query: %w", err)
+		}
+		if searchSections {
+			// Content matches must still satisfy the query
+			if filter, err = query.Compile(n, queryOptions()); err != nil {
+				return fmt.Errorf("invalid --query: %w", err)
+			}
+		}
+		// A keyword is one more term of the query
+		if len(args) > 0 {
+			keyword := &query.Term{Value: args[0]}
+			if and, ok := n.(*query.And); ok {
+				and.Nodes = append([]query.Node{keyword}, and.Nodes...)
+			} else {
+				n = &query.And{Nodes: []query.Node{keyword, n}}
+			}
+		}
+		where, err := query.Compile(n, queryOptions())
+		if err != nil {
+			return fmt.Errorf("invalid --query: %w", err)
+		}
+		results, err = store.List(storage.ListOptions{Where: where, Limit: searchLimit})
+		if err != nil {
+			return fmt.Errorf("search: %w", err)
+		}
+		label = n.String()
+	}
+
+	var sections map[string][]string
+	if searchSections {
+		results, sections, err = searchContent(store, args[0], results, filter)
+		if err != nil {
+			return err
+		}
+	}
+	return printSearchResults(label, results, sections)
 }
 
-func runKeywordSearch(store interface {
-	Search(string, int) ([]*pearl.Pearl, error)
-}, query string) error {
-	// Perform keyword search
-	results, err := store.Search(query, searchLimit)
+// searchContent finds the sections of each result's content that mention
+// the keyword, by anchor. Pearls matching filter whose content mentions
+// the keyword are added after the results, up to --limit.
+func searchContent(store *storage.Store, keyword string, results []*pearl.Pearl, filter *query.Clause) ([]*pearl.Pearl, map[string][]string, error) {
+	candidates, err := store.List(storage.ListOptions{Where: filter})
 	if err != nil {
-		return fmt.Errorf("search: %w", err)
+		return nil, nil, fmt.Errorf("search content: %w", err)
 	}
 
-	// Apply additional filters
-	filtered := filterPearls(results)
-
-	if searchJSON {
-		enc := json.NewEncoder(os.Stdout)
-		enc.SetIndent("", "  ")
-		return enc.Encode(map[string]interface{}{
-			"query":   query,
-			"results": filtered,
-			"count":   len(filtered),
-		})
+	sections := make(map[string][]string)
+	mentions := func(p *pearl.Pearl) bool {
+		content, err := store.GetContent(p)
+		if err != nil {
+			return false
+		}
+		var anchors []string
+		for _, s := range markdown.Parse(content, 1).Mentions(keyword) {
+			anchors = append(anchors, s.Anchor())
+		}
+		if len(anchors) > 0 {
+			sections[p.ID] = anchors
+		}
+		return len(anchors) > 0
 	}
 
-	if len(filtered) == 0 {
-		fmt.Printf("No results for %q\n", query)
-		return nil
+	found := make(map[string]bool, len(results))
+	for _, p := range results {
+		found[p.ID] = true
+		mentions(p)
 	}
+	for _, p := range candidates {
+		if searchLimit > 0 && len(results) >= searchLimit {
+			break
+		}
+		if !found[p.ID] && mentions(p) {
+			results = append(results, p)
+		}
+	}
+	return results, sections, nil
+}
 
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	fmt.Fprintln(w, "ID\tTYPE\tSTATUS\tDESCRIPTION")
-	fmt.Fprintln(w, "──\t────\t──────\t───────────")
+func printSearchResults(query string, results []*pearl.Pearl, sections map[string][]string) error {
+	// Apply additional filters
+	filtered := filterPearls(results)
 
-	for _, p := range filtered {
-		desc := p.Description
-		if len(desc) > 50 {
-			desc = desc[:47] + "..."
+	meta := map[string]interface{}{"query": query}
+	if sections != nil {
+		matched := make(map[string][]string)
+		for _, p := range filtered {
+			if anchors := sections[p.ID]; len(anchors) > 0 {
+				matched[p.ID] = anchors
+			}
 		}
-		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", p.ID, p.Type, p.Status, desc)
+		meta["sections"] = matched
 	}
-	w.Flush()
 
-	fmt.Printf("\n%d result(s) for %q\n", len(filtered), query)
-	return nil
+	return searchOutput.render(&render.Result{
+		Kind:    "pearl",
+		Items:   filtered,
+		Meta:    meta,
+		Columns: pearlTableColumns,
+		Text: func(w io.Writer) error {
+			if len(filtered) == 0 {
+				fmt.Fprintf(w, "No results for %q\n", query)
+				return nil
+			}
+
+			tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+			if sections != nil {
+				fmt.Fprintln(tw, "ID\tTYPE\tSTATUS\tDESCRIPTION\tSECTIONS")
+				fmt.Fprintln(tw, "──\t────\t──────\t───────────\t────────")
+			} else {
+				fmt.Fprintln(tw, "ID\tTYPE\tSTATUS\tDESCRIPTION")
+				fmt.Fprintln(tw, "──\t────\t──────\t───────────")
+			}
+
+			for _, p := range filtered {
+				desc := p.Description
+				if len(desc) > 50 {
+					desc = desc[:47] + "..."
+				}
+				if sections != nil {
+					anchors := make([]string, len(sections[p.ID]))
+					for i, a := range sections[p.ID] {
+						anchors[i] = "#" + a
+					}
+					fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", p.ID, p.Type, p.Status, desc, strings.Join(anchors, " "))
+					continue
+				}
+				fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", p.ID, p.Type, p.Status, desc)
+			}
+			tw.Flush()
+
+			fmt.Fprintf(w, "\n%d result(s) for %q\n", len(filtered), query)
+			return nil
+		},
+	})
 }
 
 func filterPearls(pearls []*pearl.Pearl) []*pearl.Pearl {
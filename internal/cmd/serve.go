@@ -0,0 +1,132 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/justrnr500/pearls/internal/server"
+)
+
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Serve the catalog as a JSON API over HTTP",
+	Long: `Serve the catalog over HTTP for tools that would otherwise shell out
+to the CLI, such as chat bots and editor extensions.
+
+Endpoints:
+  GET    /v1/pearls                 List, with the filters of 'pearls list':
+                                    namespace, type, status, tag, scope,
+                                    all_scopes, required, q, sort, limit,
+                                    offset, cursor
+  GET    /v1/pearls/{id}            Get a pearl's metadata
+  GET    /v1/pearls/{id}/content    Get its markdown (sections=a,b; raw=true
+                                    leaves embeds unexpanded)
+  GET    /v1/pearls/{id}/refs       References, links, and backlinks
+  GET    /v1/search?q=keyword       Keyword search
+  POST   /v1/context                Agent context for ids, paths, scopes, or
+                                    a query, as 'pearls context' gives it
+  POST   /v1/pearls                 Create a pearl (--write)
+  PATCH  /v1/pearls/{id}            Update a pearl (--write)
+  DELETE /v1/pearls/{id}            Archive a pearl, or delete it with
+                                    force=true (--write)
+
+Lists use the same JSON envelope as --json. GET responses carry an ETag
+and answer If-None-Match with 304 Not Modified. Errors are JSON bodies:
+{"error": {"status": 404, "message": "..."}}.
+
+The server only reads unless --write is given. Writes must send
+Content-Type: application/json and are refused when their Origin header
+names another site, so web pages cannot change the catalog. It picks up
+changes to pearls.jsonl, such as from a git pull, without a restart, and
+finishes open requests before exiting on Ctrl-C or SIGTERM.
+
+Examples:
+  pearls serve
+  pearls serve --addr :7777
+  pearls serve --addr 127.0.0.1:8080 --write`,
+	Args: cobra.NoArgs,
+	RunE: runServe,
+}
+
+var (
+	serveAddr  string
+	serveWrite bool
+	serveQuiet bool
+)
+
+// shutdownTimeout is how long open requests get to finish on exit.
+const shutdownTimeout = 10 * time.Second
+
+func init() {
+	rootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&serveAddr, "addr", "127.0.0.1:7777", "Address to listen on")
+	serveCmd.Flags().BoolVar(&serveWrite, "write", false, "Allow creating, updating, and deleting pearls")
+	serveCmd.Flags().BoolVar(&serveQuiet, "quiet", false, "Don't log requests")
+}
+
+func runServe(cmd *cobra.Command, args []string) error {
+	store, _, err := getStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	logger := log.New(os.Stderr, "", log.LstdFlags)
+	opts := server.Options{Write: serveWrite}
+	if cfg, err := getConfig(); err == nil {
+		opts.Config = cfg
+	}
+	if !serveQuiet {
+		opts.Log = logger
+	}
+
+	listener, err := net.Listen("tcp", serveAddr)
+	if err != nil {
+		return fmt.Errorf("listen: %w", err)
+	}
+	srv := &http.Server{
+		Handler:           server.New(store, opts),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(listener)
+	}()
+
+	mode := "read-only"
+	if serveWrite {
+		mode = "read-write"
+	}
+	fmt.Fprintf(os.Stderr, "✓ Serving pearls on http://%s (%s)\n", listener.Addr(), mode)
+
+	select {
+	case err := <-errc:
+		return fmt.Errorf("serve: %w", err)
+	case <-ctx.Done():
+	}
+
+	fmt.Fprintln(os.Stderr, "Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		return fmt.Errorf("shut down: %w", err)
+	}
+	if err := <-errc; err != nil && !errors.Is(err, http.ErrServerClosed) {
+		return fmt.Errorf("serve: %w", err)
+	}
+	return nil
+}
@@ -1,12 +1,15 @@
 package cmd
 
 import (
-	"encoding/json"
 	"fmt"
-	"os"
+	"io"
 	"strings"
 
 	"github.com/spf13/cobra"
+
+	"github.com/justrnr500/pearls/internal/owners"
+	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/render"
 )
 
 var showCmd = &cobra.Command{
@@ -17,26 +20,30 @@ var showCmd = &cobra.Command{
 Examples:
   pearls show db.postgres.users
   pearls show db.postgres.users --json
+  pearls show db.postgres.users --format markdown
   pearls show db.postgres.users --with-refs`,
 	Args: cobra.ExactArgs(1),
 	RunE: runShow,
 }
 
 var (
-	showJSON     bool
+	showOutput   outputFlags
 	showWithRefs bool
 )
 
 func init() {
 	rootCmd.AddCommand(showCmd)
-	showCmd.Flags().BoolVar(&showJSON, "json", false, "Output as JSON")
+	addOutputFlags(showCmd, &showOutput)
 	showCmd.Flags().BoolVar(&showWithRefs, "with-refs", false, "Include referenced pearls")
 }
 
 func runShow(cmd *cobra.Command, args []string) error {
 	id := args[0]
+	if err := showOutput.validate(); err != nil {
+		return err
+	}
 
-	store, _, err := getStore()
+	store, paths, err := getStore()
 	if err != nil {
 		return err
 	}
@@ -50,102 +57,118 @@ func runShow(cmd *cobra.Command, args []string) error {
 		return fmt.Errorf("pearl not found: %s", id)
 	}
 
-	if showJSON {
-		output := map[string]interface{}{
-			"pearl": p,
-		}
-
-		if showWithRefs && len(p.References) > 0 {
-			refs := []interface{}{}
-			for _, refID := range p.References {
-				ref, err := store.Get(refID)
-				if err == nil && ref != nil {
-					refs = append(refs, ref)
-				}
+	var refs []*pearl.Pearl
+	if showWithRefs {
+		refs = []*pearl.Pearl{}
+		for _, refID := range p.References {
+			if ref, err := store.Get(refID); err == nil && ref != nil {
+				refs = append(refs, ref)
 			}
-			output["references"] = refs
 		}
-
-		enc := json.NewEncoder(os.Stdout)
-		enc.SetIndent("", "  ")
-		return enc.Encode(output)
 	}
-
-	// Human-readable output
-	fmt.Printf("● %s\n", p.ID)
-	fmt.Printf("  Name:        %s\n", p.Name)
-	if p.Namespace != "" {
-		fmt.Printf("  Namespace:   %s\n", p.Namespace)
+	var meta map[string]interface{}
+	if refs != nil {
+		meta = map[string]interface{}{"references": refs}
 	}
-	fmt.Printf("  Type:        %s\n", p.Type)
-	fmt.Printf("  Status:      %s\n", p.Status)
 
-	if p.Description != "" {
-		fmt.Printf("  Description: %s\n", p.Description)
-	}
+	return showOutput.render(&render.Result{
+		Kind:    "pearl",
+		Items:   []*pearl.Pearl{p},
+		Meta:    meta,
+		Columns: pearlTableColumns,
+		Markdown: func(w io.Writer) error {
+			return render.WriteDocuments(w, []render.Document{{Pearl: p}}, render.DocumentOptions{Brief: true})
+		},
+		Text: func(w io.Writer) error {
+			fmt.Fprintf(w, "● %s\n", p.ID)
+			fmt.Fprintf(w, "  Name:        %s\n", p.Name)
+			if p.Namespace != "" {
+				fmt.Fprintf(w, "  Namespace:   %s\n", p.Namespace)
+			}
+			fmt.Fprintf(w, "  Type:        %s\n", p.Type)
+			fmt.Fprintf(w, "  Status:      %s\n", p.Status)
 
-	if len(p.Tags) > 0 {
-		fmt.Printf("  Tags:        %s\n", strings.Join(p.Tags, ", "))
-	}
+			if p.Description != "" {
+				fmt.Fprintf(w, "  Description: %s\n", p.Description)
+			}
 
-	if len(p.Globs) > 0 {
-		fmt.Printf("  Globs:       %s\n", strings.Join(p.Globs, ", "))
-	}
+			if len(p.Tags) > 0 {
+				fmt.Fprintf(w, "  Tags:        %s\n", strings.Join(p.Tags, ", "))
+			}
 
-	if len(p.Scopes) > 0 {
-		fmt.Printf("  Scopes:      %s\n", strings.Join(p.Scopes, ", "))
-	}
+			if len(p.Globs) > 0 {
+				fmt.Fprintf(w, "  Globs:       %s\n", strings.Join(p.Globs, ", "))
+			}
 
-	if p.Required {
-		fmt.Printf("  Required:    yes\n")
-	}
-	if p.Priority != 0 {
-		fmt.Printf("  Priority:    %d\n", p.Priority)
-	}
+			if len(p.Scopes) > 0 {
+				fmt.Fprintf(w, "  Scopes:      %s\n", strings.Join(p.Scopes, ", "))
+			}
 
-	if p.ContentPath != "" {
-		fmt.Printf("  Content:     %s\n", p.ContentPath)
-	}
+			if resolver, err := ownerResolver(store, paths); err == nil {
+				if res, err := resolver.Resolve(p); err == nil && len(res.Owners) > 0 {
+					owned := strings.Join(res.Owners, ", ")
+					if res.Source != owners.SourcePearl {
+						owned += " (" + describeOwnerSource(res) + ")"
+					}
+					fmt.Fprintf(w, "  Owners:      %s\n", owned)
+				}
+			}
 
-	if p.Connection != nil {
-		fmt.Printf("  Connection:\n")
-		fmt.Printf("    Type:      %s\n", p.Connection.Type)
-		if p.Connection.Host != "" {
-			fmt.Printf("    Host:      %s\n", p.Connection.Host)
-		}
-		if p.Connection.Port > 0 {
-			fmt.Printf("    Port:      %d\n", p.Connection.Port)
-		}
-		if p.Connection.Database != "" {
-			fmt.Printf("    Database:  %s\n", p.Connection.Database)
-		}
-	}
+			if p.Required {
+				fmt.Fprintf(w, "  Required:    yes\n")
+			}
+			if p.Priority != 0 {
+				fmt.Fprintf(w, "  Priority:    %d\n", p.Priority)
+			}
 
-	if len(p.References) > 0 {
-		fmt.Printf("  References:\n")
-		for _, ref := range p.References {
-			fmt.Printf("    → %s\n", ref)
-		}
+			if p.ContentPath != "" {
+				fmt.Fprintf(w, "  Content:     %s\n", p.ContentPath)
+			}
 
-		if showWithRefs {
-			fmt.Printf("\n  Referenced Pearls:\n")
-			for _, refID := range p.References {
-				ref, err := store.Get(refID)
-				if err != nil || ref == nil {
-					fmt.Printf("    %s (not found)\n", refID)
-					continue
+			if p.Connection != nil {
+				fmt.Fprintf(w, "  Connection:\n")
+				fmt.Fprintf(w, "    Type:      %s\n", p.Connection.Type)
+				if p.Connection.Host != "" {
+					fmt.Fprintf(w, "    Host:      %s\n", p.Connection.Host)
+				}
+				if p.Connection.Port > 0 {
+					fmt.Fprintf(w, "    Port:      %d\n", p.Connection.Port)
+				}
+				if p.Connection.Database != "" {
+					fmt.Fprintf(w, "    Database:  %s\n", p.Connection.Database)
 				}
-				fmt.Printf("    ● %s [%s] %s\n", ref.ID, ref.Type, ref.Description)
 			}
-		}
-	}
 
-	if p.Parent != "" {
-		fmt.Printf("  Parent:      %s\n", p.Parent)
-	}
+			if len(p.References) > 0 {
+				fmt.Fprintf(w, "  References:\n")
+				for _, ref := range p.References {
+					fmt.Fprintf(w, "    → %s\n", ref)
+				}
+
+				if showWithRefs {
+					fmt.Fprintf(w, "\n  Referenced Pearls:\n")
+					for _, refID := range p.References {
+						ref, err := store.Get(refID)
+						if err != nil || ref == nil {
+							fmt.Fprintf(w, "    %s (not found)\n", refID)
+							continue
+						}
+						fmt.Fprintf(w, "    ● %s [%s] %s\n", ref.ID, ref.Type, ref.Description)
+					}
+				}
+			}
 
-	fmt.Printf("  Created:     %s by %s\n", p.CreatedAt.Format("2006-01-02 15:04"), p.CreatedBy)
-	fmt.Printf("  Updated:     %s\n", p.UpdatedAt.Format("2006-01-02 15:04"))
+			if p.Parent != "" {
+				fmt.Fprintf(w, "  Parent:      %s\n", p.Parent)
+			}
+
+			fmt.Fprintf(w, "  Created:     %s by %s\n", p.CreatedAt.Format("2006-01-02 15:04"), p.CreatedBy)
+			fmt.Fprintf(w, "  Updated:     %s\n", p.UpdatedAt.Format("2006-01-02 15:04"))
+			if p.LastVerifiedAt != nil {
+				fmt.Fprintf(w, "  Verified:    %s by %s\n", p.LastVerifiedAt.Format("2006-01-02 15:04"), p.VerifiedBy)
+			}
 
-	return nil
+			return nil
+		},
+	})
 }
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/justrnr500/pearls/internal/markdown"
+	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/site"
+	"github.com/justrnr500/pearls/internal/storage"
+)
+
+var siteCmd = &cobra.Command{
+	Use:   "site",
+	Short: "Publish the catalog as a static HTML site",
+}
+
+var siteBuildCmd = &cobra.Command{
+	Use:   "build",
+	Short: "Render the catalog to static HTML",
+	Long: `Render every pearl to a static HTML site that can be published from CI
+to any static host.
+
+The site has a page per pearl, with its metadata, rendered content, and
+panels of what it references and what references it (including wiki-links
+and embeds); a page per namespace, with a namespace tree on every page;
+index pages by type, tag, and scope; a Mermaid graph of the references
+between pearls; and client-side search over search.json.
+
+Archived pearls are left out unless --archived is given. Output is
+deterministic, so rebuilding an unchanged catalog changes no files.
+Pages with diagrams load Mermaid from cdn.jsdelivr.net; everything else
+is self-contained.
+
+Examples:
+  pearls site build
+  pearls site build -o public/
+  pearls site build --clean --title "Data Catalog"`,
+	Args: cobra.NoArgs,
+	RunE: runSiteBuild,
+}
+
+var (
+	siteOutput   string
+	siteClean    bool
+	siteTitle    string
+	siteArchived bool
+)
+
+func init() {
+	rootCmd.AddCommand(siteCmd)
+	siteCmd.AddCommand(siteBuildCmd)
+	siteBuildCmd.Flags().StringVarP(&siteOutput, "output", "o", "dist", "Directory to write the site to")
+	siteBuildCmd.Flags().BoolVar(&siteClean, "clean", false, "Remove the output directory before building")
+	siteBuildCmd.Flags().StringVar(&siteTitle, "title", "", "Site title (default: project name from config)")
+	siteBuildCmd.Flags().BoolVar(&siteArchived, "archived", false, "Include archived pearls")
+}
+
+func runSiteBuild(cmd *cobra.Command, args []string) error {
+	store, paths, err := getStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	opts := site.Options{Title: siteTitle}
+	if cfg, err := getConfig(); err == nil {
+		if opts.Title == "" {
+			opts.Title = cfg.Project.Name
+		}
+		opts.Description = cfg.Project.Description
+	}
+
+	pearls, err := store.List(storage.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("list pearls: %w", err)
+	}
+	var entries []site.Entry
+	for _, p := range pearls {
+		if p.Status == pearl.StatusArchived && !siteArchived {
+			continue
+		}
+		content, err := store.GetContent(p)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %s: read content: %v\n", p.ID, err)
+		}
+		entries = append(entries, site.Entry{
+			Pearl:   p,
+			Content: transclude(store, p.ID, content),
+			Links:   markdown.Links(content),
+		})
+	}
+
+	if siteClean {
+		if err := cleanSiteDir(siteOutput, filepath.Dir(paths.Root)); err != nil {
+			return err
+		}
+	}
+	sum, err := site.Build(siteOutput, entries, opts)
+	if err != nil {
+		return fmt.Errorf("build site: %w", err)
+	}
+
+	fmt.Printf("✓ Built site for %d pearls in %s (%d files)\n", sum.Pearls, siteOutput, sum.Files)
+	return nil
+}
+
+// cleanSiteDir removes the output directory, refusing to remove the
+// project root or anything containing it.
+func cleanSiteDir(dir, projectRoot string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("resolve output directory: %w", err)
+	}
+	if rel, err := filepath.Rel(abs, projectRoot); err == nil && filepath.IsLocal(rel) {
+		return fmt.Errorf("refusing to clean %s: it contains the project", dir)
+	}
+	if err := os.RemoveAll(abs); err != nil {
+		return fmt.Errorf("clean output directory: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,150 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/storage"
+)
+
+var staleCmd = &cobra.Command{
+	Use:   "stale",
+	Short: "List pearls overdue for review",
+	Long: `List pearls whose last verification (or creation, if never verified) is
+older than their review interval, most overdue first.
+
+Intervals are set in config.yaml:
+
+  freshness:
+    default: 90d
+    types:
+      table: 30d
+    namespaces:
+      db.billing: 14d
+
+The most specific rule wins: namespace, then type, then default. Pearls
+with no interval never go stale. Mark a pearl reviewed with 'pearls verify'.
+
+Examples:
+  pearls stale
+  pearls stale --namespace db
+  pearls stale --json`,
+	RunE: runStale,
+}
+
+var (
+	staleJSON      bool
+	staleNamespace string
+	staleType      string
+)
+
+func init() {
+	rootCmd.AddCommand(staleCmd)
+	staleCmd.Flags().BoolVar(&staleJSON, "json", false, "Output as JSON")
+	staleCmd.Flags().StringVarP(&staleNamespace, "namespace", "n", "", "Filter by namespace prefix")
+	staleCmd.Flags().StringVarP(&staleType, "type", "t", "", "Filter by type")
+}
+
+// staleness describes a pearl that is overdue for review.
+type staleness struct {
+	ID             string     `json:"id"`
+	Type           string     `json:"type"`
+	LastVerifiedAt *time.Time `json:"last_verified_at,omitempty"`
+	VerifiedBy     string     `json:"verified_by,omitempty"`
+	IntervalDays   int        `json:"interval_days"`
+	OverdueDays    int        `json:"overdue_days"`
+
+	overdue time.Duration
+}
+
+// checkStale reports whether p is overdue for review as of now.
+func checkStale(cfg config.FreshnessConfig, p *pearl.Pearl, now time.Time) (*staleness, error) {
+	interval, err := cfg.Interval(p.Namespace, string(p.Type))
+	if err != nil || interval == 0 {
+		return nil, err
+	}
+
+	overdue := now.Sub(p.LastReviewed()) - interval
+	if overdue <= 0 {
+		return nil, nil
+	}
+
+	return &staleness{
+		ID:             p.ID,
+		Type:           string(p.Type),
+		LastVerifiedAt: p.LastVerifiedAt,
+		VerifiedBy:     p.VerifiedBy,
+		IntervalDays:   int(interval.Hours() / 24),
+		OverdueDays:    int(overdue.Hours() / 24),
+		overdue:        overdue,
+	}, nil
+}
+
+// describe summarizes a stale pearl's review state in one line.
+func (s *staleness) describe() string {
+	verified := "never verified"
+	if s.LastVerifiedAt != nil {
+		verified = fmt.Sprintf("last verified %s by %s", s.LastVerifiedAt.Format("2006-01-02"), s.VerifiedBy)
+	}
+	return fmt.Sprintf("%s; review every %dd, %dd overdue", verified, s.IntervalDays, s.OverdueDays)
+}
+
+func runStale(cmd *cobra.Command, args []string) error {
+	store, _, err := getStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+
+	pearls, err := store.List(storage.ListOptions{
+		Namespace: staleNamespace,
+		Type:      staleType,
+		Status:    string(pearl.StatusActive),
+	})
+	if err != nil {
+		return fmt.Errorf("list pearls: %w", err)
+	}
+
+	now := time.Now()
+	stale := []*staleness{}
+	for _, p := range pearls {
+		s, err := checkStale(cfg.Freshness, p, now)
+		if err != nil {
+			return fmt.Errorf("freshness config: %w", err)
+		}
+		if s != nil {
+			stale = append(stale, s)
+		}
+	}
+	sort.SliceStable(stale, func(i, j int) bool { return stale[i].overdue > stale[j].overdue })
+
+	if staleJSON {
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(stale)
+	}
+
+	if len(stale) == 0 {
+		fmt.Println("No stale pearls")
+		return nil
+	}
+
+	fmt.Printf("%d stale pearls:\n", len(stale))
+	for _, s := range stale {
+		fmt.Printf("  %-30s %-12s %s\n", s.ID, s.Type, s.describe())
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+func TestCheckStale(t *testing.T) {
+	now := time.Date(2025, 6, 1, 0, 0, 0, 0, time.UTC)
+	daysAgo := func(n int) time.Time { return now.Add(-time.Duration(n) * 24 * time.Hour) }
+
+	cfg := config.FreshnessConfig{
+		Default:    "90d",
+		Types:      map[string]string{"table": "30d"},
+		Namespaces: map[string]string{"db.billing": "2w", "scratch": "0"},
+	}
+
+	tests := []struct {
+		name      string
+		namespace string
+		typ       pearl.AssetType
+		created   int
+		verified  int // days ago; -1 if never verified
+		overdue   int // expected overdue days; -1 if not stale
+	}{
+		{"fresh default", "api", pearl.TypeAPI, 10, -1, -1},
+		{"stale by default", "api", pearl.TypeAPI, 100, -1, 10},
+		{"stale by type", "db", pearl.TypeTable, 45, -1, 15},
+		{"verification resets clock", "db", pearl.TypeTable, 400, 5, -1},
+		{"stale since verification", "db", pearl.TypeTable, 400, 40, 10},
+		{"namespace beats type", "db.billing.v2", pearl.TypeTable, 20, -1, 6},
+		{"zero interval never stale", "scratch", pearl.TypeTable, 1000, -1, -1},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &pearl.Pearl{
+				ID:        tt.namespace + ".x",
+				Namespace: tt.namespace,
+				Type:      tt.typ,
+				CreatedAt: daysAgo(tt.created),
+			}
+			if tt.verified >= 0 {
+				at := daysAgo(tt.verified)
+				p.LastVerifiedAt = &at
+				p.VerifiedBy = "alice"
+			}
+
+			s, err := checkStale(cfg, p, now)
+			if err != nil {
+				t.Fatalf("checkStale: %v", err)
+			}
+			if tt.overdue < 0 {
+				if s != nil {
+					t.Errorf("expected fresh, got %d days overdue", s.OverdueDays)
+				}
+				return
+			}
+			if s == nil {
+				t.Fatalf("expected stale")
+			}
+			if s.OverdueDays != tt.overdue {
+				t.Errorf("overdue = %d, want %d", s.OverdueDays, tt.overdue)
+			}
+		})
+	}
+}
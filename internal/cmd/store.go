@@ -5,11 +5,30 @@ import (
 	"os"
 
 	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/history"
 	"github.com/justrnr500/pearls/internal/storage"
 )
 
-// getStore finds the pearls root and returns an open store.
+// getStore finds the pearls root and returns an open store, rebuilt from
+// pearls.jsonl if that changed since the database last synced with it.
 func getStore() (*storage.Store, *config.Paths, error) {
+	store, paths, err := openStore()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// Rebuild transparently if pearls.jsonl changed underneath the database
+	// (git pull, checkout, merge). A broken JSONL is reported by doctor/sync.
+	if _, err := store.EnsureFresh(); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: database may be stale: %v\n", err)
+	}
+
+	return store, paths, nil
+}
+
+// openStore is getStore without the rebuild, for commands that must see
+// the database as it is, such as doctor --fix deciding which way to sync.
+func openStore() (*storage.Store, *config.Paths, error) {
 	cwd, err := os.Getwd()
 	if err != nil {
 		return nil, nil, fmt.Errorf("get working directory: %w", err)
@@ -26,6 +45,13 @@ func getStore() (*storage.Store, *config.Paths, error) {
 		return nil, nil, fmt.Errorf("open store: %w", err)
 	}
 
+	store.SetHistory(history.NewLog(paths.History, paths.Objects))
+
+	// Config errors are reported by doctor; fall back to defaults here
+	if cfg, err := config.Load(paths.Config); err == nil {
+		store.SetFrontmatter(cfg.Storage.Frontmatter)
+	}
+
 	return store, paths, nil
 }
 
@@ -0,0 +1,6 @@
+# >>> pearls >>>
+# Rebuild the local pearls database when pearls.jsonl changes (pull/checkout).
+if command -v pearls >/dev/null 2>&1; then
+  pearls sync >/dev/null 2>&1 || true
+fi
+# <<< pearls <<<
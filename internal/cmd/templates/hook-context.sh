@@ -23,13 +23,12 @@
   # Nothing changed — nothing to inject
   [ -z "$FILES" ] && exit 0
 
-  CONTEXT=""
+  # One lookup for all files: pearls matching several files appear once
+  ARGS=()
   while IFS= read -r FILE; do
-    RESULT="$(pearls context --for "$FILE" 2>/dev/null)" || true
-    if [ -n "$RESULT" ]; then
-      CONTEXT="${CONTEXT}${RESULT}"$'\n'
-    fi
+    ARGS+=(--for "$FILE")
   done <<< "$FILES"
+  CONTEXT="$(pearls context "${ARGS[@]}" 2>/dev/null)" || true
 
   # No matching pearls — exit cleanly
   [ -z "$CONTEXT" ] && exit 0
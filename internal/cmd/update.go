@@ -26,6 +26,7 @@ Examples:
   pearls update db.postgres.users --type view
   pearls update db.postgres.users --globs "src/models/**/*.go,db/migrations/*.sql"
   pearls update db.postgres.users --scopes backend,data-eng
+  pearls update db.postgres.users --owners @alice,@org/data
   pearls update db.postgres.users --required --priority 10
   pearls update db.postgres.users --no-required`,
 	Args: cobra.ExactArgs(1),
@@ -38,6 +39,7 @@ var (
 	updateType        string
 	updateGlobs       string
 	updateScopes      string
+	updateOwners      string
 	updateAddTags     []string
 	updateRemoveTags  []string
 	updateAddRefs     []string
@@ -53,8 +55,9 @@ func init() {
 	updateCmd.Flags().StringVarP(&updateDescription, "description", "d", "", "Update description")
 	updateCmd.Flags().StringVar(&updateStatus, "status", "", "Update status (active, deprecated, archived)")
 	updateCmd.Flags().StringVarP(&updateType, "type", "t", "", "Update asset type (lowercase alphanumeric + hyphens)")
-	updateCmd.Flags().StringVar(&updateGlobs, "globs", "", "Comma-separated file glob patterns for push-based context injection")
+	updateCmd.Flags().StringVar(&updateGlobs, "globs", "", "Comma-separated file glob patterns for push-based context injection (prefix with ! to exclude)")
 	updateCmd.Flags().StringVar(&updateScopes, "scopes", "", "Comma-separated scope names for scope-based injection")
+	updateCmd.Flags().StringVar(&updateOwners, "owners", "", `Comma-separated owners (use "" to clear and inherit)`)
 	updateCmd.Flags().StringSliceVar(&updateAddTags, "add-tag", nil, "Add tag(s)")
 	updateCmd.Flags().StringSliceVar(&updateRemoveTags, "remove-tag", nil, "Remove tag(s)")
 	updateCmd.Flags().StringSliceVar(&updateAddRefs, "add-ref", nil, "Add reference(s)")
@@ -133,10 +136,24 @@ func runUpdate(cmd *cobra.Command, args []string) error {
 		if err := pearl.ValidateScopes(scopes); err != nil {
 			return fmt.Errorf("invalid scopes: %w", err)
 		}
+		warnUndeclaredScopes(scopes)
 		p.Scopes = scopes
 		changed = true
 	}
 
+	// Update owners
+	if cmd.Flags().Changed("owners") {
+		var owners []string
+		if updateOwners != "" {
+			owners = strings.Split(updateOwners, ",")
+		}
+		if err := pearl.ValidateOwners(owners); err != nil {
+			return fmt.Errorf("invalid owners: %w", err)
+		}
+		p.Owners = owners
+		changed = true
+	}
+
 	// Add tags
 	if len(updateAddTags) > 0 {
 		tagSet := make(map[string]bool)
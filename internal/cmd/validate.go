@@ -0,0 +1,228 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/storage"
+	"github.com/justrnr500/pearls/internal/validate"
+)
+
+var validateCmd = &cobra.Command{
+	Use:   "validate [id...]",
+	Short: "Lint pearl metadata and content",
+	Long: `Check pearls against content rules and exit non-zero on errors.
+
+Rules:
+  description         Pearls have a one-line description (warning)
+  required-sections   Content has the sections configured for its type (error)
+  unfilled-template   Template sections are filled in (warning)
+  max-size            Content is within max_content_bytes (error)
+  forbidden-phrases   Content avoids forbidden_phrases (error)
+  heading-structure   One H1, no skipped levels, no duplicate headings (warning)
+
+Configure rules in config.yaml:
+
+  validation:
+    rules:
+      heading-structure: off
+      description: error
+    required_sections:
+      table: [Schema, Relationships]
+    max_content_bytes: 32768
+    forbidden_phrases: [TODO, TBD]
+
+With --strict, warnings also fail. --format sarif writes SARIF 2.1.0 for
+code scanning.
+
+Examples:
+  pearls validate
+  pearls validate db.postgres.users
+  pearls validate --strict
+  pearls validate --format sarif > pearls.sarif`,
+	RunE: runValidate,
+}
+
+var (
+	validateStrict    bool
+	validateFormat    string
+	validateJSON      bool
+	validateNamespace string
+)
+
+func init() {
+	rootCmd.AddCommand(validateCmd)
+	validateCmd.Flags().BoolVar(&validateStrict, "strict", false, "Fail on warnings as well as errors")
+	validateCmd.Flags().StringVar(&validateFormat, "format", "text", "Output format: text, json, or sarif")
+	validateCmd.Flags().BoolVar(&validateJSON, "json", false, "Output as JSON (same as --format json)")
+	validateCmd.Flags().StringVarP(&validateNamespace, "namespace", "n", "", "Only validate pearls in this namespace")
+}
+
+// validateReport is the JSON output of pearls validate.
+type validateReport struct {
+	Checked    int                  `json:"checked"`
+	Errors     int                  `json:"errors"`
+	Warnings   int                  `json:"warnings"`
+	Notes      int                  `json:"notes"`
+	Violations []validate.Violation `json:"violations"`
+}
+
+func runValidate(cmd *cobra.Command, args []string) error {
+	format := validateFormat
+	if validateJSON {
+		format = "json"
+	}
+	switch format {
+	case "text", "json", "sarif":
+	default:
+		return fmt.Errorf("invalid --format %q: use text, json, or sarif", format)
+	}
+	// From here on, failures are findings, not usage mistakes
+	cmd.SilenceUsage = true
+
+	store, paths, err := getStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	cfg, err := getConfig()
+	if err != nil {
+		return err
+	}
+	engine, err := validate.New(cfg.Validation)
+	if err != nil {
+		return fmt.Errorf("validation config: %w", err)
+	}
+
+	pearls, err := pearlsToValidate(store, args)
+	if err != nil {
+		return err
+	}
+
+	report := validateReport{Checked: len(pearls), Violations: []validate.Violation{}}
+	for _, p := range pearls {
+		t, err := validationTarget(store, paths, p)
+		if err != nil {
+			return err
+		}
+		report.Violations = append(report.Violations, engine.Check(t)...)
+	}
+	counts := validate.Count(report.Violations)
+	report.Errors = counts[validate.SeverityError]
+	report.Warnings = counts[validate.SeverityWarning]
+	report.Notes = counts[validate.SeverityNote]
+
+	switch format {
+	case "json":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(report); err != nil {
+			return err
+		}
+	case "sarif":
+		enc := json.NewEncoder(os.Stdout)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(engine.SARIF(report.Violations)); err != nil {
+			return err
+		}
+	default:
+		printViolations(report)
+	}
+
+	if report.Errors > 0 || (validateStrict && report.Warnings > 0) {
+		return fmt.Errorf("validation failed")
+	}
+	return nil
+}
+
+// pearlsToValidate returns the named pearls, or every active pearl.
+func pearlsToValidate(store *storage.Store, ids []string) ([]*pearl.Pearl, error) {
+	if len(ids) == 0 {
+		pearls, err := store.List(storage.ListOptions{
+			Namespace: validateNamespace,
+			Status:    string(pearl.StatusActive),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("list pearls: %w", err)
+		}
+		return pearls, nil
+	}
+
+	var pearls []*pearl.Pearl
+	for _, id := range ids {
+		p, err := store.Get(id)
+		if err != nil {
+			return nil, fmt.Errorf("get pearl: %w", err)
+		}
+		if p == nil {
+			return nil, fmt.Errorf("pearl not found: %s", id)
+		}
+		pearls = append(pearls, p)
+	}
+	return pearls, nil
+}
+
+// validationTarget loads a pearl's content for validation. Report paths are
+// relative to the project root.
+func validationTarget(store *storage.Store, paths *config.Paths, p *pearl.Pearl) (*validate.Target, error) {
+	root := filepath.Dir(paths.Root)
+	t := &validate.Target{
+		Pearl:        p,
+		BodyLine:     1,
+		Template:     store.Content().Template(p),
+		MetadataPath: relPath(root, paths.JSONL),
+	}
+	if p.ContentPath == "" || !store.Content().Exists(p.ContentPath) {
+		return t, nil
+	}
+
+	raw, err := store.Content().Read(p.ContentPath)
+	if err != nil {
+		return nil, fmt.Errorf("read content for %s: %w", p.ID, err)
+	}
+	body := storage.StripFrontmatter(raw)
+	t.HasContent = true
+	t.Content = body
+	t.BodyLine = strings.Count(raw[:len(raw)-len(body)], "\n") + 1
+	t.Path = relPath(root, filepath.Join(paths.Content, p.ContentPath))
+	return t, nil
+}
+
+func relPath(root, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil {
+		return rel
+	}
+	return path
+}
+
+func printViolations(report validateReport) {
+	var current string
+	for _, v := range report.Violations {
+		if v.PearlID != current {
+			if current != "" {
+				fmt.Println()
+			}
+			current = v.PearlID
+			fmt.Printf("%s\n", v.PearlID)
+		}
+		loc := v.Path
+		if v.Line > 0 {
+			loc = fmt.Sprintf("%s:%d", v.Path, v.Line)
+		}
+		fmt.Printf("  %-8s %-18s %s  %s\n", v.Severity, v.Rule, v.Message, loc)
+	}
+
+	if len(report.Violations) == 0 {
+		fmt.Printf("✓ %d pearls valid\n", report.Checked)
+		return
+	}
+	fmt.Printf("\n%d errors, %d warnings, %d notes in %d pearls checked\n", report.Errors, report.Warnings, report.Notes, report.Checked)
+}
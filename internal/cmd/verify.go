@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/justrnr500/pearls/internal/history"
+)
+
+var verifyCmd = &cobra.Command{
+	Use:   "verify <id> [id...]",
+	Short: "Mark pearls as reviewed and still accurate",
+	Long: `Record that pearls were reviewed and their documentation is still accurate.
+
+Sets last_verified_at to now and verified_by to the current user, which
+resets the pearl's freshness clock (see 'pearls stale'). updated_at is
+left alone, since a review is not an edit.
+
+Examples:
+  pearls verify db.postgres.users
+  pearls verify db.postgres.users db.postgres.orders --by alice`,
+	Args: cobra.MinimumNArgs(1),
+	RunE: runVerify,
+}
+
+var verifyBy string
+
+func init() {
+	rootCmd.AddCommand(verifyCmd)
+	verifyCmd.Flags().StringVar(&verifyBy, "by", "", "Reviewer name (default: $USER)")
+}
+
+func runVerify(cmd *cobra.Command, args []string) error {
+	store, _, err := getStore()
+	if err != nil {
+		return err
+	}
+	defer store.Close()
+
+	by := verifyBy
+	if by == "" {
+		by = history.Author()
+	}
+
+	now := time.Now()
+	for _, id := range args {
+		p, err := store.Get(id)
+		if err != nil {
+			return fmt.Errorf("get pearl: %w", err)
+		}
+		if p == nil {
+			return fmt.Errorf("pearl not found: %s", id)
+		}
+
+		p.LastVerifiedAt = &now
+		p.VerifiedBy = by
+		if err := store.Update(p, nil); err != nil {
+			return fmt.Errorf("update pearl: %w", err)
+		}
+
+		fmt.Printf("✓ Verified %s\n", id)
+	}
+
+	return nil
+}
@@ -20,16 +20,24 @@ const (
 	JSONLFile = "pearls.jsonl"
 	// ContentDir is the name of the content directory.
 	ContentDir = "content"
+	// HistoryFile is the name of the change history log.
+	HistoryFile = "history.jsonl"
+	// HistoryDir is the name of the directory holding historical content.
+	HistoryDir = "history"
 	// GitIgnoreFile is the name of the gitignore file.
 	GitIgnoreFile = ".gitignore"
 )
 
 // Config represents the pearls configuration.
 type Config struct {
-	Project  ProjectConfig     `yaml:"project"`
-	Storage  StorageConfig     `yaml:"storage"`
-	Defaults DefaultsConfig    `yaml:"defaults"`
-	Aliases  map[string]string `yaml:"aliases,omitempty"`
+	Project    ProjectConfig     `yaml:"project"`
+	Storage    StorageConfig     `yaml:"storage"`
+	Defaults   DefaultsConfig    `yaml:"defaults"`
+	Aliases    map[string]string `yaml:"aliases,omitempty"`
+	Scopes     Scopes            `yaml:"scopes,omitempty"`
+	Freshness  FreshnessConfig   `yaml:"freshness,omitempty"`
+	Context    ContextConfig     `yaml:"context,omitempty"`
+	Validation ValidationConfig  `yaml:"validation,omitempty"`
 }
 
 // ProjectConfig holds project identification settings.
@@ -41,6 +49,9 @@ type ProjectConfig struct {
 // StorageConfig holds storage settings.
 type StorageConfig struct {
 	ContentDir string `yaml:"content_dir"`
+	// Frontmatter makes content files carry pearl metadata in a YAML block,
+	// which takes precedence over pearls.jsonl on sync.
+	Frontmatter bool `yaml:"frontmatter,omitempty"`
 }
 
 // DefaultsConfig holds default values for new pearls.
@@ -87,6 +98,17 @@ func Load(path string) (*Config, error) {
 	return &cfg, nil
 }
 
+// Validate checks settings that parse as YAML but are not usable.
+func (c *Config) Validate() error {
+	if err := c.Scopes.Validate(); err != nil {
+		return err
+	}
+	if err := c.Freshness.Validate(); err != nil {
+		return err
+	}
+	return c.Validation.Validate()
+}
+
 // Save writes the configuration to a file.
 func (c *Config) Save(path string) error {
 	data, err := yaml.Marshal(c)
@@ -108,6 +130,8 @@ type Paths struct {
 	DB      string // pearls.db
 	JSONL   string // pearls.jsonl
 	Content string // content/
+	History string // history.jsonl
+	Objects string // history/
 }
 
 // ResolvePaths returns the paths for a pearls installation rooted at the given directory.
@@ -119,6 +143,8 @@ func ResolvePaths(root string) *Paths {
 		DB:      filepath.Join(pearlsDir, DBFile),
 		JSONL:   filepath.Join(pearlsDir, JSONLFile),
 		Content: filepath.Join(pearlsDir, ContentDir),
+		History: filepath.Join(pearlsDir, HistoryFile),
+		Objects: filepath.Join(pearlsDir, HistoryDir),
 	}
 }
 
@@ -0,0 +1,27 @@
+package config
+
+// ContextConfig holds settings for 'pearls context' and 'pearls clutch'.
+type ContextConfig struct {
+	// BriefSections lists, by pearl type, the content sections that brief
+	// output includes after the metadata, e.g. table: [columns]. A type
+	// set here replaces its default; an empty list includes none.
+	BriefSections map[string][]string `yaml:"brief_sections,omitempty"`
+}
+
+// defaultBriefSections name the sections of the content templates and of
+// introspected tables that are worth keeping in brief output.
+var defaultBriefSections = map[string][]string{
+	"table":    {"columns", "schema"},
+	"api":      {"endpoints"},
+	"endpoint": {"endpoints"},
+	"database": {"tables"},
+	"schema":   {"tables"},
+}
+
+// SectionsFor returns the sections brief output includes for a pearl type.
+func (c ContextConfig) SectionsFor(assetType string) []string {
+	if sections, ok := c.BriefSections[assetType]; ok {
+		return sections
+	}
+	return defaultBriefSections[assetType]
+}
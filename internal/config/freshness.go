@@ -0,0 +1,88 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// FreshnessConfig sets how often pearls must be re-verified. Intervals are
+// written like "90d", "2w", or "36h"; empty or "0" means never stale.
+//
+// The most specific rule wins: the longest matching namespace, then the
+// pearl's type, then the default.
+type FreshnessConfig struct {
+	Default    string            `yaml:"default,omitempty"`
+	Types      map[string]string `yaml:"types,omitempty"`
+	Namespaces map[string]string `yaml:"namespaces,omitempty"`
+	// FlagContext marks stale pearls in 'pearls context' output.
+	FlagContext bool `yaml:"flag_context,omitempty"`
+}
+
+// Interval returns the review interval for a pearl with the given namespace
+// and type. Zero means the pearl never goes stale.
+func (f FreshnessConfig) Interval(namespace, assetType string) (time.Duration, error) {
+	best := ""
+	for ns := range f.Namespaces {
+		if (namespace == ns || strings.HasPrefix(namespace, ns+".")) && len(ns) > len(best) {
+			best = ns
+		}
+	}
+	if best != "" {
+		return ParseInterval(f.Namespaces[best])
+	}
+	if v, ok := f.Types[assetType]; ok {
+		return ParseInterval(v)
+	}
+	return ParseInterval(f.Default)
+}
+
+// Validate checks that every interval parses.
+func (f FreshnessConfig) Validate() error {
+	if _, err := ParseInterval(f.Default); err != nil {
+		return fmt.Errorf("freshness.default: %w", err)
+	}
+	for _, group := range []struct {
+		name  string
+		rules map[string]string
+	}{{"types", f.Types}, {"namespaces", f.Namespaces}} {
+		keys := make([]string, 0, len(group.rules))
+		for k := range group.rules {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			if _, err := ParseInterval(group.rules[k]); err != nil {
+				return fmt.Errorf("freshness.%s.%s: %w", group.name, k, err)
+			}
+		}
+	}
+	return nil
+}
+
+// ParseInterval parses a review interval: a number of days ("30d") or weeks
+// ("2w"), or a Go duration ("36h"). Empty means zero.
+func ParseInterval(s string) (time.Duration, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "0" {
+		return 0, nil
+	}
+
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if n, ok := strings.CutSuffix(s, suffix); ok {
+			v, err := strconv.Atoi(n)
+			if err != nil || v < 0 {
+				return 0, fmt.Errorf("invalid interval %q", s)
+			}
+			return time.Duration(v) * unit, nil
+		}
+	}
+
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("invalid interval %q: use e.g. 30d, 2w, or 36h", s)
+	}
+	return d, nil
+}
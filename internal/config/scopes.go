@@ -0,0 +1,130 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+// ScopeConfig declares a scope. A scope with a parent is part of it, so
+// asking for the parent includes pearls in the child.
+type ScopeConfig struct {
+	Description string `yaml:"description,omitempty"`
+	Parent      string `yaml:"parent,omitempty"`
+}
+
+// Scopes is the scope registry, keyed by scope name. Pearls may use
+// undeclared scopes; they simply have no description or hierarchy.
+type Scopes map[string]ScopeConfig
+
+// Declared reports whether a scope is in the registry.
+func (s Scopes) Declared(name string) bool {
+	_, ok := s[name]
+	return ok
+}
+
+// Children returns the scopes declared directly under name, sorted.
+func (s Scopes) Children(name string) []string {
+	var children []string
+	for child, sc := range s {
+		if sc.Parent == name {
+			children = append(children, child)
+		}
+	}
+	sort.Strings(children)
+	return children
+}
+
+// Roots returns the declared scopes without a declared parent, sorted.
+func (s Scopes) Roots() []string {
+	var roots []string
+	for name, sc := range s {
+		if sc.Parent == "" || !s.Declared(sc.Parent) {
+			roots = append(roots, name)
+		}
+	}
+	sort.Strings(roots)
+	return roots
+}
+
+// Expand returns name followed by every scope beneath it, depth first.
+// Undeclared names expand to themselves.
+func (s Scopes) Expand(name string) []string {
+	scopes := []string{name}
+	seen := map[string]bool{name: true}
+	var walk func(string)
+	walk = func(parent string) {
+		for _, child := range s.Children(parent) {
+			if !seen[child] {
+				seen[child] = true
+				scopes = append(scopes, child)
+				walk(child)
+			}
+		}
+	}
+	walk(name)
+	return scopes
+}
+
+// Filter turns scope names into a storage filter. Each scope includes
+// the scopes declared under it. With all, a pearl must match every scope;
+// otherwise any one.
+func (s Scopes) Filter(names []string, all bool) ([][]string, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	if err := pearl.ValidateScopes(names); err != nil {
+		return nil, err
+	}
+
+	var groups [][]string
+	for _, name := range names {
+		groups = append(groups, s.Expand(name))
+	}
+	if all {
+		return groups, nil
+	}
+
+	var union []string
+	seen := make(map[string]bool)
+	for _, g := range groups {
+		for _, sc := range g {
+			if !seen[sc] {
+				seen[sc] = true
+				union = append(union, sc)
+			}
+		}
+	}
+	return [][]string{union}, nil
+}
+
+// Validate checks scope names, that parents are declared, and that no
+// scope is its own ancestor.
+func (s Scopes) Validate() error {
+	names := make([]string, 0, len(s))
+	for name := range s {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if err := pearl.ValidateScopes([]string{name}); err != nil {
+			return fmt.Errorf("scopes: %w", err)
+		}
+		parent := s[name].Parent
+		if parent == "" {
+			continue
+		}
+		if !s.Declared(parent) {
+			return fmt.Errorf("scopes.%s: parent %q is not declared", name, parent)
+		}
+		for seen, p := map[string]bool{name: true}, parent; p != ""; p = s[p].Parent {
+			if seen[p] {
+				return fmt.Errorf("scopes.%s: parent cycle through %q", name, p)
+			}
+			seen[p] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Rule severities for 'pearls validate'. SeverityOff disables a rule.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityNote    = "note"
+	SeverityOff     = "off"
+)
+
+// ValidationConfig configures the content rules 'pearls validate' applies.
+type ValidationConfig struct {
+	// Rules overrides rule severities by rule ID: error, warning, note, or off.
+	Rules map[string]string `yaml:"rules,omitempty"`
+	// RequiredSections lists section headings each pearl type must have,
+	// e.g. table: [Schema, Relationships].
+	RequiredSections map[string][]string `yaml:"required_sections,omitempty"`
+	// MaxContentBytes caps content size; zero means no limit.
+	MaxContentBytes int `yaml:"max_content_bytes,omitempty"`
+	// ForbiddenPhrases are matched case-insensitively outside code blocks.
+	ForbiddenPhrases []string `yaml:"forbidden_phrases,omitempty"`
+}
+
+// Validate checks that severities are known and limits are non-negative.
+func (v ValidationConfig) Validate() error {
+	ids := make([]string, 0, len(v.Rules))
+	for id := range v.Rules {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		switch v.Rules[id] {
+		case SeverityError, SeverityWarning, SeverityNote, SeverityOff:
+		default:
+			return fmt.Errorf("validation.rules.%s: invalid severity %q: use error, warning, note, or off", id, v.Rules[id])
+		}
+	}
+	if v.MaxContentBytes < 0 {
+		return fmt.Errorf("validation.max_content_bytes: must not be negative")
+	}
+	for _, p := range v.ForbiddenPhrases {
+		if p == "" {
+			return fmt.Errorf("validation.forbidden_phrases: phrases must not be empty")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,192 @@
+// Package coverage reports which files in a repository receive injected
+// context from pearl globs, and which receive too much.
+package coverage
+
+import (
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+// BytesPerToken is the rough ratio used to estimate context size.
+const BytesPerToken = 4
+
+// Options controls an analysis.
+type Options struct {
+	// Sizes maps pearl IDs to content size in bytes.
+	Sizes map[string]int64
+	// OverCovered is the number of matching pearls at which a file counts
+	// as over-covered. Zero disables the report.
+	OverCovered int
+}
+
+// Dir is the coverage of one directory and everything beneath it.
+type Dir struct {
+	Path    string `json:"path"`
+	Files   int    `json:"files"`
+	Covered int    `json:"covered"`
+	Dirs    []*Dir `json:"dirs,omitempty"`
+}
+
+// Percent returns the share of files covered, from 0 to 100.
+func (d *Dir) Percent() float64 {
+	return percent(d.Covered, d.Files)
+}
+
+// File is a file matched by several pearls, and the context it pulls in.
+type File struct {
+	Path   string   `json:"path"`
+	Pearls []string `json:"pearls"`
+	Bytes  int64    `json:"bytes"`
+	Tokens int64    `json:"estimated_tokens"`
+}
+
+// PearlMatches counts the files a pearl's globs match.
+type PearlMatches struct {
+	ID      string   `json:"id"`
+	Globs   []string `json:"globs"`
+	Matches int      `json:"matches"`
+}
+
+// Report is the coverage of a set of files.
+type Report struct {
+	Files       int             `json:"files"`
+	Covered     int             `json:"covered"`
+	Percent     float64         `json:"percent"`
+	Tree        *Dir            `json:"tree"`
+	Uncovered   []*Dir          `json:"uncovered_dirs"`
+	OverCovered []File          `json:"over_covered_files"`
+	Pearls      []*PearlMatches `json:"pearls"`
+}
+
+// Analyze matches every file against every pearl's globs. Files are
+// slash-separated paths relative to the repository root. Pearls without
+// globs are ignored.
+func Analyze(files []string, pearls []*pearl.Pearl, opts Options) *Report {
+	var globbed []*pearl.Pearl
+	for _, p := range pearls {
+		if len(p.Globs) > 0 {
+			globbed = append(globbed, p)
+		}
+	}
+
+	r := &Report{
+		Tree:        &Dir{Path: "."},
+		Uncovered:   []*Dir{},
+		OverCovered: []File{},
+		Pearls:      make([]*PearlMatches, len(globbed)),
+	}
+	index := pearl.NewGlobIndex()
+	byID := make(map[string]*PearlMatches)
+	for i, p := range globbed {
+		r.Pearls[i] = &PearlMatches{ID: p.ID, Globs: p.Globs}
+		byID[p.ID] = r.Pearls[i]
+		index.Add(p.ID, p.Globs)
+	}
+
+	dirs := map[string]*Dir{".": r.Tree}
+	for _, f := range files {
+		matched := index.Match(f)
+		var bytes int64
+		for _, id := range matched {
+			byID[id].Matches++
+			bytes += opts.Sizes[id]
+		}
+
+		covered := len(matched) > 0
+		r.Files++
+		if covered {
+			r.Covered++
+		}
+		for dir := path.Dir(f); ; dir = path.Dir(dir) {
+			d := dirFor(dirs, dir)
+			d.Files++
+			if covered {
+				d.Covered++
+			}
+			if dir == "." {
+				break
+			}
+		}
+
+		if opts.OverCovered > 0 && len(matched) >= opts.OverCovered {
+			r.OverCovered = append(r.OverCovered, File{
+				Path:   f,
+				Pearls: matched,
+				Bytes:  bytes,
+				Tokens: bytes / BytesPerToken,
+			})
+		}
+	}
+	r.Percent = percent(r.Covered, r.Files)
+
+	sortTree(r.Tree)
+	r.Uncovered = uncovered(r.Tree, r.Uncovered)
+
+	sort.SliceStable(r.OverCovered, func(i, j int) bool {
+		a, b := r.OverCovered[i], r.OverCovered[j]
+		if len(a.Pearls) != len(b.Pearls) {
+			return len(a.Pearls) > len(b.Pearls)
+		}
+		return a.Bytes > b.Bytes
+	})
+	sort.SliceStable(r.Pearls, func(i, j int) bool {
+		return r.Pearls[i].Matches > r.Pearls[j].Matches
+	})
+	return r
+}
+
+// dirFor returns the directory, creating it and any missing parents.
+func dirFor(dirs map[string]*Dir, dir string) *Dir {
+	if d, ok := dirs[dir]; ok {
+		return d
+	}
+	d := &Dir{Path: dir}
+	dirs[dir] = d
+	parent := dirFor(dirs, path.Dir(dir))
+	parent.Dirs = append(parent.Dirs, d)
+	return d
+}
+
+func sortTree(d *Dir) {
+	sort.Slice(d.Dirs, func(i, j int) bool { return d.Dirs[i].Path < d.Dirs[j].Path })
+	for _, c := range d.Dirs {
+		sortTree(c)
+	}
+}
+
+// uncovered appends the highest directories with no covered files.
+func uncovered(d *Dir, out []*Dir) []*Dir {
+	if d.Covered == 0 && d.Path != "." {
+		return append(out, d)
+	}
+	for _, c := range d.Dirs {
+		out = uncovered(c, out)
+	}
+	return out
+}
+
+// Within keeps the files under dir, which is relative to the repository
+// root. "" and "." keep everything.
+func Within(files []string, dir string) []string {
+	dir = strings.TrimSuffix(path.Clean(dir), "/")
+	if dir == "." || dir == "" {
+		return files
+	}
+	var kept []string
+	for _, f := range files {
+		if f == dir || strings.HasPrefix(f, dir+"/") {
+			kept = append(kept, f)
+		}
+	}
+	return kept
+}
+
+func percent(n, total int) float64 {
+	if total == 0 {
+		return 0
+	}
+	return float64(n) * 100 / float64(total)
+}
@@ -0,0 +1,108 @@
+package coverage
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+func TestAnalyze(t *testing.T) {
+	files := []string{
+		"main.go",
+		"src/api/handler.go",
+		"src/api/routes.go",
+		"src/legacy/old.go",
+		"src/legacy/deep/older.go",
+		"docs/guide.md",
+	}
+	pearls := []*pearl.Pearl{
+		{ID: "api.conventions", Globs: []string{"src/api/**"}},
+		{ID: "go.style", Globs: []string{"**/*.go"}},
+		{ID: "api.routes", Globs: []string{"src/api/routes.go"}},
+		{ID: "no.globs"},
+		{ID: "dead.glob", Globs: []string{"vendor/**"}},
+	}
+	sizes := map[string]int64{"api.conventions": 4000, "go.style": 2000, "api.routes": 400}
+
+	r := Analyze(files, pearls, Options{Sizes: sizes, OverCovered: 2})
+
+	if r.Files != 6 || r.Covered != 5 {
+		t.Errorf("covered %d of %d, want 5 of 6", r.Covered, r.Files)
+	}
+
+	// Directory tree, sorted by path
+	var paths []string
+	var walk func(d *Dir)
+	walk = func(d *Dir) {
+		paths = append(paths, d.Path)
+		for _, c := range d.Dirs {
+			walk(c)
+		}
+	}
+	walk(r.Tree)
+	wantPaths := []string{".", "docs", "src", "src/api", "src/legacy", "src/legacy/deep"}
+	if !reflect.DeepEqual(paths, wantPaths) {
+		t.Errorf("tree = %v, want %v", paths, wantPaths)
+	}
+	if src := r.Tree.Dirs[1]; src.Files != 4 || src.Covered != 4 || src.Percent() != 100 {
+		t.Errorf("src = %+v", src)
+	}
+
+	if len(r.Uncovered) != 1 || r.Uncovered[0].Path != "docs" {
+		t.Errorf("uncovered = %+v", r.Uncovered)
+	}
+
+	// routes.go has three pearls, handler.go two
+	if len(r.OverCovered) != 2 {
+		t.Fatalf("over-covered = %+v", r.OverCovered)
+	}
+	routes := r.OverCovered[0]
+	if routes.Path != "src/api/routes.go" || len(routes.Pearls) != 3 || routes.Bytes != 6400 || routes.Tokens != 1600 {
+		t.Errorf("routes = %+v", routes)
+	}
+	if r.OverCovered[1].Path != "src/api/handler.go" {
+		t.Errorf("second over-covered = %s", r.OverCovered[1].Path)
+	}
+
+	var counts []string
+	for _, pm := range r.Pearls {
+		counts = append(counts, pm.ID)
+	}
+	wantCounts := []string{"go.style", "api.conventions", "api.routes", "dead.glob"}
+	if !reflect.DeepEqual(counts, wantCounts) {
+		t.Errorf("pearl order = %v, want %v", counts, wantCounts)
+	}
+	if r.Pearls[0].Matches != 5 || r.Pearls[3].Matches != 0 {
+		t.Errorf("matches = %d, %d", r.Pearls[0].Matches, r.Pearls[3].Matches)
+	}
+}
+
+func TestAnalyze_Empty(t *testing.T) {
+	r := Analyze(nil, nil, Options{})
+	if r.Files != 0 || r.Percent != 0 || len(r.Uncovered) != 0 || r.Pearls == nil {
+		t.Errorf("report = %+v", r)
+	}
+}
+
+func TestWithin(t *testing.T) {
+	files := []string{"src/a.go", "src/api/b.go", "srcs/c.go", "d.go"}
+
+	tests := []struct {
+		dir  string
+		want []string
+	}{
+		{"", files},
+		{".", files},
+		{"src", []string{"src/a.go", "src/api/b.go"}},
+		{"src/", []string{"src/a.go", "src/api/b.go"}},
+		{"src/api", []string{"src/api/b.go"}},
+		{"d.go", []string{"d.go"}},
+		{"missing", nil},
+	}
+	for _, tt := range tests {
+		if got := Within(files, tt.dir); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Within(%q) = %v, want %v", tt.dir, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,184 @@
+// Package drift measures how much the code a pearl documents has changed
+// since the pearl was last revised, using git history.
+package drift
+
+import (
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+// Commit is a git commit and the files it changed, relative to the
+// repository directory it was read from.
+type Commit struct {
+	Hash   string
+	At     time.Time
+	Author string
+	Files  []string
+}
+
+// FileChange counts the commits that touched one file.
+type FileChange struct {
+	Path    string `json:"path"`
+	Commits int    `json:"commits"`
+}
+
+// Report is the drift of one pearl: the commits to files matching its
+// globs made after its baseline.
+type Report struct {
+	PearlID        string       `json:"id"`
+	Globs          []string     `json:"globs"`
+	Baseline       time.Time    `json:"baseline"`
+	BaselineCommit string       `json:"baseline_commit,omitempty"`
+	Commits        int          `json:"commits"`
+	FileCount      int          `json:"files"`
+	Files          []FileChange `json:"top_files"`
+	Authors        []string     `json:"authors"`
+}
+
+// Repo reads history from the git repository containing Dir. Paths are
+// relative to Dir, matching how pearl globs are written.
+type Repo struct {
+	Dir string
+}
+
+// Commits returns commits since the given time, newest first. It returns
+// nil if Dir is not in a git repository.
+func (r *Repo) Commits(since time.Time) ([]Commit, error) {
+	args := []string{"log", "--relative", "--name-only", "--no-renames", "--format=%x00%H%x00%cI%x00%an"}
+	if !since.IsZero() {
+		args = append(args, "--since="+since.Format(time.RFC3339))
+	}
+	out, err := r.git(args...)
+	if err != nil {
+		if r.inRepo() {
+			return nil, fmt.Errorf("git log: %w", err)
+		}
+		return nil, nil
+	}
+	return ParseLog(out), nil
+}
+
+// LastChange returns the most recent commit touching path, or "" and the
+// zero time if it was never committed.
+func (r *Repo) LastChange(path string) (string, time.Time, error) {
+	out, err := r.git("log", "-1", "--format=%H%x00%cI", "--", path)
+	if err != nil {
+		return "", time.Time{}, nil
+	}
+	parts := strings.Split(strings.TrimSpace(string(out)), "\x00")
+	if len(parts) != 2 {
+		return "", time.Time{}, nil
+	}
+	at, err := time.Parse(time.RFC3339, parts[1])
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("parse commit time: %w", err)
+	}
+	return parts[0], at, nil
+}
+
+func (r *Repo) inRepo() bool {
+	_, err := r.git("rev-parse", "--git-dir")
+	return err == nil
+}
+
+func (r *Repo) git(args ...string) ([]byte, error) {
+	return exec.Command("git", append([]string{"-C", r.Dir}, args...)...).Output()
+}
+
+// ParseLog parses git log output written with
+// --name-only --format=%x00%H%x00%cI%x00%an.
+func ParseLog(data []byte) []Commit {
+	// Each commit is "\x00hash\x00time\x00author\n\nfile\nfile\n"
+	fields := strings.Split(string(data), "\x00")
+	var commits []Commit
+	for i := 1; i+2 < len(fields); i += 3 {
+		at, err := time.Parse(time.RFC3339, fields[i+1])
+		if err != nil {
+			continue
+		}
+		lines := strings.Split(fields[i+2], "\n")
+		c := Commit{Hash: fields[i], At: at, Author: lines[0]}
+		for _, f := range lines[1:] {
+			if f = strings.TrimSpace(f); f != "" {
+				c.Files = append(c.Files, f)
+			}
+		}
+		commits = append(commits, c)
+	}
+	return commits
+}
+
+// Analyze reports the commits after baseline that touched files matching
+// p's globs. topFiles limits the files listed (0 for all). It returns nil
+// if nothing changed.
+func Analyze(p *pearl.Pearl, baseline time.Time, commits []Commit, topFiles int) *Report {
+	if len(p.Globs) == 0 {
+		return nil
+	}
+
+	counts := make(map[string]int)
+	authors := make(map[string]bool)
+	n := 0
+	for _, c := range commits {
+		if !c.At.After(baseline) {
+			continue
+		}
+		touched := false
+		for _, f := range c.Files {
+			if pearl.MatchPath(f, p.Globs) {
+				counts[f]++
+				touched = true
+			}
+		}
+		if touched {
+			n++
+			authors[c.Author] = true
+		}
+	}
+	if n == 0 {
+		return nil
+	}
+
+	files := make([]FileChange, 0, len(counts))
+	for f, c := range counts {
+		files = append(files, FileChange{Path: f, Commits: c})
+	}
+	sort.Slice(files, func(i, j int) bool {
+		if files[i].Commits != files[j].Commits {
+			return files[i].Commits > files[j].Commits
+		}
+		return files[i].Path < files[j].Path
+	})
+
+	r := &Report{
+		PearlID:   p.ID,
+		Globs:     p.Globs,
+		Baseline:  baseline,
+		Commits:   n,
+		FileCount: len(files),
+		Files:     files,
+	}
+	if topFiles > 0 && len(r.Files) > topFiles {
+		r.Files = r.Files[:topFiles]
+	}
+	for a := range authors {
+		r.Authors = append(r.Authors, a)
+	}
+	sort.Strings(r.Authors)
+	return r
+}
+
+// Rank sorts reports by how much their code changed: commits, then files.
+func Rank(reports []*Report) {
+	sort.SliceStable(reports, func(i, j int) bool {
+		if reports[i].Commits != reports[j].Commits {
+			return reports[i].Commits > reports[j].Commits
+		}
+		return reports[i].FileCount > reports[j].FileCount
+	})
+}
@@ -0,0 +1,127 @@
+package drift
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+func TestParseLog(t *testing.T) {
+	out := "\x00aaa\x002026-01-02T10:00:00Z\x00alice\n\nsrc/a.go\nsrc/b.go\n" +
+		"\x00bbb\x002026-01-01T10:00:00+02:00\x00bob\n" +
+		"\x00ccc\x002026-01-01T09:00:00Z\x00carol\n\ndocs/x.md\n"
+
+	commits := ParseLog([]byte(out))
+	if len(commits) != 3 {
+		t.Fatalf("got %d commits, want 3", len(commits))
+	}
+	if c := commits[0]; c.Hash != "aaa" || c.Author != "alice" || !reflect.DeepEqual(c.Files, []string{"src/a.go", "src/b.go"}) {
+		t.Errorf("commit 0 = %+v", c)
+	}
+	if c := commits[1]; c.Hash != "bbb" || len(c.Files) != 0 || !c.At.Equal(time.Date(2026, 1, 1, 8, 0, 0, 0, time.UTC)) {
+		t.Errorf("commit 1 = %+v", c)
+	}
+	if c := commits[2]; c.Author != "carol" || !reflect.DeepEqual(c.Files, []string{"docs/x.md"}) {
+		t.Errorf("commit 2 = %+v", c)
+	}
+
+	if got := ParseLog(nil); got != nil {
+		t.Errorf("empty log = %+v", got)
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := func(n int) time.Time { return t0.Add(time.Duration(n) * 24 * time.Hour) }
+	commits := []Commit{
+		{Hash: "5", At: day(5), Author: "bob", Files: []string{"src/pay/a.go", "src/pay/b.go"}},
+		{Hash: "4", At: day(4), Author: "alice", Files: []string{"src/pay/a.go"}},
+		{Hash: "3", At: day(3), Author: "alice", Files: []string{"src/other/c.go"}},
+		{Hash: "2", At: day(2), Author: "carol", Files: []string{"src/pay/a.go", "src/pay/d.go"}},
+		{Hash: "1", At: day(1), Author: "dave", Files: []string{"src/pay/a.go"}},
+	}
+	p := &pearl.Pearl{ID: "api.pay", Globs: []string{"src/pay/**"}}
+
+	r := Analyze(p, day(1), commits, 2)
+	if r == nil {
+		t.Fatal("expected drift")
+	}
+	if r.Commits != 3 || r.FileCount != 3 {
+		t.Errorf("commits = %d, files = %d; want 3, 3", r.Commits, r.FileCount)
+	}
+	want := []FileChange{{"src/pay/a.go", 3}, {"src/pay/b.go", 1}}
+	if !reflect.DeepEqual(r.Files, want) {
+		t.Errorf("top files = %+v, want %+v", r.Files, want)
+	}
+	if !reflect.DeepEqual(r.Authors, []string{"alice", "bob", "carol"}) {
+		t.Errorf("authors = %v", r.Authors)
+	}
+
+	if r := Analyze(p, day(5), commits, 0); r != nil {
+		t.Errorf("no commits after baseline: got %+v", r)
+	}
+	if r := Analyze(&pearl.Pearl{ID: "x"}, t0, commits, 0); r != nil {
+		t.Errorf("pearl without globs: got %+v", r)
+	}
+
+	small := &Report{PearlID: "small", Commits: 1}
+	wide := &Report{PearlID: "wide", Commits: 3, FileCount: 9}
+	reports := []*Report{small, Analyze(p, t0, commits, 0), wide}
+	Rank(reports)
+	if reports[0].PearlID != "api.pay" || reports[1] != wide || reports[2] != small {
+		t.Errorf("rank order = %s, %s, %s", reports[0].PearlID, reports[1].PearlID, reports[2].PearlID)
+	}
+}
+
+func TestRepo(t *testing.T) {
+	dir := t.TempDir()
+	if err := exec.Command("git", "init", "-q", dir).Run(); err != nil {
+		t.Skipf("git not available: %v", err)
+	}
+
+	commit := func(date, path string) {
+		t.Helper()
+		full := filepath.Join(dir, path)
+		os.MkdirAll(filepath.Dir(full), 0755)
+		os.WriteFile(full, []byte(date), 0644)
+		for _, args := range [][]string{{"add", "-A"}, {"commit", "-q", "-m", path}} {
+			cmd := exec.Command("git", append([]string{"-C", dir, "-c", "user.name=alice", "-c", "user.email=a@example.com"}, args...)...)
+			cmd.Env = append(os.Environ(), "GIT_AUTHOR_DATE="+date, "GIT_COMMITTER_DATE="+date)
+			if out, err := cmd.CombinedOutput(); err != nil {
+				t.Fatalf("git %v: %v\n%s", args, err, out)
+			}
+		}
+	}
+	commit("2026-01-01T00:00:00Z", ".pearls/content/api/pay.md")
+	commit("2026-01-02T00:00:00Z", "src/pay/a.go")
+	commit("2026-01-03T00:00:00Z", "src/pay/b.go")
+
+	repo := &Repo{Dir: dir}
+	hash, at, err := repo.LastChange(".pearls/content/api/pay.md")
+	if err != nil || hash == "" || !at.Equal(time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("last change = %q, %v, %v", hash, at, err)
+	}
+	if hash, _, _ := repo.LastChange("missing.md"); hash != "" {
+		t.Errorf("last change of uncommitted file = %q", hash)
+	}
+
+	commits, err := repo.Commits(at)
+	if err != nil {
+		t.Fatalf("commits: %v", err)
+	}
+	r := Analyze(&pearl.Pearl{ID: "api.pay", Globs: []string{"src/pay/**"}}, at, commits, 0)
+	if r == nil || r.Commits != 2 || r.FileCount != 2 {
+		t.Errorf("report = %+v", r)
+	}
+
+	// Outside a repository there is simply no history
+	commits, err = (&Repo{Dir: t.TempDir()}).Commits(time.Time{})
+	if err != nil || commits != nil {
+		t.Errorf("outside git: %v, %v", commits, err)
+	}
+}
@@ -0,0 +1,198 @@
+// Package history records per-pearl change history.
+//
+// Every Store mutation appends a Revision to an append-only JSONL changelog
+// (.pearls/history.jsonl). Content is saved separately, addressed by its
+// SHA256, so a revision can be diffed and restored without git. For pearls
+// that predate the changelog, revisions are reconstructed from git.
+package history
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+// Op is the kind of change a revision records.
+type Op string
+
+const (
+	OpCreate  Op = "create"
+	OpUpdate  Op = "update"
+	OpDelete  Op = "delete"
+	OpRestore Op = "restore"
+)
+
+// Revision is one recorded change to a pearl.
+type Revision struct {
+	PearlID string    `json:"pearl_id"`
+	Rev     int       `json:"rev,omitempty"` // 1-based, assigned on read
+	Op      Op        `json:"op"`
+	At      time.Time `json:"at"`
+	By      string    `json:"by"`
+
+	// Fields lists the metadata fields that changed (updates only).
+	Fields []string `json:"fields,omitempty"`
+
+	// HashBefore and HashAfter are the content hashes around the change.
+	HashBefore string `json:"hash_before,omitempty"`
+	HashAfter  string `json:"hash_after,omitempty"`
+
+	// Pearl is the metadata as of this revision. For deletes it is the
+	// last state before deletion.
+	Pearl *pearl.Pearl `json:"pearl"`
+
+	// Commit is set for revisions reconstructed from git.
+	Commit string `json:"commit,omitempty"`
+}
+
+// Deleted reports whether the pearl did not exist after this revision.
+func (r *Revision) Deleted() bool {
+	return r.Op == OpDelete
+}
+
+// Log is the changelog plus its content object store.
+type Log struct {
+	path       string
+	objectsDir string
+}
+
+// NewLog creates a changelog at path that stores content under objectsDir.
+func NewLog(path, objectsDir string) *Log {
+	return &Log{path: path, objectsDir: objectsDir}
+}
+
+// Path returns the changelog file path.
+func (l *Log) Path() string {
+	return l.path
+}
+
+// Author returns the name recorded on new revisions.
+func Author() string {
+	if user := os.Getenv("USER"); user != "" {
+		return user
+	}
+	return "unknown"
+}
+
+// Append adds revisions to the end of the changelog.
+func (l *Log) Append(revs ...*Revision) error {
+	if len(revs) == 0 {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(l.path), 0755); err != nil {
+		return fmt.Errorf("create history directory: %w", err)
+	}
+
+	file, err := os.OpenFile(l.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("open history: %w", err)
+	}
+	defer file.Close()
+
+	encoder := json.NewEncoder(file)
+	encoder.SetEscapeHTML(false)
+	for _, r := range revs {
+		entry := *r
+		entry.Rev = 0 // numbering is positional, so merged logs stay consistent
+		if entry.By == "" {
+			entry.By = Author()
+		}
+		if err := encoder.Encode(&entry); err != nil {
+			return fmt.Errorf("write history: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// For returns a pearl's revisions, oldest first, numbered from 1.
+func (l *Log) For(id string) ([]*Revision, error) {
+	file, err := os.Open(l.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open history: %w", err)
+	}
+	defer file.Close()
+
+	var revs []*Revision
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var r Revision
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			return nil, fmt.Errorf("parse history line %d: %w", lineNum, err)
+		}
+		if r.PearlID == id {
+			revs = append(revs, &r)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read history: %w", err)
+	}
+
+	// Entries from merged branches may interleave; order by time
+	sortRevisions(revs)
+	return revs, nil
+}
+
+// PutObject saves content in the object store and returns its hash.
+func (l *Log) PutObject(content string) (string, error) {
+	hash := hashString(content)
+	path := l.objectPath(hash)
+	if _, err := os.Stat(path); err == nil {
+		return hash, nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return "", fmt.Errorf("create object directory: %w", err)
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, []byte(content), 0644); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("write object: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return "", fmt.Errorf("rename object: %w", err)
+	}
+	return hash, nil
+}
+
+// Object returns stored content by hash.
+func (l *Log) Object(hash string) (string, bool, error) {
+	data, err := os.ReadFile(l.objectPath(hash))
+	if os.IsNotExist(err) {
+		return "", false, nil
+	}
+	if err != nil {
+		return "", false, fmt.Errorf("read object %s: %w", hash, err)
+	}
+	return string(data), true, nil
+}
+
+func (l *Log) objectPath(hash string) string {
+	if len(hash) < 3 {
+		return filepath.Join(l.objectsDir, hash)
+	}
+	return filepath.Join(l.objectsDir, hash[:2], hash[2:])
+}
+
+func hashString(content string) string {
+	h := sha256.Sum256([]byte(content))
+	return hex.EncodeToString(h[:])
+}
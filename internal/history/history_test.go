@@ -0,0 +1,156 @@
+package history
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+func TestLog(t *testing.T) {
+	dir := t.TempDir()
+	log := NewLog(filepath.Join(dir, "history.jsonl"), filepath.Join(dir, "history"))
+
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	users := &pearl.Pearl{ID: "db.users", Name: "users", Namespace: "db", Type: pearl.TypeTable}
+
+	// Appended out of time order, as after a union merge of two branches
+	err := log.Append(
+		&Revision{PearlID: "db.users", Op: OpUpdate, At: t0.Add(time.Hour), By: "bob", Fields: []string{"tags"}, Pearl: users},
+		&Revision{PearlID: "db.orders", Op: OpCreate, At: t0, By: "alice", Pearl: users},
+		&Revision{PearlID: "db.users", Op: OpCreate, At: t0, By: "alice", Pearl: users, Rev: 9},
+	)
+	if err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	revs, err := log.For("db.users")
+	if err != nil {
+		t.Fatalf("for: %v", err)
+	}
+	if len(revs) != 2 {
+		t.Fatalf("got %d revisions, want 2", len(revs))
+	}
+	if revs[0].Op != OpCreate || revs[0].Rev != 1 || revs[1].Op != OpUpdate || revs[1].Rev != 2 {
+		t.Errorf("revisions = %+v, %+v; want create r1 then update r2", revs[0], revs[1])
+	}
+
+	if revs, _ := log.For("db.missing"); len(revs) != 0 {
+		t.Errorf("unknown pearl has %d revisions", len(revs))
+	}
+
+	hash, err := log.PutObject("# Users\n")
+	if err != nil {
+		t.Fatalf("put object: %v", err)
+	}
+	if again, _ := log.PutObject("# Users\n"); again != hash {
+		t.Errorf("same content stored under %s and %s", hash, again)
+	}
+	content, ok, err := log.Object(hash)
+	if err != nil || !ok || content != "# Users\n" {
+		t.Errorf("object = %q, %v, %v", content, ok, err)
+	}
+	if _, ok, _ := log.Object("0000"); ok {
+		t.Error("missing object reported as present")
+	}
+}
+
+func TestFind(t *testing.T) {
+	revs := []*Revision{{Rev: 1, Commit: "abc123"}, {Rev: 2, Commit: "def456"}}
+
+	tests := []struct {
+		rev     string
+		want    int
+		wantErr bool
+	}{
+		{"1", 1, false},
+		{"r2", 2, false},
+		{"def", 2, false},
+		{"3", 0, true},
+		{"0", 0, true},
+		{"zzz", 0, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.rev, func(t *testing.T) {
+			got, err := Find(revs, tt.rev)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && got.Rev != tt.want {
+				t.Errorf("rev = %d, want %d", got.Rev, tt.want)
+			}
+		})
+	}
+}
+
+func TestChanges(t *testing.T) {
+	a := &pearl.Pearl{ID: "db.a", Description: "old", Tags: []string{"x"}, UpdatedAt: time.Unix(0, 0)}
+	b := &pearl.Pearl{ID: "db.a", Description: "new", Tags: []string{"x"}, UpdatedAt: time.Unix(100, 0)}
+
+	got := Changes(a, b)
+	if len(got) != 1 || got[0].Field != "description" || string(got[0].Before) != `"old"` || string(got[0].After) != `"new"` {
+		t.Errorf("Changes = %+v, want description only", got)
+	}
+
+	// Against nothing, every field is new
+	if got := Changes(nil, b); len(got) == 0 || got[0].Before != nil {
+		t.Errorf("Changes(nil, b) = %+v", got)
+	}
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{
+			"change in middle",
+			"1\n2\n3\n4\n5\n",
+			"1\n2\nthree\n4\n5\n",
+			"--- old\n+++ new\n@@ -1,5 +1,5 @@\n 1\n 2\n-3\n+three\n 4\n 5\n",
+		},
+		{
+			"from empty",
+			"",
+			"a\nb\n",
+			"--- old\n+++ new\n@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			"to empty",
+			"a\n",
+			"",
+			"--- old\n+++ new\n@@ -1 +0,0 @@\n-a\n",
+		},
+		{
+			"separate hunks",
+			"1\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\n12\n",
+			"one\n2\n3\n4\n5\n6\n7\n8\n9\n10\n11\ntwelve\n",
+			"--- old\n+++ new\n@@ -1,4 +1,4 @@\n-1\n+one\n 2\n 3\n 4\n@@ -9,4 +9,4 @@\n 9\n 10\n 11\n-12\n+twelve\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := UnifiedDiff(tt.a, tt.b, "old", "new")
+			if got != tt.want {
+				t.Errorf("UnifiedDiff =\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDiffLinesMinimal(t *testing.T) {
+	ops := diffLines([]string{"a", "b", "c"}, []string{"a", "x", "c"})
+	var kinds []byte
+	for _, op := range ops {
+		kinds = append(kinds, op.kind)
+	}
+	if want := []byte(" -+ "); !reflect.DeepEqual(kinds, want) {
+		t.Errorf("ops = %q, want %q", kinds, want)
+	}
+}
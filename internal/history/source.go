@@ -0,0 +1,222 @@
+package history
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+// Source resolves a pearl's history: from the changelog when it has
+// entries for the pearl, otherwise from git commits of the catalog files.
+type Source struct {
+	Log *Log
+
+	// Dir is the .pearls directory; JSONLFile and ContentDir are relative
+	// to it. They locate catalog files in past git commits.
+	Dir        string
+	JSONLFile  string
+	ContentDir string
+}
+
+// Revisions returns a pearl's revisions, oldest first. contentPath is the
+// pearl's content file relative to the content directory.
+func (s *Source) Revisions(id, contentPath string) ([]*Revision, error) {
+	revs, err := s.Log.For(id)
+	if err != nil || len(revs) > 0 {
+		return revs, err
+	}
+	return s.gitRevisions(id, contentPath)
+}
+
+// Find returns the revision matching rev: a revision number, or a commit
+// hash prefix for revisions from git.
+func Find(revs []*Revision, rev string) (*Revision, error) {
+	if n, err := strconv.Atoi(strings.TrimPrefix(rev, "r")); err == nil {
+		if n < 1 || n > len(revs) {
+			return nil, fmt.Errorf("no revision %d (have 1-%d)", n, len(revs))
+		}
+		return revs[n-1], nil
+	}
+	for _, r := range revs {
+		if r.Commit != "" && strings.HasPrefix(r.Commit, rev) {
+			return r, nil
+		}
+	}
+	return nil, fmt.Errorf("no revision %q", rev)
+}
+
+// Content returns the pearl's content as of r. It reports false if the
+// content is unavailable (no content, or not saved at the time).
+func (s *Source) Content(r *Revision) (string, bool, error) {
+	hash := r.HashAfter
+	if r.Deleted() {
+		hash = r.HashBefore
+	}
+	if r.Commit != "" {
+		if r.Pearl == nil || r.Pearl.ContentPath == "" {
+			return "", false, nil
+		}
+		data, err := s.git("show", r.Commit+":./"+filepath.ToSlash(filepath.Join(s.ContentDir, r.Pearl.ContentPath)))
+		if err != nil {
+			return "", false, nil
+		}
+		return string(data), true, nil
+	}
+	if hash == "" {
+		return "", false, nil
+	}
+	return s.Log.Object(hash)
+}
+
+// gitRevisions reconstructs history from commits touching the JSONL file or
+// the pearl's content file. Commits that did not change the pearl are
+// skipped. Returns nil if the catalog is not in a git repository.
+func (s *Source) gitRevisions(id, contentPath string) ([]*Revision, error) {
+	paths := []string{s.JSONLFile}
+	if contentPath != "" {
+		paths = append(paths, filepath.Join(s.ContentDir, contentPath))
+	}
+	out, err := s.git(append([]string{"log", "--format=%H%x00%an%x00%aI", "--"}, paths...)...)
+	if err != nil {
+		return nil, nil
+	}
+
+	lines := strings.Split(strings.TrimSpace(string(out)), "\n")
+	var revs []*Revision
+	var prev *pearl.Pearl
+	var prevHash string
+
+	// git log is newest first; walk oldest first
+	for i := len(lines) - 1; i >= 0; i-- {
+		parts := strings.Split(lines[i], "\x00")
+		if len(parts) != 3 {
+			continue
+		}
+		commit, author := parts[0], parts[1]
+		at, _ := time.Parse(time.RFC3339, parts[2])
+
+		p := s.recordAt(commit, id)
+		hash := ""
+		if p != nil && p.ContentPath != "" {
+			if data, err := s.git("show", commit+":./"+filepath.ToSlash(filepath.Join(s.ContentDir, p.ContentPath))); err == nil {
+				hash = hashString(string(data))
+			}
+		}
+
+		r := &Revision{PearlID: id, At: at, By: author, Commit: commit, HashBefore: prevHash, HashAfter: hash, Pearl: p}
+		switch {
+		case p == nil && prev == nil:
+			continue
+		case p == nil:
+			r.Op, r.Pearl, r.HashAfter = OpDelete, prev, ""
+		case prev == nil:
+			r.Op = OpCreate
+		default:
+			r.Fields = changedKeys(prev, p)
+			if len(r.Fields) == 0 && hash == prevHash {
+				continue
+			}
+			r.Op = OpUpdate
+		}
+
+		revs = append(revs, r)
+		prev, prevHash = p, hash
+	}
+
+	for i, r := range revs {
+		r.Rev = i + 1
+	}
+	return revs, nil
+}
+
+// recordAt returns the pearl's JSONL record as of commit, or nil.
+func (s *Source) recordAt(commit, id string) *pearl.Pearl {
+	data, err := s.git("show", commit+":./"+filepath.ToSlash(s.JSONLFile))
+	if err != nil {
+		return nil
+	}
+	for _, line := range bytes.Split(data, []byte("\n")) {
+		if !bytes.Contains(line, []byte(`"`+id+`"`)) {
+			continue
+		}
+		var p pearl.Pearl
+		if err := json.Unmarshal(line, &p); err != nil || p.ID != id {
+			continue
+		}
+		delete(p.Extra, "_format")
+		if len(p.Extra) == 0 {
+			p.Extra = nil
+		}
+		return &p
+	}
+	return nil
+}
+
+func (s *Source) git(args ...string) ([]byte, error) {
+	return exec.Command("git", append([]string{"-C", s.Dir}, args...)...).Output()
+}
+
+// FieldChange is one metadata field that differs between two versions of
+// a pearl, with JSON-encoded values. A missing value is empty.
+type FieldChange struct {
+	Field  string          `json:"field"`
+	Before json.RawMessage `json:"before,omitempty"`
+	After  json.RawMessage `json:"after,omitempty"`
+}
+
+// Changes compares two versions of a pearl field by field, ignoring
+// updated_at. Either may be nil. Results are sorted by field name.
+func Changes(a, b *pearl.Pearl) []FieldChange {
+	am, bm := jsonFields(a), jsonFields(b)
+	var changes []FieldChange
+	for k, v := range bm {
+		if k != "updated_at" && !bytes.Equal(am[k], v) {
+			changes = append(changes, FieldChange{Field: k, Before: am[k], After: v})
+		}
+	}
+	for k, v := range am {
+		if _, ok := bm[k]; !ok && k != "updated_at" {
+			changes = append(changes, FieldChange{Field: k, Before: v})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// changedKeys lists the fields that differ between two records.
+func changedKeys(a, b *pearl.Pearl) []string {
+	var keys []string
+	for _, c := range Changes(a, b) {
+		keys = append(keys, c.Field)
+	}
+	return keys
+}
+
+func jsonFields(p *pearl.Pearl) map[string]json.RawMessage {
+	if p == nil {
+		return nil
+	}
+	data, _ := json.Marshal(p)
+	var m map[string]json.RawMessage
+	json.Unmarshal(data, &m)
+	return m
+}
+
+// sortRevisions orders revisions by time, keeping log order for ties, and
+// numbers them from 1.
+func sortRevisions(revs []*Revision) {
+	sort.SliceStable(revs, func(i, j int) bool {
+		return revs[i].At.Before(revs[j].At)
+	})
+	for i, r := range revs {
+		r.Rev = i + 1
+	}
+}
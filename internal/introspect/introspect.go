@@ -4,6 +4,8 @@ package introspect
 import (
 	"fmt"
 	"strings"
+
+	"github.com/justrnr500/pearls/internal/markdown"
 )
 
 // Introspector connects to a database and discovers schemas and tables.
@@ -114,6 +116,56 @@ func GenerateTableContent(tbl Table, prefix string) string {
 	return sb.String()
 }
 
+// ParseColumns reads the columns back from a table pearl's content: the
+// first table under a "Columns" or "Schema" heading, as GenerateTableContent
+// and the table template write it. Only Name and DataType are filled in.
+// Rows without a name, such as the template's placeholder cells, are
+// skipped.
+func ParseColumns(content string) []Column {
+	doc := markdown.Parse(content, 1)
+	section := doc.Find("Columns")
+	if section == nil {
+		section = doc.Find("Schema")
+	}
+	if section == nil {
+		return nil
+	}
+
+	for _, b := range section.Blocks {
+		if b.Kind != markdown.KindTable {
+			continue
+		}
+		rows := strings.Split(b.Text, "\n")
+		if len(rows) < 3 {
+			return nil
+		}
+		name, dataType := 0, 1
+		for i, h := range markdown.TableCells(rows[0]) {
+			switch strings.ToLower(h) {
+			case "column", "name":
+				name = i
+			case "type", "data type":
+				dataType = i
+			}
+		}
+
+		var columns []Column
+		for _, row := range rows[2:] {
+			cells := markdown.TableCells(row)
+			if name >= len(cells) || cells[name] == "" {
+				continue
+			}
+			col := Column{Name: strings.Trim(cells[name], "`")}
+			if dataType < len(cells) {
+				col.DataType = cells[dataType]
+			}
+			columns = append(columns, col)
+		}
+		return columns
+	}
+	return nil
+}
+
 // DefaultEnvVar returns the default environment variable name for a database type.
 func DefaultEnvVar(dbType string) string {
 	switch strings.ToLower(dbType) {
@@ -95,6 +95,31 @@ func TestGenerateTableContent_NoFKsOrIndexes(t *testing.T) {
 	}
 }
 
+func TestParseColumns(t *testing.T) {
+	generated := GenerateTableContent(Table{
+		Name: "users",
+		Columns: []Column{
+			{Name: "id", DataType: "bigint", PrimaryKey: true},
+			{Name: "email", DataType: "varchar(255)"},
+		},
+		Indexes: []Index{{Name: "users_pkey", Columns: []string{"id"}}},
+	}, "db")
+	got := ParseColumns(generated)
+	if len(got) != 2 || got[0] != (Column{Name: "id", DataType: "bigint"}) || got[1] != (Column{Name: "email", DataType: "varchar(255)"}) {
+		t.Errorf("generated = %+v", got)
+	}
+
+	handWritten := "# orders\n\n## Schema\n\n| Type | Column |\n|---|---|\n| int | `id` |\n| | |\n\n## Notes\n"
+	got = ParseColumns(handWritten)
+	if len(got) != 1 || got[0] != (Column{Name: "id", DataType: "int"}) {
+		t.Errorf("hand-written = %+v", got)
+	}
+
+	if got := ParseColumns("# notes\n\nNo table here.\n"); got != nil {
+		t.Errorf("no columns = %+v", got)
+	}
+}
+
 func TestDefaultEnvVar(t *testing.T) {
 	tests := []struct {
 		dbType string
@@ -0,0 +1,532 @@
+package lsp
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/justrnr500/pearls/internal/introspect"
+	"github.com/justrnr500/pearls/internal/markdown"
+	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/storage"
+	"github.com/justrnr500/pearls/internal/validate"
+)
+
+// Frontmatter lists are written either inline, "references: [a, b]", or
+// as a block of "- item" lines under "references:".
+var (
+	flowList  = regexp.MustCompile(`^([A-Za-z_]+):\s*\[`)
+	blockList = regexp.MustCompile(`^([A-Za-z_]+):\s*$`)
+	listItem  = regexp.MustCompile(`^\s*-\s*`)
+)
+
+// qualified matches a table's name or ID, a dot, and the start of a column
+// name at the end of a line prefix: "users.em" or "db.users.".
+var qualified = regexp.MustCompile(`([A-Za-z0-9_][A-Za-z0-9_.\-]*)\.([A-Za-z0-9_]*)$`)
+
+// fmItem is an entry in a frontmatter list.
+type fmItem struct {
+	key   string
+	value string
+	// line is 0-based; start and end are byte offsets in it.
+	line, start, end int
+}
+
+// fmItems returns the entries of the lists in a document's frontmatter.
+func fmItems(doc *document) []fmItem {
+	var items []fmItem
+	key := ""
+	for n := 1; n < doc.fmEnd; n++ {
+		line := doc.line(n)
+		if m := flowList.FindStringSubmatchIndex(line); m != nil {
+			key = line[m[2]:m[3]]
+			end := len(line)
+			if i := strings.IndexByte(line[m[1]:], ']'); i >= 0 {
+				end = m[1] + i
+			}
+			pos := m[1]
+			for _, part := range strings.Split(line[m[1]:end], ",") {
+				if it, ok := newItem(key, n, pos, part); ok {
+					items = append(items, it)
+				}
+				pos += len(part) + 1
+			}
+			key = ""
+			continue
+		}
+		if m := blockList.FindStringSubmatch(line); m != nil {
+			key = m[1]
+			continue
+		}
+		if m := listItem.FindStringIndex(line); m != nil && key != "" {
+			if it, ok := newItem(key, n, m[1], line[m[1]:]); ok {
+				items = append(items, it)
+			}
+			continue
+		}
+		key = ""
+	}
+	return items
+}
+
+// newItem returns the list entry written as text at offset pos, without
+// surrounding space or quotes.
+func newItem(key string, line, pos int, text string) (fmItem, bool) {
+	const cutset = " \t\"'"
+	value := strings.Trim(text, cutset)
+	if value == "" {
+		return fmItem{}, false
+	}
+	start := pos + len(text) - len(strings.TrimLeft(text, cutset))
+	return fmItem{key: key, value: value, line: line, start: start, end: start + len(value)}, true
+}
+
+// listContext returns the frontmatter list the cursor is in, given the
+// text of its line before it, and where the entry being typed starts.
+func listContext(doc *document, n int, prefix string) (string, int, bool) {
+	if m := flowList.FindStringSubmatchIndex(prefix); m != nil {
+		if strings.Contains(prefix[m[1]:], "]") {
+			return "", 0, false
+		}
+		start := m[1]
+		if i := strings.LastIndex(prefix, ","); i >= start {
+			start = i + 1
+		}
+		start += len(prefix[start:]) - len(strings.TrimLeft(prefix[start:], " \t\"'"))
+		return prefix[m[2]:m[3]], start, true
+	}
+	if m := listItem.FindStringIndex(prefix); m != nil {
+		for i := n - 1; i > 0; i-- {
+			line := doc.line(i)
+			if listItem.MatchString(line) {
+				continue
+			}
+			if km := blockList.FindStringSubmatch(line); km != nil {
+				start := m[1] + len(prefix[m[1]:]) - len(strings.TrimLeft(prefix[m[1]:], "\"'"))
+				return km[1], start, true
+			}
+			break
+		}
+	}
+	return "", 0, false
+}
+
+// openLink returns what has been typed of a wiki-link the cursor is in,
+// given the text of its line before the cursor.
+func openLink(prefix string) (string, bool) {
+	i := strings.LastIndex(prefix, "[[")
+	if i < 0 {
+		return "", false
+	}
+	partial := prefix[i+2:]
+	if strings.ContainsAny(partial, "[]") {
+		return "", false
+	}
+	return partial, true
+}
+
+// matches reports whether a completion label fits what has been typed.
+func matches(label, typed string) bool {
+	return strings.Contains(strings.ToLower(label), strings.ToLower(typed))
+}
+
+func (s *Server) completion(params TextDocumentPositionParams) (*CompletionList, error) {
+	list := &CompletionList{Items: []CompletionItem{}}
+	doc := s.document(params.TextDocument.URI)
+	if doc == nil {
+		return list, nil
+	}
+
+	n := params.Position.Line
+	line := doc.line(n)
+	col := byteOffset(line, params.Position.Character)
+	prefix := line[:col]
+
+	var items []CompletionItem
+	var err error
+	switch {
+	case doc.inFrontmatter(n):
+		if key, start, ok := listContext(doc, n, prefix); ok {
+			items, err = s.completeList(key, prefix[start:], lineRange(doc.lines, n, start, col))
+		}
+	case n >= doc.bodyStart:
+		if partial, ok := openLink(prefix); ok && !markdown.InCode(doc.body, n-doc.bodyStart) {
+			items, err = s.completeLink(partial, doc.lines, n, col)
+		} else if m := qualified.FindStringSubmatch(prefix); m != nil {
+			items, err = s.completeColumns(m[1], m[2], lineRange(doc.lines, n, col-len(m[2]), col))
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if items != nil {
+		list.Items = items
+	}
+	return list, nil
+}
+
+// completeList offers entries for a frontmatter list: pearl IDs for
+// references and declared scopes for scopes.
+func (s *Server) completeList(key, typed string, rng Range) ([]CompletionItem, error) {
+	switch key {
+	case "references":
+		return s.completeIDs(typed, rng)
+	case "scopes":
+		scopes := s.opts.Config.Scopes
+		names := make([]string, 0, len(scopes))
+		for name := range scopes {
+			if matches(name, typed) {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		var items []CompletionItem
+		for _, name := range names {
+			items = append(items, CompletionItem{
+				Label:         name,
+				Kind:          KindReference,
+				Documentation: scopes[name].Description,
+				TextEdit:      &TextEdit{Range: rng, NewText: name},
+			})
+		}
+		return items, nil
+	}
+	return nil, nil
+}
+
+// completeLink offers pearl IDs inside "[[", or section anchors after
+// "[[id#".
+func (s *Server) completeLink(partial string, lines []string, n, col int) ([]CompletionItem, error) {
+	if strings.Contains(partial, "|") {
+		return nil, nil
+	}
+	id, typed, ok := strings.Cut(partial, "#")
+	if !ok {
+		return s.completeIDs(partial, lineRange(lines, n, col-len(partial), col))
+	}
+
+	target, err := s.store.Get(strings.TrimSpace(id))
+	if err != nil {
+		return nil, fmt.Errorf("get pearl: %w", err)
+	}
+	if target == nil {
+		return nil, nil
+	}
+	tdoc, err := s.documentFor(target)
+	if err != nil || tdoc == nil {
+		return nil, err
+	}
+
+	rng := lineRange(lines, n, col-len(typed), col)
+	var items []CompletionItem
+	markdown.Parse(tdoc.body, tdoc.bodyStart+1).Walk(func(sec *markdown.Section) {
+		if !matches(sec.Anchor(), typed) && !matches(sec.Title(), typed) {
+			return
+		}
+		items = append(items, CompletionItem{
+			Label:    sec.Anchor(),
+			Kind:     KindReference,
+			Detail:   sec.Heading.Source(),
+			TextEdit: &TextEdit{Range: rng, NewText: sec.Anchor()},
+		})
+	})
+	return items, nil
+}
+
+// completeIDs offers the IDs of pearls that are not archived.
+func (s *Server) completeIDs(typed string, rng Range) ([]CompletionItem, error) {
+	pearls, err := s.store.DB().All()
+	if err != nil {
+		return nil, fmt.Errorf("list pearls: %w", err)
+	}
+	var items []CompletionItem
+	for _, p := range pearls {
+		if p.Status == pearl.StatusArchived || !matches(p.ID, typed) {
+			continue
+		}
+		items = append(items, CompletionItem{
+			Label:         p.ID,
+			Kind:          KindModule,
+			Detail:        string(p.Type),
+			Documentation: p.Description,
+			TextEdit:      &TextEdit{Range: rng, NewText: p.ID},
+		})
+	}
+	return items, nil
+}
+
+// completeColumns offers the columns of the table pearl named by
+// qualifier, as documented in its content.
+func (s *Server) completeColumns(qualifier, typed string, rng Range) ([]CompletionItem, error) {
+	table, err := s.tableNamed(qualifier)
+	if err != nil || table == nil {
+		return nil, err
+	}
+	tdoc, err := s.documentFor(table)
+	if err != nil || tdoc == nil {
+		return nil, err
+	}
+
+	var items []CompletionItem
+	for _, c := range introspect.ParseColumns(tdoc.body) {
+		if !matches(c.Name, typed) {
+			continue
+		}
+		items = append(items, CompletionItem{
+			Label:         c.Name,
+			Kind:          KindField,
+			Detail:        c.DataType,
+			Documentation: table.ID,
+			TextEdit:      &TextEdit{Range: rng, NewText: c.Name},
+		})
+	}
+	return items, nil
+}
+
+// tableNamed returns the table pearl with the given ID, or else the only
+// one with the given name, or nil.
+func (s *Server) tableNamed(name string) (*pearl.Pearl, error) {
+	pearls, err := s.store.DB().All()
+	if err != nil {
+		return nil, fmt.Errorf("list pearls: %w", err)
+	}
+	var named []*pearl.Pearl
+	for _, p := range pearls {
+		if p.Type != pearl.TypeTable {
+			continue
+		}
+		if p.ID == name {
+			return p, nil
+		}
+		if strings.EqualFold(p.Name, name) {
+			named = append(named, p)
+		}
+	}
+	if len(named) == 1 {
+		return named[0], nil
+	}
+	return nil, nil
+}
+
+// reference is a mention of a pearl in a document: a wiki-link, or an
+// entry in the frontmatter references.
+type reference struct {
+	target  string
+	section string
+	rng     Range
+}
+
+// referenceAt returns the reference under the cursor, or nil.
+func referenceAt(doc *document, pos Position) *reference {
+	n := pos.Line
+	col := byteOffset(doc.line(n), pos.Character)
+	if doc.inFrontmatter(n) {
+		for _, it := range fmItems(doc) {
+			if it.key == "references" && it.line == n && it.start <= col && col <= it.end {
+				return &reference{target: it.value, rng: lineRange(doc.lines, n, it.start, it.end)}
+			}
+		}
+		return nil
+	}
+	for _, span := range markdown.LinkSpans(doc.body) {
+		if doc.bodyStart+span.Line-1 == n && span.Start <= col && col < span.End {
+			return &reference{
+				target:  span.Target,
+				section: span.Section,
+				rng:     lineRange(doc.lines, n, span.Start, span.End),
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Server) hover(params TextDocumentPositionParams) (*Hover, error) {
+	doc := s.document(params.TextDocument.URI)
+	if doc == nil {
+		return nil, nil
+	}
+	ref := referenceAt(doc, params.Position)
+	if ref == nil {
+		return nil, nil
+	}
+	p, err := s.store.Get(ref.target)
+	if err != nil {
+		return nil, fmt.Errorf("get pearl: %w", err)
+	}
+
+	var b strings.Builder
+	if p == nil {
+		fmt.Fprintf(&b, "**%s**: pearl not found", ref.target)
+		return &Hover{Contents: MarkupContent{Kind: "markdown", Value: b.String()}, Range: &ref.rng}, nil
+	}
+	fmt.Fprintf(&b, "**%s** · %s · %s", p.ID, p.Type, p.Status)
+	if p.Description != "" {
+		fmt.Fprintf(&b, "\n\n%s", p.Description)
+	}
+	if len(p.Tags) > 0 {
+		fmt.Fprintf(&b, "\n\nTags: %s", strings.Join(p.Tags, ", "))
+	}
+	if ref.section != "" {
+		sec, err := s.section(p, ref.section)
+		if err != nil {
+			return nil, err
+		}
+		if sec != nil {
+			fmt.Fprintf(&b, "\n\nSection: %s", sec.Title())
+		} else {
+			fmt.Fprintf(&b, "\n\nNo such section: #%s", ref.section)
+		}
+	}
+	return &Hover{Contents: MarkupContent{Kind: "markdown", Value: b.String()}, Range: &ref.rng}, nil
+}
+
+// definition returns the content file of the pearl under the cursor, at
+// the linked section's heading if there is one.
+func (s *Server) definition(params TextDocumentPositionParams) (*Location, error) {
+	doc := s.document(params.TextDocument.URI)
+	if doc == nil {
+		return nil, nil
+	}
+	ref := referenceAt(doc, params.Position)
+	if ref == nil {
+		return nil, nil
+	}
+	p, err := s.store.Get(ref.target)
+	if err != nil {
+		return nil, fmt.Errorf("get pearl: %w", err)
+	}
+	if p == nil {
+		return nil, nil
+	}
+	tdoc, err := s.documentFor(p)
+	if err != nil || tdoc == nil {
+		return nil, err
+	}
+
+	line := 0
+	if ref.section != "" {
+		if sec := markdown.Parse(tdoc.body, tdoc.bodyStart+1).FindAnchor(ref.section); sec != nil {
+			line = sec.Heading.Line - 1
+		}
+	}
+	return &Location{URI: tdoc.uri, Range: lineRange(tdoc.lines, line, 0, 0)}, nil
+}
+
+// section returns the section of p's content named by selector, or nil.
+func (s *Server) section(p *pearl.Pearl, selector string) (*markdown.Section, error) {
+	tdoc, err := s.documentFor(p)
+	if err != nil || tdoc == nil {
+		return nil, err
+	}
+	return markdown.Parse(tdoc.body, tdoc.bodyStart+1).FindAnchor(selector), nil
+}
+
+// diagnostics checks a content file as 'pearls doctor' and 'pearls
+// validate' would: a file no pearl points to, broken wiki-links and
+// references, undeclared scopes, and validation rule violations. Files
+// outside the content directory are not checked.
+func (s *Server) diagnostics(doc *document) ([]Diagnostic, error) {
+	if doc.path == "" || filepath.Ext(doc.path) != ".md" || !s.inContentDir(doc.path) {
+		return nil, nil
+	}
+
+	var diags []Diagnostic
+	add := func(n, start, end, severity int, code, format string, args ...interface{}) {
+		diags = append(diags, Diagnostic{
+			Range:    lineRange(doc.lines, n, start, end),
+			Severity: severity,
+			Code:     code,
+			Source:   "pearls",
+			Message:  fmt.Sprintf(format, args...),
+		})
+	}
+
+	p, err := s.pearlFor(doc.path)
+	if err != nil {
+		return nil, err
+	}
+	if p == nil {
+		add(0, 0, len(doc.line(0)), SeverityWarning, "orphaned-content", "no pearl points to this content file")
+	}
+
+	fm, _, fmErr := storage.ParseFrontmatter(doc.text)
+	if fmErr != nil {
+		add(0, 0, len(doc.line(0)), SeverityError, "frontmatter", "%v", fmErr)
+	}
+
+	for _, it := range fmItems(doc) {
+		switch it.key {
+		case "references":
+			target, err := s.store.Get(it.value)
+			if err != nil {
+				return nil, fmt.Errorf("get pearl: %w", err)
+			}
+			if target == nil {
+				add(it.line, it.start, it.end, SeverityError, "references", "%s: pearl not found", it.value)
+			}
+		case "scopes":
+			scopes := s.opts.Config.Scopes
+			if len(scopes) > 0 && !scopes.Declared(it.value) {
+				add(it.line, it.start, it.end, SeverityWarning, "scopes", "%s is not declared in config.yaml", it.value)
+			}
+		}
+	}
+
+	for _, span := range markdown.LinkSpans(doc.body) {
+		n := doc.bodyStart + span.Line - 1
+		target, err := s.store.Get(span.Target)
+		if err != nil {
+			return nil, fmt.Errorf("get pearl: %w", err)
+		}
+		if target == nil {
+			add(n, span.Start, span.End, SeverityError, "wiki-link", "%s: pearl not found", span.Link)
+			continue
+		}
+		if span.Section == "" {
+			continue
+		}
+		sec, err := s.section(target, span.Section)
+		if err != nil {
+			return nil, err
+		}
+		if sec == nil {
+			add(n, span.Start, span.End, SeverityError, "wiki-link", "%s: no such section", span.Link)
+		}
+	}
+
+	if p == nil || s.engine == nil {
+		return diags, nil
+	}
+	// Check the metadata as the frontmatter being edited will leave it
+	target := *p
+	if fm != nil {
+		fm.Apply(&target)
+	}
+	for _, v := range s.engine.Check(&validate.Target{
+		Pearl:      &target,
+		HasContent: true,
+		Content:    doc.body,
+		BodyLine:   doc.bodyStart + 1,
+		Template:   s.store.Content().Template(&target),
+	}) {
+		n := 0
+		if v.Line > 0 {
+			n = v.Line - 1
+		}
+		add(n, 0, len(doc.line(n)), severity(v.Severity), v.Rule, "%s", v.Message)
+	}
+	return diags, nil
+}
+
+// severity maps a rule's severity to the protocol's.
+func severity(s validate.Severity) int {
+	switch s {
+	case validate.SeverityError:
+		return SeverityError
+	case validate.SeverityWarning:
+		return SeverityWarning
+	}
+	return SeverityInformation
+}
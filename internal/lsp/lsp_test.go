@@ -0,0 +1,471 @@
+package lsp
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/storage"
+)
+
+func setupLSPTestStore(t *testing.T) *storage.Store {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := storage.NewStore(filepath.Join(dir, "pearls.db"), filepath.Join(dir, "pearls.jsonl"), filepath.Join(dir, "content"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	t.Cleanup(func() { store.Close() })
+	return store
+}
+
+// uriOf returns the URI of a pearl's content file.
+func uriOf(t *testing.T, store *storage.Store, id string) string {
+	t.Helper()
+	p, err := store.Get(id)
+	if err != nil || p == nil {
+		t.Fatalf("get %s: %v", id, err)
+	}
+	return pathToURI(store.Content().FullPath(p.ContentPath))
+}
+
+// session scripts the messages of a client.
+type session struct {
+	in bytes.Buffer
+	id int
+}
+
+func (c *session) send(v interface{}) {
+	body, _ := json.Marshal(v)
+	c.in.WriteString("Content-Length: " + itoa(len(body)) + "\r\n\r\n")
+	c.in.Write(body)
+}
+
+func itoa(n int) string {
+	b, _ := json.Marshal(n)
+	return string(b)
+}
+
+// request sends a request and returns its ID.
+func (c *session) request(method string, params interface{}) int {
+	c.id++
+	c.send(map[string]interface{}{"jsonrpc": "2.0", "id": c.id, "method": method, "params": params})
+	return c.id
+}
+
+func (c *session) notify(method string, params interface{}) {
+	c.send(map[string]interface{}{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+func (c *session) open(uri, text string) {
+	c.notify("textDocument/didOpen", DidOpenTextDocumentParams{TextDocument: TextDocumentItem{URI: uri, LanguageID: "markdown", Text: text}})
+}
+
+// reply is a message from the server.
+type reply struct {
+	ID     int             `json:"id"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params"`
+	Result json.RawMessage `json:"result"`
+	Error  *rpcError       `json:"error"`
+}
+
+// run serves the scripted messages and returns the server's replies.
+func (c *session) run(t *testing.T, s *Server) []reply {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.Serve(&c.in, &out); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	var replies []reply
+	r := newConn(&out, nil)
+	for {
+		body, err := r.read()
+		if errors.Is(err, io.EOF) {
+			return replies
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var rep reply
+		if err := json.Unmarshal(body, &rep); err != nil {
+			t.Fatal(err)
+		}
+		replies = append(replies, rep)
+	}
+}
+
+func result(t *testing.T, replies []reply, id int, v interface{}) {
+	t.Helper()
+	for _, r := range replies {
+		if r.Method == "" && r.ID == id {
+			if r.Error != nil {
+				t.Fatalf("request %d: %v", id, r.Error)
+			}
+			if err := json.Unmarshal(r.Result, v); err != nil {
+				t.Fatal(err)
+			}
+			return
+		}
+	}
+	t.Fatalf("no reply to request %d", id)
+}
+
+func at(line, character int) TextDocumentPositionParams {
+	return TextDocumentPositionParams{Position: Position{Line: line, Character: character}}
+}
+
+func positionParams(uri string, line, character int) TextDocumentPositionParams {
+	p := at(line, character)
+	p.TextDocument.URI = uri
+	return p
+}
+
+func TestLifecycle(t *testing.T) {
+	s := New(setupLSPTestStore(t), Options{})
+	var c session
+	initID := c.request("initialize", map[string]interface{}{})
+	c.notify("initialized", map[string]interface{}{})
+	c.notify("$/unknown", nil)
+	unknownID := c.request("workspace/symbol", map[string]interface{}{})
+	shutdownID := c.request("shutdown", nil)
+	c.notify("exit", nil)
+	c.request("initialize", nil) // after exit; never read
+
+	replies := c.run(t, s)
+	if len(replies) != 3 {
+		t.Fatalf("got %d replies, want 3: %+v", len(replies), replies)
+	}
+
+	var init struct {
+		Capabilities map[string]json.RawMessage `json:"capabilities"`
+		ServerInfo   struct{ Name string }      `json:"serverInfo"`
+	}
+	result(t, replies, initID, &init)
+	for _, cap := range []string{"textDocumentSync", "completionProvider", "hoverProvider", "definitionProvider"} {
+		if init.Capabilities[cap] == nil {
+			t.Errorf("capability %s missing", cap)
+		}
+	}
+	if init.ServerInfo.Name != "pearls" {
+		t.Errorf("serverInfo = %+v", init.ServerInfo)
+	}
+
+	if replies[1].ID != unknownID || replies[1].Error == nil || replies[1].Error.Code != codeMethodNotFound {
+		t.Errorf("unknown method reply = %+v", replies[1])
+	}
+	if replies[2].ID != shutdownID || replies[2].Error != nil {
+		t.Errorf("shutdown reply = %+v", replies[2])
+	}
+}
+
+func TestCompletion(t *testing.T) {
+	store := setupLSPTestStore(t)
+	now := time.Now()
+	for _, c := range []struct {
+		p       *pearl.Pearl
+		content string
+	}{
+		{&pearl.Pearl{ID: "db.users", Name: "users", Namespace: "db", Type: pearl.TypeTable, Status: pearl.StatusActive,
+			Description: "User accounts", CreatedAt: now, UpdatedAt: now},
+			"# Users\n\n## Columns\n\n| Column | Type |\n|---|---|\n| id | bigint |\n| email | text |\n\n## Notes\n\nSoft-deleted.\n"},
+		{&pearl.Pearl{ID: "db.orders", Name: "orders", Namespace: "db", Type: pearl.TypeTable, Status: pearl.StatusActive, CreatedAt: now, UpdatedAt: now},
+			"# Orders\n"},
+		{&pearl.Pearl{ID: "api.billing", Name: "billing", Namespace: "api", Type: pearl.TypeAPI, Status: pearl.StatusActive, CreatedAt: now, UpdatedAt: now},
+			"# Billing\n"},
+	} {
+		if err := store.Create(c.p, c.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.Default()
+	cfg.Scopes = config.Scopes{"payments": {Description: "Payment flows"}, "backend": {}}
+	s := New(store, Options{Config: cfg})
+
+	uri := uriOf(t, store, "api.billing")
+	lines := []string{
+		"---",
+		"references: [db.users, db.or",
+		"scopes:",
+		"  - pay",
+		"---",
+		"",
+		"# Billing",
+		"",
+		"See [[db.u",
+		"See [[db.users#no",
+		"```sql",
+		"SELECT users.em",
+		"-- [[db.",
+		"```",
+		"See [[db.users|",
+	}
+	var c session
+	c.open(uri, strings.Join(lines, "\n"))
+	ids := make([]int, len(lines))
+	for _, n := range []int{1, 3, 8, 9, 11, 12, 14} {
+		ids[n] = c.request("textDocument/completion", positionParams(uri, n, len(lines[n])))
+	}
+	replies := c.run(t, s)
+
+	labels := func(n int) ([]string, CompletionList) {
+		var list CompletionList
+		result(t, replies, ids[n], &list)
+		var got []string
+		for _, item := range list.Items {
+			got = append(got, item.Label)
+		}
+		return got, list
+	}
+
+	tests := []struct {
+		line int
+		want string
+	}{
+		{1, "db.orders"},
+		{3, "payments"},
+		{8, "db.users"},
+		{9, "notes"},
+		{11, "email"},
+		{12, ""},
+		{14, ""},
+	}
+	for _, tt := range tests {
+		got, list := labels(tt.line)
+		if strings.Join(got, ",") != tt.want {
+			t.Errorf("line %d (%q): got %v, want %q", tt.line, lines[tt.line], got, tt.want)
+			continue
+		}
+		if tt.want == "" {
+			continue
+		}
+		// The edit replaces what has been typed
+		edit := list.Items[0].TextEdit
+		typed := lines[tt.line][edit.Range.Start.Character:edit.Range.End.Character]
+		if edit.Range.Start.Line != tt.line || edit.Range.End.Character != len(lines[tt.line]) || !strings.Contains(tt.want, typed) {
+			t.Errorf("line %d: edit %+v replaces %q", tt.line, edit, typed)
+		}
+	}
+
+	if _, list := labels(8); list.Items[0].Detail != "table" || list.Items[0].Documentation != "User accounts" {
+		t.Errorf("ID item = %+v", list.Items[0])
+	}
+	if _, list := labels(11); list.Items[0].Detail != "text" || list.Items[0].Kind != KindField {
+		t.Errorf("column item = %+v", list.Items[0])
+	}
+}
+
+func TestHoverAndDefinition(t *testing.T) {
+	store := setupLSPTestStore(t)
+	now := time.Now()
+	for _, c := range []struct {
+		p       *pearl.Pearl
+		content string
+	}{
+		{&pearl.Pearl{ID: "db.users", Name: "users", Namespace: "db", Type: pearl.TypeTable, Status: pearl.StatusActive,
+			Description: "User accounts", Tags: []string{"pii"}, CreatedAt: now, UpdatedAt: now},
+			"# Users\n\n## Notes\n\nSoft-deleted.\n"},
+		{&pearl.Pearl{ID: "db.orders", Name: "orders", Namespace: "db", Type: pearl.TypeTable, Status: pearl.StatusActive, CreatedAt: now, UpdatedAt: now},
+			"# Orders\n"},
+		{&pearl.Pearl{ID: "api.billing", Name: "billing", Namespace: "api", Type: pearl.TypeAPI, Status: pearl.StatusActive, CreatedAt: now, UpdatedAt: now},
+			"# Billing\n"},
+	} {
+		if err := store.Create(c.p, c.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	s := New(store, Options{})
+
+	uri := uriOf(t, store, "api.billing")
+	text := "---\nreferences: [db.users]\n---\n\n# Billing\n\nSee [[db.orders]] and [[db.users#Notes]] and [[db.gone]].\n"
+	var c session
+	c.open(uri, text)
+	refHover := c.request("textDocument/hover", positionParams(uri, 1, 16))
+	linkHover := c.request("textDocument/hover", positionParams(uri, 6, 28))
+	goneHover := c.request("textDocument/hover", positionParams(uri, 6, 50))
+	noHover := c.request("textDocument/hover", positionParams(uri, 6, 1))
+	linkDef := c.request("textDocument/definition", positionParams(uri, 6, 28))
+	refDef := c.request("textDocument/definition", positionParams(uri, 6, 8))
+	goneDef := c.request("textDocument/definition", positionParams(uri, 6, 50))
+	replies := c.run(t, s)
+
+	var hover Hover
+	result(t, replies, refHover, &hover)
+	if v := hover.Contents.Value; !strings.Contains(v, "**db.users** · table · active") || !strings.Contains(v, "User accounts") || !strings.Contains(v, "Tags: pii") {
+		t.Errorf("reference hover = %q", v)
+	}
+	if hover.Range == nil || hover.Range.Start != (Position{Line: 1, Character: 13}) || hover.Range.End != (Position{Line: 1, Character: 21}) {
+		t.Errorf("reference hover range = %+v", hover.Range)
+	}
+
+	result(t, replies, linkHover, &hover)
+	if !strings.Contains(hover.Contents.Value, "Section: Notes") {
+		t.Errorf("link hover = %q", hover.Contents.Value)
+	}
+	result(t, replies, goneHover, &hover)
+	if !strings.Contains(hover.Contents.Value, "pearl not found") {
+		t.Errorf("missing pearl hover = %q", hover.Contents.Value)
+	}
+	var none *Hover
+	result(t, replies, noHover, &none)
+	if none != nil {
+		t.Errorf("hover outside links = %+v", none)
+	}
+
+	var loc *Location
+	result(t, replies, linkDef, &loc)
+	raw, err := os.ReadFile(uriToPath(uriOf(t, store, "db.users")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	notes := strings.Index(string(raw), "## Notes")
+	if loc == nil || loc.URI != uriOf(t, store, "db.users") || loc.Range.Start.Line != strings.Count(string(raw[:notes]), "\n") {
+		t.Errorf("section definition = %+v", loc)
+	}
+	result(t, replies, refDef, &loc)
+	if loc == nil || loc.URI != uriOf(t, store, "db.orders") || loc.Range.Start.Line != 0 {
+		t.Errorf("definition = %+v", loc)
+	}
+	loc = nil
+	result(t, replies, goneDef, &loc)
+	if loc != nil {
+		t.Errorf("missing pearl definition = %+v", loc)
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	store := setupLSPTestStore(t)
+	now := time.Now()
+	for _, c := range []struct {
+		p       *pearl.Pearl
+		content string
+	}{
+		{&pearl.Pearl{ID: "db.users", Name: "users", Namespace: "db", Type: pearl.TypeTable, Status: pearl.StatusActive, CreatedAt: now, UpdatedAt: now},
+			"# Users\n"},
+		{&pearl.Pearl{ID: "db.orders", Name: "orders", Namespace: "db", Type: pearl.TypeTable, Status: pearl.StatusActive, CreatedAt: now, UpdatedAt: now},
+			"# Orders\n"},
+		{&pearl.Pearl{ID: "api.billing", Name: "billing", Namespace: "api", Type: pearl.TypeAPI, Status: pearl.StatusActive,
+			Description: "Billing API", CreatedAt: now, UpdatedAt: now},
+			"# Billing\n"},
+	} {
+		if err := store.Create(c.p, c.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cfg := config.Default()
+	cfg.Scopes = config.Scopes{"backend": {}}
+	s := New(store, Options{Config: cfg})
+
+	uri := uriOf(t, store, "api.billing")
+	lines := []string{
+		"---",
+		"references: [db.users, db.missing]",
+		"scopes:",
+		"  - payments",
+		"---",
+		"",
+		"# Billing",
+		"",
+		"See [[db.orders]], [[db.nope]], and [[db.users#nowhere]].",
+		"",
+		"```",
+		"[[db.in-code]]",
+		"```",
+		"",
+		"### Skipped",
+	}
+	stray := filepath.Join(store.Content().BaseDir(), "db", "stray.md")
+	if err := os.WriteFile(stray, []byte("# Stray\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	var c session
+	c.open(uri, strings.Join(lines, "\n"))
+	c.open(pathToURI(stray), "# Stray\n")
+	c.open(pathToURI(filepath.Join(t.TempDir(), "README.md")), "[[nope]]\n")
+	c.notify("textDocument/didClose", DidCloseTextDocumentParams{TextDocument: TextDocumentIdentifier{URI: uri}})
+	replies := c.run(t, s)
+
+	var published []PublishDiagnosticsParams
+	for _, r := range replies {
+		if r.Method != "textDocument/publishDiagnostics" {
+			t.Fatalf("unexpected reply %+v", r)
+		}
+		var p PublishDiagnosticsParams
+		if err := json.Unmarshal(r.Params, &p); err != nil {
+			t.Fatal(err)
+		}
+		published = append(published, p)
+	}
+	if len(published) != 4 {
+		t.Fatalf("got %d publishes, want 4", len(published))
+	}
+
+	type diag struct {
+		line     int
+		severity int
+		code     string
+		message  string
+	}
+	want := []diag{
+		{1, SeverityError, "references", "db.missing: pearl not found"},
+		{3, SeverityWarning, "scopes", "payments is not declared in config.yaml"},
+		{8, SeverityError, "wiki-link", "[[db.nope]]: pearl not found"},
+		{8, SeverityError, "wiki-link", "[[db.users#nowhere]]: no such section"},
+		{14, SeverityWarning, "heading-structure", `heading "Skipped" skips from H1 to H3`},
+	}
+	var got []diag
+	for _, d := range published[0].Diagnostics {
+		got = append(got, diag{d.Range.Start.Line, d.Severity, d.Code, d.Message})
+		if d.Source != "pearls" {
+			t.Errorf("source = %q", d.Source)
+		}
+	}
+	if len(got) != len(want) {
+		t.Fatalf("diagnostics = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("diagnostic %d = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+	if r := published[0].Diagnostics[1].Range; r.Start.Character != 4 || r.End.Character != 12 {
+		t.Errorf("scope range = %+v", r)
+	}
+
+	if d := published[1].Diagnostics; len(d) != 1 || d[0].Code != "orphaned-content" {
+		t.Errorf("orphan diagnostics = %+v", d)
+	}
+	if d := published[2].Diagnostics; len(d) != 0 {
+		t.Errorf("file outside content = %+v", d)
+	}
+	if published[3].URI != uri || len(published[3].Diagnostics) != 0 {
+		t.Errorf("close = %+v", published[3])
+	}
+}
+
+func TestFmItems(t *testing.T) {
+	doc := newDocument("", "", "---\nreferences: [a, \"b\" ,c]\ntags:\n  - x\n- 'y'\nowner: me\n  - z\n---\nbody\n")
+	var got []string
+	for _, it := range fmItems(doc) {
+		got = append(got, it.key+"="+it.value+"@"+doc.line(it.line)[it.start:it.end])
+	}
+	want := "references=a@a,references=b@b,references=c@c,tags=x@x,tags=y@y"
+	if strings.Join(got, ",") != want {
+		t.Errorf("fmItems = %v, want %s", got, want)
+	}
+	if doc.bodyStart != 8 || doc.fmEnd != 7 {
+		t.Errorf("bodyStart, fmEnd = %d, %d", doc.bodyStart, doc.fmEnd)
+	}
+}
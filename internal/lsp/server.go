@@ -0,0 +1,349 @@
+// Package lsp is a language server for pearl content files. Editors start
+// it with 'pearls lsp' and talk to it over stdio. It completes pearl IDs
+// in wiki-links and frontmatter reference lists, section anchors after
+// '#', and column names after a table pearl's name; shows a pearl's
+// summary on hover; jumps to a linked pearl's content file; and reports
+// broken links and references and validation rule violations as
+// diagnostics while the file is edited.
+package lsp
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"path/filepath"
+	"strings"
+
+	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/storage"
+	"github.com/justrnr500/pearls/internal/validate"
+)
+
+// Options configures a Server.
+type Options struct {
+	// Config supplies validation rules and the scope registry; nil uses
+	// the defaults.
+	Config *config.Config
+	// Log, if set, receives errors that cannot be reported to the client.
+	Log *log.Logger
+}
+
+// Server answers requests from one client about the files of a store.
+type Server struct {
+	store  *storage.Store
+	opts   Options
+	engine *validate.Engine
+	conn   *conn
+	// docs are the open files, keyed by URI.
+	docs map[string]*document
+}
+
+// New returns a Server for store.
+func New(store *storage.Store, opts Options) *Server {
+	if opts.Config == nil {
+		opts.Config = config.Default()
+	}
+	s := &Server{store: store, opts: opts, docs: make(map[string]*document)}
+	engine, err := validate.New(opts.Config.Validation)
+	if err != nil {
+		s.logf("validation disabled: %v", err)
+	} else {
+		s.engine = engine
+	}
+	return s
+}
+
+// Serve reads requests from r and writes responses to w until the client
+// sends exit or closes r.
+func (s *Server) Serve(r io.Reader, w io.Writer) error {
+	s.conn = newConn(r, w)
+	for {
+		body, err := s.conn.read()
+		if errors.Is(err, io.EOF) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("read message: %w", err)
+		}
+
+		var msg message
+		if err := json.Unmarshal(body, &msg); err != nil {
+			if err := s.conn.reply(json.RawMessage("null"), nil, &rpcError{Code: codeParseError, Message: err.Error()}); err != nil {
+				return err
+			}
+			continue
+		}
+		if msg.Method == "exit" {
+			return nil
+		}
+
+		if _, err := s.store.EnsureFresh(); err != nil {
+			s.logf("refresh: %v", err)
+		}
+		result, err := s.dispatch(&msg)
+		if msg.ID == nil {
+			if err != nil {
+				s.logf("%s: %v", msg.Method, err)
+			}
+			continue
+		}
+		if err := s.conn.reply(msg.ID, result, err); err != nil {
+			return fmt.Errorf("write response: %w", err)
+		}
+	}
+}
+
+// dispatch handles one message. Unknown notifications are ignored.
+func (s *Server) dispatch(msg *message) (interface{}, error) {
+	switch msg.Method {
+	case "initialize":
+		return map[string]interface{}{
+			"capabilities": map[string]interface{}{
+				"textDocumentSync": map[string]interface{}{
+					"openClose": true,
+					"change":    1, // full text
+					"save":      map[string]bool{"includeText": true},
+				},
+				"completionProvider": map[string]interface{}{
+					"triggerCharacters": []string{"[", "#", ".", ",", " "},
+				},
+				"hoverProvider":      true,
+				"definitionProvider": true,
+			},
+			"serverInfo": map[string]string{"name": "pearls"},
+		}, nil
+	case "initialized", "shutdown":
+		return nil, nil
+
+	case "textDocument/didOpen":
+		var params DidOpenTextDocumentParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		s.open(params.TextDocument.URI, params.TextDocument.Text)
+		return nil, s.publish(params.TextDocument.URI)
+	case "textDocument/didChange":
+		var params DidChangeTextDocumentParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		if n := len(params.ContentChanges); n > 0 {
+			s.open(params.TextDocument.URI, params.ContentChanges[n-1].Text)
+		}
+		return nil, s.publish(params.TextDocument.URI)
+	case "textDocument/didSave":
+		var params DidSaveTextDocumentParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		if params.Text != nil {
+			s.open(params.TextDocument.URI, *params.Text)
+		}
+		// Saving may fix or break links in other open files
+		for uri := range s.docs {
+			if err := s.publish(uri); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case "textDocument/didClose":
+		var params DidCloseTextDocumentParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		delete(s.docs, params.TextDocument.URI)
+		return nil, s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{
+			URI:         params.TextDocument.URI,
+			Diagnostics: []Diagnostic{},
+		})
+
+	case "textDocument/completion":
+		var params TextDocumentPositionParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.completion(params)
+	case "textDocument/hover":
+		var params TextDocumentPositionParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.hover(params)
+	case "textDocument/definition":
+		var params TextDocumentPositionParams
+		if err := decodeParams(msg, &params); err != nil {
+			return nil, err
+		}
+		return s.definition(params)
+	}
+
+	if msg.ID == nil {
+		return nil, nil
+	}
+	return nil, &rpcError{Code: codeMethodNotFound, Message: "method not found: " + msg.Method}
+}
+
+func decodeParams(msg *message, v interface{}) error {
+	if err := json.Unmarshal(msg.Params, v); err != nil {
+		return &rpcError{Code: codeInvalidParams, Message: fmt.Sprintf("%s: %v", msg.Method, err)}
+	}
+	return nil
+}
+
+// open records the text of a file the client is editing.
+func (s *Server) open(uri, text string) {
+	s.docs[uri] = newDocument(uri, uriToPath(uri), text)
+}
+
+// publish sends the diagnostics of an open file.
+func (s *Server) publish(uri string) error {
+	doc, ok := s.docs[uri]
+	if !ok {
+		return nil
+	}
+	diags, err := s.diagnostics(doc)
+	if err != nil {
+		return err
+	}
+	if diags == nil {
+		diags = []Diagnostic{}
+	}
+	return s.conn.notify("textDocument/publishDiagnostics", PublishDiagnosticsParams{URI: uri, Diagnostics: diags})
+}
+
+// document returns the open file with the given URI, or nil.
+func (s *Server) document(uri string) *document {
+	return s.docs[uri]
+}
+
+// documentFor returns a pearl's content file, from the editor if it is
+// open there and from disk otherwise. It returns nil if the pearl has no
+// content file.
+func (s *Server) documentFor(p *pearl.Pearl) (*document, error) {
+	if p.ContentPath == "" {
+		return nil, nil
+	}
+	path := s.store.Content().FullPath(p.ContentPath)
+	for _, doc := range s.docs {
+		if doc.path != "" && samePath(doc.path, path) {
+			return doc, nil
+		}
+	}
+	if !s.store.Content().Exists(p.ContentPath) {
+		return nil, nil
+	}
+	text, err := s.store.Content().Read(p.ContentPath)
+	if err != nil {
+		return nil, fmt.Errorf("read content for %s: %w", p.ID, err)
+	}
+	return newDocument(pathToURI(path), path, text), nil
+}
+
+// pearlFor returns the pearl whose content file is at path, or nil.
+func (s *Server) pearlFor(path string) (*pearl.Pearl, error) {
+	pearls, err := s.store.DB().All()
+	if err != nil {
+		return nil, fmt.Errorf("list pearls: %w", err)
+	}
+	for _, p := range pearls {
+		if p.ContentPath != "" && samePath(s.store.Content().FullPath(p.ContentPath), path) {
+			return p, nil
+		}
+	}
+	return nil, nil
+}
+
+// inContentDir reports whether path is under the content directory.
+func (s *Server) inContentDir(path string) bool {
+	rel, err := filepath.Rel(absPath(s.store.Content().BaseDir()), absPath(path))
+	return err == nil && filepath.IsLocal(rel)
+}
+
+func samePath(a, b string) bool {
+	return absPath(a) == absPath(b)
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+	if real, err := filepath.EvalSymlinks(path); err == nil {
+		path = real
+	}
+	return path
+}
+
+func (s *Server) logf(format string, args ...interface{}) {
+	if s.opts.Log != nil {
+		s.opts.Log.Printf(format, args...)
+	}
+}
+
+// document is a content file: frontmatter, then a markdown body.
+type document struct {
+	uri   string
+	path  string
+	text  string
+	lines []string
+	// body is the text after the frontmatter, which starts on the 0-based
+	// line bodyStart.
+	body      string
+	bodyStart int
+	// fmEnd is the line of the closing frontmatter fence; 0 without
+	// frontmatter.
+	fmEnd int
+}
+
+// newDocument splits text into frontmatter and body. The frontmatter is
+// found by its fences alone, so that it still is while half-typed YAML
+// fails to parse.
+func newDocument(uri, path, text string) *document {
+	d := &document{uri: uri, path: path, text: text, lines: splitLines(text), body: text}
+	if d.line(0) != "---" {
+		return d
+	}
+	for i := 1; i < len(d.lines); i++ {
+		if d.lines[i] == "---" {
+			d.fmEnd = i
+			break
+		}
+	}
+	if d.fmEnd == 0 {
+		return d
+	}
+
+	off := 0
+	for n := 0; n <= d.fmEnd; n++ {
+		i := strings.IndexByte(text[off:], '\n')
+		if i < 0 {
+			off = len(text)
+			break
+		}
+		off += i + 1
+	}
+	d.bodyStart = d.fmEnd + 1
+	// A blank line after the fence is not part of the body
+	if strings.HasPrefix(text[off:], "\n") {
+		off++
+		d.bodyStart++
+	}
+	d.body = text[off:]
+	return d
+}
+
+// line returns the 0-based line n, or "" past the end.
+func (d *document) line(n int) string {
+	if n < 0 || n >= len(d.lines) {
+		return ""
+	}
+	return d.lines[n]
+}
+
+// inFrontmatter reports whether line n is inside the frontmatter block,
+// between its fences.
+func (d *document) inFrontmatter(n int) bool {
+	return n > 0 && n < d.fmEnd
+}
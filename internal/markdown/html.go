@@ -0,0 +1,288 @@
+package markdown
+
+import (
+	"fmt"
+	"html"
+	"regexp"
+	"strings"
+)
+
+// HTMLOptions controls HTML rendering.
+type HTMLOptions struct {
+	// WikiLink returns the URL for a wiki-link, or "" if its target does
+	// not exist. Nil renders wiki-links as plain text.
+	WikiLink func(l Link) string
+}
+
+// HTML renders content as HTML. Raw HTML in content is escaped rather than
+// passed through, and link URLs other than http, https, mailto, and
+// relative ones are dropped, so untrusted content is safe to publish.
+// Headings get their anchor as an id; mermaid code blocks are left for
+// Mermaid to draw.
+func HTML(content string, opts HTMLOptions) string {
+	r := &htmlRenderer{opts: opts}
+	var b strings.Builder
+	for _, blk := range Parse(content, 1).Blocks {
+		r.block(&b, blk)
+	}
+	return b.String()
+}
+
+type htmlRenderer struct {
+	opts HTMLOptions
+}
+
+func (r *htmlRenderer) block(b *strings.Builder, blk *Block) {
+	switch blk.Kind {
+	case KindHeading:
+		fmt.Fprintf(b, "<h%d id=\"%s\">%s</h%d>\n", blk.Level, Slug(blk.Text), r.inline(blk.Text), blk.Level)
+	case KindParagraph:
+		fmt.Fprintf(b, "<p>%s</p>\n", r.inline(blk.Text))
+	case KindList:
+		r.list(b, blk.Text)
+	case KindTable:
+		r.table(b, blk.Text)
+	case KindQuote:
+		lines := strings.Split(blk.Text, "\n")
+		for i, line := range lines {
+			line = strings.TrimPrefix(strings.TrimSpace(line), ">")
+			lines[i] = strings.TrimPrefix(line, " ")
+		}
+		b.WriteString("<blockquote>\n")
+		b.WriteString(HTML(strings.Join(lines, "\n"), r.opts))
+		b.WriteString("</blockquote>\n")
+	case KindCode:
+		if blk.Info == "mermaid" {
+			fmt.Fprintf(b, "<pre class=\"mermaid\">%s</pre>\n", html.EscapeString(blk.Text))
+			return
+		}
+		class := ""
+		if lang := strings.Fields(blk.Info); len(lang) > 0 {
+			class = fmt.Sprintf(" class=\"language-%s\"", html.EscapeString(lang[0]))
+		}
+		fmt.Fprintf(b, "<pre><code%s>%s</code></pre>\n", class, html.EscapeString(blk.Text))
+	case KindRule:
+		b.WriteString("<hr>\n")
+	}
+}
+
+// listItem is an item of a list block, with its continuation lines.
+type listItem struct {
+	indent  int
+	ordered bool
+	text    string
+}
+
+// list renders a list block, nesting items by indentation.
+func (r *htmlRenderer) list(b *strings.Builder, text string) {
+	var items []*listItem
+	for _, line := range strings.Split(text, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if !isListItem(trimmed) {
+			if len(items) > 0 {
+				items[len(items)-1].text += "\n" + trimmed
+			}
+			continue
+		}
+		expanded := strings.ReplaceAll(line, "\t", "    ")
+		indent := len(expanded) - len(strings.TrimLeft(expanded, " "))
+		marker := strings.IndexByte(trimmed, ' ')
+		items = append(items, &listItem{
+			indent:  indent,
+			ordered: trimmed[0] >= '0' && trimmed[0] <= '9',
+			text:    strings.TrimSpace(trimmed[marker:]),
+		})
+	}
+
+	type level struct {
+		indent int
+		tag    string
+	}
+	var stack []level
+	for _, it := range items {
+		if len(stack) == 0 || it.indent > stack[len(stack)-1].indent {
+			tag := "ul"
+			if it.ordered {
+				tag = "ol"
+			}
+			if len(stack) > 0 {
+				b.WriteString("\n")
+			}
+			fmt.Fprintf(b, "<%s>\n", tag)
+			stack = append(stack, level{it.indent, tag})
+		} else {
+			for len(stack) > 1 && it.indent < stack[len(stack)-1].indent {
+				fmt.Fprintf(b, "</li>\n</%s>\n", stack[len(stack)-1].tag)
+				stack = stack[:len(stack)-1]
+			}
+			b.WriteString("</li>\n")
+		}
+		fmt.Fprintf(b, "<li>%s", r.inline(it.text))
+	}
+	for len(stack) > 0 {
+		fmt.Fprintf(b, "</li>\n</%s>\n", stack[len(stack)-1].tag)
+		stack = stack[:len(stack)-1]
+	}
+}
+
+var tableRule = regexp.MustCompile(`^:?-+:?$`)
+
+// table renders a table block; a rule under the first row makes it the
+// header.
+func (r *htmlRenderer) table(b *strings.Builder, text string) {
+	var rows [][]string
+	for _, line := range strings.Split(text, "\n") {
+		rows = append(rows, TableCells(line))
+	}
+
+	header := len(rows) > 1
+	if header {
+		for _, c := range rows[1] {
+			if !tableRule.MatchString(c) {
+				header = false
+				break
+			}
+		}
+	}
+
+	b.WriteString("<table>\n")
+	if header {
+		b.WriteString("<thead>\n")
+		r.tableRow(b, rows[0], "th")
+		b.WriteString("</thead>\n")
+		rows = rows[2:]
+	}
+	b.WriteString("<tbody>\n")
+	for _, row := range rows {
+		r.tableRow(b, row, "td")
+	}
+	b.WriteString("</tbody>\n</table>\n")
+}
+
+func (r *htmlRenderer) tableRow(b *strings.Builder, cells []string, tag string) {
+	b.WriteString("<tr>")
+	for _, c := range cells {
+		fmt.Fprintf(b, "<%s>%s</%s>", tag, r.inline(c), tag)
+	}
+	b.WriteString("</tr>\n")
+}
+
+// TableCells splits a table row into its trimmed cells, on unescaped
+// pipes.
+func TableCells(line string) []string {
+	line = strings.TrimSpace(line)
+	line = strings.TrimSuffix(strings.TrimPrefix(line, "|"), "|")
+	var cells []string
+	var cell strings.Builder
+	for i := 0; i < len(line); i++ {
+		switch {
+		case line[i] == '\\' && i+1 < len(line) && line[i+1] == '|':
+			cell.WriteByte('|')
+			i++
+		case line[i] == '|':
+			cells = append(cells, strings.TrimSpace(cell.String()))
+			cell.Reset()
+		default:
+			cell.WriteByte(line[i])
+		}
+	}
+	return append(cells, strings.TrimSpace(cell.String()))
+}
+
+// inline renders inline markdown: code spans, then everything else.
+func (r *htmlRenderer) inline(s string) string {
+	var b strings.Builder
+	for s != "" {
+		i := strings.IndexByte(s, '`')
+		if i < 0 {
+			b.WriteString(r.text(s))
+			break
+		}
+		n := i
+		for n < len(s) && s[n] == '`' {
+			n++
+		}
+		ticks := s[i:n]
+		closing := strings.Index(s[n:], ticks)
+		if closing < 0 {
+			b.WriteString(r.text(s[:n]))
+			s = s[n:]
+			continue
+		}
+		b.WriteString(r.text(s[:i]))
+		fmt.Fprintf(&b, "<code>%s</code>", html.EscapeString(strings.TrimSpace(s[n:n+closing])))
+		s = s[n+closing+len(ticks):]
+	}
+	return b.String()
+}
+
+var (
+	imagePattern    = regexp.MustCompile(`!\[([^\]]*)\]\(([^)\s]+)\)`)
+	mdLinkPattern   = regexp.MustCompile(`\[([^\]]+)\]\(([^)\s]+)\)`)
+	autoLinkPattern = regexp.MustCompile(`&lt;((?:https?|mailto):[^\s&]+)&gt;`)
+	strongPattern   = regexp.MustCompile(`\*\*([^*]+)\*\*|__([^_]+)__`)
+	emPattern       = regexp.MustCompile(`\*([^*\s][^*]*)\*|\b_([^_]+)_\b`)
+	strikePattern   = regexp.MustCompile(`~~([^~]+)~~`)
+)
+
+// text renders inline markdown outside code spans. Generated tags are held
+// as placeholders until the end so later patterns cannot match inside
+// them.
+func (r *htmlRenderer) text(s string) string {
+	s = html.EscapeString(s)
+	var held []string
+	hold := func(tag string) string {
+		held = append(held, tag)
+		return fmt.Sprintf("\x00%d\x00", len(held)-1)
+	}
+
+	s = linkPattern.ReplaceAllStringFunc(s, func(m string) string {
+		l := linkAt(m, linkPattern.FindStringSubmatchIndex(m), 0)
+		text := l.Label
+		if text == "" {
+			text = strings.TrimPrefix(l.String(), "!")
+			text = strings.TrimSuffix(strings.TrimPrefix(text, "[["), "]]")
+		}
+		url := ""
+		if r.opts.WikiLink != nil {
+			url = r.opts.WikiLink(Link{Target: html.UnescapeString(l.Target), Section: html.UnescapeString(l.Section), Embed: l.Embed})
+		}
+		if url == "" {
+			return hold(`<span class="missing-link">`) + text + hold("</span>")
+		}
+		return hold(fmt.Sprintf(`<a class="wikilink" href="%s">`, html.EscapeString(url))) + text + hold("</a>")
+	})
+	s = imagePattern.ReplaceAllStringFunc(s, func(m string) string {
+		sub := imagePattern.FindStringSubmatch(m)
+		return hold(fmt.Sprintf(`<img src="%s" alt="%s">`, safeURL(sub[2]), sub[1]))
+	})
+	s = mdLinkPattern.ReplaceAllStringFunc(s, func(m string) string {
+		sub := mdLinkPattern.FindStringSubmatch(m)
+		return hold(fmt.Sprintf(`<a href="%s">`, safeURL(sub[2]))) + sub[1] + hold("</a>")
+	})
+	s = autoLinkPattern.ReplaceAllStringFunc(s, func(m string) string {
+		url := autoLinkPattern.FindStringSubmatch(m)[1]
+		return hold(fmt.Sprintf(`<a href="%s">`, url)) + url + hold("</a>")
+	})
+	s = strongPattern.ReplaceAllString(s, "<strong>$1$2</strong>")
+	s = emPattern.ReplaceAllString(s, "<em>$1$2</em>")
+	s = strikePattern.ReplaceAllString(s, "<del>$1</del>")
+
+	for i, tag := range held {
+		s = strings.Replace(s, fmt.Sprintf("\x00%d\x00", i), tag, 1)
+	}
+	return s
+}
+
+// safeURL returns an escaped URL, or "#" if its scheme is not allowed.
+func safeURL(escaped string) string {
+	u := html.UnescapeString(escaped)
+	if i := strings.IndexAny(u, ":/?#"); i >= 0 && u[i] == ':' {
+		switch strings.ToLower(u[:i]) {
+		case "http", "https", "mailto":
+		default:
+			return "#"
+		}
+	}
+	return html.EscapeString(u)
+}
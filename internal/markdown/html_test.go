@@ -0,0 +1,42 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestHTML(t *testing.T) {
+	content := "# Users & Accounts\n\n" +
+		"Holds **all** users, _see_ [docs](https://example.com/a?b=1&c=2) and `a <b>`.\n\n" +
+		"- one\n- two [[db.orders]]\n  - nested *em*\n- three [[db.gone|gone]]\n\n" +
+		"1. first\n2. second\n\n" +
+		"| Column | Type |\n|--------|:----:|\n| id | `int` |\n| a \\| b | text |\n\n" +
+		"> quoted **text**\n\n" +
+		"```sql\nSELECT * FROM users WHERE a < 1;\n```\n\n" +
+		"```mermaid\ngraph LR\n  a --> b\n```\n\n" +
+		"<script>alert(1)</script> [bad](javascript:void) snake_case_name\n\n---\n"
+
+	got := HTML(content, HTMLOptions{WikiLink: func(l Link) string {
+		if l.Target == "db.orders" {
+			return "db.orders.html"
+		}
+		return ""
+	}})
+
+	for _, want := range []string{
+		`<h1 id="users--accounts">Users &amp; Accounts</h1>`,
+		`<p>Holds <strong>all</strong> users, <em>see</em> <a href="https://example.com/a?b=1&amp;c=2">docs</a> and <code>a &lt;b&gt;</code>.</p>`,
+		"<ul>\n<li>one</li>\n<li>two <a class=\"wikilink\" href=\"db.orders.html\">db.orders</a>\n<ul>\n<li>nested <em>em</em></li>\n</ul>\n</li>\n<li>three <span class=\"missing-link\">gone</span></li>\n</ul>\n",
+		"<ol>\n<li>first</li>\n<li>second</li>\n</ol>\n",
+		"<thead>\n<tr><th>Column</th><th>Type</th></tr>\n</thead>\n<tbody>\n<tr><td>id</td><td><code>int</code></td></tr>\n<tr><td>a | b</td><td>text</td></tr>\n</tbody>",
+		"<blockquote>\n<p>quoted <strong>text</strong></p>\n</blockquote>",
+		`<pre><code class="language-sql">SELECT * FROM users WHERE a &lt; 1;</code></pre>`,
+		"<pre class=\"mermaid\">graph LR\n  a --&gt; b</pre>",
+		`<p>&lt;script&gt;alert(1)&lt;/script&gt; <a href="#">bad</a> snake_case_name</p>`,
+		"<hr>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("HTML missing:\n%s\ngot:\n%s", want, got)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package markdown
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Link is a wiki-link to another pearl: [[id]], [[id#section]], or
+// [[id|label]]. An embed, ![[id#section]], is replaced by the target's
+// content when rendered.
+type Link struct {
+	Target  string `json:"target"`
+	Section string `json:"section,omitempty"`
+	Label   string `json:"label,omitempty"`
+	Embed   bool   `json:"embed,omitempty"`
+	// Line is the 1-based line the link is on.
+	Line int `json:"line"`
+}
+
+// Via names how the link connects pearls: "embed" or "link".
+func (l Link) Via() string {
+	if l.Embed {
+		return "embed"
+	}
+	return "link"
+}
+
+// String returns the link as written, without any label.
+func (l Link) String() string {
+	s := "[[" + l.Target
+	if l.Section != "" {
+		s += "#" + l.Section
+	}
+	s += "]]"
+	if l.Embed {
+		s = "!" + s
+	}
+	return s
+}
+
+var linkPattern = regexp.MustCompile(`(!?)\[\[([^\[\]|#]+)(?:#([^\[\]|]*))?(?:\|([^\[\]]*))?\]\]`)
+
+// Links returns the wiki-links in content, in order. Links in code blocks
+// and inline code are ignored.
+func Links(content string) []Link {
+	var links []Link
+	for _, s := range LinkSpans(content) {
+		links = append(links, s.Link)
+	}
+	return links
+}
+
+// LinkSpan is a wiki-link with where it is written on its line.
+type LinkSpan struct {
+	Link
+	// Start and End are byte offsets of the link, brackets included, in
+	// its line.
+	Start, End int
+}
+
+// LinkSpans returns the wiki-links in content with their positions, as
+// Links finds them.
+func LinkSpans(content string) []LinkSpan {
+	var spans []LinkSpan
+	proseLines(content, func(i int, line string) string {
+		for _, m := range linkPattern.FindAllStringSubmatchIndex(maskInlineCode(line), -1) {
+			spans = append(spans, LinkSpan{Link: linkAt(line, m, i+1), Start: m[0], End: m[1]})
+		}
+		return line
+	})
+	return spans
+}
+
+// InCode reports whether the 0-based line n of content is in a fenced code
+// block, fences included.
+func InCode(content string, n int) bool {
+	prose := false
+	proseLines(content, func(i int, line string) string {
+		if i == n {
+			prose = true
+		}
+		return line
+	})
+	return !prose
+}
+
+// linkAt builds the link for a match of linkPattern in line.
+func linkAt(line string, m []int, lineNo int) Link {
+	group := func(n int) string {
+		if m[2*n] < 0 {
+			return ""
+		}
+		return strings.TrimSpace(line[m[2*n]:m[2*n+1]])
+	}
+	return Link{
+		Target:  group(2),
+		Section: group(3),
+		Label:   group(4),
+		Embed:   group(1) == "!",
+		Line:    lineNo,
+	}
+}
+
+// Resolver returns the content of the pearl with the given ID, and
+// whether it exists.
+type Resolver func(id string) (content string, ok bool, err error)
+
+// Transclude replaces each embed in content, the pearl id's, with the
+// content of its target, or just the named section. Embedded content is
+// expanded in turn, up to maxDepth levels. Embeds that cannot be expanded,
+// because the target or section is missing, the embed would repeat
+// itself, or the depth limit is reached, are left as written and described
+// in the returned warnings.
+func Transclude(id, content string, resolve Resolver, maxDepth int) (string, []string) {
+	t := &transcluder{resolve: resolve, maxDepth: maxDepth}
+	return t.expand(content, []string{id}), t.warnings
+}
+
+type transcluder struct {
+	resolve  Resolver
+	maxDepth int
+	warnings []string
+}
+
+func (t *transcluder) expand(content string, stack []string) string {
+	return proseLines(content, func(i int, line string) string {
+		matches := linkPattern.FindAllStringSubmatchIndex(maskInlineCode(line), -1)
+		// Replace from the end so earlier offsets stay valid
+		for j := len(matches) - 1; j >= 0; j-- {
+			m := matches[j]
+			l := linkAt(line, m, i+1)
+			if !l.Embed {
+				continue
+			}
+			if text, ok := t.embed(l, stack); ok {
+				line = line[:m[0]] + text + line[m[1]:]
+			}
+		}
+		return line
+	})
+}
+
+// embed returns the expanded content for an embed, or false if it cannot
+// be expanded.
+func (t *transcluder) embed(l Link, stack []string) (string, bool) {
+	key := l.Target
+	if l.Section != "" {
+		key += "#" + Slug(l.Section)
+	}
+	for _, k := range stack {
+		// A whole pearl on the stack includes all of its sections, and a
+		// section includes itself
+		target, section, _ := strings.Cut(k, "#")
+		if target == l.Target && (section == "" || l.Section == "" || k == key) {
+			t.warnings = append(t.warnings, fmt.Sprintf("%s: cycle %s -> %s", l, strings.Join(stack, " -> "), key))
+			return "", false
+		}
+	}
+	if len(stack) > t.maxDepth {
+		t.warnings = append(t.warnings, fmt.Sprintf("%s: nested more than %d deep", l, t.maxDepth))
+		return "", false
+	}
+
+	content, ok, err := t.resolve(l.Target)
+	if err != nil {
+		t.warnings = append(t.warnings, fmt.Sprintf("%s: %v", l, err))
+		return "", false
+	}
+	if !ok {
+		t.warnings = append(t.warnings, fmt.Sprintf("%s: pearl not found", l))
+		return "", false
+	}
+	if l.Section != "" {
+		content, _ = Extract(content, []string{l.Section})
+		if content == "" {
+			t.warnings = append(t.warnings, fmt.Sprintf("%s: no such section", l))
+			return "", false
+		}
+	}
+
+	stack = append(stack[:len(stack):len(stack)], key)
+	return strings.TrimRight(t.expand(content, stack), "\n"), true
+}
+
+// proseLines calls fn with each line outside fenced code blocks, and its
+// 0-based index, and returns content with each line replaced by fn's
+// result.
+func proseLines(content string, fn func(i int, line string) string) string {
+	lines := strings.Split(content, "\n")
+	fence := ""
+	for i, line := range lines {
+		t := strings.TrimSpace(line)
+		switch {
+		case fence != "":
+			if strings.HasPrefix(t, fence) && strings.Trim(t, fence[:1]) == "" {
+				fence = ""
+			}
+		case isFence(t):
+			fence = t[:3]
+		default:
+			lines[i] = fn(i, line)
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// maskInlineCode returns line with each inline code span replaced by
+// spaces, so offsets into it match line.
+func maskInlineCode(line string) string {
+	if !strings.Contains(line, "`") {
+		return line
+	}
+	b := []byte(line)
+	for i := 0; i < len(b); {
+		if b[i] != '`' {
+			i++
+			continue
+		}
+		n := 0
+		for i+n < len(b) && b[i+n] == '`' {
+			n++
+		}
+		closing := strings.Index(string(b[i+n:]), strings.Repeat("`", n))
+		if closing < 0 {
+			i += n
+			continue
+		}
+		end := i + n + closing + n
+		for k := i; k < end; k++ {
+			b[k] = ' '
+		}
+		i = end
+	}
+	return string(b)
+}
@@ -0,0 +1,82 @@
+package markdown
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLinks(t *testing.T) {
+	content := "# Orders\n\nBelongs to [[db.users]] and [[db.accounts|an account]].\n\n![[shared.auth#Token Refresh]]\n\n" +
+		"Not `[[code.span]]` here.\n\n```\n[[in.code]]\n```\n\nSee [[db.users#columns]].\n"
+
+	want := []Link{
+		{Target: "db.users", Line: 3},
+		{Target: "db.accounts", Label: "an account", Line: 3},
+		{Target: "shared.auth", Section: "Token Refresh", Embed: true, Line: 5},
+		{Target: "db.users", Section: "columns", Line: 13},
+	}
+	if got := Links(content); !reflect.DeepEqual(got, want) {
+		t.Errorf("Links:\ngot  %+v\nwant %+v", got, want)
+	}
+	if s := want[2].String(); s != "![[shared.auth#Token Refresh]]" {
+		t.Errorf("String() = %q", s)
+	}
+}
+
+func TestTransclude(t *testing.T) {
+	pearls := map[string]string{
+		"shared.auth": "# Auth\n\n## Tokens\n\nSend a bearer token.\n\n## Errors\n\n401 on failure.\n",
+		"api.orders":  "# Orders\n\n![[shared.auth#tokens]]\n\nInline: ![[shared.note]].\n\n```\n![[shared.auth]]\n```\n",
+		"shared.note": "a note ![[shared.deep]]\n",
+		"shared.deep": "deep\n",
+		"loop.a":      "A then ![[loop.b]]\n",
+		"loop.b":      "B then ![[loop.a]]\n",
+		"bad.refs":    "![[missing.pearl]] ![[shared.auth#nope]]\n",
+		"db.orders":   "# Orders\n\n## Columns\n\n![[db.users#Columns]]\n\nAfter.\n",
+		"db.users":    "# Users\n\n## Columns\n\n![[db.orders#Columns]]\n",
+	}
+	resolve := func(id string) (string, bool, error) {
+		c, ok := pearls[id]
+		return c, ok, nil
+	}
+
+	got, warnings := Transclude("api.orders", pearls["api.orders"], resolve, 5)
+	want := "# Orders\n\n## Tokens\n\nSend a bearer token.\n\nInline: a note deep.\n\n```\n![[shared.auth]]\n```\n"
+	if got != want || len(warnings) != 0 {
+		t.Errorf("Transclude:\ngot:\n%q\nwant:\n%q\nwarnings: %v", got, want, warnings)
+	}
+
+	got, warnings = Transclude("loop.a", pearls["loop.a"], resolve, 5)
+	if got != "A then B then ![[loop.a]]\n" {
+		t.Errorf("cycle: got %q", got)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "cycle loop.a -> loop.b -> loop.a") {
+		t.Errorf("cycle warnings = %v", warnings)
+	}
+
+	// A cycle back into the root pearl is caught at the first repeat
+	got, warnings = Transclude("db.orders", pearls["db.orders"], resolve, 5)
+	if got != "# Orders\n\n## Columns\n\n## Columns\n\n![[db.orders#Columns]]\n\nAfter.\n" {
+		t.Errorf("section cycle: got %q", got)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "cycle db.orders -> db.users#columns -> db.orders#columns") {
+		t.Errorf("section cycle warnings = %v", warnings)
+	}
+
+	got, warnings = Transclude("bad.refs", pearls["bad.refs"], resolve, 5)
+	if got != pearls["bad.refs"] || len(warnings) != 2 {
+		t.Errorf("missing: got %q, warnings %v", got, warnings)
+	}
+
+	got, warnings = Transclude("api.orders", pearls["api.orders"], resolve, 1)
+	if !strings.Contains(got, "a note ![[shared.deep]]") || len(warnings) != 1 || !strings.Contains(warnings[0], "more than 1 deep") {
+		t.Errorf("depth limit: got %q, warnings %v", got, warnings)
+	}
+
+	failing := func(id string) (string, bool, error) { return "", false, fmt.Errorf("disk error") }
+	if _, warnings := Transclude("api.orders", pearls["api.orders"], failing, 5); len(warnings) != 2 || !strings.Contains(warnings[0], "disk error") {
+		t.Errorf("resolve errors: warnings %v", warnings)
+	}
+}
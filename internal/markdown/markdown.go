@@ -0,0 +1,289 @@
+// Package markdown parses pearl content into a block-level syntax tree:
+// headings, paragraphs, lists, tables, quotes, and code blocks, grouped
+// into sections by heading. It covers the CommonMark constructs pearl
+// content uses; inline formatting is left as text, for HTML to render.
+package markdown
+
+import (
+	"strings"
+)
+
+// Kind is the type of a block.
+type Kind string
+
+const (
+	KindHeading   Kind = "heading"
+	KindParagraph Kind = "paragraph"
+	KindList      Kind = "list"
+	KindTable     Kind = "table"
+	KindQuote     Kind = "quote"
+	KindCode      Kind = "code"
+	KindRule      Kind = "rule"
+)
+
+// Block is a top-level markdown block.
+type Block struct {
+	Kind Kind
+	// Line is the 1-based line the block starts on.
+	Line int
+	// Level is the heading level (1-6); zero for other blocks.
+	Level int
+	// Text is the heading text, or the block's raw lines joined by newlines.
+	// For code blocks it excludes the fences.
+	Text string
+	// Info is a fenced code block's info string, e.g. "sql".
+	Info string
+}
+
+// Section is a heading and the blocks under it, up to the next heading of
+// the same or a higher level. Subsections nest in Children.
+type Section struct {
+	Heading  *Block
+	Blocks   []*Block
+	Children []*Section
+}
+
+// Title returns the section's heading text.
+func (s *Section) Title() string {
+	return s.Heading.Text
+}
+
+// Body returns the section's own blocks (excluding subsections) as text.
+func (s *Section) Body() string {
+	parts := make([]string, len(s.Blocks))
+	for i, b := range s.Blocks {
+		parts[i] = b.Source()
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// Empty reports whether the section has no content of its own or in its
+// subsections.
+func (s *Section) Empty() bool {
+	if len(s.Blocks) > 0 {
+		return false
+	}
+	for _, c := range s.Children {
+		if !c.Empty() {
+			return false
+		}
+	}
+	return true
+}
+
+// Source returns the block's markdown, normalized: fences are restored for
+// code blocks and headings are rendered with #'s.
+func (b *Block) Source() string {
+	switch b.Kind {
+	case KindHeading:
+		return strings.Repeat("#", b.Level) + " " + b.Text
+	case KindCode:
+		return "```" + b.Info + "\n" + b.Text + "\n```"
+	}
+	return b.Text
+}
+
+// Document is parsed markdown.
+type Document struct {
+	Blocks []*Block
+	// Preamble holds blocks before the first heading.
+	Preamble []*Block
+	// Sections are the top-level sections; a document with one H1 has one.
+	Sections []*Section
+}
+
+// Headings returns the document's headings in order.
+func (d *Document) Headings() []*Block {
+	var hs []*Block
+	for _, b := range d.Blocks {
+		if b.Kind == KindHeading {
+			hs = append(hs, b)
+		}
+	}
+	return hs
+}
+
+// Walk calls fn for every section, depth first in document order.
+func (d *Document) Walk(fn func(s *Section)) {
+	var walk func([]*Section)
+	walk = func(ss []*Section) {
+		for _, s := range ss {
+			fn(s)
+			walk(s.Children)
+		}
+	}
+	walk(d.Sections)
+}
+
+// Find returns the first section whose title matches (case-insensitively),
+// or nil.
+func (d *Document) Find(title string) *Section {
+	var found *Section
+	d.Walk(func(s *Section) {
+		if found == nil && strings.EqualFold(s.Title(), title) {
+			found = s
+		}
+	})
+	return found
+}
+
+// Parse parses markdown. firstLine is the line number of the first line of
+// content, for content that follows a frontmatter block.
+func Parse(content string, firstLine int) *Document {
+	p := &parser{lines: strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n"), offset: firstLine}
+	doc := &Document{Blocks: p.blocks()}
+	doc.Preamble, doc.Sections = group(doc.Blocks)
+	return doc
+}
+
+type parser struct {
+	lines  []string
+	offset int
+	i      int
+}
+
+func (p *parser) blocks() []*Block {
+	var blocks []*Block
+	for p.i < len(p.lines) {
+		line := p.lines[p.i]
+		trimmed := strings.TrimSpace(line)
+		start := p.i + p.offset
+
+		switch {
+		case trimmed == "":
+			p.i++
+
+		case isFence(trimmed):
+			blocks = append(blocks, p.code(start))
+
+		case headingLevel(trimmed) > 0:
+			level := headingLevel(trimmed)
+			text := strings.TrimSpace(strings.TrimRight(strings.TrimSpace(trimmed[level:]), "#"))
+			blocks = append(blocks, &Block{Kind: KindHeading, Line: start, Level: level, Text: text})
+			p.i++
+
+		case isRule(trimmed):
+			blocks = append(blocks, &Block{Kind: KindRule, Line: start, Text: trimmed})
+			p.i++
+
+		case strings.HasPrefix(trimmed, ">"):
+			blocks = append(blocks, p.run(start, KindQuote, func(t string) bool { return strings.HasPrefix(t, ">") }))
+
+		case strings.HasPrefix(trimmed, "|"):
+			blocks = append(blocks, p.run(start, KindTable, func(t string) bool { return strings.HasPrefix(t, "|") }))
+
+		case isListItem(trimmed):
+			// Items and their indented continuations
+			blocks = append(blocks, p.run(start, KindList, func(t string) bool { return true }))
+
+		default:
+			blocks = append(blocks, p.run(start, KindParagraph, func(t string) bool {
+				return !isFence(t) && headingLevel(t) == 0 && !isListItem(t) && !strings.HasPrefix(t, ">") && !strings.HasPrefix(t, "|")
+			}))
+		}
+	}
+	return blocks
+}
+
+// run consumes consecutive non-blank lines accepted by more.
+func (p *parser) run(start int, kind Kind, more func(trimmed string) bool) *Block {
+	var lines []string
+	for p.i < len(p.lines) {
+		t := strings.TrimSpace(p.lines[p.i])
+		if t == "" || (len(lines) > 0 && (!more(t) || isFence(t) || headingLevel(t) > 0)) {
+			break
+		}
+		lines = append(lines, strings.TrimRight(p.lines[p.i], " \t"))
+		p.i++
+	}
+	return &Block{Kind: kind, Line: start, Text: strings.Join(lines, "\n")}
+}
+
+// code consumes a fenced code block, to its closing fence or the end of
+// the document.
+func (p *parser) code(start int) *Block {
+	open := strings.TrimSpace(p.lines[p.i])
+	fence := open[:3]
+	info := strings.TrimSpace(strings.TrimLeft(open, fence[:1]))
+	p.i++
+
+	var lines []string
+	for p.i < len(p.lines) {
+		t := strings.TrimSpace(p.lines[p.i])
+		p.i++
+		if strings.HasPrefix(t, fence) && strings.Trim(t, fence[:1]) == "" {
+			break
+		}
+		lines = append(lines, p.lines[p.i-1])
+	}
+	return &Block{Kind: KindCode, Line: start, Text: strings.Join(lines, "\n"), Info: info}
+}
+
+// group builds the section tree from a flat block list.
+func group(blocks []*Block) ([]*Block, []*Section) {
+	var preamble []*Block
+	var roots []*Section
+	var stack []*Section
+
+	for _, b := range blocks {
+		if b.Kind != KindHeading {
+			if len(stack) == 0 {
+				preamble = append(preamble, b)
+			} else {
+				top := stack[len(stack)-1]
+				top.Blocks = append(top.Blocks, b)
+			}
+			continue
+		}
+
+		s := &Section{Heading: b}
+		for len(stack) > 0 && stack[len(stack)-1].Heading.Level >= b.Level {
+			stack = stack[:len(stack)-1]
+		}
+		if len(stack) == 0 {
+			roots = append(roots, s)
+		} else {
+			parent := stack[len(stack)-1]
+			parent.Children = append(parent.Children, s)
+		}
+		stack = append(stack, s)
+	}
+	return preamble, roots
+}
+
+func headingLevel(t string) int {
+	n := 0
+	for n < len(t) && t[n] == '#' {
+		n++
+	}
+	if n == 0 || n > 6 || (n < len(t) && t[n] != ' ' && t[n] != '\t') {
+		return 0
+	}
+	return n
+}
+
+func isFence(t string) bool {
+	return strings.HasPrefix(t, "```") || strings.HasPrefix(t, "~~~")
+}
+
+func isRule(t string) bool {
+	if len(t) < 3 {
+		return false
+	}
+	c := t[0]
+	if c != '-' && c != '*' && c != '_' {
+		return false
+	}
+	return strings.Trim(strings.ReplaceAll(t, " ", ""), string(c)) == ""
+}
+
+func isListItem(t string) bool {
+	if strings.HasPrefix(t, "- ") || strings.HasPrefix(t, "* ") || strings.HasPrefix(t, "+ ") {
+		return true
+	}
+	i := 0
+	for i < len(t) && t[i] >= '0' && t[i] <= '9' {
+		i++
+	}
+	return i > 0 && i < len(t)-1 && (t[i] == '.' || t[i] == ')') && t[i+1] == ' '
+}
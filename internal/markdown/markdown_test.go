@@ -0,0 +1,145 @@
+package markdown
+
+import (
+	"strings"
+	"testing"
+)
+
+const doc = `Intro paragraph
+spanning two lines.
+
+# Users
+
+User accounts.
+
+## Schema
+
+| Column | Type |
+|--------|------|
+| id     | int  |
+
+` + "```sql" + `
+# not a heading
+SELECT 1;
+` + "```" + `
+
+### Indexes
+
+- by email
+- by id
+
+## Notes
+
+> quoted
+---
+`
+
+func TestParse(t *testing.T) {
+	d := Parse(doc, 5)
+
+	kinds := []Kind{KindParagraph, KindHeading, KindParagraph, KindHeading, KindTable, KindCode, KindHeading, KindList, KindHeading, KindQuote, KindRule}
+	if len(d.Blocks) != len(kinds) {
+		t.Fatalf("got %d blocks, want %d", len(d.Blocks), len(kinds))
+	}
+	for i, k := range kinds {
+		if d.Blocks[i].Kind != k {
+			t.Errorf("block %d: kind = %s, want %s", i, d.Blocks[i].Kind, k)
+		}
+	}
+
+	if got := d.Blocks[0].Text; got != "Intro paragraph\nspanning two lines." {
+		t.Errorf("paragraph text = %q", got)
+	}
+	if b := d.Blocks[3]; b.Level != 2 || b.Text != "Schema" || b.Line != 12 {
+		t.Errorf("schema heading = %+v", b)
+	}
+	if b := d.Blocks[5]; b.Info != "sql" || b.Text != "# not a heading\nSELECT 1;" {
+		t.Errorf("code block = %+v", b)
+	}
+
+	if len(d.Preamble) != 1 || len(d.Sections) != 1 {
+		t.Fatalf("preamble = %d blocks, sections = %d", len(d.Preamble), len(d.Sections))
+	}
+	users := d.Sections[0]
+	if users.Title() != "Users" || len(users.Children) != 2 {
+		t.Fatalf("users section = %q with %d children", users.Title(), len(users.Children))
+	}
+	schema := users.Children[0]
+	if len(schema.Blocks) != 2 || len(schema.Children) != 1 || schema.Children[0].Title() != "Indexes" {
+		t.Errorf("schema section: %d blocks, %d children", len(schema.Blocks), len(schema.Children))
+	}
+
+	if d.Find("notes") == nil || d.Find("missing") != nil {
+		t.Error("Find should match titles case-insensitively")
+	}
+	if len(d.Headings()) != 4 {
+		t.Errorf("got %d headings, want 4", len(d.Headings()))
+	}
+}
+
+func TestSectionEmpty(t *testing.T) {
+	d := Parse("# A\n\n## B\n\n### C\n\n## D\n\ntext\n", 1)
+	b, dd := d.Find("B"), d.Find("D")
+	if !b.Empty() {
+		t.Error("B has only an empty subsection and should be empty")
+	}
+	if dd.Empty() || dd.Body() != "text" {
+		t.Errorf("D: empty = %v, body = %q", dd.Empty(), dd.Body())
+	}
+	if d.Find("A").Empty() {
+		t.Error("A contains D's text and should not be empty")
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := map[string]string{
+		"Access Patterns":    "access-patterns",
+		"Foreign Keys":       "foreign-keys",
+		"  Notes  ":          "notes",
+		"Auth (OAuth 2.0)":   "auth-oauth-20",
+		"snake_case & more!": "snake_case--more",
+	}
+	for in, want := range tests {
+		if got := Slug(in); got != want {
+			t.Errorf("Slug(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	got, missing := Extract(doc, []string{"indexes", "Notes", "missing"})
+	want := "### Indexes\n\n- by email\n- by id\n\n## Notes\n\n> quoted\n---\n"
+	if got != want {
+		t.Errorf("Extract:\ngot:\n%q\nwant:\n%q", got, want)
+	}
+	if len(missing) != 1 || missing[0] != "missing" {
+		t.Errorf("missing = %v", missing)
+	}
+
+	// A subsection of a selected section is not repeated
+	got, _ = Extract(doc, []string{"indexes", "schema"})
+	if !strings.HasPrefix(got, "## Schema\n") || strings.Count(got, "### Indexes") != 1 || strings.Contains(got, "## Notes") {
+		t.Errorf("Extract(schema) = %q", got)
+	}
+	if !strings.Contains(got, "# not a heading") {
+		t.Error("Extract should keep code blocks as written")
+	}
+
+	if got, _ := Extract(doc, nil); got != "" {
+		t.Errorf("Extract(nil) = %q", got)
+	}
+}
+
+func TestMentions(t *testing.T) {
+	d := Parse(doc, 1)
+	var anchors []string
+	for _, s := range d.Mentions("EMAIL") {
+		anchors = append(anchors, s.Anchor())
+	}
+	if strings.Join(anchors, ",") != "indexes" {
+		t.Errorf("Mentions(email) = %v", anchors)
+	}
+	if len(d.Mentions("users")) != 1 {
+		t.Error("Mentions should match heading text")
+	}
+}
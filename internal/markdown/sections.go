@@ -0,0 +1,103 @@
+package markdown
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Slug returns the anchor for a heading, as GitHub renders it: lowercase,
+// with spaces as hyphens and other punctuation dropped. "Access Patterns"
+// becomes "access-patterns".
+func Slug(title string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(strings.TrimSpace(title)) {
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_':
+			b.WriteRune(r)
+		case r == ' ':
+			b.WriteRune('-')
+		}
+	}
+	return b.String()
+}
+
+// Anchor returns the section's anchor.
+func (s *Section) Anchor() string {
+	return Slug(s.Title())
+}
+
+// FindAnchor returns the first section whose anchor matches the selector,
+// which may be an anchor ("access-patterns") or a title ("Access
+// Patterns"), or nil.
+func (d *Document) FindAnchor(selector string) *Section {
+	want := Slug(selector)
+	var found *Section
+	d.Walk(func(s *Section) {
+		if found == nil && s.Anchor() == want {
+			found = s
+		}
+	})
+	return found
+}
+
+// Mentions returns the sections whose heading or own blocks contain the
+// keyword, case-insensitively, in document order.
+func (d *Document) Mentions(keyword string) []*Section {
+	keyword = strings.ToLower(keyword)
+	var found []*Section
+	d.Walk(func(s *Section) {
+		if strings.Contains(strings.ToLower(s.Title()), keyword) || strings.Contains(strings.ToLower(s.Body()), keyword) {
+			found = append(found, s)
+		}
+	})
+	return found
+}
+
+// Extract returns the sections of content named by the selectors, each
+// with its heading and subsections, in document order and as written.
+// A selector inside another selected section adds nothing. Selectors that
+// match no section are returned as missing.
+func Extract(content string, selectors []string) (string, []string) {
+	d := Parse(content, 1)
+	lines := strings.Split(strings.ReplaceAll(content, "\r\n", "\n"), "\n")
+
+	type span struct{ start, end int }
+	var spans []span
+	var missing []string
+	for _, sel := range selectors {
+		s := d.FindAnchor(sel)
+		if s == nil {
+			missing = append(missing, sel)
+			continue
+		}
+		spans = append(spans, span{s.Heading.Line, d.sectionEnd(s, len(lines))})
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start < spans[j].start })
+
+	var parts []string
+	end := 0
+	for _, sp := range spans {
+		if sp.start < end {
+			continue // nested in, or the same as, an earlier section
+		}
+		end = sp.end
+		part := strings.TrimRight(strings.Join(lines[sp.start-1:sp.end-1], "\n"), "\n \t")
+		parts = append(parts, part)
+	}
+	if len(parts) == 0 {
+		return "", missing
+	}
+	return strings.Join(parts, "\n\n") + "\n", missing
+}
+
+// sectionEnd returns the line after the section's last line: the next
+// heading of the same or a higher level, or the end of the document.
+func (d *Document) sectionEnd(s *Section, lineCount int) int {
+	for _, h := range d.Headings() {
+		if h.Line > s.Heading.Line && h.Level <= s.Heading.Level {
+			return h.Line
+		}
+	}
+	return lineCount + 1
+}
@@ -0,0 +1,126 @@
+// Package owners resolves who is responsible for a pearl: owners set on the
+// pearl itself, inherited from its parent namespace, or derived from the
+// repository's CODEOWNERS file.
+package owners
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// CodeownersPaths are the locations GitHub and GitLab read CODEOWNERS
+// from, relative to the repository root, in lookup order.
+var CodeownersPaths = []string{"CODEOWNERS", ".github/CODEOWNERS", "docs/CODEOWNERS"}
+
+// Rule is one CODEOWNERS line: a path pattern and its owners. A rule with no
+// owners unsets ownership for the paths it matches.
+type Rule struct {
+	Pattern string
+	Owners  []string
+	Line    int
+
+	glob string // Pattern translated to doublestar syntax
+	dir  bool   // Pattern may name a directory, owning its subtree
+}
+
+// Codeowners is a parsed CODEOWNERS file.
+type Codeowners struct {
+	Path  string
+	Rules []Rule
+}
+
+// FindCodeowners loads the first CODEOWNERS file found under root. It
+// returns nil if the repository has none.
+func FindCodeowners(root string) (*Codeowners, error) {
+	for _, rel := range CodeownersPaths {
+		path := filepath.Join(root, rel)
+		f, err := os.Open(path)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("open %s: %w", rel, err)
+		}
+		defer f.Close()
+
+		co, err := ParseCodeowners(bufio.NewScanner(f))
+		if err != nil {
+			return nil, fmt.Errorf("parse %s: %w", rel, err)
+		}
+		co.Path = path
+		return co, nil
+	}
+	return nil, nil
+}
+
+// ParseCodeowners reads CODEOWNERS rules. Comments, blank lines, and
+// GitLab section headers ([Section]) are skipped.
+func ParseCodeowners(s *bufio.Scanner) (*Codeowners, error) {
+	co := &Codeowners{}
+	n := 0
+	for s.Scan() {
+		n++
+		line := strings.TrimSpace(s.Text())
+		if i := strings.Index(line, " #"); i >= 0 {
+			line = strings.TrimSpace(line[:i])
+		}
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "[") || strings.HasPrefix(line, "^[") {
+			continue
+		}
+
+		fields := strings.Fields(line)
+		glob := translatePattern(fields[0])
+		if !doublestar.ValidatePattern(glob) {
+			return nil, fmt.Errorf("line %d: invalid pattern %q", n, fields[0])
+		}
+		co.Rules = append(co.Rules, Rule{Pattern: fields[0], Owners: fields[1:], Line: n, glob: glob, dir: namesDir(fields[0])})
+	}
+	return co, s.Err()
+}
+
+// Match returns the last rule matching path, which is relative to the
+// repository root, or nil if no rule matches.
+func (co *Codeowners) Match(path string) *Rule {
+	path = strings.TrimPrefix(filepath.ToSlash(path), "/")
+	for i := len(co.Rules) - 1; i >= 0; i-- {
+		r := &co.Rules[i]
+		if ok, _ := doublestar.Match(r.glob, path); ok {
+			return r
+		}
+		// A pattern naming a directory owns everything beneath it
+		if r.dir {
+			if ok, _ := doublestar.Match(r.glob+"/**", path); ok {
+				return r
+			}
+		}
+	}
+	return nil
+}
+
+// namesDir reports whether a pattern can name a directory: it ends in a
+// slash or is a plain path without glob characters. docs/* matches the
+// entries of docs, not what lies beneath them.
+func namesDir(p string) bool {
+	return strings.HasSuffix(p, "/") || !strings.ContainsAny(p, "*?[")
+}
+
+// translatePattern converts gitignore-style CODEOWNERS syntax to a
+// doublestar glob. Patterns containing a slash other than a trailing one are
+// anchored at the root; others match at any depth.
+func translatePattern(p string) string {
+	anchored := strings.Contains(strings.TrimSuffix(p, "/"), "/")
+	p = strings.TrimPrefix(p, "/")
+	p = strings.TrimSuffix(p, "/")
+	if p == "*" || p == "" {
+		return "**"
+	}
+	if !anchored {
+		p = "**/" + p
+	}
+	return p
+}
@@ -0,0 +1,134 @@
+package owners
+
+import (
+	"os/exec"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+// Source says where a pearl's effective owners came from.
+type Source string
+
+const (
+	SourcePearl      Source = "pearl"      // set on the pearl itself
+	SourceInherited  Source = "inherited"  // set on an ancestor namespace pearl
+	SourceCodeowners Source = "codeowners" // derived from CODEOWNERS via globs
+)
+
+// Resolution is a pearl's effective owners. An unowned pearl has no owners
+// and no source.
+type Resolution struct {
+	Owners []string `json:"owners"`
+	Source Source   `json:"source,omitempty"`
+	// From is the ancestor pearl ID for inherited owners, or the matching
+	// CODEOWNERS patterns for derived ones.
+	From string `json:"from,omitempty"`
+}
+
+// Resolver computes effective owners. Precedence: owners on the pearl, then
+// the nearest ancestor namespace pearl with owners, then CODEOWNERS rules
+// for the files the pearl's globs cover.
+type Resolver struct {
+	// Lookup returns a pearl by ID, or nil if it does not exist.
+	Lookup func(id string) (*pearl.Pearl, error)
+	// Codeowners may be nil if the repository has no CODEOWNERS file.
+	Codeowners *Codeowners
+	// Files are the repository's files, relative to its root. Globs that
+	// match none of them are looked up by their literal prefix instead.
+	Files []string
+}
+
+// Resolve returns p's effective owners.
+func (r *Resolver) Resolve(p *pearl.Pearl) (*Resolution, error) {
+	if len(p.Owners) > 0 {
+		return &Resolution{Owners: p.Owners, Source: SourcePearl}, nil
+	}
+
+	for ns := p.Namespace; ns != ""; ns = pearl.ParentNamespace(ns) {
+		if ns == p.ID {
+			continue
+		}
+		parent, err := r.Lookup(ns)
+		if err != nil {
+			return nil, err
+		}
+		if parent != nil && len(parent.Owners) > 0 {
+			return &Resolution{Owners: parent.Owners, Source: SourceInherited, From: parent.ID}, nil
+		}
+	}
+
+	if r.Codeowners != nil && len(p.Globs) > 0 {
+		if owners, patterns := r.fromCodeowners(p.Globs); len(owners) > 0 {
+			return &Resolution{Owners: owners, Source: SourceCodeowners, From: strings.Join(patterns, ", ")}, nil
+		}
+	}
+
+	return &Resolution{Owners: []string{}}, nil
+}
+
+// fromCodeowners collects the owners of every path the globs cover, in
+// first-seen order, with the CODEOWNERS patterns that assigned them.
+func (r *Resolver) fromCodeowners(globs []string) ([]string, []string) {
+	var paths []string
+	for _, f := range r.Files {
+		if pearl.MatchPath(f, globs) {
+			paths = append(paths, f)
+		}
+	}
+	if len(paths) == 0 {
+		for _, g := range globs {
+			if _, negated := pearl.SplitNegation(g); negated {
+				continue
+			}
+			base, _ := doublestar.SplitPattern(g)
+			if base != "." {
+				paths = append(paths, base)
+			}
+		}
+	}
+
+	var owners, patterns []string
+	seenOwner, seenRule := make(map[string]bool), make(map[int]bool)
+	for _, path := range paths {
+		rule := r.Codeowners.Match(path)
+		if rule == nil || len(rule.Owners) == 0 {
+			continue
+		}
+		if !seenRule[rule.Line] {
+			seenRule[rule.Line] = true
+			patterns = append(patterns, rule.Pattern)
+		}
+		for _, o := range rule.Owners {
+			if !seenOwner[o] {
+				seenOwner[o] = true
+				owners = append(owners, o)
+			}
+		}
+	}
+	return owners, patterns
+}
+
+// Has reports whether owner is among the resolution's owners. The leading
+// "@" is optional.
+func (res *Resolution) Has(owner string) bool {
+	owner = strings.TrimPrefix(owner, "@")
+	for _, o := range res.Owners {
+		if strings.EqualFold(strings.TrimPrefix(o, "@"), owner) {
+			return true
+		}
+	}
+	return false
+}
+
+// RepoFiles lists the files git tracks under dir, relative to dir. It
+// returns nil outside a git repository.
+func RepoFiles(dir string) []string {
+	out, err := exec.Command("git", "-C", dir, "ls-files", "-z").Output()
+	if err != nil {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+}
@@ -0,0 +1,123 @@
+package owners
+
+import (
+	"bufio"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+const testCodeowners = `# Default owners
+*                 @org/everyone
+
+/src/payments/    @alice @org/payments
+docs/             @org/docs   # any docs directory
+*.sql             @org/data
+/src/payments/vendor/
+
+[Optional section]
+/scripts/         @bob
+`
+
+func parseTest(t *testing.T) *Codeowners {
+	t.Helper()
+	co, err := ParseCodeowners(bufio.NewScanner(strings.NewReader(testCodeowners)))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	return co
+}
+
+func TestCodeownersMatch(t *testing.T) {
+	co := parseTest(t)
+	if len(co.Rules) != 6 {
+		t.Fatalf("got %d rules, want 6", len(co.Rules))
+	}
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{"README.md", []string{"@org/everyone"}},
+		{"src/payments/checkout.go", []string{"@alice", "@org/payments"}},
+		{"src/payments", []string{"@alice", "@org/payments"}},
+		{"lib/src/payments/x.go", []string{"@org/everyone"}},
+		{"pkg/docs/guide.md", []string{"@org/docs"}},
+		{"src/payments/schema.sql", []string{"@org/data"}},
+		{"src/payments/vendor/lib.go", nil},
+		{"scripts/deploy.sh", []string{"@bob"}},
+	}
+	for _, tt := range tests {
+		r := co.Match(tt.path)
+		if r == nil {
+			t.Errorf("%s: no rule matched", tt.path)
+			continue
+		}
+		if len(r.Owners) != len(tt.want) || (len(tt.want) > 0 && !reflect.DeepEqual(r.Owners, tt.want)) {
+			t.Errorf("%s: owners = %v, want %v", tt.path, r.Owners, tt.want)
+		}
+	}
+
+	// Only a directory pattern owns its subtree; a glob matches one level
+	co, err := ParseCodeowners(bufio.NewScanner(strings.NewReader("docs/* @docs\n/build @build\n")))
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	for path, want := range map[string]string{"docs/a.md": "@docs", "docs/x/b.md": "", "build/out/app": "@build"} {
+		var got string
+		if r := co.Match(path); r != nil {
+			got = strings.Join(r.Owners, " ")
+		}
+		if got != want {
+			t.Errorf("%s: owners = %q, want %q", path, got, want)
+		}
+	}
+
+	if _, err := ParseCodeowners(bufio.NewScanner(strings.NewReader("src/[ @x\n"))); err == nil {
+		t.Error("expected error for invalid pattern")
+	}
+}
+
+func TestResolve(t *testing.T) {
+	catalog := map[string]*pearl.Pearl{
+		"db":          {ID: "db", Owners: []string{"@org/data"}},
+		"db.postgres": {ID: "db.postgres", Namespace: "db"},
+	}
+	r := &Resolver{
+		Lookup:     func(id string) (*pearl.Pearl, error) { return catalog[id], nil },
+		Codeowners: parseTest(t),
+		Files:      []string{"src/payments/checkout.go", "src/payments/vendor/lib.go", "src/other/x.go"},
+	}
+
+	tests := []struct {
+		name   string
+		p      *pearl.Pearl
+		owners []string
+		source Source
+		from   string
+	}{
+		{"own owners win", &pearl.Pearl{ID: "db.postgres.users", Namespace: "db.postgres", Owners: []string{"@alice"}}, []string{"@alice"}, SourcePearl, ""},
+		{"inherit nearest ancestor with owners", &pearl.Pearl{ID: "db.postgres.users", Namespace: "db.postgres"}, []string{"@org/data"}, SourceInherited, "db"},
+		{"codeowners via matching files", &pearl.Pearl{ID: "api.pay", Namespace: "api", Globs: []string{"src/payments/**"}}, []string{"@alice", "@org/payments"}, SourceCodeowners, "/src/payments/"},
+		{"codeowners via glob prefix", &pearl.Pearl{ID: "api.docs", Namespace: "api", Globs: []string{"site/docs/**/*.md"}}, []string{"@org/docs"}, SourceCodeowners, "docs/"},
+		{"unowned", &pearl.Pearl{ID: "api.vendor", Namespace: "api", Globs: []string{"src/payments/vendor/**"}}, []string{}, "", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := r.Resolve(tt.p)
+			if err != nil {
+				t.Fatalf("resolve: %v", err)
+			}
+			if !reflect.DeepEqual(res.Owners, tt.owners) || res.Source != tt.source || res.From != tt.from {
+				t.Errorf("got %+v, want owners %v source %q from %q", res, tt.owners, tt.source, tt.from)
+			}
+		})
+	}
+
+	res := &Resolution{Owners: []string{"@Org/Data"}}
+	if !res.Has("org/data") || !res.Has("@org/data") || res.Has("@alice") {
+		t.Error("Has should match case-insensitively with optional @")
+	}
+}
@@ -0,0 +1,82 @@
+package pearl
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// knownFields are the JSON keys that map to Pearl struct fields.
+var knownFields = func() map[string]bool {
+	known := make(map[string]bool)
+	t := reflect.TypeOf(Pearl{})
+	for i := 0; i < t.NumField(); i++ {
+		name, _, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		if name != "" && name != "-" {
+			known[name] = true
+		}
+	}
+	return known
+}()
+
+// pearlFields has Pearl's fields without its JSON methods.
+type pearlFields Pearl
+
+// MarshalJSON encodes the pearl, appending any unknown fields from Extra
+// after the known ones in sorted order.
+func (p Pearl) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(pearlFields(p))
+	if err != nil || len(p.Extra) == 0 {
+		return data, err
+	}
+
+	keys := make([]string, 0, len(p.Extra))
+	for k := range p.Extra {
+		if !knownFields[k] {
+			keys = append(keys, k)
+		}
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1]) // drop closing brace
+	for _, k := range keys {
+		key, err := json.Marshal(k)
+		if err != nil {
+			return nil, err
+		}
+		buf.WriteByte(',')
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(p.Extra[k])
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// UnmarshalJSON decodes the pearl, keeping unknown fields in Extra.
+func (p *Pearl) UnmarshalJSON(data []byte) error {
+	var fields pearlFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	for k := range raw {
+		if knownFields[k] {
+			delete(raw, k)
+		}
+	}
+	if len(raw) == 0 {
+		raw = nil
+	}
+
+	*p = Pearl(fields)
+	p.Extra = raw
+	return nil
+}
@@ -1,22 +1,51 @@
 package pearl
 
 import (
+	"strings"
+
 	"github.com/bmatcuk/doublestar/v4"
 )
 
-// MatchPath checks if a file path matches any of the given glob patterns.
-// Patterns use doublestar syntax (** matches any depth).
+// MatchPath checks if a file path matches the given glob patterns.
+// Patterns use doublestar syntax (** matches any depth) and are evaluated
+// in order, as in .gitignore: a pattern starting with "!" excludes paths
+// that earlier patterns matched, and the last pattern to match a path wins.
+// Invalid patterns are skipped.
 // Paths are relative to repo root.
 func MatchPath(path string, globs []string) bool {
-	if path == "" || len(globs) == 0 {
-		return false
+	return MatchingGlob(path, globs) != ""
+}
+
+// MatchingGlob returns the pattern that makes path match globs, by the
+// rules of MatchPath, or "" if path does not match.
+func MatchingGlob(path string, globs []string) string {
+	if path == "" {
+		return ""
 	}
 
+	match := ""
 	for _, g := range globs {
-		matched, err := doublestar.Match(g, path)
-		if err == nil && matched {
-			return true
+		pattern, negated := SplitNegation(g)
+		if (match != "") != negated {
+			continue // this pattern cannot change the result
+		}
+		ok, err := doublestar.Match(pattern, path)
+		if err == nil && ok {
+			if negated {
+				match = ""
+			} else {
+				match = g
+			}
 		}
 	}
-	return false
+	return match
+}
+
+// SplitNegation strips the leading "!" from an excluding pattern and
+// reports whether it had one.
+func SplitNegation(glob string) (string, bool) {
+	if strings.HasPrefix(glob, "!") {
+		return glob[1:], true
+	}
+	return glob, false
 }
@@ -80,3 +80,109 @@ func TestMatchPath(t *testing.T) {
 		})
 	}
 }
+
+func TestMatchPath_Negation(t *testing.T) {
+	tests := []struct {
+		name  string
+		path  string
+		globs []string
+		want  bool
+	}{
+		{
+			name:  "negation excludes an earlier match",
+			path:  "src/api/handler_test.go",
+			globs: []string{"src/**", "!**/*_test.go"},
+			want:  false,
+		},
+		{
+			name:  "negation leaves other matches alone",
+			path:  "src/api/handler.go",
+			globs: []string{"src/**", "!**/*_test.go"},
+			want:  true,
+		},
+		{
+			name:  "later pattern re-includes",
+			path:  "src/gen/keep.go",
+			globs: []string{"src/**", "!src/gen/**", "src/gen/keep.go"},
+			want:  true,
+		},
+		{
+			name:  "later negation wins over re-inclusion",
+			path:  "src/gen/keep.go",
+			globs: []string{"src/**", "src/gen/keep.go", "!src/gen/**"},
+			want:  false,
+		},
+		{
+			name:  "negation before inclusion has no effect",
+			path:  "src/api/handler_test.go",
+			globs: []string{"!**/*_test.go", "src/**"},
+			want:  true,
+		},
+		{
+			name:  "only negations match nothing",
+			path:  "src/api/handler.go",
+			globs: []string{"!**/*_test.go"},
+			want:  false,
+		},
+		{
+			name:  "negation of another pattern's path",
+			path:  "docs/guide.md",
+			globs: []string{"docs/**", "!src/**"},
+			want:  true,
+		},
+		{
+			name:  "invalid pattern skipped",
+			path:  "src/api/handler.go",
+			globs: []string{"src/**", "![", "["},
+			want:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := MatchPath(tt.path, tt.globs)
+			if got != tt.want {
+				t.Errorf("MatchPath(%q, %v) = %v, want %v", tt.path, tt.globs, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSplitNegation(t *testing.T) {
+	tests := []struct {
+		glob    string
+		pattern string
+		negated bool
+	}{
+		{"src/**", "src/**", false},
+		{"!src/**", "src/**", true},
+		{"!!a", "!a", true},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		pattern, negated := SplitNegation(tt.glob)
+		if pattern != tt.pattern || negated != tt.negated {
+			t.Errorf("SplitNegation(%q) = %q, %v, want %q, %v", tt.glob, pattern, negated, tt.pattern, tt.negated)
+		}
+	}
+}
+
+func TestMatchingGlob(t *testing.T) {
+	tests := []struct {
+		path  string
+		globs []string
+		want  string
+	}{
+		{"src/api/handler.go", []string{"docs/**", "src/**"}, "src/**"},
+		{"src/api/handler.go", []string{"src/**", "src/api/*.go"}, "src/**"},
+		{"src/gen/keep.go", []string{"src/**", "!src/gen/**", "src/gen/keep.go"}, "src/gen/keep.go"},
+		{"src/api/handler_test.go", []string{"src/**", "!**/*_test.go"}, ""},
+		{"src/api/handler.go", nil, ""},
+		{"", []string{"**"}, ""},
+	}
+	for _, tt := range tests {
+		if got := MatchingGlob(tt.path, tt.globs); got != tt.want {
+			t.Errorf("MatchingGlob(%q, %v) = %q, want %q", tt.path, tt.globs, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package pearl
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/bmatcuk/doublestar/v4"
+)
+
+// GlobPrefix returns the literal directory a pattern is confined to, such
+// as "src/api" for "src/api/**/*.go", or "" if it can match anywhere.
+func GlobPrefix(glob string) string {
+	base, _ := doublestar.SplitPattern(glob)
+	if base == "." {
+		return ""
+	}
+	return base
+}
+
+// GlobPrefixes returns the distinct prefixes of a glob list's including
+// patterns. A path can only match the list if one of them is "" or a
+// directory the path is in. Lists that only exclude have none.
+func GlobPrefixes(globs []string) []string {
+	var prefixes []string
+	seen := make(map[string]bool)
+	for _, g := range globs {
+		pattern, negated := SplitNegation(g)
+		if negated {
+			continue
+		}
+		prefix := GlobPrefix(pattern)
+		if !seen[prefix] {
+			seen[prefix] = true
+			prefixes = append(prefixes, prefix)
+		}
+	}
+	return prefixes
+}
+
+// PathPrefixes returns every prefix under which a glob matching path could
+// be filed: "", then each leading run of segments, up to the path itself.
+func PathPrefixes(path string) []string {
+	prefixes := []string{""}
+	for i := 0; i < len(path); i++ {
+		if path[i] == '/' {
+			prefixes = append(prefixes, path[:i])
+		}
+	}
+	return append(prefixes, path)
+}
+
+// GlobIndex matches paths against many glob lists without testing every
+// list. Lists are filed in a trie under the literal prefixes of their
+// including patterns, so a lookup only evaluates lists filed along the
+// path's directories.
+type GlobIndex struct {
+	root    *globNode
+	entries []globEntry
+}
+
+type globEntry struct {
+	id    string
+	globs []string
+}
+
+type globNode struct {
+	children map[string]*globNode
+	entries  []int
+}
+
+// NewGlobIndex returns an empty index.
+func NewGlobIndex() *GlobIndex {
+	return &GlobIndex{root: &globNode{}}
+}
+
+// Add files a glob list under id.
+func (ix *GlobIndex) Add(id string, globs []string) {
+	i := len(ix.entries)
+	ix.entries = append(ix.entries, globEntry{id: id, globs: globs})
+	for _, prefix := range GlobPrefixes(globs) {
+		n := ix.root
+		if prefix != "" {
+			for _, seg := range strings.Split(prefix, "/") {
+				child := n.children[seg]
+				if child == nil {
+					if n.children == nil {
+						n.children = make(map[string]*globNode)
+					}
+					child = &globNode{}
+					n.children[seg] = child
+				}
+				n = child
+			}
+		}
+		n.entries = append(n.entries, i)
+	}
+}
+
+// Len returns the number of glob lists in the index.
+func (ix *GlobIndex) Len() int {
+	return len(ix.entries)
+}
+
+// Match returns the IDs whose globs match path, in the order they were
+// added.
+func (ix *GlobIndex) Match(path string) []string {
+	if path == "" {
+		return nil
+	}
+
+	candidates := ix.root.entries
+	n := ix.root
+	for _, seg := range strings.Split(path, "/") {
+		if n = n.children[seg]; n == nil {
+			break
+		}
+		candidates = append(candidates[:len(candidates):len(candidates)], n.entries...)
+	}
+	sort.Ints(candidates)
+
+	var ids []string
+	for i, c := range candidates {
+		if i > 0 && c == candidates[i-1] {
+			continue
+		}
+		if e := ix.entries[c]; MatchPath(path, e.globs) {
+			ids = append(ids, e.id)
+		}
+	}
+	return ids
+}
+
+// MatchAll matches each path, returning the IDs per path. Paths nothing
+// matches are omitted.
+func (ix *GlobIndex) MatchAll(paths []string) map[string][]string {
+	matches := make(map[string][]string)
+	for _, path := range paths {
+		if ids := ix.Match(path); len(ids) > 0 {
+			matches[path] = ids
+		}
+	}
+	return matches
+}
@@ -0,0 +1,143 @@
+package pearl
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestGlobPrefix(t *testing.T) {
+	tests := []struct {
+		glob string
+		want string
+	}{
+		{"src/api/**/*.go", "src/api"},
+		{"src/api/**", "src/api"},
+		{"src/api/handler.go", "src/api"},
+		{"src/*/handler.go", "src"},
+		{"**/*.go", ""},
+		{"*.md", ""},
+		{"Makefile", ""},
+		{"{src,lib}/**", ""},
+	}
+	for _, tt := range tests {
+		if got := GlobPrefix(tt.glob); got != tt.want {
+			t.Errorf("GlobPrefix(%q) = %q, want %q", tt.glob, got, tt.want)
+		}
+	}
+}
+
+func TestGlobPrefixes(t *testing.T) {
+	got := GlobPrefixes([]string{"src/api/**", "!src/api/gen/**", "src/api/*.go", "**/*.md"})
+	want := []string{"src/api", ""}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("GlobPrefixes = %v, want %v", got, want)
+	}
+	if got := GlobPrefixes([]string{"!**/*_test.go"}); got != nil {
+		t.Errorf("only negations: GlobPrefixes = %v, want none", got)
+	}
+}
+
+func TestPathPrefixes(t *testing.T) {
+	got := PathPrefixes("src/api/handler.go")
+	want := []string{"", "src", "src/api", "src/api/handler.go"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("PathPrefixes = %v, want %v", got, want)
+	}
+}
+
+func TestGlobIndex(t *testing.T) {
+	lists := []struct {
+		id    string
+		globs []string
+	}{
+		{"api", []string{"src/api/**"}},
+		{"go", []string{"**/*.go", "!**/*_test.go"}},
+		{"web", []string{"src/web/**/*.tsx", "src/web/**/*.ts"}},
+		{"mixed", []string{"docs/**", "src/api/routes.go"}},
+		{"src", []string{"src"}},
+		{"excludes", []string{"!src/**"}},
+	}
+
+	ix := NewGlobIndex()
+	for _, l := range lists {
+		ix.Add(l.id, l.globs)
+	}
+	if ix.Len() != len(lists) {
+		t.Errorf("Len = %d, want %d", ix.Len(), len(lists))
+	}
+
+	paths := []string{
+		"src/api/handler.go",
+		"src/api/handler_test.go",
+		"src/api/routes.go",
+		"src/web/app/page.tsx",
+		"docs/guide.md",
+		"main.go",
+		"src",
+		"README.md",
+		"",
+	}
+	for _, path := range paths {
+		// The index must agree with testing every list
+		var want []string
+		for _, l := range lists {
+			if MatchPath(path, l.globs) {
+				want = append(want, l.id)
+			}
+		}
+		if got := ix.Match(path); !reflect.DeepEqual(got, want) {
+			t.Errorf("Match(%q) = %v, want %v", path, got, want)
+		}
+	}
+
+	all := ix.MatchAll([]string{"src/api/routes.go", "README.md"})
+	want := map[string][]string{"src/api/routes.go": {"api", "go", "mixed"}}
+	if !reflect.DeepEqual(all, want) {
+		t.Errorf("MatchAll = %v, want %v", all, want)
+	}
+}
+
+// benchmarkLists builds n glob lists spread over n/10 service directories,
+// plus a few repo-wide lists, the way conventions and module docs mix.
+func benchmarkLists(n int) [][]string {
+	lists := make([][]string, n)
+	for i := range lists {
+		switch {
+		case i%100 == 0:
+			lists[i] = []string{fmt.Sprintf("**/*.ext%d", i)}
+		default:
+			svc := i / 10
+			lists[i] = []string{
+				fmt.Sprintf("services/svc%d/**/*.go", svc),
+				fmt.Sprintf("!services/svc%d/**/*_test.go", svc),
+				fmt.Sprintf("services/svc%d/pkg%d/**", svc, i%10),
+			}
+		}
+	}
+	return lists
+}
+
+func BenchmarkGlobIndexMatch(b *testing.B) {
+	for _, n := range []int{1000, 10000} {
+		lists := benchmarkLists(n)
+		ix := NewGlobIndex()
+		for i, l := range lists {
+			ix.Add(fmt.Sprint(i), l)
+		}
+		path := fmt.Sprintf("services/svc%d/pkg3/handler.go", n/20)
+
+		b.Run(fmt.Sprintf("index/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				ix.Match(path)
+			}
+		})
+		b.Run(fmt.Sprintf("scan/%d", n), func(b *testing.B) {
+			for i := 0; i < b.N; i++ {
+				for _, l := range lists {
+					MatchPath(path, l)
+				}
+			}
+		})
+	}
+}
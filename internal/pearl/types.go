@@ -2,8 +2,10 @@
 package pearl
 
 import (
+	"encoding/json"
 	"fmt"
 	"regexp"
+	"strings"
 	"time"
 
 	"github.com/bmatcuk/doublestar/v4"
@@ -53,15 +55,38 @@ func ValidateScopes(scopes []string) error {
 	return nil
 }
 
-// ValidateGlobs checks that all glob patterns have valid syntax.
+// ValidateGlobs checks that all glob patterns have valid syntax. Patterns
+// starting with "!" exclude paths, so at least one must not.
 func ValidateGlobs(globs []string) error {
+	included := len(globs) == 0
 	for _, g := range globs {
 		if g == "" {
 			return fmt.Errorf("glob pattern cannot be empty")
 		}
-		if !doublestar.ValidatePattern(g) {
+		pattern, negated := SplitNegation(g)
+		if pattern == "" {
+			return fmt.Errorf("negated glob pattern cannot be empty")
+		}
+		if !doublestar.ValidatePattern(pattern) {
 			return fmt.Errorf("invalid glob pattern %q", g)
 		}
+		if !negated {
+			included = true
+		}
+	}
+	if !included {
+		return fmt.Errorf("globs only exclude paths: add a pattern without '!'")
+	}
+	return nil
+}
+
+// ValidateOwners checks that owners are non-empty and contain no whitespace,
+// as in CODEOWNERS: "@user", "@org/team", or an email address.
+func ValidateOwners(owners []string) error {
+	for _, o := range owners {
+		if o == "" || strings.ContainsAny(o, " \t\n") {
+			return fmt.Errorf("invalid owner %q: must be a non-empty name like @user or @org/team", o)
+		}
 	}
 	return nil
 }
@@ -129,11 +154,31 @@ type Pearl struct {
 	Required bool `json:"required"` // Whether this pearl is required context
 	Priority int  `json:"priority"` // Priority ordering (higher = more important)
 
+	// Ownership: people or teams to ask about this asset, e.g. ["@alice", "@org/data"]
+	Owners []string `json:"owners,omitempty"`
+
 	// Metadata
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 	CreatedBy string    `json:"created_by"`
 	Status    Status    `json:"status"`
+
+	// Freshness: when someone last confirmed the content is still accurate
+	LastVerifiedAt *time.Time `json:"last_verified_at,omitempty"`
+	VerifiedBy     string     `json:"verified_by,omitempty"`
+
+	// Extra holds JSON fields this version does not know about, so records
+	// written by newer versions round-trip without losing data.
+	Extra map[string]json.RawMessage `json:"-"`
+}
+
+// LastReviewed returns when the pearl was last verified, or its creation
+// time if it never has been.
+func (p *Pearl) LastReviewed() time.Time {
+	if p.LastVerifiedAt != nil {
+		return *p.LastVerifiedAt
+	}
+	return p.CreatedAt
 }
 
 // FullID returns the fully-qualified ID (namespace + name).
@@ -88,6 +88,11 @@ func TestValidateGlobs(t *testing.T) {
 		{"nil list", nil, false},
 		{"invalid empty string", []string{""}, true},
 		{"invalid bad pattern", []string{"["}, true},
+		{"valid negated pattern", []string{"src/**", "!src/**/*_test.go"}, false},
+		{"negation before inclusion", []string{"!src/gen/**", "src/**"}, false},
+		{"invalid empty negation", []string{"src/**", "!"}, true},
+		{"invalid bad negated pattern", []string{"src/**", "!["}, true},
+		{"invalid only negations", []string{"!src/**/*_test.go"}, true},
 	}
 
 	for _, tt := range tests {
@@ -249,3 +254,37 @@ func TestPearlJSONOmitEmpty(t *testing.T) {
 		t.Error("scopes should be omitted when empty")
 	}
 }
+
+func TestPearlJSONUnknownFields(t *testing.T) {
+	input := `{"id":"db.users","name":"users","namespace":"db","type":"table","tags":null,"description":"","content_path":"","content_hash":"","required":false,"priority":0,"created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","created_by":"","status":"active","owner_team":"data","sla":{"hours":4}}`
+
+	var p Pearl
+	if err := json.Unmarshal([]byte(input), &p); err != nil {
+		t.Fatalf("unmarshal: %v", err)
+	}
+	if p.ID != "db.users" {
+		t.Errorf("ID = %q, want db.users", p.ID)
+	}
+	if len(p.Extra) != 2 || string(p.Extra["owner_team"]) != `"data"` || string(p.Extra["sla"]) != `{"hours":4}` {
+		t.Errorf("Extra = %v, want owner_team and sla", p.Extra)
+	}
+
+	out, err := json.Marshal(p)
+	if err != nil {
+		t.Fatalf("marshal: %v", err)
+	}
+	if string(out) != input {
+		t.Errorf("round trip changed record:\n got %s\nwant %s", out, input)
+	}
+
+	// Known fields are never duplicated from Extra
+	p.Extra["id"] = json.RawMessage(`"shadow"`)
+	out, _ = json.Marshal(p)
+	var back map[string]any
+	if err := json.Unmarshal(out, &back); err != nil {
+		t.Fatalf("re-unmarshal: %v", err)
+	}
+	if back["id"] != "db.users" {
+		t.Errorf("id = %v, want db.users", back["id"])
+	}
+}
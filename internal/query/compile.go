@@ -0,0 +1,283 @@
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/config"
+)
+
+// Options controls compilation.
+type Options struct {
+	// Now anchors relative dates such as updated:>30d. Zero means the
+	// current time.
+	Now time.Time
+	// ExpandScope returns the scopes a scope: term matches, such as the
+	// scope and those declared beneath it. Nil matches the scope alone.
+	ExpandScope func(string) []string
+}
+
+// Clause is a compiled query: a boolean SQL expression over the pearls
+// table and the arguments for its placeholders.
+type Clause struct {
+	SQL  string
+	Args []interface{}
+}
+
+// Compile turns a parsed query into a parameterized SQL expression.
+func Compile(n Node, opts Options) (*Clause, error) {
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	c := &compiler{opts: opts}
+	sql, err := c.compile(n)
+	if err != nil {
+		return nil, err
+	}
+	return &Clause{SQL: sql, Args: c.args}, nil
+}
+
+// ParseAndCompile parses and compiles a query string.
+func ParseAndCompile(s string, opts Options) (*Clause, error) {
+	n, err := Parse(s)
+	if err != nil {
+		return nil, err
+	}
+	return Compile(n, opts)
+}
+
+// scalarColumns maps fields to the text columns they compare.
+var scalarColumns = map[string]string{
+	"id":     "id",
+	"name":   "name",
+	"type":   "type",
+	"status": "status",
+	"parent": "parent",
+}
+
+// arrayColumns maps fields to the JSON array columns they search.
+var arrayColumns = map[string]string{
+	"tag":   "tags",
+	"scope": "scopes",
+	"glob":  "globs",
+	"owner": "owners",
+	"ref":   "refs",
+}
+
+// dateColumns maps fields to the timestamp columns they compare.
+var dateColumns = map[string]string{
+	"created":  "created_at",
+	"updated":  "updated_at",
+	"verified": "last_verified_at",
+}
+
+// hasConditions maps has: values to the SQL testing for them.
+var hasConditions = map[string]string{
+	"tags":       "ifnull(json_array_length(tags), 0) > 0",
+	"globs":      "ifnull(json_array_length(globs), 0) > 0",
+	"scopes":     "ifnull(json_array_length(scopes), 0) > 0",
+	"owners":     "ifnull(json_array_length(owners), 0) > 0",
+	"refs":       "ifnull(json_array_length(refs), 0) > 0",
+	"parent":     "ifnull(parent, '') != ''",
+	"connection": "ifnull(connection, '') NOT IN ('', 'null')",
+	"verified":   "ifnull(last_verified_at, '') != ''",
+}
+
+// sqliteTime is how timestamps are bound for julianday().
+const sqliteTime = "2006-01-02 15:04:05"
+
+type compiler struct {
+	opts Options
+	args []interface{}
+}
+
+func (c *compiler) compile(n Node) (string, error) {
+	switch n := n.(type) {
+	case *And:
+		return c.compileAll(n.Nodes, " AND ")
+	case *Or:
+		return c.compileAll(n.Nodes, " OR ")
+	case *Not:
+		sql, err := c.compile(n.Node)
+		if err != nil {
+			return "", err
+		}
+		// NULL columns make a condition NULL; treat that as false so
+		// negating it matches
+		return "NOT ifnull(" + sql + ", 0)", nil
+	case *Term:
+		return c.compileTerm(n)
+	}
+	return "", fmt.Errorf("unknown query node %T", n)
+}
+
+func (c *compiler) compileAll(nodes []Node, sep string) (string, error) {
+	parts := make([]string, len(nodes))
+	for i, n := range nodes {
+		sql, err := c.compile(n)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = sql
+	}
+	return "(" + strings.Join(parts, sep) + ")", nil
+}
+
+func (c *compiler) arg(v interface{}) string {
+	c.args = append(c.args, v)
+	return "?"
+}
+
+func (c *compiler) compileTerm(t *Term) (string, error) {
+	if col, ok := scalarColumns[t.Field]; ok {
+		if hasWildcard(t.Value) {
+			return col + " GLOB " + c.arg(t.Value), nil
+		}
+		return col + " = " + c.arg(t.Value), nil
+	}
+	if col, ok := arrayColumns[t.Field]; ok {
+		return c.compileArray(t, col), nil
+	}
+	if col, ok := dateColumns[t.Field]; ok {
+		return c.compileDate(t, col)
+	}
+
+	switch t.Field {
+	case "":
+		like := "%" + escapeLike(t.Value) + "%"
+		var parts []string
+		for _, col := range []string{"id", "name", "namespace", "description"} {
+			parts = append(parts, col+` LIKE `+c.arg(like)+` ESCAPE '\'`)
+		}
+		parts = append(parts, `EXISTS (SELECT 1 FROM json_each(pearls.tags) WHERE value LIKE `+c.arg(like)+` ESCAPE '\')`)
+		return "(" + strings.Join(parts, " OR ") + ")", nil
+
+	case "ns":
+		// ns:db and ns:db.* both mean db and everything beneath it
+		ns := strings.TrimSuffix(t.Value, ".*")
+		if hasWildcard(ns) {
+			return "namespace GLOB " + c.arg(t.Value), nil
+		}
+		return "(namespace = " + c.arg(ns) + " OR substr(namespace, 1, " + c.arg(len(ns)+1) + ") = " + c.arg(ns+".") + ")", nil
+
+	case "desc":
+		return `description LIKE ` + c.arg("%"+escapeLike(t.Value)+"%") + ` ESCAPE '\'`, nil
+
+	case "required":
+		switch strings.ToLower(t.Value) {
+		case "true", "yes", "1":
+			return "required = 1", nil
+		case "false", "no", "0":
+			return "required = 0", nil
+		}
+		return "", fmt.Errorf("required: expected true or false, got %q", t.Value)
+
+	case "priority":
+		n, err := strconv.Atoi(t.Value)
+		if err != nil {
+			return "", fmt.Errorf("priority: expected a number, got %q", t.Value)
+		}
+		op := t.Op
+		if op == "" {
+			op = "="
+		}
+		return "priority " + op + " " + c.arg(n), nil
+
+	case "has":
+		cond, ok := hasConditions[strings.ToLower(t.Value)]
+		if !ok {
+			return "", fmt.Errorf("has: unknown value %q (use %s)", t.Value, strings.Join(hasValues(), ", "))
+		}
+		return cond, nil
+	}
+
+	return "", fmt.Errorf("unknown field %q", t.Field)
+}
+
+// compileArray matches any element of a JSON array column.
+func (c *compiler) compileArray(t *Term, col string) string {
+	sub := "EXISTS (SELECT 1 FROM json_each(pearls." + col + ") WHERE value "
+	if hasWildcard(t.Value) {
+		return sub + "GLOB " + c.arg(t.Value) + ")"
+	}
+
+	values := []string{t.Value}
+	if t.Field == "scope" && c.opts.ExpandScope != nil {
+		values = c.opts.ExpandScope(t.Value)
+	}
+	if len(values) == 1 {
+		return sub + "= " + c.arg(values[0]) + ")"
+	}
+	marks := make([]string, len(values))
+	for i, v := range values {
+		marks[i] = c.arg(v)
+	}
+	return sub + "IN (" + strings.Join(marks, ", ") + "))"
+}
+
+// compileDate compares a timestamp column with an absolute date
+// (2025-01-31 or RFC 3339) or an age counted back from now (30d, 2w, 36h).
+// Without an operator, an age means at or after it and a date means that
+// day.
+func (c *compiler) compileDate(t *Term, col string) (string, error) {
+	expr := "julianday(" + col + ")"
+
+	if day, err := time.ParseInLocation("2006-01-02", t.Value, time.Local); err == nil {
+		// A date covers the whole day: after it starts the next day
+		next := day.AddDate(0, 0, 1)
+		switch t.Op {
+		case ">":
+			return expr + " >= julianday(" + c.arg(sqlTime(next)) + ")", nil
+		case ">=":
+			return expr + " >= julianday(" + c.arg(sqlTime(day)) + ")", nil
+		case "<":
+			return expr + " < julianday(" + c.arg(sqlTime(day)) + ")", nil
+		case "<=":
+			return expr + " < julianday(" + c.arg(sqlTime(next)) + ")", nil
+		}
+		return "(" + expr + " >= julianday(" + c.arg(sqlTime(day)) + ") AND " +
+			expr + " < julianday(" + c.arg(sqlTime(next)) + "))", nil
+	}
+
+	var at time.Time
+	if ts, err := time.Parse(time.RFC3339, t.Value); err == nil {
+		at = ts
+	} else {
+		age, err := config.ParseInterval(t.Value)
+		if err != nil || age == 0 {
+			return "", fmt.Errorf("%s: expected a date (2025-01-31) or an age (30d, 2w, 36h), got %q", t.Field, t.Value)
+		}
+		at = c.opts.Now.Add(-age)
+	}
+
+	op := t.Op
+	if op == "" {
+		op = ">="
+	}
+	return expr + " " + op + " julianday(" + c.arg(sqlTime(at)) + ")", nil
+}
+
+func sqlTime(t time.Time) string {
+	return t.UTC().Format(sqliteTime)
+}
+
+func hasValues() []string {
+	values := make([]string, 0, len(hasConditions))
+	for v := range hasConditions {
+		values = append(values, v)
+	}
+	sort.Strings(values)
+	return values
+}
+
+func hasWildcard(s string) bool {
+	return strings.ContainsAny(s, "*?[")
+}
+
+// escapeLike escapes LIKE wildcards, for use with ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
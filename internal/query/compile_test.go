@@ -0,0 +1,126 @@
+package query
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCompile(t *testing.T) {
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	expand := func(s string) []string {
+		if s == "backend" {
+			return []string{"backend", "payments"}
+		}
+		return []string{s}
+	}
+
+	tests := []struct {
+		in       string
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{"type:table", "type = ?", []interface{}{"table"}},
+		{"id:db.*", "id GLOB ?", []interface{}{"db.*"}},
+		{
+			"tag:pii OR tag:sensitive",
+			"(EXISTS (SELECT 1 FROM json_each(pearls.tags) WHERE value = ?) OR EXISTS (SELECT 1 FROM json_each(pearls.tags) WHERE value = ?))",
+			[]interface{}{"pii", "sensitive"},
+		},
+		{"scope:backend", "EXISTS (SELECT 1 FROM json_each(pearls.scopes) WHERE value IN (?, ?))", []interface{}{"backend", "payments"}},
+		{"owner:@org/*", "EXISTS (SELECT 1 FROM json_each(pearls.owners) WHERE value GLOB ?)", []interface{}{"@org/*"}},
+		{"ns:db.*", "(namespace = ? OR substr(namespace, 1, ?) = ?)", []interface{}{"db", 3, "db."}},
+		{"ns:db", "(namespace = ? OR substr(namespace, 1, ?) = ?)", []interface{}{"db", 3, "db."}},
+		{"ns:*.pg", "namespace GLOB ?", []interface{}{"*.pg"}},
+		{"-status:archived", "NOT ifnull(status = ?, 0)", []interface{}{"archived"}},
+		{"required:yes", "required = 1", nil},
+		{"priority:>=5", "priority >= ?", []interface{}{5}},
+		{"priority:3", "priority = ?", []interface{}{3}},
+		{"updated:>30d", "julianday(updated_at) > julianday(?)", []interface{}{"2025-05-31 12:00:00"}},
+		{"created:2w", "julianday(created_at) >= julianday(?)", []interface{}{"2025-06-16 12:00:00"}},
+		{"verified:<2025-01-31T10:00:00+02:00", "julianday(last_verified_at) < julianday(?)", []interface{}{"2025-01-31 08:00:00"}},
+		{"has:globs", "ifnull(json_array_length(globs), 0) > 0", nil},
+		{"desc:50%", `description LIKE ? ESCAPE '\'`, []interface{}{`%50\%%`}},
+	}
+	for _, tt := range tests {
+		clause, err := ParseAndCompile(tt.in, Options{Now: now, ExpandScope: expand})
+		if err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		if clause.SQL != tt.wantSQL {
+			t.Errorf("%s: SQL = %s, want %s", tt.in, clause.SQL, tt.wantSQL)
+		}
+		if !reflect.DeepEqual(clause.Args, tt.wantArgs) {
+			t.Errorf("%s: args = %v, want %v", tt.in, clause.Args, tt.wantArgs)
+		}
+	}
+}
+
+func TestCompileText(t *testing.T) {
+	clause, err := ParseAndCompile("user_id", Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Count(clause.SQL, "LIKE ?"); got != 5 {
+		t.Errorf("bare word searches %d fields, want 5: %s", got, clause.SQL)
+	}
+	for _, arg := range clause.Args {
+		if arg != `%user\_id%` {
+			t.Errorf("arg = %v, want escaped pattern", arg)
+		}
+	}
+}
+
+func TestCompileDates(t *testing.T) {
+	// Dates are whole days in local time, so compare against the bounds
+	// the compiler derives from the same day
+	day, _ := time.ParseInLocation("2006-01-02", "2025-01-31", time.Local)
+	start, next := sqlTime(day), sqlTime(day.AddDate(0, 0, 1))
+
+	tests := []struct {
+		in       string
+		wantSQL  string
+		wantArgs []interface{}
+	}{
+		{"updated:2025-01-31", "(julianday(updated_at) >= julianday(?) AND julianday(updated_at) < julianday(?))", []interface{}{start, next}},
+		{"updated:>2025-01-31", "julianday(updated_at) >= julianday(?)", []interface{}{next}},
+		{"updated:>=2025-01-31", "julianday(updated_at) >= julianday(?)", []interface{}{start}},
+		{"updated:<2025-01-31", "julianday(updated_at) < julianday(?)", []interface{}{start}},
+		{"updated:<=2025-01-31", "julianday(updated_at) < julianday(?)", []interface{}{next}},
+	}
+	for _, tt := range tests {
+		clause, err := ParseAndCompile(tt.in, Options{})
+		if err != nil {
+			t.Errorf("%s: %v", tt.in, err)
+			continue
+		}
+		if clause.SQL != tt.wantSQL || !reflect.DeepEqual(clause.Args, tt.wantArgs) {
+			t.Errorf("%s = %s %v, want %s %v", tt.in, clause.SQL, clause.Args, tt.wantSQL, tt.wantArgs)
+		}
+	}
+}
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"required:maybe", "required: expected true or false"},
+		{"priority:high", "priority: expected a number"},
+		{"updated:>soon", "updated: expected a date"},
+		{"updated:0d", "updated: expected a date"},
+		{"has:wings", `has: unknown value "wings"`},
+	}
+	for _, tt := range tests {
+		_, err := ParseAndCompile(tt.in, Options{})
+		if err == nil {
+			t.Errorf("%s: expected error", tt.in)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%s: error = %q, want it to contain %q", tt.in, err, tt.want)
+		}
+	}
+}
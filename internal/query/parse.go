@@ -0,0 +1,370 @@
+// Package query parses the --query language and compiles it to SQL against
+// the pearls table.
+//
+// A query is a list of terms, all of which must match:
+//
+//	type:table (tag:pii OR tag:sensitive) updated:>30d -status:archived ns:db.*
+//
+// Terms are field:value pairs or bare words, which search the ID, name,
+// namespace, description, and tags. OR binds looser than the implicit AND,
+// parentheses group, and a leading - or NOT negates. Values with spaces are
+// quoted: desc:"user email".
+package query
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Node is a parsed query expression.
+type Node interface {
+	String() string
+}
+
+// And matches when every node matches.
+type And struct {
+	Nodes []Node
+}
+
+// Or matches when any node matches.
+type Or struct {
+	Nodes []Node
+}
+
+// Not matches when its node does not.
+type Not struct {
+	Node Node
+}
+
+// Term is a single field:value condition. Field is empty for a bare word.
+// Op is a comparison (">", ">=", "<", "<=", "=") for fields that take one.
+type Term struct {
+	Field string
+	Op    string
+	Value string
+}
+
+func (n *And) String() string { return "(" + join(n.Nodes, " ") + ")" }
+func (n *Or) String() string  { return "(" + join(n.Nodes, " OR ") + ")" }
+func (n *Not) String() string { return "-" + n.Node.String() }
+
+func (t *Term) String() string {
+	value := t.Value
+	if strings.ContainsAny(value, " \t()\"") {
+		value = fmt.Sprintf("%q", value)
+	}
+	if t.Field == "" {
+		return value
+	}
+	return t.Field + ":" + t.Op + value
+}
+
+func join(nodes []Node, sep string) string {
+	parts := make([]string, len(nodes))
+	for i, n := range nodes {
+		parts[i] = n.String()
+	}
+	return strings.Join(parts, sep)
+}
+
+// fieldAliases maps accepted field names to their canonical form.
+var fieldAliases = map[string]string{
+	"id":          "id",
+	"name":        "name",
+	"type":        "type",
+	"status":      "status",
+	"parent":      "parent",
+	"ns":          "ns",
+	"namespace":   "ns",
+	"desc":        "desc",
+	"description": "desc",
+	"tag":         "tag",
+	"tags":        "tag",
+	"scope":       "scope",
+	"scopes":      "scope",
+	"glob":        "glob",
+	"globs":       "glob",
+	"owner":       "owner",
+	"owners":      "owner",
+	"ref":         "ref",
+	"refs":        "ref",
+	"references":  "ref",
+	"required":    "required",
+	"priority":    "priority",
+	"created":     "created",
+	"updated":     "updated",
+	"verified":    "verified",
+	"has":         "has",
+}
+
+// comparable lists the fields that accept a comparison operator.
+var comparable = map[string]bool{
+	"priority": true,
+	"created":  true,
+	"updated":  true,
+	"verified": true,
+}
+
+// Fields returns the canonical field names, sorted.
+func Fields() []string {
+	seen := make(map[string]bool)
+	var names []string
+	for _, name := range fieldAliases {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Parse parses a query string.
+func Parse(s string) (Node, error) {
+	tokens, err := lex(s)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{tokens: tokens}
+	if p.peek().kind == tokEOF {
+		return nil, fmt.Errorf("empty query")
+	}
+	n, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if tok := p.peek(); tok.kind != tokEOF {
+		return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos+1)
+	}
+	return n, nil
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokLParen
+	tokRParen
+	tokNot
+	tokAnd
+	tokOr
+	tokTerm
+)
+
+type token struct {
+	kind tokenKind
+	pos  int
+	term *Term
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokEOF:
+		return "end of query"
+	case tokLParen:
+		return `"("`
+	case tokRParen:
+		return `")"`
+	case tokNot:
+		return "NOT"
+	case tokAnd:
+		return "AND"
+	case tokOr:
+		return "OR"
+	}
+	return fmt.Sprintf("%q", t.term.String())
+}
+
+func lex(s string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n':
+			i++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokLParen, pos: i})
+			i++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokRParen, pos: i})
+			i++
+		case c == '-' && i+1 < len(s) && !strings.ContainsRune(" \t\n)", rune(s[i+1])):
+			tokens = append(tokens, token{kind: tokNot, pos: i})
+			i++
+		default:
+			start := i
+			word, quoted, end, err := lexWord(s, i)
+			if err != nil {
+				return nil, err
+			}
+			i = end
+			if !quoted {
+				switch word {
+				case "OR":
+					tokens = append(tokens, token{kind: tokOr, pos: start})
+					continue
+				case "AND":
+					tokens = append(tokens, token{kind: tokAnd, pos: start})
+					continue
+				case "NOT":
+					tokens = append(tokens, token{kind: tokNot, pos: start})
+					continue
+				}
+			}
+			term, err := newTerm(word, quoted, start)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokTerm, pos: start, term: term})
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(s)}), nil
+}
+
+// lexWord reads a word starting at i, up to whitespace or a parenthesis.
+// Quoted sections are taken literally, without their quotes. quoted
+// reports whether the whole word was a quoted string.
+func lexWord(s string, i int) (word string, quoted bool, end int, err error) {
+	var b strings.Builder
+	start := i
+	for i < len(s) {
+		c := s[i]
+		if c == ' ' || c == '\t' || c == '\n' || c == '(' || c == ')' {
+			break
+		}
+		if c != '"' {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+		n := strings.IndexByte(s[i+1:], '"')
+		if n < 0 {
+			return "", false, 0, fmt.Errorf("unterminated quote at position %d", i+1)
+		}
+		b.WriteString(s[i+1 : i+1+n])
+		i += n + 2
+	}
+	quoted = s[start] == '"' && s[i-1] == '"' && !strings.Contains(s[start+1:i-1], `"`)
+	return b.String(), quoted, i, nil
+}
+
+// newTerm splits a word into field, operator, and value.
+func newTerm(word string, quoted bool, pos int) (*Term, error) {
+	if quoted {
+		return &Term{Value: word}, nil
+	}
+	name, value, ok := strings.Cut(word, ":")
+	if !ok {
+		return &Term{Value: word}, nil
+	}
+
+	field, known := fieldAliases[strings.ToLower(name)]
+	if !known {
+		return nil, fmt.Errorf("unknown field %q at position %d (fields: %s)", name, pos+1, strings.Join(Fields(), ", "))
+	}
+
+	t := &Term{Field: field, Value: value}
+	if comparable[field] {
+		for _, op := range []string{">=", "<=", ">", "<", "="} {
+			if v, ok := strings.CutPrefix(value, op); ok {
+				t.Op, t.Value = op, v
+				break
+			}
+		}
+	}
+	if t.Value == "" {
+		return nil, fmt.Errorf("missing value for %q at position %d", name, pos+1)
+	}
+	return t, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokEOF {
+		p.pos++
+	}
+	return tok
+}
+
+// parseOr parses and-expressions separated by OR.
+func (p *parser) parseOr() (Node, error) {
+	var nodes []Node
+	for {
+		n, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+		if p.peek().kind != tokOr {
+			break
+		}
+		p.next()
+	}
+	if len(nodes) == 1 {
+		return nodes[0], nil
+	}
+	return &Or{Nodes: nodes}, nil
+}
+
+// parseAnd parses unary expressions joined by AND or juxtaposition.
+func (p *parser) parseAnd() (Node, error) {
+	var nodes []Node
+	for {
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		nodes = append(nodes, n)
+
+		switch p.peek().kind {
+		case tokEOF, tokRParen, tokOr:
+			if len(nodes) == 1 {
+				return nodes[0], nil
+			}
+			return &And{Nodes: nodes}, nil
+		case tokAnd:
+			p.next()
+		}
+	}
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if p.peek().kind == tokNot {
+		p.next()
+		n, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Not{Node: n}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokTerm:
+		return tok.term, nil
+	case tokLParen:
+		n, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if end := p.next(); end.kind != tokRParen {
+			return nil, fmt.Errorf("missing \")\" for \"(\" at position %d", tok.pos+1)
+		}
+		return n, nil
+	}
+	return nil, fmt.Errorf("unexpected %s at position %d", tok, tok.pos+1)
+}
@@ -0,0 +1,74 @@
+package query
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"type:table", "type:table"},
+		{"type:table tag:pii", "(type:table tag:pii)"},
+		{"type:table AND tag:pii", "(type:table tag:pii)"},
+		{"tag:pii OR tag:sensitive", "(tag:pii OR tag:sensitive)"},
+		{"a b OR c", "((a b) OR c)"},
+		{"a (b OR c)", "(a (b OR c))"},
+		{"-status:archived", "-status:archived"},
+		{"NOT status:archived", "-status:archived"},
+		{"-(a OR b)", "-(a OR b)"},
+		{"updated:>30d", "updated:>30d"},
+		{"priority:>=5", "priority:>=5"},
+		{"namespace:db.*", "ns:db.*"},
+		{"Tags:pii", "tag:pii"},
+		{`desc:"user email"`, `desc:"user email"`},
+		{`"user email"`, `"user email"`},
+		{`"OR"`, "OR"},
+		{"foo-bar", "foo-bar"},
+		{"tag:>x", "tag:>x"},
+		{
+			"type:table (tag:pii OR tag:sensitive) updated:>30d -status:archived ns:db.*",
+			"(type:table (tag:pii OR tag:sensitive) updated:>30d -status:archived ns:db.*)",
+		},
+	}
+	for _, tt := range tests {
+		n, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if got := n.String(); got != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"", "empty query"},
+		{"   ", "empty query"},
+		{"color:red", `unknown field "color"`},
+		{"tag:", `missing value for "tag"`},
+		{"updated:>", `missing value for "updated"`},
+		{"(a OR b", `missing ")"`},
+		{"a)", `unexpected ")"`},
+		{"a OR", "unexpected end of query"},
+		{"OR a", "unexpected OR"},
+		{`desc:"open`, "unterminated quote"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.in)
+		if err == nil {
+			t.Errorf("Parse(%q): expected error", tt.in)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Parse(%q) error = %q, want it to contain %q", tt.in, err, tt.want)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+// Document is a pearl as agent context: its content, or only its metadata
+// when brief.
+type Document struct {
+	*pearl.Pearl
+	// Content is the pearl's markdown, or the selected sections of it; in
+	// brief mode, any sections to show after the metadata. Empty when it
+	// could not be read.
+	Content string
+	// Stale describes why the pearl is overdue for review, if it is.
+	Stale string
+	// Source lists why the pearl was included, such as "id",
+	// "glob:src/**", "scope:payments", "query", "ref:db.users", or
+	// "required".
+	Source []string
+}
+
+// MarshalJSON encodes the pearl's fields followed by content, stale, and
+// source.
+func (d Document) MarshalJSON() ([]byte, error) {
+	data, err := json.Marshal(d.Pearl)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	buf.Write(data[:len(data)-1]) // drop closing brace
+	for _, f := range []struct {
+		key   string
+		value interface{}
+		empty bool
+	}{
+		{"content", d.Content, d.Content == ""},
+		{"stale", d.Stale, d.Stale == ""},
+		{"source", d.Source, len(d.Source) == 0},
+	} {
+		if f.empty {
+			continue
+		}
+		value, err := json.Marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+		fmt.Fprintf(&buf, ",%q:", f.key)
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// DocumentOptions controls how documents are written.
+type DocumentOptions struct {
+	// Brief writes each pearl's metadata instead of its content.
+	Brief bool
+	// TOC starts the output with a table of contents.
+	TOC bool
+}
+
+// WriteDocuments writes documents as one markdown stream separated by
+// rules: each pearl's content, or its metadata in brief mode.
+func WriteDocuments(w io.Writer, docs []Document, opts DocumentOptions) error {
+	var out strings.Builder
+	if opts.TOC {
+		writeTOC(&out, docs)
+	}
+	for i, d := range docs {
+		if i > 0 {
+			out.WriteString("\n---\n\n")
+		}
+		writeBody(&out, d, opts.Brief)
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// WriteMarkdown writes documents as markdown with each pearl between
+// comments that carry its ID, metadata, and source, so a reader can tell
+// where one pearl ends and the next begins:
+//
+//	<!-- pearl id="db.users" type="table" status="active" source="glob:src/**" -->
+//	...
+//	<!-- /pearl -->
+//
+// A closing marker in content is escaped so blocks cannot end early.
+func WriteMarkdown(w io.Writer, docs []Document, opts DocumentOptions) error {
+	var out strings.Builder
+	if opts.TOC {
+		writeTOC(&out, docs)
+	}
+	for i, d := range docs {
+		if i > 0 {
+			out.WriteString("\n")
+		}
+		fmt.Fprintf(&out, "<!-- pearl%s -->\n", attributes(d))
+		var body strings.Builder
+		writeBody(&body, d, opts.Brief)
+		out.WriteString(strings.ReplaceAll(body.String(), "<!-- /pearl", "&lt;!-- /pearl"))
+		out.WriteString("<!-- /pearl -->\n")
+	}
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// WriteXML writes documents as XML-tagged blocks for prompts:
+//
+//	<pearls count="1">
+//	<pearl id="db.users" type="table" status="active" source="glob:src/**">
+//	...
+//	</pearl>
+//	</pearls>
+//
+// Metadata goes in attributes; brief mode puts the description, and any
+// brief sections, in the block. Content is written as is rather than
+// escaped, which keeps it readable to a model, so the output is not meant
+// for XML parsers. A closing pearl tag in content is escaped so blocks
+// cannot end early.
+func WriteXML(w io.Writer, docs []Document, opts DocumentOptions) error {
+	var out strings.Builder
+	fmt.Fprintf(&out, "<pearls count=\"%d\">\n", len(docs))
+	if opts.TOC {
+		out.WriteString("<contents>\n")
+		for _, d := range docs {
+			fmt.Fprintf(&out, "<entry id=\"%s\" type=\"%s\">%s</entry>\n", escape(d.ID), escape(string(d.Type)), escape(d.Description))
+		}
+		out.WriteString("</contents>\n")
+	}
+	for _, d := range docs {
+		fmt.Fprintf(&out, "<pearl%s>\n", attributes(d))
+		body := d.Content
+		if opts.Brief && d.Description != "" {
+			body = strings.TrimSpace(d.Description + "\n\n" + d.Content)
+		} else if body == "" {
+			body = d.Description
+		}
+		writeContent(&out, strings.ReplaceAll(body, "</pearl", "&lt;/pearl"))
+		out.WriteString("</pearl>\n")
+	}
+	out.WriteString("</pearls>\n")
+	_, err := io.WriteString(w, out.String())
+	return err
+}
+
+// writeTOC writes a markdown list of the documents.
+func writeTOC(out *strings.Builder, docs []Document) {
+	out.WriteString("## Contents\n\n")
+	for _, d := range docs {
+		fmt.Fprintf(out, "- `%s` (%s)", d.ID, d.Type)
+		if d.Description != "" {
+			fmt.Fprintf(out, ": %s", d.Description)
+		}
+		out.WriteString("\n")
+	}
+	out.WriteString("\n")
+}
+
+// writeBody writes a pearl's content, or its metadata in brief mode.
+func writeBody(out *strings.Builder, d Document, brief bool) {
+	if brief {
+		writeBrief(out, d)
+		writeContent(out, d.Content)
+		return
+	}
+
+	if d.Stale != "" {
+		fmt.Fprintf(out, "> ⚠ Stale: %s. Verify before relying on this.\n\n", d.Stale)
+	}
+	if d.Content == "" {
+		// Content could not be read; fall back to the description
+		fmt.Fprintf(out, "## %s\n\n%s\n\n", d.ID, d.Description)
+		return
+	}
+	writeContent(out, d.Content)
+}
+
+// writeContent writes markdown ending in a newline.
+func writeContent(out *strings.Builder, content string) {
+	out.WriteString(content)
+	if content != "" && !strings.HasSuffix(content, "\n") {
+		out.WriteString("\n")
+	}
+}
+
+// attributes returns a pearl's wrapper attributes, each with a leading
+// space.
+func attributes(d Document) string {
+	var b strings.Builder
+	attr := func(name, value string) {
+		fmt.Fprintf(&b, " %s=\"%s\"", name, escape(value))
+	}
+	attr("id", d.ID)
+	attr("type", string(d.Type))
+	attr("status", string(d.Status))
+	if d.Priority != 0 {
+		attr("priority", strconv.Itoa(d.Priority))
+	}
+	if d.Required {
+		attr("required", "true")
+	}
+	if len(d.Tags) > 0 {
+		attr("tags", strings.Join(d.Tags, ","))
+	}
+	if len(d.Source) > 0 {
+		attr("source", strings.Join(d.Source, " "))
+	}
+	if d.Stale != "" {
+		attr("stale", d.Stale)
+	}
+	return b.String()
+}
+
+// escape escapes s for an XML attribute or text.
+func escape(s string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(s))
+	return b.String()
+}
+
+// writeBrief writes a pearl's metadata as a markdown section.
+func writeBrief(out *strings.Builder, d Document) {
+	p := d.Pearl
+	fmt.Fprintf(out, "## %s\n\n", p.ID)
+	fmt.Fprintf(out, "- **Type:** %s\n", p.Type)
+	fmt.Fprintf(out, "- **Status:** %s\n", p.Status)
+	if p.Description != "" {
+		fmt.Fprintf(out, "- **Description:** %s\n", p.Description)
+	}
+	if d.Stale != "" {
+		fmt.Fprintf(out, "- **Stale:** %s\n", d.Stale)
+	}
+	if len(p.Tags) > 0 {
+		fmt.Fprintf(out, "- **Tags:** %s\n", strings.Join(p.Tags, ", "))
+	}
+	if p.Connection != nil {
+		fmt.Fprintf(out, "- **Connection:** %s", p.Connection.Type)
+		if p.Connection.Host != "" {
+			fmt.Fprintf(out, " @ %s", p.Connection.Host)
+		}
+		if p.Connection.Database != "" {
+			fmt.Fprintf(out, "/%s", p.Connection.Database)
+		}
+		out.WriteString("\n")
+	}
+	out.WriteString("\n")
+}
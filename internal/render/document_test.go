@@ -0,0 +1,168 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+func TestWriteDocuments(t *testing.T) {
+	users := &pearl.Pearl{
+		ID: "db.users", Type: pearl.TypeTable, Status: pearl.StatusActive,
+		Description: "Users table", Tags: []string{"pii", "core"},
+		Connection: &pearl.ConnectionInfo{Type: "postgres", Host: "db.local", Database: "app"},
+	}
+	auth := &pearl.Pearl{ID: "api.auth", Type: pearl.TypeAPI, Status: pearl.StatusActive, Description: "Auth API"}
+
+	docs := []Document{
+		{Pearl: users, Content: "# Users\n\nFull content", Stale: "never verified"},
+		{Pearl: auth},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteDocuments(&buf, docs, DocumentOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	want := "> ⚠ Stale: never verified. Verify before relying on this.\n\n# Users\n\nFull content\n" +
+		"\n---\n\n" +
+		"## api.auth\n\nAuth API\n\n"
+	if got := buf.String(); got != want {
+		t.Errorf("full:\ngot:\n%q\nwant:\n%q", got, want)
+	}
+
+	// In brief mode, content is the sections chosen to follow the metadata
+	docs[0].Content = "## Columns\n\n| id | int |"
+	buf.Reset()
+	if err := WriteDocuments(&buf, docs, DocumentOptions{Brief: true}); err != nil {
+		t.Fatal(err)
+	}
+	brief := buf.String()
+	for _, s := range []string{
+		"## db.users\n\n- **Type:** table\n- **Status:** active\n- **Description:** Users table\n- **Stale:** never verified\n- **Tags:** pii, core\n- **Connection:** postgres @ db.local/app\n\n## Columns\n\n| id | int |\n",
+		"\n---\n\n## api.auth\n",
+	} {
+		if !strings.Contains(brief, s) {
+			t.Errorf("brief output missing %q:\n%s", s, brief)
+		}
+	}
+}
+
+func TestDocumentJSON(t *testing.T) {
+	p := &pearl.Pearl{ID: "db.users", Name: "users", Type: pearl.TypeTable}
+
+	data, err := json.Marshal(Document{Pearl: p, Content: "# Users\n", Stale: "overdue", Source: []string{"id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unmarshal %s: %v", data, err)
+	}
+	if got["id"] != "db.users" || got["content"] != "# Users\n" || got["stale"] != "overdue" {
+		t.Errorf("document JSON = %s", data)
+	}
+	if source, _ := got["source"].([]interface{}); len(source) != 1 || source[0] != "id" {
+		t.Errorf("source = %v, want [id]", got["source"])
+	}
+
+	data, err = json.Marshal(Document{Pearl: p})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), `"content":`) || strings.Contains(string(data), `"stale":`) || strings.Contains(string(data), `"source":`) {
+		t.Errorf("empty fields should be omitted: %s", data)
+	}
+}
+
+func TestWriteWrapped(t *testing.T) {
+	docs := []Document{
+		{
+			Pearl: &pearl.Pearl{
+				ID: "db.users", Type: pearl.TypeTable, Status: pearl.StatusDeprecated,
+				Description: "Users & accounts", Priority: 5, Required: true, Tags: []string{"pii"},
+			},
+			Content: "# Users\n\nSee </pearl> tags",
+			Stale:   "overdue",
+			Source:  []string{"glob:src/**", "scope:payments"},
+		},
+		{Pearl: &pearl.Pearl{ID: "api.auth", Type: pearl.TypeAPI, Status: pearl.StatusActive, Description: "Auth API"}},
+	}
+
+	var buf bytes.Buffer
+	if err := WriteXML(&buf, docs, DocumentOptions{TOC: true}); err != nil {
+		t.Fatal(err)
+	}
+	want := `<pearls count="2">
+<contents>
+<entry id="db.users" type="table">Users &amp; accounts</entry>
+<entry id="api.auth" type="api">Auth API</entry>
+</contents>
+<pearl id="db.users" type="table" status="deprecated" priority="5" required="true" tags="pii" source="glob:src/** scope:payments" stale="overdue">
+# Users
+
+See &lt;/pearl> tags
+</pearl>
+<pearl id="api.auth" type="api" status="active">
+Auth API
+</pearl>
+</pearls>
+`
+	if got := buf.String(); got != want {
+		t.Errorf("xml:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	buf.Reset()
+	if err := WriteMarkdown(&buf, docs, DocumentOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	want = `<!-- pearl id="db.users" type="table" status="deprecated" priority="5" required="true" tags="pii" source="glob:src/** scope:payments" stale="overdue" -->
+> ⚠ Stale: overdue. Verify before relying on this.
+
+# Users
+
+See </pearl> tags
+<!-- /pearl -->
+
+<!-- pearl id="api.auth" type="api" status="active" -->
+## api.auth
+
+Auth API
+
+<!-- /pearl -->
+`
+	if got := buf.String(); got != want {
+		t.Errorf("markdown:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+
+	buf.Reset()
+	if err := WriteDocuments(&buf, docs, DocumentOptions{Brief: true, TOC: true}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), "## Contents\n\n- `db.users` (table): Users & accounts\n- `api.auth` (api): Auth API\n\n## db.users\n") {
+		t.Errorf("toc:\n%s", buf.String())
+	}
+}
+
+func TestWriteMarkdownEscapesEndMarker(t *testing.T) {
+	docs := []Document{{
+		Pearl:   &pearl.Pearl{ID: "docs.context", Type: pearl.TypeFile, Status: pearl.StatusActive},
+		Content: "Blocks end with\n<!-- /pearl -->\nin markdown output.\n",
+	}}
+
+	var buf bytes.Buffer
+	if err := WriteMarkdown(&buf, docs, DocumentOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	want := `<!-- pearl id="docs.context" type="file" status="active" -->
+Blocks end with
+&lt;!-- /pearl -->
+in markdown output.
+<!-- /pearl -->
+`
+	if got := buf.String(); got != want {
+		t.Errorf("markdown:\ngot:\n%s\nwant:\n%s", got, want)
+	}
+}
@@ -0,0 +1,271 @@
+// Package render writes command results in the output formats shared by
+// every read command: text, json, ndjson, yaml, csv, markdown, xml, and Go
+// templates.
+package render
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"reflect"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is an output format.
+type Format string
+
+const (
+	Text     Format = "text"
+	JSON     Format = "json"
+	NDJSON   Format = "ndjson"
+	YAML     Format = "yaml"
+	CSV      Format = "csv"
+	Markdown Format = "markdown"
+	XML      Format = "xml"
+)
+
+// Formats returns every output format.
+func Formats() []Format {
+	return []Format{Text, JSON, NDJSON, YAML, CSV, Markdown, XML}
+}
+
+// ParseFormat parses a format name. Empty means text; "yml" and "md" are
+// accepted as short forms.
+func ParseFormat(s string) (Format, error) {
+	switch strings.ToLower(s) {
+	case "":
+		return Text, nil
+	case "yml":
+		return YAML, nil
+	case "md":
+		return Markdown, nil
+	}
+	for _, f := range Formats() {
+		if Format(strings.ToLower(s)) == f {
+			return f, nil
+		}
+	}
+	names := make([]string, len(Formats()))
+	for i, f := range Formats() {
+		names[i] = string(f)
+	}
+	return "", fmt.Errorf("unknown format %q (formats: %s)", s, strings.Join(names, ", "))
+}
+
+// Column is one column of CSV and markdown table output.
+type Column struct {
+	Name  string
+	Value func(item interface{}) string
+}
+
+// Result is the output of a command: a list of items plus anything else
+// the command reports about them.
+type Result struct {
+	// Kind names what each item is, such as "pearl" or "check".
+	Kind string
+	// Items is a slice of the results.
+	Items interface{}
+	// Meta holds other fields for structured formats, such as a total.
+	Meta map[string]interface{}
+	// Columns are the fields written by csv, and by markdown when the
+	// result has no Markdown writer.
+	Columns []Column
+	// Text writes the human-readable output.
+	Text func(w io.Writer) error
+	// Markdown writes markdown output; nil renders Columns as a table.
+	Markdown func(w io.Writer) error
+	// XML writes xml output; nil means the result has none.
+	XML func(w io.Writer) error
+}
+
+// Envelope is the shape of every result in json and yaml.
+type Envelope struct {
+	Kind  string                 `json:"kind"`
+	Count int                    `json:"count"`
+	Items interface{}            `json:"items"`
+	Meta  map[string]interface{} `json:"meta,omitempty"`
+}
+
+// Options selects how a result is written.
+type Options struct {
+	Format Format
+	// Template, if set, is a Go template executed for each item instead
+	// of the format, e.g. '{{.ID}} {{.Type}}'.
+	Template string
+}
+
+// Render writes r to w.
+func Render(w io.Writer, r *Result, opts Options) error {
+	if opts.Template != "" {
+		return renderTemplate(w, r, opts.Template)
+	}
+
+	switch opts.Format {
+	case Text, "":
+		if r.Text == nil {
+			return renderTable(w, r)
+		}
+		return r.Text(w)
+	case JSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(envelope(r))
+	case NDJSON:
+		enc := json.NewEncoder(w)
+		for _, item := range items(r) {
+			if err := enc.Encode(item); err != nil {
+				return err
+			}
+		}
+		return nil
+	case YAML:
+		return renderYAML(w, envelope(r))
+	case CSV:
+		return renderCSV(w, r)
+	case Markdown:
+		if r.Markdown != nil {
+			return r.Markdown(w)
+		}
+		return renderTable(w, r)
+	case XML:
+		if r.XML == nil {
+			return fmt.Errorf("xml output is not available for %s results", r.Kind)
+		}
+		return r.XML(w)
+	}
+	return fmt.Errorf("unknown format %q", opts.Format)
+}
+
+func envelope(r *Result) Envelope {
+	list := items(r)
+	return Envelope{Kind: r.Kind, Count: len(list), Items: list, Meta: r.Meta}
+}
+
+// items returns r.Items as a slice, empty rather than nil.
+func items(r *Result) []interface{} {
+	list := []interface{}{}
+	v := reflect.ValueOf(r.Items)
+	if v.Kind() != reflect.Slice {
+		return list
+	}
+	for i := 0; i < v.Len(); i++ {
+		list = append(list, v.Index(i).Interface())
+	}
+	return list
+}
+
+// renderYAML writes v as block-style YAML with the same keys, in the same
+// order, as its JSON.
+func renderYAML(w io.Writer, v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	blockStyle(&doc)
+
+	enc := yaml.NewEncoder(w)
+	enc.SetIndent(2)
+	if err := enc.Encode(&doc); err != nil {
+		return err
+	}
+	return enc.Close()
+}
+
+// blockStyle clears the flow and quoting styles JSON input carries.
+func blockStyle(n *yaml.Node) {
+	n.Style = 0
+	for _, c := range n.Content {
+		blockStyle(c)
+	}
+}
+
+func renderCSV(w io.Writer, r *Result) error {
+	if len(r.Columns) == 0 {
+		return fmt.Errorf("csv output is not available for %s results", r.Kind)
+	}
+	cw := csv.NewWriter(w)
+	header := make([]string, len(r.Columns))
+	for i, c := range r.Columns {
+		header[i] = c.Name
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, item := range items(r) {
+		row := make([]string, len(r.Columns))
+		for i, c := range r.Columns {
+			row[i] = c.Value(item)
+		}
+		if err := cw.Write(row); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// renderTable writes Columns as a markdown table.
+func renderTable(w io.Writer, r *Result) error {
+	if len(r.Columns) == 0 {
+		return fmt.Errorf("no text output for %s results", r.Kind)
+	}
+	cell := strings.NewReplacer("|", `\|`, "\n", " ")
+
+	var buf bytes.Buffer
+	header := make([]string, len(r.Columns))
+	rule := make([]string, len(r.Columns))
+	for i, c := range r.Columns {
+		header[i] = c.Name
+		rule[i] = "---"
+	}
+	fmt.Fprintf(&buf, "| %s |\n| %s |\n", strings.Join(header, " | "), strings.Join(rule, " | "))
+	for _, item := range items(r) {
+		row := make([]string, len(r.Columns))
+		for i, c := range r.Columns {
+			row[i] = cell.Replace(c.Value(item))
+		}
+		fmt.Fprintf(&buf, "| %s |\n", strings.Join(row, " | "))
+	}
+	_, err := w.Write(buf.Bytes())
+	return err
+}
+
+// templateFuncs are available to --template.
+var templateFuncs = template.FuncMap{
+	"join": strings.Join,
+	"json": func(v interface{}) (string, error) {
+		data, err := json.Marshal(v)
+		return string(data), err
+	},
+	"upper": strings.ToUpper,
+	"lower": strings.ToLower,
+}
+
+// renderTemplate executes tmpl for each item, one per line.
+func renderTemplate(w io.Writer, r *Result, tmpl string) error {
+	t, err := template.New("output").Funcs(templateFuncs).Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return fmt.Errorf("invalid template: %w", err)
+	}
+
+	var buf bytes.Buffer
+	for _, item := range items(r) {
+		if err := t.Execute(&buf, item); err != nil {
+			return fmt.Errorf("template: %w", err)
+		}
+		if buf.Len() > 0 && buf.Bytes()[buf.Len()-1] != '\n' {
+			buf.WriteByte('\n')
+		}
+	}
+	_, err = w.Write(buf.Bytes())
+	return err
+}
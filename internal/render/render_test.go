@@ -0,0 +1,147 @@
+package render
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+)
+
+type item struct {
+	ID   string   `json:"id"`
+	Tags []string `json:"tags"`
+	Note string   `json:"note,omitempty"`
+}
+
+func testResult() *Result {
+	return &Result{
+		Kind: "item",
+		Items: []item{
+			{ID: "a.one", Tags: []string{"x", "y"}, Note: "true"},
+			{ID: "a.two", Note: "has | pipe, and comma"},
+		},
+		Meta: map[string]interface{}{"total": 5},
+		Columns: []Column{
+			{Name: "id", Value: func(v interface{}) string { return v.(item).ID }},
+			{Name: "note", Value: func(v interface{}) string { return v.(item).Note }},
+		},
+		Text: func(w io.Writer) error {
+			_, err := fmt.Fprintln(w, "two items")
+			return err
+		},
+	}
+}
+
+func TestParseFormat(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Format
+	}{
+		{"", Text},
+		{"json", JSON},
+		{"NDJSON", NDJSON},
+		{"yml", YAML},
+		{"md", Markdown},
+		{"csv", CSV},
+		{"xml", XML},
+	}
+	for _, tt := range tests {
+		got, err := ParseFormat(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseFormat(%q) = %q, %v; want %q", tt.in, got, err, tt.want)
+		}
+	}
+	if _, err := ParseFormat("toml"); err == nil || !strings.Contains(err.Error(), "ndjson") {
+		t.Errorf("ParseFormat(toml) error = %v, want the list of formats", err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	tests := []struct {
+		opts Options
+		want string
+	}{
+		{Options{Format: Text}, "two items\n"},
+		{Options{Format: NDJSON}, `{"id":"a.one","tags":["x","y"],"note":"true"}` + "\n" + `{"id":"a.two","tags":null,"note":"has | pipe, and comma"}` + "\n"},
+		{Options{Format: CSV}, "id,note\na.one,true\na.two,\"has | pipe, and comma\"\n"},
+		{Options{Format: Markdown}, "| id | note |\n| --- | --- |\n| a.one | true |\n| a.two | has \\| pipe, and comma |\n"},
+		{Options{Template: "{{.ID}}: {{join .Tags \"+\"}}"}, "a.one: x+y\na.two: \n"},
+		{Options{Format: YAML}, `kind: item
+count: 2
+items:
+  - id: a.one
+    tags:
+      - x
+      - y
+    note: "true"
+  - id: a.two
+    tags: null
+    note: has | pipe, and comma
+meta:
+  total: 5
+`},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		if err := Render(&buf, testResult(), tt.opts); err != nil {
+			t.Errorf("%+v: %v", tt.opts, err)
+			continue
+		}
+		if got := buf.String(); got != tt.want {
+			t.Errorf("%+v:\ngot:\n%s\nwant:\n%s", tt.opts, got, tt.want)
+		}
+	}
+}
+
+func TestRenderJSONEnvelope(t *testing.T) {
+	var buf bytes.Buffer
+	if err := Render(&buf, testResult(), Options{Format: JSON}); err != nil {
+		t.Fatal(err)
+	}
+	var env struct {
+		Kind  string                   `json:"kind"`
+		Count int                      `json:"count"`
+		Items []map[string]interface{} `json:"items"`
+		Meta  map[string]interface{}   `json:"meta"`
+	}
+	if err := json.Unmarshal(buf.Bytes(), &env); err != nil {
+		t.Fatalf("unmarshal: %v\n%s", err, buf.String())
+	}
+	if env.Kind != "item" || env.Count != 2 || len(env.Items) != 2 || env.Meta["total"] != float64(5) {
+		t.Errorf("envelope = %+v", env)
+	}
+
+	// Empty results are an empty list, not null
+	buf.Reset()
+	if err := Render(&buf, &Result{Kind: "item"}, Options{Format: JSON}); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), `"items": []`) || strings.Contains(buf.String(), "meta") {
+		t.Errorf("empty envelope = %s", buf.String())
+	}
+}
+
+func TestRenderErrors(t *testing.T) {
+	tests := []struct {
+		opts Options
+		want string
+	}{
+		{Options{Template: "{{.ID"}, "invalid template"},
+		{Options{Template: "{{.Missing}}"}, "template:"},
+		{Options{Format: "toml"}, "unknown format"},
+		{Options{Format: XML}, "xml output is not available"},
+	}
+	for _, tt := range tests {
+		err := Render(io.Discard, testResult(), tt.opts)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("%+v: error = %v, want %q", tt.opts, err, tt.want)
+		}
+	}
+
+	noColumns := &Result{Kind: "thing", Items: []int{1}}
+	if err := Render(io.Discard, noColumns, Options{Format: CSV}); err == nil {
+		t.Error("csv without columns: expected error")
+	}
+}
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"net/http"
+
+	"github.com/justrnr500/pearls/internal/bundle"
+	"github.com/justrnr500/pearls/internal/render"
+)
+
+// contextRequest is the body of POST /v1/context. Its fields mirror the
+// flags of 'pearls context'.
+type contextRequest struct {
+	// IDs are pearl IDs, each optionally with #section,section.
+	IDs []string `json:"ids"`
+	// Paths are files, relative to the project root, matched against
+	// pearl globs.
+	Paths     []string `json:"paths"`
+	Scopes    []string `json:"scopes"`
+	AllScopes bool     `json:"all_scopes"`
+	Query     string   `json:"query"`
+	WithRefs  bool     `json:"with_refs"`
+	Brief     bool     `json:"brief"`
+	Sections  []string `json:"sections"`
+	TOC       bool     `json:"toc"`
+	// Format is json (the default), text, markdown, or xml.
+	Format string `json:"format"`
+}
+
+// context handles POST /v1/context: the content of the pearls named or
+// matched by the request, as agent context. JSON items carry a source
+// saying why each pearl was included; meta lists IDs that were not found
+// and embeds that could not be expanded.
+func (s *Server) context(w http.ResponseWriter, r *http.Request) error {
+	var req contextRequest
+	if err := readJSON(r, &req); err != nil {
+		return err
+	}
+	if len(req.IDs) == 0 && len(req.Paths) == 0 && len(req.Scopes) == 0 && req.Query == "" {
+		return errorf(http.StatusBadRequest, "at least one of ids, paths, scopes, or query must be given")
+	}
+	format := render.JSON
+	if req.Format != "" {
+		var err error
+		if format, err = render.ParseFormat(req.Format); err != nil {
+			return errorf(http.StatusBadRequest, "invalid format: %v", err)
+		}
+	}
+
+	where, err := s.compileQuery(req.Query)
+	if err != nil {
+		return err
+	}
+	scopes, err := s.scopeFilter(req.Scopes, req.AllScopes)
+	if err != nil {
+		return err
+	}
+	b, err := bundle.Assemble(s.store, bundle.Request{
+		IDs:      req.IDs,
+		Paths:    req.Paths,
+		Scopes:   scopes,
+		Where:    where,
+		WithRefs: req.WithRefs,
+		Brief:    req.Brief,
+		Sections: req.Sections,
+		Config:   s.opts.Config.Context,
+	})
+	if err != nil {
+		return err
+	}
+
+	opts := render.DocumentOptions{Brief: req.Brief, TOC: req.TOC}
+	switch format {
+	case render.JSON:
+		return writeResult(w, &render.Result{
+			Kind:  "pearl",
+			Items: b.Documents,
+			Meta:  map[string]interface{}{"missing": b.Missing, "warnings": b.Warnings},
+		})
+	case render.Text:
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		return render.WriteDocuments(w, b.Documents, opts)
+	case render.Markdown:
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		return render.WriteMarkdown(w, b.Documents, opts)
+	case render.XML:
+		w.Header().Set("Content-Type", "application/xml; charset=utf-8")
+		return render.WriteXML(w, b.Documents, opts)
+	}
+	return errorf(http.StatusBadRequest, "format %s is not available for context; use json, text, markdown, or xml", format)
+}
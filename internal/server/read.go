@@ -0,0 +1,230 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/justrnr500/pearls/internal/bundle"
+	"github.com/justrnr500/pearls/internal/markdown"
+	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/render"
+	"github.com/justrnr500/pearls/internal/storage"
+)
+
+// listPearls handles GET /v1/pearls. Filters match the flags of
+// 'pearls list': namespace, type, status, tag, scope (with all_scopes),
+// required, q, sort, limit, offset, and cursor.
+func (s *Server) listPearls(w http.ResponseWriter, r *http.Request) error {
+	params := r.URL.Query()
+	opts := storage.ListOptions{
+		Namespace: params.Get("namespace"),
+		Type:      params.Get("type"),
+		Status:    params.Get("status"),
+		Tag:       params.Get("tag"),
+		Cursor:    params.Get("cursor"),
+	}
+
+	var err error
+	if opts.Scopes, err = s.scopeFilter(bundle.SplitList(params.Get("scope")), params.Get("all_scopes") == "true"); err != nil {
+		return err
+	}
+	if opts.Where, err = s.compileQuery(params.Get("q")); err != nil {
+		return err
+	}
+	if opts.Sort, err = storage.ParseSort(params.Get("sort")); err != nil {
+		return errorf(http.StatusBadRequest, "invalid sort: %v", err)
+	}
+	if opts.Limit, err = intParam(params.Get("limit")); err != nil {
+		return err
+	}
+	if opts.Offset, err = intParam(params.Get("offset")); err != nil {
+		return err
+	}
+	if v := params.Get("required"); v != "" {
+		required, err := strconv.ParseBool(v)
+		if err != nil {
+			return errorf(http.StatusBadRequest, "invalid required %q: use true or false", v)
+		}
+		opts.Required = &required
+	}
+
+	page, err := s.store.ListPage(opts)
+	if err != nil {
+		return fmt.Errorf("list pearls: %w", err)
+	}
+	meta := map[string]interface{}{"total": page.Total}
+	if page.NextCursor != "" {
+		meta["next_cursor"] = page.NextCursor
+	}
+	return writeResult(w, &render.Result{Kind: "pearl", Items: page.Pearls, Meta: meta})
+}
+
+// getPearl handles GET /v1/pearls/{id}.
+func (s *Server) getPearl(w http.ResponseWriter, r *http.Request) error {
+	p, err := s.pearl(r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	return writeJSON(w, http.StatusOK, p)
+}
+
+// getContent handles GET /v1/pearls/{id}/content: the pearl's markdown
+// with embeds expanded, unless raw=true. sections=a,b returns only those
+// sections.
+func (s *Server) getContent(w http.ResponseWriter, r *http.Request) error {
+	p, err := s.pearl(r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+	content, err := s.store.GetContent(p)
+	if err != nil {
+		return fmt.Errorf("read content: %w", err)
+	}
+	if sections := bundle.SplitList(r.URL.Query().Get("sections")); len(sections) > 0 {
+		var missing []string
+		content, missing = markdown.Extract(content, sections)
+		if content == "" {
+			return errorf(http.StatusNotFound, "%s has no section %s", p.ID, strings.Join(missing, ", "))
+		}
+	}
+	if r.URL.Query().Get("raw") != "true" {
+		content, _ = bundle.Transclude(s.store, p.ID, content)
+	}
+	w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+	_, err = w.Write([]byte(content))
+	return err
+}
+
+// refEntry is one reference to or from a pearl, as 'pearls refs --json'
+// reports it.
+type refEntry struct {
+	Direction   string   `json:"direction"`
+	ID          string   `json:"id"`
+	Found       bool     `json:"found"`
+	Type        string   `json:"type,omitempty"`
+	Description string   `json:"description,omitempty"`
+	Via         []string `json:"via"`
+}
+
+// getRefs handles GET /v1/pearls/{id}/refs: what the pearl references,
+// links to, or embeds, and what does so to it.
+func (s *Server) getRefs(w http.ResponseWriter, r *http.Request) error {
+	p, err := s.pearl(r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	var entries []refEntry
+	add := func(direction, id, via string) {
+		for i := range entries {
+			e := &entries[i]
+			if e.Direction == direction && e.ID == id {
+				for _, v := range e.Via {
+					if v == via {
+						return
+					}
+				}
+				e.Via = append(e.Via, via)
+				return
+			}
+		}
+		entries = append(entries, refEntry{Direction: direction, ID: id, Via: []string{via}})
+	}
+
+	for _, ref := range p.References {
+		add("outgoing", ref, "reference")
+	}
+	links, err := s.store.Links(p)
+	if err != nil {
+		return fmt.Errorf("read links: %w", err)
+	}
+	for _, l := range links {
+		add("outgoing", l.Target, l.Via())
+	}
+
+	referencing, err := s.store.DB().FindReferencingPearls(p.ID)
+	if err != nil {
+		return fmt.Errorf("find referencing pearls: %w", err)
+	}
+	for _, ref := range referencing {
+		add("incoming", ref, "reference")
+	}
+	graph, err := s.store.LinkGraph()
+	if err != nil {
+		return fmt.Errorf("find backlinks: %w", err)
+	}
+	from := make([]string, 0, len(graph))
+	for id := range graph {
+		from = append(from, id)
+	}
+	sort.Strings(from)
+	for _, id := range from {
+		for _, l := range graph[id] {
+			if l.Target == p.ID {
+				add("incoming", id, l.Via())
+			}
+		}
+	}
+
+	for i := range entries {
+		if ref, _ := s.store.Get(entries[i].ID); ref != nil {
+			entries[i].Found, entries[i].Type, entries[i].Description = true, string(ref.Type), ref.Description
+		}
+	}
+	return writeResult(w, &render.Result{Kind: "reference", Items: entries, Meta: map[string]interface{}{"id": p.ID}})
+}
+
+// search handles GET /v1/search?q=keyword, matching IDs, names,
+// namespaces, descriptions, and tags.
+func (s *Server) search(w http.ResponseWriter, r *http.Request) error {
+	keyword := strings.TrimSpace(r.URL.Query().Get("q"))
+	if keyword == "" {
+		return errorf(http.StatusBadRequest, "missing q parameter")
+	}
+	limit, err := intParam(r.URL.Query().Get("limit"))
+	if err != nil {
+		return err
+	}
+	results, err := s.store.Search(keyword, limit)
+	if err != nil {
+		return fmt.Errorf("search: %w", err)
+	}
+	return writeResult(w, &render.Result{Kind: "pearl", Items: results, Meta: map[string]interface{}{"query": keyword}})
+}
+
+// pearl returns the pearl with id, or a not-found error.
+func (s *Server) pearl(id string) (*pearl.Pearl, error) {
+	p, err := s.store.Get(id)
+	if err != nil {
+		return nil, fmt.Errorf("get pearl: %w", err)
+	}
+	if p == nil {
+		return nil, errorf(http.StatusNotFound, "pearl not found: %s", id)
+	}
+	return p, nil
+}
+
+// scopeFilter expands scopes through the registry into a storage filter:
+// any of them, or with all, every one.
+func (s *Server) scopeFilter(names []string, all bool) ([][]string, error) {
+	scopes, err := s.opts.Config.Scopes.Filter(names, all)
+	if err != nil {
+		return nil, errorf(http.StatusBadRequest, "invalid scope: %v", err)
+	}
+	return scopes, nil
+}
+
+// intParam parses a non-negative integer parameter; empty is zero.
+func intParam(v string) (int, error) {
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, errorf(http.StatusBadRequest, "invalid number %q", v)
+	}
+	return n, nil
+}
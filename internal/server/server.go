@@ -0,0 +1,272 @@
+// Package server serves the catalog over HTTP as a JSON API, for tools
+// that would otherwise shell out to the CLI. Lists use the same envelope
+// as the CLI's --json output. Responses to GET requests carry an ETag, so
+// clients can poll cheaply with If-None-Match. Writes are refused unless
+// the server is started with them enabled.
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/query"
+	"github.com/justrnr500/pearls/internal/render"
+	"github.com/justrnr500/pearls/internal/storage"
+)
+
+// maxBodyBytes limits request bodies.
+const maxBodyBytes = 4 << 20
+
+// Options configures a Server.
+type Options struct {
+	// Write enables creating, updating, and deleting pearls.
+	Write bool
+	// Config supplies the scope registry and context settings; nil uses
+	// the defaults.
+	Config *config.Config
+	// Log, if set, receives a line per request.
+	Log *log.Logger
+}
+
+// Server handles API requests against a store.
+type Server struct {
+	store *storage.Store
+	opts  Options
+	mux   *http.ServeMux
+	// mu serializes changes to the store, including rebuilds from JSONL.
+	mu sync.Mutex
+}
+
+// New returns a Server for store.
+func New(store *storage.Store, opts Options) *Server {
+	if opts.Config == nil {
+		opts.Config = config.Default()
+	}
+	s := &Server{store: store, opts: opts, mux: http.NewServeMux()}
+
+	s.handle("GET /v1/pearls", s.listPearls)
+	s.handle("GET /v1/pearls/{id}", s.getPearl)
+	s.handle("GET /v1/pearls/{id}/content", s.getContent)
+	s.handle("GET /v1/pearls/{id}/refs", s.getRefs)
+	s.handle("GET /v1/search", s.search)
+	s.handle("POST /v1/context", s.context)
+	s.handle("POST /v1/pearls", s.writes(s.createPearl))
+	s.handle("PATCH /v1/pearls/{id}", s.writes(s.updatePearl))
+	s.handle("DELETE /v1/pearls/{id}", s.writes(s.deletePearl))
+	s.handle("/", func(w http.ResponseWriter, r *http.Request) error {
+		if allow := s.allowed(r); len(allow) > 0 {
+			w.Header().Set("Allow", strings.Join(allow, ", "))
+			return errorf(http.StatusMethodNotAllowed, "%s is not allowed on %s; use %s", r.Method, r.URL.Path, strings.Join(allow, ", "))
+		}
+		return errorf(http.StatusNotFound, "no such endpoint: %s %s", r.Method, r.URL.Path)
+	})
+	return s
+}
+
+// allowed returns the methods routed for r's path, other than to the
+// catch-all, or nil if the path is not an endpoint.
+func (s *Server) allowed(r *http.Request) []string {
+	var methods []string
+	for _, m := range []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPatch, http.MethodDelete} {
+		probe := r.WithContext(r.Context())
+		probe.Method = m
+		if _, pattern := s.mux.Handler(probe); pattern != "/" {
+			methods = append(methods, m)
+		}
+	}
+	return methods
+}
+
+// ServeHTTP implements http.Handler.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+	rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+	s.mux.ServeHTTP(rec, r)
+	if s.opts.Log != nil {
+		s.opts.Log.Printf("%s %s %d %s", r.Method, r.URL.RequestURI(), rec.status, time.Since(start).Round(time.Microsecond))
+	}
+}
+
+// handlerFunc is a handler that may fail with an error, which is written
+// as a JSON error body.
+type handlerFunc func(w http.ResponseWriter, r *http.Request) error
+
+func (s *Server) handle(pattern string, h handlerFunc) {
+	s.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		// Pick up changes to pearls.jsonl, e.g. from a git pull
+		s.mu.Lock()
+		_, err := s.store.EnsureFresh()
+		s.mu.Unlock()
+		if err != nil && s.opts.Log != nil {
+			s.opts.Log.Printf("Warning: database may be stale: %v", err)
+		}
+
+		if r.Method != http.MethodGet && r.Method != http.MethodHead {
+			if err := h(w, r); err != nil {
+				writeError(w, err)
+			}
+			return
+		}
+
+		// Buffer GET responses to tag them
+		buf := &bufferedWriter{header: w.Header(), status: http.StatusOK}
+		if err := h(buf, r); err != nil {
+			writeError(w, err)
+			return
+		}
+		if buf.status == http.StatusOK {
+			sum := sha256.Sum256(buf.body.Bytes())
+			etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+			w.Header().Set("ETag", etag)
+			w.Header().Set("Cache-Control", "no-cache")
+			if matchETag(r.Header.Get("If-None-Match"), etag) {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		w.WriteHeader(buf.status)
+		if r.Method != http.MethodHead {
+			w.Write(buf.body.Bytes())
+		}
+	})
+}
+
+// writes guards a handler that changes the store: it is refused unless
+// writes are enabled, and runs alone. Browsers send simple cross-site
+// requests without a CORS preflight, so a write must not come from another
+// origin, and its body must be JSON, which a cross-site form cannot send.
+func (s *Server) writes(h handlerFunc) handlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) error {
+		if !s.opts.Write {
+			return errorf(http.StatusForbidden, "the server is read-only; start it with --write to allow changes")
+		}
+		if origin := r.Header.Get("Origin"); origin != "" && !sameOrigin(origin, r.Host) {
+			return errorf(http.StatusForbidden, "cross-origin writes are not allowed (Origin %s)", origin)
+		}
+		if r.Method != http.MethodDelete && !isJSON(r.Header.Get("Content-Type")) {
+			return errorf(http.StatusUnsupportedMediaType, "request body must be application/json")
+		}
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		return h(w, r)
+	}
+}
+
+// sameOrigin reports whether an Origin header names the host the request
+// was sent to.
+func sameOrigin(origin, host string) bool {
+	u, err := url.Parse(origin)
+	return err == nil && u.Host != "" && strings.EqualFold(u.Host, host)
+}
+
+// isJSON reports whether a Content-Type header is application/json.
+func isJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	return err == nil && mediaType == "application/json"
+}
+
+// matchETag reports whether an If-None-Match header matches etag.
+func matchETag(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimPrefix(strings.TrimSpace(candidate), "W/")
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// apiError is an error with an HTTP status.
+type apiError struct {
+	Status  int    `json:"status"`
+	Message string `json:"message"`
+}
+
+func (e *apiError) Error() string {
+	return e.Message
+}
+
+func errorf(status int, format string, args ...interface{}) error {
+	return &apiError{Status: status, Message: fmt.Sprintf(format, args...)}
+}
+
+// writeError writes err as {"error": {"status": ..., "message": ...}}.
+// Errors without a status are internal.
+func writeError(w http.ResponseWriter, err error) {
+	var e *apiError
+	if !errors.As(err, &e) {
+		e = &apiError{Status: http.StatusInternalServerError, Message: err.Error()}
+	}
+	w.Header().Del("ETag")
+	writeJSON(w, e.Status, map[string]interface{}{"error": e})
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// writeResult writes a result in the CLI's JSON envelope.
+func writeResult(w http.ResponseWriter, r *render.Result) error {
+	w.Header().Set("Content-Type", "application/json")
+	return render.Render(w, r, render.Options{Format: render.JSON})
+}
+
+// readJSON decodes a request body into v, rejecting unknown fields.
+func readJSON(r *http.Request, v interface{}) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return errorf(http.StatusBadRequest, "invalid request body: %v", err)
+	}
+	return nil
+}
+
+// compileQuery compiles a query, expanding scopes through the registry.
+func (s *Server) compileQuery(q string) (*query.Clause, error) {
+	if strings.TrimSpace(q) == "" {
+		return nil, nil
+	}
+	clause, err := query.ParseAndCompile(q, query.Options{ExpandScope: s.opts.Config.Scopes.Expand})
+	if err != nil {
+		return nil, errorf(http.StatusBadRequest, "invalid query: %v", err)
+	}
+	return clause, nil
+}
+
+// bufferedWriter holds a response until the handler finishes.
+type bufferedWriter struct {
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func (b *bufferedWriter) Header() http.Header         { return b.header }
+func (b *bufferedWriter) Write(p []byte) (int, error) { return b.body.Write(p) }
+func (b *bufferedWriter) WriteHeader(status int)      { b.status = status }
+
+// statusRecorder remembers the status written, for the request log.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (s *statusRecorder) WriteHeader(status int) {
+	s.status = status
+	s.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,416 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/storage"
+)
+
+func newTestServer(t *testing.T, opts Options) (*httptest.Server, *storage.Store) {
+	t.Helper()
+	dir := t.TempDir()
+	store, err := storage.NewStore(filepath.Join(dir, "pearls.db"), filepath.Join(dir, "pearls.jsonl"), filepath.Join(dir, "content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { store.Close() })
+
+	srv := httptest.NewServer(New(store, opts))
+	t.Cleanup(srv.Close)
+	return srv, store
+}
+
+func do(t *testing.T, srv *httptest.Server, method, path, body string, header ...string) (*http.Response, string) {
+	t.Helper()
+	req, err := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i+1 < len(header); i += 2 {
+		req.Header.Set(header[i], header[i+1])
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return resp, string(data)
+}
+
+// envelope is the JSON shape of list results.
+type envelope struct {
+	Kind  string                   `json:"kind"`
+	Count int                      `json:"count"`
+	Items []map[string]interface{} `json:"items"`
+	Meta  map[string]interface{}   `json:"meta"`
+}
+
+func decode(t *testing.T, body string) envelope {
+	t.Helper()
+	var e envelope
+	if err := json.Unmarshal([]byte(body), &e); err != nil {
+		t.Fatalf("decode %q: %v", body, err)
+	}
+	return e
+}
+
+func ids(e envelope) string {
+	var ids []string
+	for _, item := range e.Items {
+		ids = append(ids, item["id"].(string))
+	}
+	return strings.Join(ids, ",")
+}
+
+func TestListAndGet(t *testing.T) {
+	srv, store := newTestServer(t, Options{})
+
+	now := time.Now()
+	for _, p := range []*pearl.Pearl{
+		{ID: "db.users", Name: "users", Namespace: "db", Type: pearl.TypeTable, Status: pearl.StatusActive,
+			Description: "User accounts", Tags: []string{"pii"}, Scopes: []string{"backend"}, CreatedAt: now, UpdatedAt: now},
+		{ID: "db.orders", Name: "orders", Namespace: "db", Type: pearl.TypeTable, Status: pearl.StatusActive,
+			Description: "Orders", CreatedAt: now, UpdatedAt: now},
+		{ID: "api.billing", Name: "billing", Namespace: "api", Type: pearl.TypeAPI, Status: pearl.StatusActive,
+			Description: "Billing API", Scopes: []string{"payments"}, CreatedAt: now, UpdatedAt: now},
+	} {
+		if err := store.Create(p, "# "+p.Name+"\n"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/v1/pearls?sort=id", "api.billing,db.orders,db.users"},
+		{"/v1/pearls?namespace=db&sort=id", "db.orders,db.users"},
+		{"/v1/pearls?type=api", "api.billing"},
+		{"/v1/pearls?tag=pii", "db.users"},
+		{"/v1/pearls?scope=payments,backend&sort=id", "api.billing,db.users"},
+		{"/v1/pearls?q=type:table%20-tag:pii", "db.orders"},
+		{"/v1/pearls?sort=id&limit=1&offset=1", "db.orders"},
+	}
+	for _, tt := range tests {
+		resp, body := do(t, srv, "GET", tt.path, "")
+		if resp.StatusCode != http.StatusOK {
+			t.Errorf("%s: status %d: %s", tt.path, resp.StatusCode, body)
+			continue
+		}
+		if got := ids(decode(t, body)); got != tt.want {
+			t.Errorf("%s = %s, want %s", tt.path, got, tt.want)
+		}
+	}
+
+	resp, body := do(t, srv, "GET", "/v1/pearls?sort=id&limit=1", "")
+	if e := decode(t, body); e.Kind != "pearl" || e.Count != 1 || e.Meta["total"] != 3.0 || resp.Header.Get("Content-Type") != "application/json" {
+		t.Errorf("envelope = %+v", e)
+	}
+
+	_, body = do(t, srv, "GET", "/v1/pearls/db.users", "")
+	var p pearl.Pearl
+	if err := json.Unmarshal([]byte(body), &p); err != nil || p.ID != "db.users" || p.Description != "User accounts" {
+		t.Errorf("get = %s", body)
+	}
+
+	_, body = do(t, srv, "GET", "/v1/search?q=billing", "")
+	if got := ids(decode(t, body)); got != "api.billing" {
+		t.Errorf("search = %s", got)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	srv, _ := newTestServer(t, Options{})
+
+	tests := []struct {
+		method, path, body string
+		status             int
+		message            string
+	}{
+		{"GET", "/v1/pearls/nope", "", http.StatusNotFound, "pearl not found: nope"},
+		{"GET", "/v1/pearls?q=type:", "", http.StatusBadRequest, "invalid query"},
+		{"GET", "/v1/pearls?sort=colour", "", http.StatusBadRequest, "invalid sort"},
+		{"GET", "/v1/pearls?limit=-1", "", http.StatusBadRequest, "invalid number"},
+		{"GET", "/v1/search", "", http.StatusBadRequest, "missing q"},
+		{"GET", "/v1/nothing", "", http.StatusNotFound, "no such endpoint"},
+		{"PUT", "/v1/pearls/db.users", "{}", http.StatusMethodNotAllowed, "PUT is not allowed"},
+		{"GET", "/v1/context", "", http.StatusMethodNotAllowed, "use POST"},
+		{"POST", "/v1/context", "{}", http.StatusBadRequest, "at least one of"},
+		{"POST", "/v1/context", `{"idz": ["x"]}`, http.StatusBadRequest, "invalid request body"},
+		{"POST", "/v1/pearls", `{"id": "db.new"}`, http.StatusForbidden, "read-only"},
+		{"DELETE", "/v1/pearls/db.users", "", http.StatusForbidden, "read-only"},
+	}
+	for _, tt := range tests {
+		resp, body := do(t, srv, tt.method, tt.path, tt.body)
+		var e struct {
+			Error apiError `json:"error"`
+		}
+		if err := json.Unmarshal([]byte(body), &e); err != nil {
+			t.Errorf("%s %s: body %q is not a JSON error", tt.method, tt.path, body)
+			continue
+		}
+		if resp.StatusCode != tt.status || e.Error.Status != tt.status || !strings.Contains(e.Error.Message, tt.message) {
+			t.Errorf("%s %s = %d %+v, want %d %q", tt.method, tt.path, resp.StatusCode, e.Error, tt.status, tt.message)
+		}
+	}
+}
+
+func TestMethodNotAllowed(t *testing.T) {
+	srv, _ := newTestServer(t, Options{})
+
+	tests := []struct {
+		method, path string
+		status       int
+		allow        string
+	}{
+		{"PUT", "/v1/pearls/db.users", http.StatusMethodNotAllowed, "GET, HEAD, PATCH, DELETE"},
+		{"DELETE", "/v1/pearls", http.StatusMethodNotAllowed, "GET, HEAD, POST"},
+		{"POST", "/v1/search", http.StatusMethodNotAllowed, "GET, HEAD"},
+		{"PUT", "/v1/nothing", http.StatusNotFound, ""},
+		// Writes to a read-only server are refused, not unrouted
+		{"POST", "/v1/pearls", http.StatusForbidden, ""},
+	}
+	for _, tt := range tests {
+		resp, body := do(t, srv, tt.method, tt.path, "{}", "Content-Type", "application/json")
+		if resp.StatusCode != tt.status || resp.Header.Get("Allow") != tt.allow {
+			t.Errorf("%s %s = %d with Allow %q, want %d with %q", tt.method, tt.path, resp.StatusCode, resp.Header.Get("Allow"), tt.status, tt.allow)
+		}
+		if !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/json") {
+			t.Errorf("%s %s: body %q is not JSON", tt.method, tt.path, body)
+		}
+	}
+}
+
+func TestETag(t *testing.T) {
+	srv, store := newTestServer(t, Options{})
+
+	now := time.Now()
+	users := &pearl.Pearl{ID: "db.users", Name: "users", Namespace: "db", Type: pearl.TypeTable, Status: pearl.StatusActive, CreatedAt: now, UpdatedAt: now}
+	if err := store.Create(users, "# Users\n"); err != nil {
+		t.Fatal(err)
+	}
+
+	resp, _ := do(t, srv, "GET", "/v1/pearls/db.users", "")
+	etag := resp.Header.Get("ETag")
+	if etag == "" {
+		t.Fatal("no ETag")
+	}
+
+	resp, body := do(t, srv, "GET", "/v1/pearls/db.users", "", "If-None-Match", etag)
+	if resp.StatusCode != http.StatusNotModified || body != "" {
+		t.Errorf("unchanged = %d %q, want 304", resp.StatusCode, body)
+	}
+
+	p, _ := store.Get("db.users")
+	p.Description = "Changed"
+	if err := store.Update(p, nil); err != nil {
+		t.Fatal(err)
+	}
+	resp, _ = do(t, srv, "GET", "/v1/pearls/db.users", "", "If-None-Match", etag)
+	if resp.StatusCode != http.StatusOK || resp.Header.Get("ETag") == etag {
+		t.Errorf("changed = %d with ETag %s, want 200 and a new ETag", resp.StatusCode, resp.Header.Get("ETag"))
+	}
+}
+
+func TestContentAndRefs(t *testing.T) {
+	srv, store := newTestServer(t, Options{})
+
+	now := time.Now()
+	for _, c := range []struct {
+		p       *pearl.Pearl
+		content string
+	}{
+		{&pearl.Pearl{ID: "db.users", Name: "users", Namespace: "db", Type: pearl.TypeTable, Status: pearl.StatusActive, CreatedAt: now, UpdatedAt: now},
+			"# Users\n\n## Columns\n\n- id\n\n## Notes\n\nSoft-deleted.\n"},
+		{&pearl.Pearl{ID: "db.orders", Name: "orders", Namespace: "db", Type: pearl.TypeTable, Status: pearl.StatusActive,
+			References: []string{"db.users"}, CreatedAt: now, UpdatedAt: now},
+			"# Orders\n\n![[db.users#Columns]]\n"},
+		{&pearl.Pearl{ID: "api.billing", Name: "billing", Namespace: "api", Type: pearl.TypeAPI, Status: pearl.StatusActive, CreatedAt: now, UpdatedAt: now},
+			"# Billing\n\nSee [[db.orders]].\n"},
+	} {
+		if err := store.Create(c.p, c.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp, body := do(t, srv, "GET", "/v1/pearls/db.orders/content", "")
+	if !strings.Contains(body, "- id") || strings.Contains(body, "![[") || !strings.HasPrefix(resp.Header.Get("Content-Type"), "text/markdown") {
+		t.Errorf("content = %q", body)
+	}
+	_, body = do(t, srv, "GET", "/v1/pearls/db.orders/content?raw=true", "")
+	if !strings.Contains(body, "![[db.users#Columns]]") {
+		t.Errorf("raw content = %q", body)
+	}
+	_, body = do(t, srv, "GET", "/v1/pearls/db.users/content?sections=notes", "")
+	if body != "## Notes\n\nSoft-deleted.\n" {
+		t.Errorf("section = %q", body)
+	}
+	resp, _ = do(t, srv, "GET", "/v1/pearls/db.users/content?sections=indexes", "")
+	if resp.StatusCode != http.StatusNotFound {
+		t.Errorf("missing section = %d, want 404", resp.StatusCode)
+	}
+
+	_, body = do(t, srv, "GET", "/v1/pearls/db.orders/refs", "")
+	e := decode(t, body)
+	var got []string
+	for _, item := range e.Items {
+		var via []string
+		for _, v := range item["via"].([]interface{}) {
+			via = append(via, v.(string))
+		}
+		got = append(got, item["direction"].(string)+":"+item["id"].(string)+":"+strings.Join(via, "+"))
+	}
+	if want := "outgoing:db.users:reference+embed incoming:api.billing:link"; strings.Join(got, " ") != want {
+		t.Errorf("refs = %v, want %s", got, want)
+	}
+}
+
+func TestContext(t *testing.T) {
+	srv, store := newTestServer(t, Options{})
+
+	now := time.Now()
+	for _, c := range []struct {
+		p       *pearl.Pearl
+		content string
+	}{
+		{&pearl.Pearl{ID: "db.users", Name: "users", Namespace: "db", Type: pearl.TypeTable, Status: pearl.StatusActive,
+			Globs: []string{"src/users/**"}, CreatedAt: now, UpdatedAt: now},
+			"# Users\n\n## Notes\n\nSoft-deleted.\n"},
+		{&pearl.Pearl{ID: "db.orders", Name: "orders", Namespace: "db", Type: pearl.TypeTable, Status: pearl.StatusActive,
+			References: []string{"db.users"}, CreatedAt: now, UpdatedAt: now},
+			"# Orders\n"},
+		{&pearl.Pearl{ID: "api.billing", Name: "billing", Namespace: "api", Type: pearl.TypeAPI, Status: pearl.StatusActive,
+			Scopes: []string{"payments"}, CreatedAt: now, UpdatedAt: now},
+			"# Billing\n"},
+	} {
+		if err := store.Create(c.p, c.content); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	resp, body := do(t, srv, "POST", "/v1/context", `{"ids": ["db.users#notes", "nope"], "paths": ["src/users/model.go"], "scopes": ["payments"]}`)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("status %d: %s", resp.StatusCode, body)
+	}
+	e := decode(t, body)
+	if got := ids(e); got != "db.users,api.billing" {
+		t.Errorf("ids = %s", got)
+	}
+	if src := e.Items[0]["source"].([]interface{}); len(src) != 2 || src[0] != "id" || src[1] != "glob:src/users/**" {
+		t.Errorf("source = %v", src)
+	}
+	if content := e.Items[0]["content"].(string); content != "## Notes\n\nSoft-deleted.\n" {
+		t.Errorf("content = %q", content)
+	}
+	if missing := e.Meta["missing"].([]interface{}); len(missing) != 1 || missing[0] != "nope" {
+		t.Errorf("missing = %v", missing)
+	}
+
+	resp, body = do(t, srv, "POST", "/v1/context", `{"ids": ["db.orders"], "with_refs": true, "format": "xml"}`)
+	if !strings.Contains(body, `<pearl id="db.orders"`) || !strings.Contains(body, `source="ref:db.orders"`) || !strings.HasPrefix(resp.Header.Get("Content-Type"), "application/xml") {
+		t.Errorf("xml = %s", body)
+	}
+}
+
+// jsonType is the header writes must send.
+var jsonType = []string{"Content-Type", "application/json"}
+
+func TestWrites(t *testing.T) {
+	srv, store := newTestServer(t, Options{Write: true})
+
+	resp, body := do(t, srv, "POST", "/v1/pearls", `{"id": "db.invoices", "description": "Invoices", "tags": ["billing"], "content": "# Invoices\n"}`, jsonType...)
+	if resp.StatusCode != http.StatusCreated || resp.Header.Get("Location") != "/v1/pearls/db.invoices" {
+		t.Fatalf("create = %d: %s", resp.StatusCode, body)
+	}
+	p, _ := store.Get("db.invoices")
+	if p == nil || p.Type != pearl.TypeTable || p.Namespace != "db" || p.CreatedBy != "api" {
+		t.Fatalf("created = %+v", p)
+	}
+	if content, _ := store.GetContent(p); content != "# Invoices\n" {
+		t.Errorf("content = %q", content)
+	}
+
+	resp, _ = do(t, srv, "POST", "/v1/pearls", `{"id": "db.invoices"}`, jsonType...)
+	if resp.StatusCode != http.StatusConflict {
+		t.Errorf("duplicate create = %d, want 409", resp.StatusCode)
+	}
+	resp, _ = do(t, srv, "POST", "/v1/pearls", `{"id": "db.x", "type": "Not A Type"}`, jsonType...)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bad type = %d, want 400", resp.StatusCode)
+	}
+
+	resp, body = do(t, srv, "PATCH", "/v1/pearls/db.invoices", `{"status": "deprecated", "tags": [], "content": "# Invoices\n\nOld.\n"}`, jsonType...)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("update = %d: %s", resp.StatusCode, body)
+	}
+	p, _ = store.Get("db.invoices")
+	if p.Status != pearl.StatusDeprecated || len(p.Tags) != 0 || p.Description != "Invoices" {
+		t.Errorf("updated = %+v", p)
+	}
+	resp, _ = do(t, srv, "PATCH", "/v1/pearls/db.invoices", `{"status": "gone"}`, jsonType...)
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("bad status = %d, want 400", resp.StatusCode)
+	}
+
+	resp, _ = do(t, srv, "DELETE", "/v1/pearls/db.invoices", "")
+	if p, _ = store.Get("db.invoices"); resp.StatusCode != http.StatusOK || p.Status != pearl.StatusArchived {
+		t.Errorf("delete = %d, status %s, want archived", resp.StatusCode, p.Status)
+	}
+	resp, _ = do(t, srv, "DELETE", "/v1/pearls/db.invoices?force=true", "")
+	if p, _ = store.Get("db.invoices"); resp.StatusCode != http.StatusNoContent || p != nil {
+		t.Errorf("force delete = %d, pearl %v", resp.StatusCode, p)
+	}
+}
+
+func TestCrossSiteWrites(t *testing.T) {
+	srv, store := newTestServer(t, Options{Write: true})
+
+	now := time.Now()
+	users := &pearl.Pearl{ID: "db.users", Name: "users", Namespace: "db", Type: pearl.TypeTable, Status: pearl.StatusActive, CreatedAt: now, UpdatedAt: now}
+	if err := store.Create(users, "# Users\n"); err != nil {
+		t.Fatal(err)
+	}
+	body := `{"id": "evil.pwn"}`
+
+	// A page on another site can send this without a CORS preflight
+	resp, _ := do(t, srv, "POST", "/v1/pearls", body, "Content-Type", "text/plain")
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("text/plain body = %d, want 415", resp.StatusCode)
+	}
+	resp, _ = do(t, srv, "POST", "/v1/pearls", body)
+	if resp.StatusCode != http.StatusUnsupportedMediaType {
+		t.Errorf("no Content-Type = %d, want 415", resp.StatusCode)
+	}
+	resp, _ = do(t, srv, "POST", "/v1/pearls", body, "Content-Type", "application/json", "Origin", "https://evil.example")
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("cross-origin create = %d, want 403", resp.StatusCode)
+	}
+	resp, _ = do(t, srv, "DELETE", "/v1/pearls/db.users?force=true", "", "Origin", "https://evil.example")
+	if resp.StatusCode != http.StatusForbidden {
+		t.Errorf("cross-origin delete = %d, want 403", resp.StatusCode)
+	}
+	if p, _ := store.Get("evil.pwn"); p != nil {
+		t.Error("cross-site request created a pearl")
+	}
+	if p, _ := store.Get("db.users"); p == nil {
+		t.Error("cross-site request deleted a pearl")
+	}
+
+	// The server's own pages may write
+	resp, _ = do(t, srv, "POST", "/v1/pearls", body, "Content-Type", "application/json; charset=utf-8", "Origin", srv.URL)
+	if resp.StatusCode != http.StatusCreated {
+		t.Errorf("same-origin create = %d, want 201", resp.StatusCode)
+	}
+}
@@ -0,0 +1,200 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+// createRequest is the body of POST /v1/pearls.
+type createRequest struct {
+	ID          string   `json:"id"`
+	Type        string   `json:"type"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags"`
+	Globs       []string `json:"globs"`
+	Scopes      []string `json:"scopes"`
+	Owners      []string `json:"owners"`
+	References  []string `json:"references"`
+	Required    bool     `json:"required"`
+	Priority    int      `json:"priority"`
+	CreatedBy   string   `json:"created_by"`
+	// Content is the pearl's markdown; nil uses the template for its type.
+	Content *string `json:"content"`
+}
+
+// createPearl handles POST /v1/pearls. The type defaults to table, as in
+// 'pearls create'.
+func (s *Server) createPearl(w http.ResponseWriter, r *http.Request) error {
+	var req createRequest
+	if err := readJSON(r, &req); err != nil {
+		return err
+	}
+	if err := pearl.ValidateNamespace(req.ID); err != nil {
+		return errorf(http.StatusBadRequest, "invalid id %q: %v", req.ID, err)
+	}
+	if req.Type == "" {
+		req.Type = string(pearl.TypeTable)
+	}
+	if req.CreatedBy == "" {
+		req.CreatedBy = "api"
+	}
+
+	now := time.Now()
+	p := &pearl.Pearl{
+		ID:          req.ID,
+		Name:        pearl.LastSegment(req.ID),
+		Namespace:   pearl.ParentNamespace(req.ID),
+		Type:        pearl.AssetType(req.Type),
+		Tags:        req.Tags,
+		Globs:       req.Globs,
+		Scopes:      req.Scopes,
+		Owners:      req.Owners,
+		References:  req.References,
+		Description: req.Description,
+		Required:    req.Required,
+		Priority:    req.Priority,
+		Status:      pearl.StatusActive,
+		CreatedAt:   now,
+		UpdatedAt:   now,
+		CreatedBy:   req.CreatedBy,
+	}
+	if err := validate(p); err != nil {
+		return err
+	}
+
+	existing, err := s.store.Get(p.ID)
+	if err != nil {
+		return fmt.Errorf("check existing: %w", err)
+	}
+	if existing != nil {
+		return errorf(http.StatusConflict, "pearl %q already exists", p.ID)
+	}
+
+	content := s.store.Content().Template(p)
+	if req.Content != nil {
+		content = *req.Content
+	}
+	if err := s.store.Create(p, content); err != nil {
+		return fmt.Errorf("create pearl: %w", err)
+	}
+
+	w.Header().Set("Location", "/v1/pearls/"+url.PathEscape(p.ID))
+	return writeJSON(w, http.StatusCreated, p)
+}
+
+// updateRequest is the body of PATCH /v1/pearls/{id}. Only the fields
+// present are changed; a list replaces the old one.
+type updateRequest struct {
+	Description *string   `json:"description"`
+	Status      *string   `json:"status"`
+	Type        *string   `json:"type"`
+	Tags        *[]string `json:"tags"`
+	Globs       *[]string `json:"globs"`
+	Scopes      *[]string `json:"scopes"`
+	Owners      *[]string `json:"owners"`
+	References  *[]string `json:"references"`
+	Required    *bool     `json:"required"`
+	Priority    *int      `json:"priority"`
+	Content     *string   `json:"content"`
+}
+
+// updatePearl handles PATCH /v1/pearls/{id}.
+func (s *Server) updatePearl(w http.ResponseWriter, r *http.Request) error {
+	var req updateRequest
+	if err := readJSON(r, &req); err != nil {
+		return err
+	}
+	p, err := s.pearl(r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	if req.Description != nil {
+		p.Description = *req.Description
+	}
+	if req.Status != nil {
+		p.Status = pearl.Status(*req.Status)
+		if !p.Status.IsValid() {
+			return errorf(http.StatusBadRequest, "invalid status %q: must be active, deprecated, or archived", *req.Status)
+		}
+	}
+	if req.Type != nil {
+		p.Type = pearl.AssetType(*req.Type)
+	}
+	if req.Tags != nil {
+		p.Tags = *req.Tags
+	}
+	if req.Globs != nil {
+		p.Globs = *req.Globs
+	}
+	if req.Scopes != nil {
+		p.Scopes = *req.Scopes
+	}
+	if req.Owners != nil {
+		p.Owners = *req.Owners
+	}
+	if req.References != nil {
+		p.References = *req.References
+	}
+	if req.Required != nil {
+		p.Required = *req.Required
+	}
+	if req.Priority != nil {
+		p.Priority = *req.Priority
+	}
+	if err := validate(p); err != nil {
+		return err
+	}
+
+	p.UpdatedAt = time.Now()
+	if err := s.store.Update(p, req.Content); err != nil {
+		return fmt.Errorf("update pearl: %w", err)
+	}
+	return writeJSON(w, http.StatusOK, p)
+}
+
+// deletePearl handles DELETE /v1/pearls/{id}. Like 'pearls delete', it
+// archives the pearl unless force=true is given, which removes it and its
+// content.
+func (s *Server) deletePearl(w http.ResponseWriter, r *http.Request) error {
+	p, err := s.pearl(r.PathValue("id"))
+	if err != nil {
+		return err
+	}
+
+	if r.URL.Query().Get("force") != "true" {
+		p.Status = pearl.StatusArchived
+		p.UpdatedAt = time.Now()
+		if err := s.store.Update(p, nil); err != nil {
+			return fmt.Errorf("archive pearl: %w", err)
+		}
+		return writeJSON(w, http.StatusOK, p)
+	}
+
+	if err := s.store.Delete(p.ID); err != nil {
+		return fmt.Errorf("delete pearl: %w", err)
+	}
+	w.WriteHeader(http.StatusNoContent)
+	return nil
+}
+
+// validate checks the fields a client may set.
+func validate(p *pearl.Pearl) error {
+	if !p.Type.IsValid() {
+		return errorf(http.StatusBadRequest, "invalid type %q: must be lowercase alphanumeric + hyphens, starting with a letter", p.Type)
+	}
+	if err := pearl.ValidateGlobs(p.Globs); err != nil {
+		return errorf(http.StatusBadRequest, "invalid globs: %v", err)
+	}
+	if err := pearl.ValidateScopes(p.Scopes); err != nil {
+		return errorf(http.StatusBadRequest, "invalid scopes: %v", err)
+	}
+	if err := pearl.ValidateOwners(p.Owners); err != nil {
+		return errorf(http.StatusBadRequest, "invalid owners: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,64 @@
+// Client-side search over search.json. Every term must appear in a pearl's
+// ID, name, description, tags, scopes, or headings.
+(function () {
+  "use strict";
+
+  var script = document.currentScript;
+  var root = (script && script.dataset.root) || "";
+  var input = document.getElementById("search");
+  var results = document.getElementById("results");
+  if (!input || !results) {
+    return;
+  }
+
+  var index = null;
+
+  function haystack(p) {
+    return [p.id, p.name, p.namespace, p.type, p.description]
+      .concat(p.tags || [], p.scopes || [], p.headings || [])
+      .join(" ")
+      .toLowerCase();
+  }
+
+  function render(matches) {
+    results.textContent = "";
+    matches.slice(0, 50).forEach(function (p) {
+      var li = document.createElement("li");
+      var a = document.createElement("a");
+      a.href = root + p.url;
+      a.textContent = p.id;
+      li.appendChild(a);
+      var type = document.createElement("span");
+      type.className = "type";
+      type.textContent = " " + p.type;
+      li.appendChild(type);
+      if (p.description) {
+        li.appendChild(document.createTextNode(" — " + p.description));
+      }
+      results.appendChild(li);
+    });
+  }
+
+  function search() {
+    var terms = input.value.toLowerCase().split(/\s+/).filter(Boolean);
+    if (!index || terms.length === 0) {
+      results.textContent = "";
+      return;
+    }
+    render(index.filter(function (p) {
+      return terms.every(function (t) { return p.text.indexOf(t) >= 0; });
+    }));
+  }
+
+  fetch(root + "search.json")
+    .then(function (r) { return r.json(); })
+    .then(function (data) {
+      index = data.map(function (p) {
+        p.text = haystack(p);
+        return p;
+      });
+      search();
+    });
+
+  input.addEventListener("input", search);
+})();
@@ -0,0 +1,525 @@
+// Package site renders the catalog as a static HTML site: a page per pearl
+// with its content, references, and backlinks, namespace pages, index pages
+// by type, tag, and scope, a Mermaid graph of references, and a JSON index
+// for client-side search. The output has no server-side parts, so it can be
+// published from CI to any static host.
+package site
+
+import (
+	"bytes"
+	"embed"
+	"encoding/json"
+	"fmt"
+	"html"
+	"html/template"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/markdown"
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+//go:embed templates/*.html
+var templateFS embed.FS
+
+//go:embed assets/*
+var assetFS embed.FS
+
+// Entry is a pearl to publish.
+type Entry struct {
+	Pearl *pearl.Pearl
+	// Content is the pearl's markdown, with embeds expanded.
+	Content string
+	// Links are the wiki-links in the content as written.
+	Links []markdown.Link
+}
+
+// Options describes the site.
+type Options struct {
+	// Title is shown in the header of every page.
+	Title string
+	// Description is shown on the home page.
+	Description string
+}
+
+// Summary reports what Build wrote.
+type Summary struct {
+	Pearls int
+	Files  int
+}
+
+// Build writes the site for entries into dir, replacing files it wrote
+// before but leaving others alone.
+func Build(dir string, entries []Entry, opts Options) (*Summary, error) {
+	s := newSite(entries, opts)
+	files, err := s.files()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, fmt.Errorf("create directory: %w", err)
+		}
+		if err := os.WriteFile(path, files[name], 0644); err != nil {
+			return nil, fmt.Errorf("write %s: %w", name, err)
+		}
+	}
+	return &Summary{Pearls: len(entries), Files: len(files)}, nil
+}
+
+// site is the catalog arranged for publishing.
+type site struct {
+	opts     Options
+	entries  []Entry
+	byID     map[string]*pearl.Pearl
+	outgoing map[string]*refList
+	incoming map[string]*refList
+	root     *namespace
+}
+
+// namespace is a node of the namespace hierarchy.
+type namespace struct {
+	Name     string // full name, e.g. "db.postgres"; empty for the root
+	Segment  string // last segment, e.g. "postgres"
+	Children []*namespace
+	Pearls   []*pearl.Pearl
+}
+
+// ref is one reference, link, or embed between pearls.
+type ref struct {
+	ID    string
+	Via   []string
+	Pearl *pearl.Pearl // nil if the pearl does not exist
+}
+
+// refList collects refs in order, merging those to the same pearl.
+type refList struct {
+	refs  []*ref
+	index map[string]*ref
+}
+
+func (l *refList) add(id, via string, p *pearl.Pearl) {
+	if l.index == nil {
+		l.index = make(map[string]*ref)
+	}
+	r, ok := l.index[id]
+	if !ok {
+		r = &ref{ID: id, Pearl: p}
+		l.index[id] = r
+		l.refs = append(l.refs, r)
+	}
+	for _, v := range r.Via {
+		if v == via {
+			return
+		}
+	}
+	r.Via = append(r.Via, via)
+}
+
+func newSite(entries []Entry, opts Options) *site {
+	entries = append([]Entry(nil), entries...)
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Pearl.ID < entries[j].Pearl.ID })
+
+	s := &site{
+		opts:     opts,
+		entries:  entries,
+		byID:     make(map[string]*pearl.Pearl),
+		outgoing: make(map[string]*refList),
+		incoming: make(map[string]*refList),
+		root:     &namespace{},
+	}
+	for _, e := range entries {
+		s.byID[e.Pearl.ID] = e.Pearl
+		s.outgoing[e.Pearl.ID] = &refList{}
+		s.incoming[e.Pearl.ID] = &refList{}
+	}
+
+	for _, e := range entries {
+		p := e.Pearl
+		connect := func(to, via string) {
+			s.outgoing[p.ID].add(to, via, s.byID[to])
+			if in, ok := s.incoming[to]; ok {
+				in.add(p.ID, via, p)
+			}
+		}
+		for _, to := range p.References {
+			connect(to, "reference")
+		}
+		for _, l := range e.Links {
+			if l.Embed {
+				connect(l.Target, "embed")
+			} else {
+				connect(l.Target, "link")
+			}
+		}
+		s.namespace(p.Namespace).Pearls = append(s.namespace(p.Namespace).Pearls, p)
+	}
+	return s
+}
+
+// namespace returns the node for a namespace, creating it and its
+// ancestors as needed.
+func (s *site) namespace(name string) *namespace {
+	n := s.root
+	if name == "" {
+		return n
+	}
+	segments := strings.Split(name, ".")
+	for i, seg := range segments {
+		full := strings.Join(segments[:i+1], ".")
+		var child *namespace
+		for _, c := range n.Children {
+			if c.Name == full {
+				child = c
+				break
+			}
+		}
+		if child == nil {
+			child = &namespace{Name: full, Segment: seg}
+			n.Children = append(n.Children, child)
+			sort.Slice(n.Children, func(i, j int) bool { return n.Children[i].Name < n.Children[j].Name })
+		}
+		n = child
+	}
+	return n
+}
+
+// page is the data every template gets.
+type page struct {
+	Title   string
+	Heading string
+	// Root is the relative path from the page to the site root.
+	Root string
+	Nav  template.HTML
+	Data interface{}
+}
+
+// crumb is one step of a breadcrumb trail.
+type crumb struct {
+	Name string
+	URL  string
+}
+
+// group is one value of a type, tag, or scope index.
+type group struct {
+	Name   string
+	Pearls []*pearl.Pearl
+}
+
+// files renders every file of the site, by path.
+func (s *site) files() (map[string][]byte, error) {
+	files := make(map[string][]byte)
+	render := func(name, tmpl, heading string, data interface{}) error {
+		root := strings.Repeat("../", strings.Count(name, "/"))
+		out, err := s.render(tmpl, page{
+			Title: s.title(), Heading: heading, Root: root, Nav: s.nav(root), Data: data,
+		})
+		if err != nil {
+			return fmt.Errorf("render %s: %w", name, err)
+		}
+		files[name] = out
+		return nil
+	}
+
+	if err := render("index.html", "index.html", "", map[string]interface{}{
+		"Description": s.opts.Description,
+		"Count":       len(s.entries),
+		"Namespaces":  s.root.Children,
+		"Unsorted":    s.root.Pearls,
+		"Types":       s.groups(func(p *pearl.Pearl) []string { return []string{string(p.Type)} }),
+	}); err != nil {
+		return nil, err
+	}
+
+	for _, e := range s.entries {
+		p := e.Pearl
+		root := "../"
+		content := markdown.HTML(e.Content, markdown.HTMLOptions{WikiLink: func(l markdown.Link) string {
+			if s.byID[l.Target] == nil {
+				return ""
+			}
+			url := root + pearlURL(l.Target)
+			if l.Section != "" {
+				url += "#" + markdown.Slug(l.Section)
+			}
+			return url
+		}})
+		if err := render(pearlURL(p.ID), "pearl.html", p.ID, map[string]interface{}{
+			"Pearl":      p,
+			"Breadcrumb": s.breadcrumb(p.Namespace, root),
+			"Content":    template.HTML(content),
+			"Mermaid":    strings.Contains(content, `<pre class="mermaid">`),
+			"Outgoing":   s.outgoing[p.ID].refs,
+			"Incoming":   s.incoming[p.ID].refs,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	var walk func(n *namespace) error
+	walk = func(n *namespace) error {
+		for _, c := range n.Children {
+			if err := render(namespaceURL(c.Name), "namespace.html", c.Name, map[string]interface{}{
+				"Namespace":  c,
+				"Breadcrumb": s.breadcrumb(pearl.ParentNamespace(c.Name), "../"),
+			}); err != nil {
+				return err
+			}
+			if err := walk(c); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if err := walk(s.root); err != nil {
+		return nil, err
+	}
+
+	for _, facet := range []struct {
+		file, heading string
+		values        func(p *pearl.Pearl) []string
+	}{
+		{"types.html", "Types", func(p *pearl.Pearl) []string { return []string{string(p.Type)} }},
+		{"tags.html", "Tags", func(p *pearl.Pearl) []string { return p.Tags }},
+		{"scopes.html", "Scopes", func(p *pearl.Pearl) []string { return p.Scopes }},
+	} {
+		if err := render(facet.file, "index-page.html", facet.heading, s.groups(facet.values)); err != nil {
+			return nil, err
+		}
+	}
+
+	graph, edges := s.mermaid("../")
+	if err := render("graph.html", "graph.html", "Graph", map[string]interface{}{
+		"Mermaid": graph,
+		"Edges":   edges,
+	}); err != nil {
+		return nil, err
+	}
+	graph, _ = s.mermaid("")
+	files["graph.mmd"] = []byte(graph)
+
+	index, err := s.searchIndex()
+	if err != nil {
+		return nil, err
+	}
+	files["search.json"] = index
+
+	assets, err := assetFS.ReadDir("assets")
+	if err != nil {
+		return nil, err
+	}
+	for _, a := range assets {
+		data, err := assetFS.ReadFile("assets/" + a.Name())
+		if err != nil {
+			return nil, err
+		}
+		files["assets/"+a.Name()] = data
+	}
+	return files, nil
+}
+
+func (s *site) title() string {
+	if s.opts.Title != "" {
+		return s.opts.Title
+	}
+	return "Pearls"
+}
+
+// render executes a page template inside the layout.
+func (s *site) render(name string, p page) ([]byte, error) {
+	t, err := template.New(name).Funcs(templateFuncs).ParseFS(templateFS, "templates/layout.html", "templates/"+name)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := t.ExecuteTemplate(&buf, "layout", p); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+var templateFuncs = template.FuncMap{
+	"pearlURL":     pearlURL,
+	"namespaceURL": namespaceURL,
+	"join":         strings.Join,
+	"dict": func(pairs ...interface{}) map[string]interface{} {
+		m := make(map[string]interface{}, len(pairs)/2)
+		for i := 0; i+1 < len(pairs); i += 2 {
+			m[fmt.Sprint(pairs[i])] = pairs[i+1]
+		}
+		return m
+	},
+	"date": func(t time.Time) string {
+		return t.Format("2006-01-02")
+	},
+}
+
+func pearlURL(id string) string {
+	return "p/" + id + ".html"
+}
+
+func namespaceURL(name string) string {
+	return "ns/" + name + ".html"
+}
+
+// breadcrumb returns the trail from the home page to a namespace.
+func (s *site) breadcrumb(ns, root string) []crumb {
+	trail := []crumb{{Name: "home", URL: root + "index.html"}}
+	if ns == "" {
+		return trail
+	}
+	segments := strings.Split(ns, ".")
+	for i, seg := range segments {
+		trail = append(trail, crumb{Name: seg, URL: root + namespaceURL(strings.Join(segments[:i+1], "."))})
+	}
+	return trail
+}
+
+// nav renders the namespace tree for the sidebar.
+func (s *site) nav(root string) template.HTML {
+	var b strings.Builder
+	var walk func(ns []*namespace)
+	walk = func(ns []*namespace) {
+		if len(ns) == 0 {
+			return
+		}
+		b.WriteString("<ul>")
+		for _, n := range ns {
+			fmt.Fprintf(&b, `<li><a href="%s">%s</a> <span class="count">%d</span>`,
+				html.EscapeString(root+namespaceURL(n.Name)), html.EscapeString(n.Segment), n.total())
+			walk(n.Children)
+			b.WriteString("</li>")
+		}
+		b.WriteString("</ul>")
+	}
+	walk(s.root.Children)
+	return template.HTML(b.String())
+}
+
+// total counts the pearls in a namespace and beneath it.
+func (n *namespace) total() int {
+	count := len(n.Pearls)
+	for _, c := range n.Children {
+		count += c.total()
+	}
+	return count
+}
+
+// groups groups pearls by the values of a field, sorted by value.
+func (s *site) groups(values func(p *pearl.Pearl) []string) []group {
+	byValue := make(map[string][]*pearl.Pearl)
+	for _, e := range s.entries {
+		for _, v := range values(e.Pearl) {
+			byValue[v] = append(byValue[v], e.Pearl)
+		}
+	}
+	groups := make([]group, 0, len(byValue))
+	for v, pearls := range byValue {
+		groups = append(groups, group{Name: v, Pearls: pearls})
+	}
+	sort.Slice(groups, func(i, j int) bool { return groups[i].Name < groups[j].Name })
+	return groups
+}
+
+// mermaid returns a Mermaid flowchart of the references, links, and
+// embeds between pearls. Pearls with no connections are left out.
+// Solid arrows are references, dotted ones links, and thick ones embeds.
+// Click targets are relative to root. It also returns the number of edges.
+func (s *site) mermaid(root string) (string, int) {
+	nodes := make(map[string]string)
+	node := func(id string) string {
+		if n, ok := nodes[id]; ok {
+			return n
+		}
+		nodes[id] = fmt.Sprintf("n%d", len(nodes))
+		return nodes[id]
+	}
+
+	var edges []string
+	for _, e := range s.entries {
+		for _, r := range s.outgoing[e.Pearl.ID].refs {
+			if r.Pearl == nil {
+				continue
+			}
+			arrow := "-->"
+			switch {
+			case contains(r.Via, "reference"):
+			case contains(r.Via, "embed"):
+				arrow = "==>"
+			default:
+				arrow = "-.->"
+			}
+			edges = append(edges, fmt.Sprintf("  %s %s %s", node(e.Pearl.ID), arrow, node(r.ID)))
+		}
+	}
+
+	ids := make([]string, 0, len(nodes))
+	for id := range nodes {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return nodes[ids[i]] < nodes[ids[j]] })
+
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+	for _, id := range ids {
+		fmt.Fprintf(&b, "  %s[\"%s\"]\n", nodes[id], id)
+	}
+	for _, e := range edges {
+		b.WriteString(e + "\n")
+	}
+	for _, id := range ids {
+		fmt.Fprintf(&b, "  click %s \"%s\"\n", nodes[id], root+pearlURL(id))
+	}
+	return b.String(), len(edges)
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+// searchEntry is one pearl in search.json.
+type searchEntry struct {
+	ID          string   `json:"id"`
+	Name        string   `json:"name"`
+	Namespace   string   `json:"namespace"`
+	Type        string   `json:"type"`
+	Status      string   `json:"status"`
+	Description string   `json:"description"`
+	Tags        []string `json:"tags,omitempty"`
+	Scopes      []string `json:"scopes,omitempty"`
+	Headings    []string `json:"headings,omitempty"`
+	URL         string   `json:"url"`
+}
+
+// searchIndex returns search.json: each pearl's metadata and headings.
+func (s *site) searchIndex() ([]byte, error) {
+	index := make([]searchEntry, 0, len(s.entries))
+	for _, e := range s.entries {
+		p := e.Pearl
+		var headings []string
+		for _, h := range markdown.Parse(e.Content, 1).Headings() {
+			headings = append(headings, h.Text)
+		}
+		index = append(index, searchEntry{
+			ID: p.ID, Name: p.Name, Namespace: p.Namespace,
+			Type: string(p.Type), Status: string(p.Status), Description: p.Description,
+			Tags: p.Tags, Scopes: p.Scopes, Headings: headings, URL: pearlURL(p.ID),
+		})
+	}
+	return json.Marshal(index)
+}
@@ -0,0 +1,143 @@
+package site
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/markdown"
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+func testEntries() []Entry {
+	t0 := time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC)
+	users := &pearl.Pearl{
+		ID: "db.users", Name: "users", Namespace: "db", Type: pearl.TypeTable,
+		Status: pearl.StatusActive, Description: "User accounts <admin>",
+		Tags: []string{"pii"}, Scopes: []string{"backend"}, UpdatedAt: t0,
+	}
+	orders := &pearl.Pearl{
+		ID: "db.sales.orders", Name: "orders", Namespace: "db.sales", Type: pearl.TypeTable,
+		Status: pearl.StatusActive, Description: "Orders", References: []string{"db.users"},
+		Tags: []string{"pii", "sales"}, UpdatedAt: t0,
+	}
+	readme := &pearl.Pearl{
+		ID: "readme", Name: "readme", Type: pearl.TypeCustom, Status: pearl.StatusActive, UpdatedAt: t0,
+	}
+	ordersContent := "# Orders\n\nOwned by [[db.users#Columns|users]], see [[nope]].\n\n## Columns\n\n| name | type |\n|---|---|\n| id | int |\n"
+	return []Entry{
+		{Pearl: users, Content: "# Users\n\n## Columns\n\n- id\n\n```mermaid\ngraph LR\n  a --> b\n```\n"},
+		{Pearl: orders, Content: ordersContent, Links: markdown.Links(ordersContent)},
+		{Pearl: readme, Content: "Start here: [[db.sales.orders]]", Links: markdown.Links("Start here: [[db.sales.orders]]")},
+	}
+}
+
+func TestBuild(t *testing.T) {
+	dir := t.TempDir()
+	sum, err := Build(dir, testEntries(), Options{Title: "Acme", Description: "The catalog"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if sum.Pearls != 3 {
+		t.Errorf("Pearls = %d, want 3", sum.Pearls)
+	}
+
+	read := func(name string) string {
+		t.Helper()
+		data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	for _, name := range []string{
+		"index.html", "types.html", "tags.html", "scopes.html", "graph.html", "graph.mmd",
+		"search.json", "assets/style.css", "assets/search.js",
+		"p/db.users.html", "p/db.sales.orders.html", "p/readme.html",
+		"ns/db.html", "ns/db.sales.html",
+	} {
+		if _, err := os.Stat(filepath.Join(dir, filepath.FromSlash(name))); err != nil {
+			t.Errorf("%s not written: %v", name, err)
+		}
+	}
+
+	orders := read("p/db.sales.orders.html")
+	for _, want := range []string{
+		`href="../assets/style.css"`,
+		`<a href="../index.html">home</a> / <a href="../ns/db.html">db</a> / <a href="../ns/db.sales.html">sales</a> / db.sales.orders`,
+		`<a class="wikilink" href="../p/db.users.html#columns">users</a>`,
+		`<span class="missing-link">nope</span>`,
+		`<th>name</th>`,
+		`href="../tags.html#sales">sales</a>`,
+	} {
+		if !strings.Contains(orders, want) {
+			t.Errorf("orders page missing %q", want)
+		}
+	}
+
+	users := read("p/db.users.html")
+	if !strings.Contains(users, "User accounts &lt;admin&gt;") {
+		t.Error("description not escaped")
+	}
+	// Diagrams from content must not run click handlers or HTML labels
+	if !strings.Contains(users, `securityLevel: "strict"`) || strings.Contains(users, "loose") {
+		t.Error("pearl page should draw Mermaid with the strict security level")
+	}
+	refs := users[strings.Index(users, "Referenced by"):]
+	if !strings.Contains(refs, `<a href="../p/db.sales.orders.html">db.sales.orders</a> <span class="via">reference, link</span>`) {
+		t.Errorf("backlink missing:\n%s", refs)
+	}
+
+	if tags := read("tags.html"); !strings.Contains(tags, `<h2 id="pii">pii</h2>`) {
+		t.Error("tags page missing pii group")
+	}
+	if ns := read("ns/db.html"); !strings.Contains(ns, `<a href="../ns/db.sales.html">sales</a>`) || !strings.Contains(ns, "db.users") {
+		t.Errorf("namespace page:\n%s", ns)
+	}
+	if nav := read("index.html"); !strings.Contains(nav, `<a href="ns/db.html">db</a> <span class="count">2</span>`) {
+		t.Error("nav missing namespace counts")
+	}
+
+	if page := read("graph.html"); !strings.Contains(page, `securityLevel: "loose"`) {
+		t.Error("graph page needs the loose security level for its links")
+	}
+	graph := read("graph.mmd")
+	for _, want := range []string{`n0 --> n1`, `n2 -.-> n0`, `click n1 "p/db.users.html"`} {
+		if !strings.Contains(graph, want) {
+			t.Errorf("graph missing %q:\n%s", want, graph)
+		}
+	}
+
+	var index []searchEntry
+	if err := json.Unmarshal([]byte(read("search.json")), &index); err != nil {
+		t.Fatal(err)
+	}
+	if len(index) != 3 || index[0].ID != "db.sales.orders" || index[0].URL != "p/db.sales.orders.html" {
+		t.Fatalf("search index = %+v", index)
+	}
+	if got := strings.Join(index[0].Headings, ","); got != "Orders,Columns" {
+		t.Errorf("headings = %q", got)
+	}
+}
+
+func TestBuildDeterministic(t *testing.T) {
+	a, b := t.TempDir(), t.TempDir()
+	entries := testEntries()
+	if _, err := Build(a, entries, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	reversed := []Entry{entries[2], entries[1], entries[0]}
+	if _, err := Build(b, reversed, Options{}); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"index.html", "graph.mmd", "search.json", "p/db.users.html"} {
+		x, _ := os.ReadFile(filepath.Join(a, name))
+		y, _ := os.ReadFile(filepath.Join(b, name))
+		if string(x) != string(y) {
+			t.Errorf("%s differs between builds", name)
+		}
+	}
+}
@@ -53,6 +53,7 @@ func (c *Content) Read(relativePath string) (string, error) {
 }
 
 // Write writes content to a pearl's markdown file.
+// Writes are atomic: temp file, then rename.
 func (c *Content) Write(relativePath, content string) error {
 	fullPath := c.FullPath(relativePath)
 
@@ -62,10 +63,17 @@ func (c *Content) Write(relativePath, content string) error {
 		return fmt.Errorf("create content directory: %w", err)
 	}
 
-	if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+	tmpPath := fullPath + tmpSuffix
+	if err := os.WriteFile(tmpPath, []byte(content), 0644); err != nil {
+		os.Remove(tmpPath)
 		return fmt.Errorf("write content file: %w", err)
 	}
 
+	if err := os.Rename(tmpPath, fullPath); err != nil {
+		os.Remove(tmpPath)
+		return fmt.Errorf("rename content file: %w", err)
+	}
+
 	return nil
 }
 
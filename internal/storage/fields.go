@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"reflect"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+// scalarField describes a single-valued pearl field for comparison and copying.
+type scalarField struct {
+	name string
+	get  func(p *pearl.Pearl) interface{}
+	set  func(dst, src *pearl.Pearl)
+}
+
+// listField describes a string-list pearl field merged with set semantics.
+type listField struct {
+	name string
+	get  func(p *pearl.Pearl) []string
+	set  func(p *pearl.Pearl, v []string)
+}
+
+// scalarFields lists every single-valued field except ID, UpdatedAt, and
+// the verification fields, which callers handle explicitly.
+var scalarFields = []scalarField{
+	{"name", func(p *pearl.Pearl) interface{} { return p.Name }, func(d, s *pearl.Pearl) { d.Name = s.Name }},
+	{"namespace", func(p *pearl.Pearl) interface{} { return p.Namespace }, func(d, s *pearl.Pearl) { d.Namespace = s.Namespace }},
+	{"type", func(p *pearl.Pearl) interface{} { return p.Type }, func(d, s *pearl.Pearl) { d.Type = s.Type }},
+	{"description", func(p *pearl.Pearl) interface{} { return p.Description }, func(d, s *pearl.Pearl) { d.Description = s.Description }},
+	{"content_path", func(p *pearl.Pearl) interface{} { return p.ContentPath }, func(d, s *pearl.Pearl) { d.ContentPath = s.ContentPath }},
+	{"content_hash", func(p *pearl.Pearl) interface{} { return p.ContentHash }, func(d, s *pearl.Pearl) { d.ContentHash = s.ContentHash }},
+	{"parent", func(p *pearl.Pearl) interface{} { return p.Parent }, func(d, s *pearl.Pearl) { d.Parent = s.Parent }},
+	{"connection", func(p *pearl.Pearl) interface{} { return p.Connection }, func(d, s *pearl.Pearl) { d.Connection = s.Connection }},
+	{"required", func(p *pearl.Pearl) interface{} { return p.Required }, func(d, s *pearl.Pearl) { d.Required = s.Required }},
+	{"priority", func(p *pearl.Pearl) interface{} { return p.Priority }, func(d, s *pearl.Pearl) { d.Priority = s.Priority }},
+	{"status", func(p *pearl.Pearl) interface{} { return p.Status }, func(d, s *pearl.Pearl) { d.Status = s.Status }},
+	{"created_at", func(p *pearl.Pearl) interface{} { return p.CreatedAt }, func(d, s *pearl.Pearl) { d.CreatedAt = s.CreatedAt }},
+	{"created_by", func(p *pearl.Pearl) interface{} { return p.CreatedBy }, func(d, s *pearl.Pearl) { d.CreatedBy = s.CreatedBy }},
+}
+
+// verificationFields record the last review. 'pearls verify' sets them
+// together without touching UpdatedAt, so they are merged as a pair.
+var verificationFields = []scalarField{
+	{"last_verified_at", func(p *pearl.Pearl) interface{} { return p.LastVerifiedAt }, func(d, s *pearl.Pearl) { d.LastVerifiedAt = s.LastVerifiedAt }},
+	{"verified_by", func(p *pearl.Pearl) interface{} { return p.VerifiedBy }, func(d, s *pearl.Pearl) { d.VerifiedBy = s.VerifiedBy }},
+}
+
+var listFields = []listField{
+	{"tags", func(p *pearl.Pearl) []string { return p.Tags }, func(p *pearl.Pearl, v []string) { p.Tags = v }},
+	{"globs", func(p *pearl.Pearl) []string { return p.Globs }, func(p *pearl.Pearl, v []string) { p.Globs = v }},
+	{"scopes", func(p *pearl.Pearl) []string { return p.Scopes }, func(p *pearl.Pearl, v []string) { p.Scopes = v }},
+	{"references", func(p *pearl.Pearl) []string { return p.References }, func(p *pearl.Pearl, v []string) { p.References = v }},
+	{"owners", func(p *pearl.Pearl) []string { return p.Owners }, func(p *pearl.Pearl, v []string) { p.Owners = v }},
+}
+
+// ChangedFields returns the names of metadata fields that differ between a
+// and b, in declaration order. UpdatedAt is not considered.
+func ChangedFields(a, b *pearl.Pearl) []string {
+	var changed []string
+	for _, fields := range [][]scalarField{scalarFields, verificationFields} {
+		for _, f := range fields {
+			if !valuesEqual(f.get(a), f.get(b)) {
+				changed = append(changed, f.name)
+			}
+		}
+	}
+	for _, f := range listFields {
+		if !equalLists(f.get(a), f.get(b)) {
+			changed = append(changed, f.name)
+		}
+	}
+	return changed
+}
+
+// valuesEqual compares two field values, treating times by instant.
+func valuesEqual(a, b interface{}) bool {
+	if ta, ok := a.(time.Time); ok {
+		tb, ok := b.(time.Time)
+		return ok && ta.Equal(tb)
+	}
+	if ta, ok := a.(*time.Time); ok {
+		tb, ok := b.(*time.Time)
+		return ok && (ta == nil) == (tb == nil) && (ta == nil || ta.Equal(*tb))
+	}
+	return reflect.DeepEqual(a, b)
+}
@@ -0,0 +1,199 @@
+package storage
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+const frontmatterFence = "---"
+
+// Frontmatter holds the pearl metadata that can be carried in a YAML block at
+// the top of a content file. Absent keys are left nil so that a hand-written
+// block only overrides the fields it actually names.
+type Frontmatter struct {
+	Type        string   `yaml:"type,omitempty"`
+	Description *string  `yaml:"description,omitempty"`
+	Tags        []string `yaml:"tags,flow"`
+	Globs       []string `yaml:"globs,flow"`
+	Scopes      []string `yaml:"scopes,flow"`
+	References  []string `yaml:"references,flow"`
+	Owners      []string `yaml:"owners,flow"`
+	Required    *bool    `yaml:"required,omitempty"`
+	Priority    *int     `yaml:"priority,omitempty"`
+}
+
+// FrontmatterFor builds a complete frontmatter block from a pearl's metadata.
+// Every field is set, so the block is authoritative when read back.
+func FrontmatterFor(p *pearl.Pearl) *Frontmatter {
+	desc := p.Description
+	required := p.Required
+	priority := p.Priority
+	return &Frontmatter{
+		Type:        string(p.Type),
+		Description: &desc,
+		Tags:        nonNil(p.Tags),
+		Globs:       nonNil(p.Globs),
+		Scopes:      nonNil(p.Scopes),
+		References:  nonNil(p.References),
+		Owners:      nonNil(p.Owners),
+		Required:    &required,
+		Priority:    &priority,
+	}
+}
+
+// ParseFrontmatter splits content into its frontmatter block and markdown body.
+// If the content has no frontmatter, it returns nil and the content unchanged.
+func ParseFrontmatter(content string) (*Frontmatter, string, error) {
+	if !strings.HasPrefix(content, frontmatterFence+"\n") {
+		return nil, content, nil
+	}
+
+	rest := content[len(frontmatterFence)+1:]
+	var block, body string
+	switch {
+	case strings.HasPrefix(rest, frontmatterFence+"\n"):
+		block, body = "", rest[len(frontmatterFence)+1:]
+	case rest == frontmatterFence:
+		block, body = "", ""
+	default:
+		end := strings.Index(rest, "\n"+frontmatterFence+"\n")
+		if end >= 0 {
+			block, body = rest[:end+1], rest[end+len(frontmatterFence)+2:]
+		} else if strings.HasSuffix(rest, "\n"+frontmatterFence) {
+			block, body = rest[:len(rest)-len(frontmatterFence)], ""
+		} else {
+			return nil, content, fmt.Errorf("unterminated frontmatter block")
+		}
+	}
+
+	var fm Frontmatter
+	if err := yaml.Unmarshal([]byte(block), &fm); err != nil {
+		return nil, content, fmt.Errorf("parse frontmatter: %w", err)
+	}
+
+	return &fm, strings.TrimPrefix(body, "\n"), nil
+}
+
+// RenderFrontmatter prepends a frontmatter block to a markdown body.
+func RenderFrontmatter(fm *Frontmatter, body string) (string, error) {
+	data, err := yaml.Marshal(fm)
+	if err != nil {
+		return "", fmt.Errorf("marshal frontmatter: %w", err)
+	}
+
+	var sb strings.Builder
+	sb.WriteString(frontmatterFence + "\n")
+	sb.Write(data)
+	sb.WriteString(frontmatterFence + "\n\n")
+	sb.WriteString(body)
+	return sb.String(), nil
+}
+
+// StripFrontmatter returns the markdown body of content, dropping any
+// frontmatter block. Malformed frontmatter is returned untouched.
+func StripFrontmatter(content string) string {
+	_, body, err := ParseFrontmatter(content)
+	if err != nil {
+		return content
+	}
+	return body
+}
+
+// Apply overlays the fields present in the frontmatter onto p.
+// It reports whether any field changed.
+func (fm *Frontmatter) Apply(p *pearl.Pearl) bool {
+	changed := false
+	for _, d := range fm.Diff(p) {
+		changed = true
+		switch d.Field {
+		case "type":
+			p.Type = pearl.AssetType(fm.Type)
+		case "description":
+			p.Description = *fm.Description
+		case "tags":
+			p.Tags = fm.Tags
+		case "globs":
+			p.Globs = fm.Globs
+		case "scopes":
+			p.Scopes = fm.Scopes
+		case "references":
+			p.References = fm.References
+		case "owners":
+			p.Owners = fm.Owners
+		case "required":
+			p.Required = *fm.Required
+		case "priority":
+			p.Priority = *fm.Priority
+		}
+	}
+	return changed
+}
+
+// FieldDiff describes a single field where frontmatter and metadata disagree.
+type FieldDiff struct {
+	Field       string
+	Frontmatter interface{}
+	Metadata    interface{}
+}
+
+func (d FieldDiff) String() string {
+	return fmt.Sprintf("%s: frontmatter=%v metadata=%v", d.Field, d.Frontmatter, d.Metadata)
+}
+
+// Diff lists the fields present in the frontmatter whose values differ from p.
+// Nil and empty lists are treated as equal.
+func (fm *Frontmatter) Diff(p *pearl.Pearl) []FieldDiff {
+	var diffs []FieldDiff
+	add := func(field string, fmVal, pVal interface{}) {
+		diffs = append(diffs, FieldDiff{Field: field, Frontmatter: fmVal, Metadata: pVal})
+	}
+
+	if fm.Type != "" && fm.Type != string(p.Type) {
+		add("type", fm.Type, p.Type)
+	}
+	if fm.Description != nil && *fm.Description != p.Description {
+		add("description", *fm.Description, p.Description)
+	}
+	if fm.Tags != nil && !equalLists(fm.Tags, p.Tags) {
+		add("tags", fm.Tags, p.Tags)
+	}
+	if fm.Globs != nil && !equalLists(fm.Globs, p.Globs) {
+		add("globs", fm.Globs, p.Globs)
+	}
+	if fm.Scopes != nil && !equalLists(fm.Scopes, p.Scopes) {
+		add("scopes", fm.Scopes, p.Scopes)
+	}
+	if fm.References != nil && !equalLists(fm.References, p.References) {
+		add("references", fm.References, p.References)
+	}
+	if fm.Owners != nil && !equalLists(fm.Owners, p.Owners) {
+		add("owners", fm.Owners, p.Owners)
+	}
+	if fm.Required != nil && *fm.Required != p.Required {
+		add("required", *fm.Required, p.Required)
+	}
+	if fm.Priority != nil && *fm.Priority != p.Priority {
+		add("priority", *fm.Priority, p.Priority)
+	}
+
+	return diffs
+}
+
+func equalLists(a, b []string) bool {
+	if len(a) == 0 && len(b) == 0 {
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func nonNil(s []string) []string {
+	if s == nil {
+		return []string{}
+	}
+	return s
+}
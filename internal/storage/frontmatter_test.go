@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+func TestParseFrontmatter(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		wantFM   bool
+		wantBody string
+		wantErr  bool
+	}{
+		{"no frontmatter", "# Users\n", false, "# Users\n", false},
+		{"with frontmatter", "---\ntype: table\n---\n\n# Users\n", true, "# Users\n", false},
+		{"empty block", "---\n---\n# Users\n", true, "# Users\n", false},
+		{"block only", "---\ntype: table\n---", true, "", false},
+		{"unterminated", "---\ntype: table\n# Users\n", false, "", true},
+		{"bad yaml", "---\ntype: [\n---\n", false, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			fm, body, err := ParseFrontmatter(tt.content)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("err = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if (fm != nil) != tt.wantFM {
+				t.Errorf("frontmatter present = %v, want %v", fm != nil, tt.wantFM)
+			}
+			if body != tt.wantBody {
+				t.Errorf("body = %q, want %q", body, tt.wantBody)
+			}
+		})
+	}
+}
+
+func TestFrontmatterRoundTrip(t *testing.T) {
+	p := &pearl.Pearl{
+		ID: "db.users", Name: "users", Namespace: "db",
+		Type:        pearl.TypeTable,
+		Description: "User accounts",
+		Tags:        []string{"pii"},
+		Globs:       []string{"src/users/**"},
+		Required:    true,
+		Priority:    5,
+	}
+
+	rendered, err := RenderFrontmatter(FrontmatterFor(p), "# users\n")
+	if err != nil {
+		t.Fatalf("render: %v", err)
+	}
+	if !strings.HasPrefix(rendered, "---\n") {
+		t.Errorf("rendered content should start with fence: %q", rendered)
+	}
+
+	fm, body, err := ParseFrontmatter(rendered)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if body != "# users\n" {
+		t.Errorf("body = %q", body)
+	}
+	if diffs := fm.Diff(p); len(diffs) != 0 {
+		t.Errorf("round trip produced diffs: %v", diffs)
+	}
+}
+
+func TestFrontmatterPartialApply(t *testing.T) {
+	p := &pearl.Pearl{
+		Type:     pearl.TypeTable,
+		Tags:     []string{"core"},
+		Priority: 3,
+	}
+
+	fm, _, err := ParseFrontmatter("---\ntags: [pii, core]\n---\n")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+
+	if !fm.Apply(p) {
+		t.Fatal("expected Apply to report a change")
+	}
+	if len(p.Tags) != 2 || p.Tags[0] != "pii" {
+		t.Errorf("Tags = %v, want [pii core]", p.Tags)
+	}
+	// Fields absent from the block are untouched
+	if p.Type != pearl.TypeTable || p.Priority != 3 {
+		t.Errorf("absent fields changed: type=%s priority=%d", p.Type, p.Priority)
+	}
+	if fm.Apply(p) {
+		t.Error("second Apply should be a no-op")
+	}
+}
+
+func TestStoreFrontmatterMode(t *testing.T) {
+	tmpDir := t.TempDir()
+	store, err := NewStore(
+		filepath.Join(tmpDir, "pearls.db"),
+		filepath.Join(tmpDir, "pearls.jsonl"),
+		filepath.Join(tmpDir, "content"),
+	)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+	store.SetFrontmatter(true)
+
+	now := time.Now()
+	p := &pearl.Pearl{
+		ID: "db.users", Name: "users", Namespace: "db",
+		Type: pearl.TypeTable, Status: pearl.StatusActive,
+		CreatedAt: now, UpdatedAt: now,
+	}
+	if err := store.Create(p, "# users\n"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	raw, _ := store.Content().Read(p.ContentPath)
+	if !strings.HasPrefix(raw, "---\ntype: table\n") {
+		t.Errorf("content file missing frontmatter: %q", raw)
+	}
+	body, _ := store.GetContent(p)
+	if body != "# users\n" {
+		t.Errorf("GetContent = %q, want body only", body)
+	}
+
+	// Metadata updates are mirrored into the file
+	p.Tags = []string{"pii"}
+	if err := store.Update(p, nil); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	raw, _ = store.Content().Read(p.ContentPath)
+	if !strings.Contains(raw, "tags: [pii]") {
+		t.Errorf("frontmatter not updated: %q", raw)
+	}
+
+	// Editor change to frontmatter wins on sync
+	edited := strings.Replace(raw, "priority: 0", "priority: 9", 1)
+	os.WriteFile(store.Content().FullPath(p.ContentPath), []byte(edited), 0644)
+
+	// New pearl authored entirely in a content file
+	os.MkdirAll(store.Content().FullPath("conv"), 0755)
+	os.WriteFile(store.Content().FullPath("conv/naming.md"),
+		[]byte("---\ntype: convention\ndescription: Naming rules\n---\n\n# naming\n"), 0644)
+
+	if err := store.SyncFromJSONL(); err != nil {
+		t.Fatalf("sync: %v", err)
+	}
+
+	got, _ := store.Get("db.users")
+	if got.Priority != 9 {
+		t.Errorf("Priority = %d, want 9 from frontmatter", got.Priority)
+	}
+
+	adopted, _ := store.Get("conv.naming")
+	if adopted == nil {
+		t.Fatal("expected conv.naming to be adopted from content file")
+	}
+	if adopted.Type != "convention" || adopted.Description != "Naming rules" {
+		t.Errorf("adopted = %s %q", adopted.Type, adopted.Description)
+	}
+
+	// Reconciled metadata is written back to JSONL
+	jsonlPearls, _ := store.JSONL().ReadAll()
+	if len(jsonlPearls) != 2 {
+		t.Errorf("JSONL has %d pearls, want 2", len(jsonlPearls))
+	}
+}
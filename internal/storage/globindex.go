@@ -0,0 +1,222 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+// The glob_index table files each pearl under the literal prefixes of its
+// globs (see pearl.GlobPrefixes), so FindByGlobs only loads pearls whose
+// globs could match instead of decoding every pearl that has globs. It is
+// kept current by insertPearl, updatePearl, and deletePearl.
+
+// maxParams keeps IN (...) lists under SQLite's bound-parameter limit.
+const maxParams = 500
+
+func createGlobIndex(ex execer) error {
+	_, err := ex.Exec(`
+		CREATE TABLE IF NOT EXISTS glob_index (
+			prefix TEXT NOT NULL,
+			pearl_id TEXT NOT NULL,
+			PRIMARY KEY (prefix, pearl_id)
+		) WITHOUT ROWID;
+		CREATE INDEX IF NOT EXISTS idx_glob_index_pearl ON glob_index(pearl_id);
+	`)
+	if err != nil {
+		return fmt.Errorf("create glob index: %w", err)
+	}
+
+	// Backfill from pearls that predate the index
+	rows, err := ex.Query("SELECT id, globs FROM pearls WHERE globs != '[]' AND globs != '' AND globs != 'null'")
+	if err != nil {
+		return fmt.Errorf("query globs: %w", err)
+	}
+	globsByID := make(map[string][]string)
+	for rows.Next() {
+		var id, raw string
+		if err := rows.Scan(&id, &raw); err != nil {
+			rows.Close()
+			return fmt.Errorf("scan globs: %w", err)
+		}
+		var globs []string
+		if err := json.Unmarshal([]byte(raw), &globs); err != nil {
+			rows.Close()
+			return fmt.Errorf("unmarshal globs for %s: %w", id, err)
+		}
+		globsByID[id] = globs
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for id, globs := range globsByID {
+		if err := indexGlobs(ex, id, globs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// indexGlobs replaces a pearl's entries in the glob index.
+func indexGlobs(ex execer, id string, globs []string) error {
+	if err := unindexGlobs(ex, id); err != nil {
+		return err
+	}
+	for _, prefix := range pearl.GlobPrefixes(globs) {
+		if _, err := ex.Exec("INSERT INTO glob_index (prefix, pearl_id) VALUES (?, ?)", prefix, id); err != nil {
+			return fmt.Errorf("index globs: %w", err)
+		}
+	}
+	return nil
+}
+
+func unindexGlobs(ex execer, id string) error {
+	if _, err := ex.Exec("DELETE FROM glob_index WHERE pearl_id = ?", id); err != nil {
+		return fmt.Errorf("unindex globs: %w", err)
+	}
+	return nil
+}
+
+// FindByGlob returns all pearls whose globs match the given path.
+func (d *DB) FindByGlob(path string) ([]*pearl.Pearl, error) {
+	matches, err := d.FindByGlobs([]string{path})
+	if err != nil {
+		return nil, err
+	}
+	return matches[path], nil
+}
+
+// FindByGlobs matches many paths in one pass, returning the pearls whose
+// globs match each path, ordered by namespace and name. Paths nothing
+// matches are omitted.
+//
+// SQLite cannot evaluate doublestar patterns, so the glob index narrows the
+// candidates to pearls filed under a prefix of some path, and
+// pearl.MatchPath decides.
+func (d *DB) FindByGlobs(paths []string) (map[string][]*pearl.Pearl, error) {
+	var prefixes []string
+	seen := make(map[string]bool)
+	for _, path := range paths {
+		if path == "" {
+			continue
+		}
+		for _, prefix := range pearl.PathPrefixes(path) {
+			if !seen[prefix] {
+				seen[prefix] = true
+				prefixes = append(prefixes, prefix)
+			}
+		}
+	}
+
+	var ids []string
+	for _, chunk := range chunks(prefixes) {
+		found, err := queryStrings(d.db,
+			"SELECT DISTINCT pearl_id FROM glob_index WHERE prefix IN ("+placeholders(len(chunk))+")", chunk)
+		if err != nil {
+			return nil, fmt.Errorf("query glob index: %w", err)
+		}
+		ids = append(ids, found...)
+	}
+
+	byID := make(map[string]*pearl.Pearl)
+	for _, chunk := range chunks(ids) {
+		pearls, err := queryPearls(d.db,
+			"SELECT "+pearlColumns+" FROM pearls WHERE id IN ("+placeholders(len(chunk))+")", chunk)
+		if err != nil {
+			return nil, fmt.Errorf("query glob candidates: %w", err)
+		}
+		for _, p := range pearls {
+			byID[p.ID] = p
+		}
+	}
+
+	candidates := make([]*pearl.Pearl, 0, len(byID))
+	for _, p := range byID {
+		candidates = append(candidates, p)
+	}
+	sort.Slice(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	index := pearl.NewGlobIndex()
+	for _, p := range candidates {
+		index.Add(p.ID, p.Globs)
+	}
+
+	matches := make(map[string][]*pearl.Pearl)
+	for path, matched := range index.MatchAll(paths) {
+		for _, id := range matched {
+			matches[path] = append(matches[path], byID[id])
+		}
+	}
+	return matches, nil
+}
+
+func chunks(values []string) [][]string {
+	var out [][]string
+	for len(values) > maxParams {
+		out = append(out, values[:maxParams])
+		values = values[maxParams:]
+	}
+	if len(values) > 0 {
+		out = append(out, values)
+	}
+	return out
+}
+
+func placeholders(n int) string {
+	return strings.TrimSuffix(strings.Repeat("?, ", n), ", ")
+}
+
+func args(values []string) []interface{} {
+	out := make([]interface{}, len(values))
+	for i, v := range values {
+		out[i] = v
+	}
+	return out
+}
+
+func queryStrings(ex execer, query string, values []string) ([]string, error) {
+	rows, err := ex.Query(query, args(values)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var out []string
+	for rows.Next() {
+		var s string
+		if err := rows.Scan(&s); err != nil {
+			return nil, err
+		}
+		out = append(out, s)
+	}
+	return out, rows.Err()
+}
+
+func queryPearls(ex execer, query string, values []string) ([]*pearl.Pearl, error) {
+	rows, err := ex.Query(query, args(values)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var pearls []*pearl.Pearl
+	for rows.Next() {
+		p, err := scanPearlRows(rows)
+		if err != nil {
+			return nil, err
+		}
+		pearls = append(pearls, p)
+	}
+	return pearls, rows.Err()
+}
@@ -0,0 +1,163 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+func pearlIDs(pearls []*pearl.Pearl) []string {
+	var out []string
+	for _, p := range pearls {
+		out = append(out, p.ID)
+	}
+	return out
+}
+
+func TestFindByGlobs(t *testing.T) {
+	db, err := OpenDB(filepath.Join(t.TempDir(), "pearls.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now().Truncate(time.Second)
+	for _, p := range []*pearl.Pearl{
+		{ID: "conv.go", Name: "go", Namespace: "conv", Globs: []string{"**/*.go", "!**/*_test.go"}},
+		{ID: "api.style", Name: "style", Namespace: "api", Globs: []string{"src/api/**"}},
+		{ID: "api.routes", Name: "routes", Namespace: "api", Globs: []string{"src/api/routes.go"}},
+		{ID: "web.style", Name: "style", Namespace: "web", Globs: []string{"src/web/**/*.tsx"}},
+		{ID: "conv.none", Name: "none", Namespace: "conv"},
+	} {
+		p.Type, p.Status, p.CreatedAt, p.UpdatedAt = pearl.TypeCustom, pearl.StatusActive, now, now
+		if err := db.Insert(p); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	matches, err := db.FindByGlobs([]string{"src/api/routes.go", "src/api/routes_test.go", "src/web/App.tsx", "README.md", ""})
+	if err != nil {
+		t.Fatalf("find by globs: %v", err)
+	}
+	want := map[string][]string{
+		"src/api/routes.go":      {"api.routes", "api.style", "conv.go"},
+		"src/api/routes_test.go": {"api.style"},
+		"src/web/App.tsx":        {"web.style"},
+	}
+	got := make(map[string][]string)
+	for path, pearls := range matches {
+		got[path] = pearlIDs(pearls)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindByGlobs = %v, want %v", got, want)
+	}
+
+	// Updating globs moves the pearl in the index
+	p, _ := db.Get("web.style")
+	p.Globs = []string{"web/**"}
+	if err := db.Update(p); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if results, _ := db.FindByGlob("src/web/App.tsx"); len(results) != 0 {
+		t.Errorf("after update, old path matched %v", pearlIDs(results))
+	}
+	if results, _ := db.FindByGlob("web/index.html"); !reflect.DeepEqual(pearlIDs(results), []string{"web.style"}) {
+		t.Errorf("after update, new path matched %v", pearlIDs(results))
+	}
+
+	// Deleting removes it
+	if err := db.Delete("web.style"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	var rows int
+	db.db.QueryRow("SELECT COUNT(*) FROM glob_index WHERE pearl_id = 'web.style'").Scan(&rows)
+	if rows != 0 {
+		t.Errorf("%d index rows left after delete", rows)
+	}
+}
+
+func TestGlobIndexMigration(t *testing.T) {
+	db, err := OpenDB(filepath.Join(t.TempDir(), "pearls.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now().Truncate(time.Second)
+	p := &pearl.Pearl{
+		ID: "api.style", Name: "style", Namespace: "api",
+		Type: pearl.TypeCustom, Status: pearl.StatusActive,
+		Globs:     []string{"src/api/**"},
+		CreatedAt: now, UpdatedAt: now,
+	}
+	if err := db.Insert(p); err != nil {
+		t.Fatalf("insert: %v", err)
+	}
+
+	// Simulate a database from before the index existed
+	if _, err := db.db.Exec("DROP TABLE glob_index; DELETE FROM schema_version WHERE version = 7"); err != nil {
+		t.Fatalf("drop index: %v", err)
+	}
+	if _, err := db.Migrate(); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	results, err := db.FindByGlob("src/api/handler.go")
+	if err != nil {
+		t.Fatalf("find by glob: %v", err)
+	}
+	if !reflect.DeepEqual(pearlIDs(results), []string{"api.style"}) {
+		t.Errorf("after backfill, matched %v", pearlIDs(results))
+	}
+}
+
+func BenchmarkFindByGlob(b *testing.B) {
+	for _, n := range []int{1000, 10000} {
+		b.Run(fmt.Sprint(n), func(b *testing.B) {
+			db, err := OpenDB(filepath.Join(b.TempDir(), "pearls.db"))
+			if err != nil {
+				b.Fatalf("open db: %v", err)
+			}
+			defer db.Close()
+
+			tx, err := db.Begin()
+			if err != nil {
+				b.Fatalf("begin: %v", err)
+			}
+			now := time.Now()
+			for i := 0; i < n; i++ {
+				svc := i / 10
+				globs := []string{
+					fmt.Sprintf("services/svc%d/**/*.go", svc),
+					fmt.Sprintf("!services/svc%d/**/*_test.go", svc),
+				}
+				if i%100 == 0 {
+					globs = []string{fmt.Sprintf("**/*.ext%d", i)}
+				}
+				p := &pearl.Pearl{
+					ID: fmt.Sprintf("svc%d.doc%d", svc, i), Name: fmt.Sprintf("doc%d", i), Namespace: fmt.Sprintf("svc%d", svc),
+					Type: pearl.TypeCustom, Status: pearl.StatusActive,
+					Globs: globs, CreatedAt: now, UpdatedAt: now,
+				}
+				if err := tx.Insert(p); err != nil {
+					b.Fatalf("insert: %v", err)
+				}
+			}
+			if err := tx.Commit(); err != nil {
+				b.Fatalf("commit: %v", err)
+			}
+
+			path := fmt.Sprintf("services/svc%d/api/handler.go", n/20)
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				if _, err := db.FindByGlob(path); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
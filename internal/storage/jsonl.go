@@ -2,6 +2,7 @@ package storage
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -10,6 +11,19 @@ import (
 	"github.com/justrnr500/pearls/internal/pearl"
 )
 
+// JSONLFormat is the record format this build writes. Each record carries
+// its format in the formatKey field; records without one predate
+// versioning and are read as format 1.
+const JSONLFormat = 1
+
+// formatKey is the JSONL field holding a record's format version.
+const formatKey = "_format"
+
+// jsonlUpgrades convert a record from format N to N+1, keyed by N. Add an
+// entry whenever a field is renamed or its meaning changes; purely
+// additive fields need none, since unknown fields round-trip via Extra.
+var jsonlUpgrades = map[int]func(record map[string]json.RawMessage) error{}
+
 // JSONL handles reading and writing pearls to JSONL files.
 type JSONL struct {
 	path string
@@ -51,11 +65,11 @@ func (j *JSONL) ReadAll() ([]*pearl.Pearl, error) {
 			continue
 		}
 
-		var p pearl.Pearl
-		if err := json.Unmarshal(line, &p); err != nil {
+		p, err := decodeRecord(line)
+		if err != nil {
 			return nil, fmt.Errorf("parse line %d: %w", lineNum, err)
 		}
-		pearls = append(pearls, &p)
+		pearls = append(pearls, p)
 	}
 
 	if err := scanner.Err(); err != nil {
@@ -74,17 +88,18 @@ func (j *JSONL) WriteAll(pearls []*pearl.Pearl) error {
 	}
 
 	// Write to temp file first, then rename for atomicity
-	tmpPath := j.path + ".tmp"
+	tmpPath := j.path + tmpSuffix
 	file, err := os.Create(tmpPath)
 	if err != nil {
 		return fmt.Errorf("create temp file: %w", err)
 	}
 
-	encoder := json.NewEncoder(file)
-	encoder.SetEscapeHTML(false)
-
 	for _, p := range pearls {
-		if err := encoder.Encode(p); err != nil {
+		record, err := encodeRecord(p)
+		if err == nil {
+			_, err = file.Write(record)
+		}
+		if err != nil {
 			file.Close()
 			os.Remove(tmpPath)
 			return fmt.Errorf("encode pearl %s: %w", p.ID, err)
@@ -124,16 +139,121 @@ func (j *JSONL) Append(p *pearl.Pearl) error {
 	}
 	defer file.Close()
 
-	encoder := json.NewEncoder(file)
-	encoder.SetEscapeHTML(false)
-
-	if err := encoder.Encode(p); err != nil {
+	record, err := encodeRecord(p)
+	if err != nil {
 		return fmt.Errorf("encode pearl: %w", err)
 	}
+	if _, err := file.Write(record); err != nil {
+		return fmt.Errorf("write pearl: %w", err)
+	}
 
 	return nil
 }
 
+// Formats counts the file's records by format version. Records from before
+// versioning are counted as format 0.
+func (j *JSONL) Formats() (map[int]int, error) {
+	file, err := os.Open(j.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("open jsonl file: %w", err)
+	}
+	defer file.Close()
+
+	counts := make(map[int]int)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+
+	lineNum := 0
+	for scanner.Scan() {
+		lineNum++
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var header struct {
+			Format int `json:"_format"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+			return nil, fmt.Errorf("parse line %d: %w", lineNum, err)
+		}
+		counts[header.Format]++
+	}
+
+	return counts, scanner.Err()
+}
+
+// encodeRecord renders one JSONL line: the format version, then the pearl.
+func encodeRecord(p *pearl.Pearl) ([]byte, error) {
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(p); err != nil {
+		return nil, err
+	}
+
+	// Splice the format in as the first field: {"_format":1,"id":...}
+	record := fmt.Appendf(nil, "{%q:%d,", formatKey, JSONLFormat)
+	return append(record, buf.Bytes()[1:]...), nil
+}
+
+// decodeRecord parses one JSONL line, upgrading older formats and refusing
+// newer ones.
+func decodeRecord(line []byte) (*pearl.Pearl, error) {
+	var header struct {
+		Format int `json:"_format"`
+	}
+	if err := json.Unmarshal(line, &header); err != nil {
+		return nil, err
+	}
+	format := max(header.Format, 1)
+	if format > JSONLFormat {
+		return nil, fmt.Errorf("record format %d is newer than this pearls supports (%d): upgrade pearls", format, JSONLFormat)
+	}
+
+	if format < JSONLFormat {
+		var record map[string]json.RawMessage
+		if err := json.Unmarshal(line, &record); err != nil {
+			return nil, err
+		}
+		for v := format; v < JSONLFormat; v++ {
+			if upgrade := jsonlUpgrades[v]; upgrade != nil {
+				if err := upgrade(record); err != nil {
+					return nil, fmt.Errorf("upgrade from format %d: %w", v, err)
+				}
+			}
+		}
+		upgraded, err := json.Marshal(record)
+		if err != nil {
+			return nil, err
+		}
+		line = upgraded
+	}
+
+	var p pearl.Pearl
+	if err := json.Unmarshal(line, &p); err != nil {
+		return nil, err
+	}
+	delete(p.Extra, formatKey)
+	if len(p.Extra) == 0 {
+		p.Extra = nil
+	}
+	return &p, nil
+}
+
+// Hash returns the SHA256 hash of the JSONL file, or "" if it does not exist.
+func (j *JSONL) Hash() (string, error) {
+	data, err := os.ReadFile(j.path)
+	if os.IsNotExist(err) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("read jsonl file: %w", err)
+	}
+	return HashContent(data), nil
+}
+
 // Exists returns true if the JSONL file exists.
 func (j *JSONL) Exists() bool {
 	_, err := os.Stat(j.path)
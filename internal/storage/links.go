@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"fmt"
+
+	"github.com/justrnr500/pearls/internal/markdown"
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+// Links returns the wiki-links and embeds in a pearl's content. They are
+// parsed on each call rather than indexed, so they stay current when
+// content files are edited directly.
+func (s *Store) Links(p *pearl.Pearl) ([]markdown.Link, error) {
+	content, err := s.GetContent(p)
+	if err != nil {
+		return nil, err
+	}
+	return markdown.Links(content), nil
+}
+
+// LinkGraph returns the links in every pearl's content, by pearl ID.
+// Pearls whose content cannot be read are skipped.
+func (s *Store) LinkGraph() (map[string][]markdown.Link, error) {
+	pearls, err := s.db.All()
+	if err != nil {
+		return nil, fmt.Errorf("list pearls: %w", err)
+	}
+
+	graph := make(map[string][]markdown.Link)
+	for _, p := range pearls {
+		links, err := s.Links(p)
+		if err != nil || len(links) == 0 {
+			continue
+		}
+		graph[p.ID] = links
+	}
+	return graph, nil
+}
+
+// Transclude expands the embeds in a pearl's content; see
+// markdown.Transclude.
+func (s *Store) Transclude(id, content string, maxDepth int) (string, []string) {
+	resolve := func(target string) (string, bool, error) {
+		p, err := s.db.Get(target)
+		if err != nil || p == nil {
+			return "", false, err
+		}
+		content, err := s.GetContent(p)
+		return content, true, err
+	}
+	return markdown.Transclude(id, content, resolve, maxDepth)
+}
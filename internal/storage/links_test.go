@@ -0,0 +1,52 @@
+package storage
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+func TestLinks(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewStore(filepath.Join(dir, "pearls.db"), filepath.Join(dir, "pearls.jsonl"), filepath.Join(dir, "content"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer s.Close()
+
+	now := time.Now()
+	create := func(id, content string) {
+		p := &pearl.Pearl{
+			ID: id, Name: pearl.LastSegment(id), Namespace: pearl.ParentNamespace(id),
+			Type: pearl.TypeAPI, Status: pearl.StatusActive, CreatedAt: now, UpdatedAt: now,
+		}
+		if err := s.Create(p, content); err != nil {
+			t.Fatalf("create %s: %v", id, err)
+		}
+	}
+	create("shared.auth", "# Auth\n\n## Tokens\n\nSend a bearer token.\n")
+	create("api.orders", "# Orders\n\n![[shared.auth#tokens]]\n\nSee [[api.users]].\n")
+	create("api.users", "# Users\n\n![[shared.auth#tokens]]\n")
+
+	graph, err := s.LinkGraph()
+	if err != nil {
+		t.Fatalf("link graph: %v", err)
+	}
+	if len(graph) != 2 || len(graph["api.orders"]) != 2 || graph["api.orders"][1].Target != "api.users" {
+		t.Errorf("graph = %+v", graph)
+	}
+
+	if want := []string{"shared.auth", "shared.auth"}; !reflect.DeepEqual([]string{graph["api.orders"][0].Target, graph["api.users"][0].Target}, want) {
+		t.Errorf("embed targets = %+v", graph)
+	}
+
+	orders, _ := s.Get("api.orders")
+	content, _ := s.GetContent(orders)
+	expanded, warnings := s.Transclude("api.orders", content, 5)
+	if want := "# Orders\n\n## Tokens\n\nSend a bearer token.\n\nSee [[api.users]].\n"; expanded != want || len(warnings) != 0 {
+		t.Errorf("transclude = %q, %v", expanded, warnings)
+	}
+}
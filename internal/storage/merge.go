@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+// MergeConflict describes a change that a three-way merge could not resolve.
+type MergeConflict struct {
+	ID     string `json:"id"`
+	Field  string `json:"field,omitempty"`
+	Reason string `json:"reason"`
+}
+
+func (c MergeConflict) String() string {
+	if c.Field == "" {
+		return fmt.Sprintf("%s: %s", c.ID, c.Reason)
+	}
+	return fmt.Sprintf("%s.%s: %s", c.ID, c.Field, c.Reason)
+}
+
+// MergePearls performs a three-way merge of pearl sets keyed by ID.
+//
+// Scalar fields take whichever side changed relative to base; when both
+// sides changed a field differently, the side with the newer UpdatedAt wins.
+// The last verification and who made it come from the side verified later.
+// List fields (tags, globs, scopes, references, owners) are merged as sets: additions
+// from either side are kept and removals from either side are honored.
+//
+// Only two situations are reported as conflicts, and in both the merge still
+// produces a result: a scalar changed differently on both sides with equal
+// UpdatedAt (ours is kept), and a pearl deleted on one side but modified on
+// the other (the modified version is kept).
+//
+// The result is ordered like Store writes it: priority DESC, namespace, name.
+func MergePearls(base, ours, theirs []*pearl.Pearl) ([]*pearl.Pearl, []MergeConflict) {
+	b, o, t := indexByID(base), indexByID(ours), indexByID(theirs)
+
+	ids := make(map[string]bool)
+	for _, m := range []map[string]*pearl.Pearl{b, o, t} {
+		for id := range m {
+			ids[id] = true
+		}
+	}
+	sorted := make([]string, 0, len(ids))
+	for id := range ids {
+		sorted = append(sorted, id)
+	}
+	sort.Strings(sorted)
+
+	var merged []*pearl.Pearl
+	var conflicts []MergeConflict
+
+	for _, id := range sorted {
+		bp, op, tp := b[id], o[id], t[id]
+
+		switch {
+		case op != nil && tp != nil:
+			p, cs := mergePearl(bp, op, tp)
+			merged = append(merged, p)
+			conflicts = append(conflicts, cs...)
+
+		case op == nil && tp == nil:
+			// Deleted on both sides (or never existed)
+
+		case bp == nil:
+			// Added on one side only
+			if op != nil {
+				merged = append(merged, op)
+			} else {
+				merged = append(merged, tp)
+			}
+
+		case op == nil:
+			// Deleted in ours
+			if len(ChangedFields(bp, tp)) > 0 {
+				merged = append(merged, tp)
+				conflicts = append(conflicts, MergeConflict{ID: id, Reason: "deleted in ours, modified in theirs"})
+			}
+
+		default:
+			// Deleted in theirs
+			if len(ChangedFields(bp, op)) > 0 {
+				merged = append(merged, op)
+				conflicts = append(conflicts, MergeConflict{ID: id, Reason: "modified in ours, deleted in theirs"})
+			}
+		}
+	}
+
+	sort.SliceStable(merged, func(i, j int) bool {
+		a, b := merged[i], merged[j]
+		if a.Priority != b.Priority {
+			return a.Priority > b.Priority
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		return a.Name < b.Name
+	})
+
+	return merged, conflicts
+}
+
+// mergePearl merges one pearl present on both sides. base may be nil when
+// both sides added the same ID independently.
+func mergePearl(base, ours, theirs *pearl.Pearl) (*pearl.Pearl, []MergeConflict) {
+	result := *ours
+	var conflicts []MergeConflict
+
+	for _, f := range scalarFields {
+		ov, tv := f.get(ours), f.get(theirs)
+		switch {
+		case valuesEqual(ov, tv):
+			// agree
+		case base != nil && valuesEqual(ov, f.get(base)):
+			f.set(&result, theirs)
+		case base != nil && valuesEqual(tv, f.get(base)):
+			// only ours changed
+		case theirs.UpdatedAt.After(ours.UpdatedAt):
+			f.set(&result, theirs)
+		case ours.UpdatedAt.After(theirs.UpdatedAt):
+			// ours is newer
+		default:
+			conflicts = append(conflicts, MergeConflict{
+				ID:     ours.ID,
+				Field:  f.name,
+				Reason: fmt.Sprintf("both sides changed with equal updated_at (ours=%v, theirs=%v)", ov, tv),
+			})
+		}
+	}
+
+	// The later verification wins, with whoever made it
+	if verifiedLater(theirs, ours) {
+		for _, f := range verificationFields {
+			f.set(&result, theirs)
+		}
+	}
+
+	for _, f := range listFields {
+		var bv []string
+		if base != nil {
+			bv = f.get(base)
+		}
+		f.set(&result, mergeSet(bv, f.get(ours), f.get(theirs)))
+	}
+
+	if theirs.UpdatedAt.After(ours.UpdatedAt) {
+		result.UpdatedAt = theirs.UpdatedAt
+	}
+
+	return &result, conflicts
+}
+
+// verifiedLater reports whether a was verified after b.
+func verifiedLater(a, b *pearl.Pearl) bool {
+	if a.LastVerifiedAt == nil {
+		return false
+	}
+	return b.LastVerifiedAt == nil || a.LastVerifiedAt.After(*b.LastVerifiedAt)
+}
+
+// mergeSet merges string lists as sets. An element survives if both sides
+// kept it or either side added it; removing a base element on either side
+// removes it. Order follows ours, then theirs' additions.
+func mergeSet(base, ours, theirs []string) []string {
+	inBase := toSet(base)
+	inOurs := toSet(ours)
+	inTheirs := toSet(theirs)
+
+	keep := func(v string) bool {
+		if inBase[v] {
+			return inOurs[v] && inTheirs[v]
+		}
+		return true
+	}
+
+	var result []string
+	seen := make(map[string]bool)
+	for _, list := range [][]string{ours, theirs} {
+		for _, v := range list {
+			if !seen[v] && keep(v) {
+				result = append(result, v)
+				seen[v] = true
+			}
+		}
+	}
+
+	if result == nil && ours != nil {
+		return []string{}
+	}
+	return result
+}
+
+func toSet(list []string) map[string]bool {
+	set := make(map[string]bool, len(list))
+	for _, v := range list {
+		set[v] = true
+	}
+	return set
+}
+
+func indexByID(pearls []*pearl.Pearl) map[string]*pearl.Pearl {
+	m := make(map[string]*pearl.Pearl, len(pearls))
+	for _, p := range pearls {
+		m[p.ID] = p
+	}
+	return m
+}
@@ -0,0 +1,313 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+func TestMergePearls(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	t1 := t0.Add(time.Hour)
+	t2 := t0.Add(2 * time.Hour)
+
+	// mk builds a pearl with the given ID and applies optional edits.
+	mk := func(id string, updated time.Time, edits ...func(*pearl.Pearl)) *pearl.Pearl {
+		p := &pearl.Pearl{
+			ID: id, Name: pearl.LastSegment(id), Namespace: pearl.ParentNamespace(id),
+			Type: pearl.TypeTable, Status: pearl.StatusActive,
+			Description: "base",
+			Tags:        []string{"core"},
+			CreatedAt:   t0, UpdatedAt: updated,
+		}
+		for _, e := range edits {
+			e(p)
+		}
+		return p
+	}
+	desc := func(d string) func(*pearl.Pearl) { return func(p *pearl.Pearl) { p.Description = d } }
+	tags := func(ts ...string) func(*pearl.Pearl) { return func(p *pearl.Pearl) { p.Tags = ts } }
+	prio := func(n int) func(*pearl.Pearl) { return func(p *pearl.Pearl) { p.Priority = n } }
+	status := func(s pearl.Status) func(*pearl.Pearl) { return func(p *pearl.Pearl) { p.Status = s } }
+	verified := func(at time.Time, by string) func(*pearl.Pearl) {
+		return func(p *pearl.Pearl) { p.LastVerifiedAt, p.VerifiedBy = &at, by }
+	}
+
+	tests := []struct {
+		name          string
+		base          []*pearl.Pearl
+		ours          []*pearl.Pearl
+		theirs        []*pearl.Pearl
+		wantIDs       []string
+		check         func(t *testing.T, got map[string]*pearl.Pearl)
+		wantConflicts []string // "id" or "id.field"
+	}{
+		{
+			name:    "both sides add different pearls",
+			base:    nil,
+			ours:    []*pearl.Pearl{mk("db.a", t1)},
+			theirs:  []*pearl.Pearl{mk("db.b", t1)},
+			wantIDs: []string{"db.a", "db.b"},
+		},
+		{
+			name:    "only ours changed a scalar",
+			base:    []*pearl.Pearl{mk("db.a", t0)},
+			ours:    []*pearl.Pearl{mk("db.a", t1, desc("ours"))},
+			theirs:  []*pearl.Pearl{mk("db.a", t0)},
+			wantIDs: []string{"db.a"},
+			check: func(t *testing.T, got map[string]*pearl.Pearl) {
+				if got["db.a"].Description != "ours" {
+					t.Errorf("Description = %q, want ours", got["db.a"].Description)
+				}
+			},
+		},
+		{
+			name:    "only theirs changed a scalar",
+			base:    []*pearl.Pearl{mk("db.a", t0)},
+			ours:    []*pearl.Pearl{mk("db.a", t0)},
+			theirs:  []*pearl.Pearl{mk("db.a", t1, desc("theirs"))},
+			wantIDs: []string{"db.a"},
+			check: func(t *testing.T, got map[string]*pearl.Pearl) {
+				if got["db.a"].Description != "theirs" {
+					t.Errorf("Description = %q, want theirs", got["db.a"].Description)
+				}
+				if !got["db.a"].UpdatedAt.Equal(t1) {
+					t.Errorf("UpdatedAt = %v, want newest %v", got["db.a"].UpdatedAt, t1)
+				}
+			},
+		},
+		{
+			name:    "different scalars changed on each side",
+			base:    []*pearl.Pearl{mk("db.a", t0)},
+			ours:    []*pearl.Pearl{mk("db.a", t1, desc("ours"))},
+			theirs:  []*pearl.Pearl{mk("db.a", t2, prio(7))},
+			wantIDs: []string{"db.a"},
+			check: func(t *testing.T, got map[string]*pearl.Pearl) {
+				p := got["db.a"]
+				if p.Description != "ours" || p.Priority != 7 {
+					t.Errorf("got description=%q priority=%d, want ours/7", p.Description, p.Priority)
+				}
+			},
+		},
+		{
+			name:    "same scalar changed, newer updated_at wins",
+			base:    []*pearl.Pearl{mk("db.a", t0)},
+			ours:    []*pearl.Pearl{mk("db.a", t2, desc("ours"))},
+			theirs:  []*pearl.Pearl{mk("db.a", t1, desc("theirs"))},
+			wantIDs: []string{"db.a"},
+			check: func(t *testing.T, got map[string]*pearl.Pearl) {
+				if got["db.a"].Description != "ours" {
+					t.Errorf("Description = %q, want ours (newer)", got["db.a"].Description)
+				}
+			},
+		},
+		{
+			name:          "same scalar changed with equal updated_at conflicts",
+			base:          []*pearl.Pearl{mk("db.a", t0)},
+			ours:          []*pearl.Pearl{mk("db.a", t1, status(pearl.StatusDeprecated))},
+			theirs:        []*pearl.Pearl{mk("db.a", t1, status(pearl.StatusArchived))},
+			wantIDs:       []string{"db.a"},
+			wantConflicts: []string{"db.a.status"},
+			check: func(t *testing.T, got map[string]*pearl.Pearl) {
+				if got["db.a"].Status != pearl.StatusDeprecated {
+					t.Errorf("Status = %q, want ours on conflict", got["db.a"].Status)
+				}
+			},
+		},
+		{
+			name:    "tags added on both sides are unioned",
+			base:    []*pearl.Pearl{mk("db.a", t0)},
+			ours:    []*pearl.Pearl{mk("db.a", t1, tags("core", "pii"))},
+			theirs:  []*pearl.Pearl{mk("db.a", t1, tags("core", "billing"))},
+			wantIDs: []string{"db.a"},
+			check: func(t *testing.T, got map[string]*pearl.Pearl) {
+				want := []string{"core", "pii", "billing"}
+				if !reflect.DeepEqual(got["db.a"].Tags, want) {
+					t.Errorf("Tags = %v, want %v", got["db.a"].Tags, want)
+				}
+			},
+		},
+		{
+			name:    "tag removed on one side stays removed",
+			base:    []*pearl.Pearl{mk("db.a", t0, tags("core", "old"))},
+			ours:    []*pearl.Pearl{mk("db.a", t1, tags("core"))},
+			theirs:  []*pearl.Pearl{mk("db.a", t1, tags("core", "old", "new"))},
+			wantIDs: []string{"db.a"},
+			check: func(t *testing.T, got map[string]*pearl.Pearl) {
+				want := []string{"core", "new"}
+				if !reflect.DeepEqual(got["db.a"].Tags, want) {
+					t.Errorf("Tags = %v, want %v", got["db.a"].Tags, want)
+				}
+			},
+		},
+		{
+			name: "references and globs merge independently",
+			base: []*pearl.Pearl{mk("db.a", t0)},
+			ours: []*pearl.Pearl{mk("db.a", t1, func(p *pearl.Pearl) {
+				p.References = []string{"db.b"}
+				p.Globs = []string{"src/**"}
+			})},
+			theirs: []*pearl.Pearl{mk("db.a", t1, func(p *pearl.Pearl) {
+				p.References = []string{"db.c"}
+				p.Scopes = []string{"backend"}
+			})},
+			wantIDs: []string{"db.a"},
+			check: func(t *testing.T, got map[string]*pearl.Pearl) {
+				p := got["db.a"]
+				if !reflect.DeepEqual(p.References, []string{"db.b", "db.c"}) {
+					t.Errorf("References = %v", p.References)
+				}
+				if !reflect.DeepEqual(p.Globs, []string{"src/**"}) {
+					t.Errorf("Globs = %v", p.Globs)
+				}
+				if !reflect.DeepEqual(p.Scopes, []string{"backend"}) {
+					t.Errorf("Scopes = %v", p.Scopes)
+				}
+			},
+		},
+		{
+			name:    "both sides add same ID, newer wins scalars",
+			base:    nil,
+			ours:    []*pearl.Pearl{mk("db.a", t1, desc("ours"), tags("x"))},
+			theirs:  []*pearl.Pearl{mk("db.a", t2, desc("theirs"), tags("y"))},
+			wantIDs: []string{"db.a"},
+			check: func(t *testing.T, got map[string]*pearl.Pearl) {
+				p := got["db.a"]
+				if p.Description != "theirs" {
+					t.Errorf("Description = %q, want theirs", p.Description)
+				}
+				if !reflect.DeepEqual(p.Tags, []string{"x", "y"}) {
+					t.Errorf("Tags = %v, want union", p.Tags)
+				}
+			},
+		},
+		{
+			name:    "deleted in ours, untouched in theirs",
+			base:    []*pearl.Pearl{mk("db.a", t0), mk("db.b", t0)},
+			ours:    []*pearl.Pearl{mk("db.b", t0)},
+			theirs:  []*pearl.Pearl{mk("db.a", t0), mk("db.b", t0)},
+			wantIDs: []string{"db.b"},
+		},
+		{
+			name:    "deleted in theirs, untouched in ours",
+			base:    []*pearl.Pearl{mk("db.a", t0)},
+			ours:    []*pearl.Pearl{mk("db.a", t0)},
+			theirs:  nil,
+			wantIDs: nil,
+		},
+		{
+			name:          "deleted in ours, modified in theirs conflicts",
+			base:          []*pearl.Pearl{mk("db.a", t0)},
+			ours:          nil,
+			theirs:        []*pearl.Pearl{mk("db.a", t1, desc("theirs"))},
+			wantIDs:       []string{"db.a"},
+			wantConflicts: []string{"db.a"},
+		},
+		{
+			name:          "modified in ours, deleted in theirs conflicts",
+			base:          []*pearl.Pearl{mk("db.a", t0)},
+			ours:          []*pearl.Pearl{mk("db.a", t1, prio(3))},
+			theirs:        nil,
+			wantIDs:       []string{"db.a"},
+			wantConflicts: []string{"db.a"},
+		},
+		{
+			name:    "both sides verified, later verification wins",
+			base:    []*pearl.Pearl{mk("db.a", t0)},
+			ours:    []*pearl.Pearl{mk("db.a", t0, verified(t2, "alice"))},
+			theirs:  []*pearl.Pearl{mk("db.a", t0, verified(t1, "bob"))},
+			wantIDs: []string{"db.a"},
+			check: func(t *testing.T, got map[string]*pearl.Pearl) {
+				a := got["db.a"]
+				if a.LastVerifiedAt == nil || !a.LastVerifiedAt.Equal(t2) || a.VerifiedBy != "alice" {
+					t.Errorf("verified = %v by %q, want %v by alice", a.LastVerifiedAt, a.VerifiedBy, t2)
+				}
+			},
+		},
+		{
+			name:    "theirs verified later, both fields taken together",
+			base:    []*pearl.Pearl{mk("db.a", t0, verified(t0, "carol"))},
+			ours:    []*pearl.Pearl{mk("db.a", t0, verified(t1, "alice"))},
+			theirs:  []*pearl.Pearl{mk("db.a", t0, verified(t2, "bob"))},
+			wantIDs: []string{"db.a"},
+			check: func(t *testing.T, got map[string]*pearl.Pearl) {
+				a := got["db.a"]
+				if a.LastVerifiedAt == nil || !a.LastVerifiedAt.Equal(t2) || a.VerifiedBy != "bob" {
+					t.Errorf("verified = %v by %q, want %v by bob", a.LastVerifiedAt, a.VerifiedBy, t2)
+				}
+			},
+		},
+		{
+			name:    "deleted on both sides",
+			base:    []*pearl.Pearl{mk("db.a", t0)},
+			ours:    nil,
+			theirs:  nil,
+			wantIDs: nil,
+		},
+		{
+			name:    "result ordered by priority then namespace and name",
+			base:    nil,
+			ours:    []*pearl.Pearl{mk("db.z", t0), mk("api.a", t0)},
+			theirs:  []*pearl.Pearl{mk("db.a", t0, prio(5))},
+			wantIDs: []string{"db.a", "api.a", "db.z"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			merged, conflicts := MergePearls(tt.base, tt.ours, tt.theirs)
+
+			var gotIDs []string
+			byID := make(map[string]*pearl.Pearl)
+			for _, p := range merged {
+				gotIDs = append(gotIDs, p.ID)
+				byID[p.ID] = p
+			}
+			if !reflect.DeepEqual(gotIDs, tt.wantIDs) {
+				t.Errorf("IDs = %v, want %v", gotIDs, tt.wantIDs)
+			}
+
+			var gotConflicts []string
+			for _, c := range conflicts {
+				key := c.ID
+				if c.Field != "" {
+					key += "." + c.Field
+				}
+				gotConflicts = append(gotConflicts, key)
+			}
+			if !reflect.DeepEqual(gotConflicts, tt.wantConflicts) {
+				t.Errorf("conflicts = %v, want %v", gotConflicts, tt.wantConflicts)
+			}
+
+			if tt.check != nil {
+				tt.check(t, byID)
+			}
+		})
+	}
+}
+
+func TestMergeSet(t *testing.T) {
+	tests := []struct {
+		name               string
+		base, ours, theirs []string
+		want               []string
+	}{
+		{"all nil", nil, nil, nil, nil},
+		{"unchanged", []string{"a"}, []string{"a"}, []string{"a"}, []string{"a"}},
+		{"both add same", nil, []string{"a"}, []string{"a"}, []string{"a"}},
+		{"both remove", []string{"a"}, []string{}, []string{}, []string{}},
+		{"remove vs keep", []string{"a", "b"}, []string{"a"}, []string{"a", "b"}, []string{"a"}},
+		{"remove vs add", []string{"a"}, []string{}, []string{"a", "b"}, []string{"b"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := mergeSet(tt.base, tt.ours, tt.theirs)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("mergeSet = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
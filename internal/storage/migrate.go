@@ -0,0 +1,234 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// Migration is one step in the SQLite schema's history. Migrations are
+// applied in order and each is recorded in the schema_version table.
+//
+// Databases created before versioning have no schema_version table, so
+// every migration must be safe to run against a table that may already
+// have the change (CREATE ... IF NOT EXISTS, addColumn).
+type Migration struct {
+	Version     int
+	Description string
+	Up          func(tx *sql.Tx) error
+}
+
+// AppliedMigration is a row of the schema_version table.
+type AppliedMigration struct {
+	Version     int       `json:"version"`
+	Description string    `json:"description"`
+	AppliedAt   time.Time `json:"applied_at"`
+}
+
+// migrations is the ordered schema history. Append new steps; never edit
+// or reorder released ones.
+var migrations = []Migration{
+	{1, "create pearls table", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS pearls (
+				id TEXT PRIMARY KEY,
+				name TEXT NOT NULL,
+				namespace TEXT NOT NULL DEFAULT '',
+				type TEXT NOT NULL,
+				tags TEXT NOT NULL DEFAULT '[]',
+				description TEXT NOT NULL DEFAULT '',
+				content_path TEXT NOT NULL DEFAULT '',
+				content_hash TEXT NOT NULL DEFAULT '',
+				refs TEXT NOT NULL DEFAULT '[]',
+				parent TEXT NOT NULL DEFAULT '',
+				connection TEXT,
+				required INTEGER NOT NULL DEFAULT 0,
+				priority INTEGER NOT NULL DEFAULT 0,
+				created_at TEXT NOT NULL,
+				updated_at TEXT NOT NULL,
+				created_by TEXT NOT NULL DEFAULT '',
+				status TEXT NOT NULL DEFAULT 'active'
+			);
+			CREATE INDEX IF NOT EXISTS idx_pearls_namespace ON pearls(namespace);
+			CREATE INDEX IF NOT EXISTS idx_pearls_type ON pearls(type);
+			CREATE INDEX IF NOT EXISTS idx_pearls_status ON pearls(status);
+		`)
+		return err
+	}},
+	{2, "add globs and scopes columns", func(tx *sql.Tx) error {
+		if err := addColumn(tx, "pearls", "globs", "TEXT NOT NULL DEFAULT '[]'"); err != nil {
+			return err
+		}
+		return addColumn(tx, "pearls", "scopes", "TEXT NOT NULL DEFAULT '[]'")
+	}},
+	{3, "create meta table", func(tx *sql.Tx) error {
+		_, err := tx.Exec(`
+			CREATE TABLE IF NOT EXISTS meta (
+				key TEXT PRIMARY KEY,
+				value TEXT NOT NULL
+			)
+		`)
+		return err
+	}},
+	{4, "add extra column for unknown fields", func(tx *sql.Tx) error {
+		return addColumn(tx, "pearls", "extra", "TEXT NOT NULL DEFAULT '{}'")
+	}},
+	{5, "add verification columns", func(tx *sql.Tx) error {
+		if err := addColumn(tx, "pearls", "last_verified_at", "TEXT"); err != nil {
+			return err
+		}
+		return addColumn(tx, "pearls", "verified_by", "TEXT NOT NULL DEFAULT ''")
+	}},
+	{6, "add owners column", func(tx *sql.Tx) error {
+		return addColumn(tx, "pearls", "owners", "TEXT NOT NULL DEFAULT '[]'")
+	}},
+	{7, "create glob index", func(tx *sql.Tx) error {
+		return createGlobIndex(tx)
+	}},
+}
+
+// Migrations returns the schema history known to this build.
+func Migrations() []Migration {
+	return migrations
+}
+
+// LatestSchemaVersion returns the schema version this build migrates to.
+func LatestSchemaVersion() int {
+	return migrations[len(migrations)-1].Version
+}
+
+// SchemaVersion returns the database's current schema version.
+func (d *DB) SchemaVersion() (int, error) {
+	return schemaVersion(d.db)
+}
+
+// MigrationHistory returns the applied migrations, oldest first.
+func (d *DB) MigrationHistory() ([]AppliedMigration, error) {
+	rows, err := d.db.Query("SELECT version, description, applied_at FROM schema_version ORDER BY version")
+	if err != nil {
+		return nil, fmt.Errorf("query schema history: %w", err)
+	}
+	defer rows.Close()
+
+	var history []AppliedMigration
+	for rows.Next() {
+		var m AppliedMigration
+		var appliedAt string
+		if err := rows.Scan(&m.Version, &m.Description, &appliedAt); err != nil {
+			return nil, fmt.Errorf("scan schema history: %w", err)
+		}
+		m.AppliedAt, _ = time.Parse(time.RFC3339, appliedAt)
+		history = append(history, m)
+	}
+
+	return history, rows.Err()
+}
+
+// Migrate applies any pending migrations and returns the ones it applied.
+// It fails if the database was migrated by a newer build than this one.
+func (d *DB) Migrate() ([]Migration, error) {
+	if _, err := d.db.Exec(`
+		CREATE TABLE IF NOT EXISTS schema_version (
+			version INTEGER PRIMARY KEY,
+			description TEXT NOT NULL,
+			applied_at TEXT NOT NULL
+		)
+	`); err != nil {
+		return nil, fmt.Errorf("create schema_version table: %w", err)
+	}
+
+	current, err := d.SchemaVersion()
+	if err != nil {
+		return nil, err
+	}
+	if current > LatestSchemaVersion() {
+		return nil, fmt.Errorf("database schema version %d is newer than this pearls supports (%d): upgrade pearls, or delete %s to rebuild it from JSONL", current, LatestSchemaVersion(), d.path)
+	}
+
+	var applied []Migration
+	for _, m := range migrations {
+		if m.Version <= current {
+			continue
+		}
+		ok, err := d.apply(m)
+		if err != nil {
+			return applied, err
+		}
+		if ok {
+			applied = append(applied, m)
+		}
+	}
+
+	return applied, nil
+}
+
+// apply runs one migration in its own transaction. It reports false if
+// another process applied it first.
+func (d *DB) apply(m Migration) (bool, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return false, fmt.Errorf("begin migration %d: %w", m.Version, err)
+	}
+	defer tx.Rollback()
+
+	// Re-check under the write lock: a concurrent opener may have won
+	current, err := schemaVersion(tx)
+	if err != nil {
+		return false, err
+	}
+	if current >= m.Version {
+		return false, nil
+	}
+
+	if err := m.Up(tx); err != nil {
+		return false, fmt.Errorf("migration %d (%s): %w", m.Version, m.Description, err)
+	}
+
+	if _, err := tx.Exec(
+		"INSERT INTO schema_version (version, description, applied_at) VALUES (?, ?, ?)",
+		m.Version, m.Description, time.Now().UTC().Format(time.RFC3339),
+	); err != nil {
+		return false, fmt.Errorf("record migration %d: %w", m.Version, err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, fmt.Errorf("commit migration %d: %w", m.Version, err)
+	}
+	return true, nil
+}
+
+func schemaVersion(ex execer) (int, error) {
+	var version int
+	if err := ex.QueryRow("SELECT COALESCE(MAX(version), 0) FROM schema_version").Scan(&version); err != nil {
+		return 0, fmt.Errorf("get schema version: %w", err)
+	}
+	return version, nil
+}
+
+// addColumn adds a column to table unless it already exists.
+func addColumn(tx *sql.Tx, table, column, definition string) error {
+	rows, err := tx.Query(fmt.Sprintf("PRAGMA table_info(%s)", table))
+	if err != nil {
+		return fmt.Errorf("inspect %s: %w", table, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var cid, notNull, pk int
+		var name, colType string
+		var dflt sql.NullString
+		if err := rows.Scan(&cid, &name, &colType, &notNull, &dflt, &pk); err != nil {
+			return fmt.Errorf("inspect %s: %w", table, err)
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	rows.Close()
+
+	_, err = tx.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, definition))
+	return err
+}
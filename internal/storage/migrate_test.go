@@ -0,0 +1,150 @@
+package storage
+
+import (
+	"database/sql"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMigrateLegacyDatabase(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "pearls.db")
+
+	// A database from before globs, scopes, and versioning
+	raw, err := sql.Open("sqlite3", dbPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = raw.Exec(`
+		CREATE TABLE pearls (
+			id TEXT PRIMARY KEY, name TEXT NOT NULL, namespace TEXT NOT NULL DEFAULT '',
+			type TEXT NOT NULL, tags TEXT NOT NULL DEFAULT '[]', description TEXT NOT NULL DEFAULT '',
+			content_path TEXT NOT NULL DEFAULT '', content_hash TEXT NOT NULL DEFAULT '',
+			refs TEXT NOT NULL DEFAULT '[]', parent TEXT NOT NULL DEFAULT '', connection TEXT,
+			required INTEGER NOT NULL DEFAULT 0, priority INTEGER NOT NULL DEFAULT 0,
+			created_at TEXT NOT NULL, updated_at TEXT NOT NULL,
+			created_by TEXT NOT NULL DEFAULT '', status TEXT NOT NULL DEFAULT 'active'
+		);
+		INSERT INTO pearls (id, name, namespace, type, created_at, updated_at)
+		VALUES ('db.users', 'users', 'db', 'table', '2026-01-01T00:00:00Z', '2026-01-01T00:00:00Z');
+	`)
+	raw.Close()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	db, err := OpenDB(dbPath)
+	if err != nil {
+		t.Fatalf("open legacy db: %v", err)
+	}
+
+	version, err := db.SchemaVersion()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if version != LatestSchemaVersion() {
+		t.Errorf("version = %d, want %d", version, LatestSchemaVersion())
+	}
+
+	p, err := db.Get("db.users")
+	if err != nil || p == nil {
+		t.Fatalf("get legacy pearl: %v, %v", p, err)
+	}
+	p.Globs = []string{"src/**"}
+	if err := db.Update(p); err != nil {
+		t.Fatalf("update migrated pearl: %v", err)
+	}
+
+	history, err := db.MigrationHistory()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != len(Migrations()) {
+		t.Errorf("history has %d entries, want %d", len(history), len(Migrations()))
+	}
+	db.Close()
+
+	// Reopening applies nothing
+	db, err = OpenDB(dbPath)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	applied, err := db.Migrate()
+	if err != nil || len(applied) != 0 {
+		t.Errorf("second migrate applied %v, err %v", applied, err)
+	}
+
+	// A database from a newer build is refused
+	if _, err := db.db.Exec("INSERT INTO schema_version VALUES (?, 'future', '')", LatestSchemaVersion()+1); err != nil {
+		t.Fatal(err)
+	}
+	db.Close()
+	if _, err := OpenDB(dbPath); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("open newer db: err = %v, want newer-version error", err)
+	}
+}
+
+func TestJSONLFormat(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "pearls.jsonl")
+
+	// Unversioned record from an older build, plus a field this build
+	// does not know about
+	legacy := `{"id":"db.a","name":"a","namespace":"db","type":"table","tags":null,"description":"","content_path":"","content_hash":"","required":false,"priority":0,"created_at":"2026-01-01T00:00:00Z","updated_at":"2026-01-01T00:00:00Z","created_by":"","status":"active","owner_team":"data"}` + "\n"
+	if err := os.WriteFile(path, []byte(legacy), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	j := NewJSONL(path)
+	formats, err := j.Formats()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if formats[0] != 1 {
+		t.Errorf("formats = %v, want one unversioned record", formats)
+	}
+
+	store, err := NewStore(filepath.Join(dir, "pearls.db"), path, filepath.Join(dir, "content"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer store.Close()
+	if err := store.SyncFromJSONL(); err != nil {
+		t.Fatalf("sync from jsonl: %v", err)
+	}
+
+	// Unknown fields survive the database and a rewrite
+	p, _ := store.Get("db.a")
+	if p == nil || string(p.Extra["owner_team"]) != `"data"` {
+		t.Fatalf("extra lost in database: %+v", p)
+	}
+	if err := store.SyncToJSONL(); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var record map[string]json.RawMessage
+	if err := json.Unmarshal(data, &record); err != nil {
+		t.Fatal(err)
+	}
+	if string(record[formatKey]) != "1" || string(record["owner_team"]) != `"data"` {
+		t.Errorf("rewritten record = %s", data)
+	}
+	if !strings.HasPrefix(string(data), `{"_format":1,"id":"db.a"`) {
+		t.Errorf("format should lead the record: %s", data)
+	}
+
+	// Records from a newer format are refused rather than misread
+	future := strings.Replace(string(data), `"_format":1`, `"_format":99`, 1)
+	if err := os.WriteFile(path, []byte(future), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := j.ReadAll(); err == nil || !strings.Contains(err.Error(), "newer") {
+		t.Errorf("read future format: err = %v, want newer-format error", err)
+	}
+}
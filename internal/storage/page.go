@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+// SortKey orders results by one field.
+type SortKey struct {
+	Field string
+	Desc  bool
+}
+
+func (k SortKey) String() string {
+	if k.Desc {
+		return k.Field + ":desc"
+	}
+	return k.Field
+}
+
+// sortExprs maps sortable fields to the SQL they order by. Nullable columns
+// are coalesced so cursors can compare them.
+var sortExprs = map[string]string{
+	"id":        "id",
+	"name":      "name",
+	"namespace": "namespace",
+	"type":      "type",
+	"status":    "status",
+	"parent":    "ifnull(parent, '')",
+	"priority":  "priority",
+	"required":  "required",
+	"created":   "julianday(created_at)",
+	"updated":   "julianday(updated_at)",
+	"verified":  "ifnull(julianday(last_verified_at), 0)",
+}
+
+// defaultSort is the order when none is given: highest priority first,
+// then by namespace and name.
+var defaultSort = []SortKey{{Field: "priority", Desc: true}, {Field: "namespace"}, {Field: "name"}}
+
+// SortFields returns the fields results can be sorted by.
+func SortFields() []string {
+	fields := make([]string, 0, len(sortExprs))
+	for f := range sortExprs {
+		fields = append(fields, f)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// ParseSort parses a comma-separated sort such as "updated:desc,name".
+// Each field may end in :asc (the default) or :desc.
+func ParseSort(spec string) ([]SortKey, error) {
+	var keys []SortKey
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		field, dir, _ := strings.Cut(part, ":")
+		if field == "ns" {
+			field = "namespace"
+		}
+		if _, ok := sortExprs[field]; !ok {
+			return nil, fmt.Errorf("unknown sort field %q (fields: %s)", field, strings.Join(SortFields(), ", "))
+		}
+		key := SortKey{Field: field}
+		switch strings.ToLower(dir) {
+		case "", "asc":
+		case "desc":
+			key.Desc = true
+		default:
+			return nil, fmt.Errorf("invalid sort direction %q for %s: use asc or desc", dir, field)
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// sortKeys returns the full order for a sort: the default if none is given,
+// ending in id so that every pearl has a distinct position.
+func sortKeys(keys []SortKey) []SortKey {
+	if len(keys) == 0 {
+		keys = defaultSort
+	}
+	for _, k := range keys {
+		if k.Field == "id" {
+			return keys
+		}
+	}
+	return append(keys[:len(keys):len(keys)], SortKey{Field: "id"})
+}
+
+func orderBy(keys []SortKey) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = sortExprs[k.Field]
+		if k.Desc {
+			parts[i] += " DESC"
+		}
+	}
+	return " ORDER BY " + strings.Join(parts, ", ")
+}
+
+func sortSpec(keys []SortKey) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// Page is one page of List results.
+type Page struct {
+	Pearls []*pearl.Pearl `json:"pearls"`
+	// Total is the number of pearls matching the filters, on every page.
+	Total int `json:"total"`
+	// NextCursor continues after this page; empty on the last page.
+	NextCursor string `json:"next_cursor,omitempty"`
+}
+
+// cursor is the decoded form of a cursor token: the sort it was made for
+// and the sort values of the last pearl on its page.
+type cursor struct {
+	Sort  string        `json:"sort"`
+	After []interface{} `json:"after"`
+}
+
+// ListPage lists one page of pearls, with the total matching the filters
+// and a cursor for the next page when Limit cuts the results short.
+func (d *DB) ListPage(opts ListOptions) (*Page, error) {
+	total, err := countPearls(d.db, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	fetch := opts
+	if opts.Limit > 0 {
+		fetch.Limit = opts.Limit + 1
+	}
+	pearls, err := listPearls(d.db, fetch)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &Page{Pearls: pearls, Total: total}
+	if opts.Limit > 0 && len(pearls) > opts.Limit {
+		page.Pearls = pearls[:opts.Limit]
+		last := page.Pearls[len(page.Pearls)-1]
+		if page.NextCursor, err = encodeCursor(d.db, opts.Sort, last.ID); err != nil {
+			return nil, err
+		}
+	}
+	if page.Pearls == nil {
+		page.Pearls = []*pearl.Pearl{}
+	}
+	return page, nil
+}
+
+// encodeCursor records the sort values of the pearl with the given ID, as
+// SQL computes them, so the next page can resume exactly after it.
+func encodeCursor(ex execer, sortBy []SortKey, id string) (string, error) {
+	keys := sortKeys(sortBy)
+	exprs := make([]string, len(keys))
+	for i, k := range keys {
+		exprs[i] = sortExprs[k.Field]
+	}
+
+	values := make([]interface{}, len(keys))
+	dest := make([]interface{}, len(keys))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	if err := ex.QueryRow("SELECT "+strings.Join(exprs, ", ")+" FROM pearls WHERE id = ?", id).Scan(dest...); err != nil {
+		return "", fmt.Errorf("read cursor values: %w", err)
+	}
+	for i, v := range values {
+		if b, ok := v.([]byte); ok {
+			values[i] = string(b)
+		}
+	}
+
+	data, err := json.Marshal(cursor{Sort: sortSpec(keys), After: values})
+	if err != nil {
+		return "", fmt.Errorf("encode cursor: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+// cursorClause decodes a cursor token into a condition selecting the
+// pearls after it in the given sort.
+func cursorClause(sortBy []SortKey, token string) (string, []interface{}, error) {
+	if token == "" {
+		return "", nil, nil
+	}
+
+	keys := sortKeys(sortBy)
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return "", nil, fmt.Errorf("invalid cursor")
+	}
+	var c cursor
+	if err := json.Unmarshal(data, &c); err != nil {
+		return "", nil, fmt.Errorf("invalid cursor")
+	}
+	if c.Sort != sortSpec(keys) {
+		return "", nil, fmt.Errorf("cursor is for sort %q, not %q", c.Sort, sortSpec(keys))
+	}
+	if len(c.After) != len(keys) {
+		return "", nil, fmt.Errorf("invalid cursor")
+	}
+
+	// (a > ?) OR (a = ? AND b > ?) OR ..., flipped for descending keys
+	var alternatives []string
+	var args []interface{}
+	for i, k := range keys {
+		var terms []string
+		for j := 0; j < i; j++ {
+			terms = append(terms, sortExprs[keys[j].Field]+" = ?")
+			args = append(args, c.After[j])
+		}
+		op := " > ?"
+		if k.Desc {
+			op = " < ?"
+		}
+		terms = append(terms, sortExprs[k.Field]+op)
+		args = append(args, c.After[i])
+		alternatives = append(alternatives, "("+strings.Join(terms, " AND ")+")")
+	}
+	return " AND (" + strings.Join(alternatives, " OR ") + ")", args, nil
+}
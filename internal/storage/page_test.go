@@ -0,0 +1,158 @@
+package storage
+
+import (
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+func TestParseSort(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    []SortKey
+		wantErr string
+	}{
+		{"", nil, ""},
+		{"name", []SortKey{{Field: "name"}}, ""},
+		{"updated:desc, ns:asc", []SortKey{{Field: "updated", Desc: true}, {Field: "namespace"}}, ""},
+		{"color", nil, `unknown sort field "color"`},
+		{"name:up", nil, `invalid sort direction "up"`},
+	}
+	for _, tt := range tests {
+		got, err := ParseSort(tt.spec)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseSort(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseSort(%q): %v", tt.spec, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseSort(%q) = %v, want %v", tt.spec, got, tt.want)
+		}
+	}
+}
+
+func setupPageTestDB(t *testing.T) *DB {
+	t.Helper()
+	db, err := OpenDB(filepath.Join(t.TempDir(), "pearls.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 0; i < 11; i++ {
+		p := &pearl.Pearl{
+			ID:        fmt.Sprintf("ns%d.p%02d", i%2, i),
+			Name:      fmt.Sprintf("p%02d", i),
+			Namespace: fmt.Sprintf("ns%d", i%2),
+			Type:      pearl.TypeCustom,
+			Status:    pearl.StatusActive,
+			// Several pearls share a priority and a timestamp, so ties
+			// must be broken for paging to be stable
+			Priority:  i % 3,
+			CreatedAt: base,
+			UpdatedAt: base.Add(time.Duration(i%4) * time.Hour),
+		}
+		if i == 5 {
+			p.Parent = "ns1.p01"
+		}
+		if err := db.Insert(p); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+	return db
+}
+
+func TestListPageCursor(t *testing.T) {
+	db := setupPageTestDB(t)
+
+	for _, spec := range []string{"", "name", "updated:desc", "priority,updated:desc", "parent:desc,name", "verified"} {
+		sortBy, err := ParseSort(spec)
+		if err != nil {
+			t.Fatal(err)
+		}
+		all, err := db.List(ListOptions{Sort: sortBy})
+		if err != nil {
+			t.Fatalf("%q: list: %v", spec, err)
+		}
+
+		var paged []string
+		opts := ListOptions{Sort: sortBy, Limit: 4}
+		for pages := 0; ; pages++ {
+			if pages > 5 {
+				t.Fatalf("%q: too many pages", spec)
+			}
+			page, err := db.ListPage(opts)
+			if err != nil {
+				t.Fatalf("%q: page: %v", spec, err)
+			}
+			if page.Total != 11 {
+				t.Errorf("%q: total = %d, want 11", spec, page.Total)
+			}
+			paged = append(paged, pearlIDs(page.Pearls)...)
+			if page.NextCursor == "" {
+				break
+			}
+			opts.Cursor = page.NextCursor
+		}
+
+		if !reflect.DeepEqual(paged, pearlIDs(all)) {
+			t.Errorf("%q: paged = %v, want %v", spec, paged, pearlIDs(all))
+		}
+	}
+}
+
+func TestListPageOffset(t *testing.T) {
+	db := setupPageTestDB(t)
+	sortBy := []SortKey{{Field: "name"}}
+
+	page, err := db.ListPage(ListOptions{Sort: sortBy, Offset: 9})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pearlIDs(page.Pearls); !reflect.DeepEqual(got, []string{"ns1.p09", "ns0.p10"}) {
+		t.Errorf("offset without limit = %v", got)
+	}
+	if page.NextCursor != "" {
+		t.Errorf("last page has cursor %q", page.NextCursor)
+	}
+
+	page, err = db.ListPage(ListOptions{Sort: sortBy, Offset: 2, Limit: 2, Namespace: "ns0"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := pearlIDs(page.Pearls); !reflect.DeepEqual(got, []string{"ns0.p04", "ns0.p06"}) {
+		t.Errorf("offset with limit = %v", got)
+	}
+	if page.Total != 6 {
+		t.Errorf("total = %d, want 6", page.Total)
+	}
+}
+
+func TestListPageCursorErrors(t *testing.T) {
+	db := setupPageTestDB(t)
+
+	page, err := db.ListPage(ListOptions{Limit: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := db.List(ListOptions{Sort: []SortKey{{Field: "name"}}, Cursor: page.NextCursor}); err == nil || !strings.Contains(err.Error(), "cursor is for sort") {
+		t.Errorf("cursor with another sort: err = %v", err)
+	}
+	for _, token := range []string{"not base64!", "bm90IGpzb24", page.NextCursor[:len(page.NextCursor)-4]} {
+		if _, err := db.List(ListOptions{Cursor: token}); err == nil {
+			t.Errorf("cursor %q: expected error", token)
+		}
+	}
+}
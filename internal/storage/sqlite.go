@@ -9,39 +9,14 @@ import (
 	"path/filepath"
 	"time"
 
-	"github.com/bmatcuk/doublestar/v4"
 	_ "github.com/mattn/go-sqlite3"
 
 	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/query"
 )
 
-const schema = `
-CREATE TABLE IF NOT EXISTS pearls (
-	id TEXT PRIMARY KEY,
-	name TEXT NOT NULL,
-	namespace TEXT NOT NULL DEFAULT '',
-	type TEXT NOT NULL,
-	tags TEXT NOT NULL DEFAULT '[]',
-	globs TEXT NOT NULL DEFAULT '[]',
-	scopes TEXT NOT NULL DEFAULT '[]',
-	description TEXT NOT NULL DEFAULT '',
-	content_path TEXT NOT NULL DEFAULT '',
-	content_hash TEXT NOT NULL DEFAULT '',
-	refs TEXT NOT NULL DEFAULT '[]',
-	parent TEXT NOT NULL DEFAULT '',
-	connection TEXT,
-	required INTEGER NOT NULL DEFAULT 0,
-	priority INTEGER NOT NULL DEFAULT 0,
-	created_at TEXT NOT NULL,
-	updated_at TEXT NOT NULL,
-	created_by TEXT NOT NULL DEFAULT '',
-	status TEXT NOT NULL DEFAULT 'active'
-);
-
-CREATE INDEX IF NOT EXISTS idx_pearls_namespace ON pearls(namespace);
-CREATE INDEX IF NOT EXISTS idx_pearls_type ON pearls(type);
-CREATE INDEX IF NOT EXISTS idx_pearls_status ON pearls(status);
-`
+// pearlColumns lists the pearls table columns in scan order.
+const pearlColumns = "id, name, namespace, type, tags, globs, scopes, description, content_path, content_hash, refs, parent, connection, required, priority, created_at, updated_at, created_by, status, extra, last_verified_at, verified_by, owners"
 
 // DB wraps the SQLite database connection.
 type DB struct {
@@ -57,18 +32,20 @@ func OpenDB(path string) (*DB, error) {
 		return nil, fmt.Errorf("create db directory: %w", err)
 	}
 
-	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=5000")
+	// Immediate transactions take the write lock up front, so concurrent
+	// openers and writers wait on the busy timeout instead of failing.
+	db, err := sql.Open("sqlite3", path+"?_journal_mode=WAL&_busy_timeout=5000&_txlock=immediate")
 	if err != nil {
 		return nil, fmt.Errorf("open database: %w", err)
 	}
 
-	// Initialize schema
-	if _, err := db.Exec(schema); err != nil {
+	d := &DB{db: db, path: path}
+	if _, err := d.Migrate(); err != nil {
 		db.Close()
-		return nil, fmt.Errorf("initialize schema: %w", err)
+		return nil, err
 	}
 
-	return &DB{db: db, path: path}, nil
+	return d, nil
 }
 
 // Close closes the database connection.
@@ -81,8 +58,45 @@ func (d *DB) Path() string {
 	return d.path
 }
 
+// GetMeta returns a value from the metadata table, or "" if the key is unset.
+func (d *DB) GetMeta(key string) (string, error) {
+	return getMeta(d.db, key)
+}
+
+// SetMeta stores a value in the metadata table.
+func (d *DB) SetMeta(key, value string) error {
+	return setMeta(d.db, key, value)
+}
+
+func getMeta(ex execer, key string) (string, error) {
+	var value string
+	err := ex.QueryRow("SELECT value FROM meta WHERE key = ?", key).Scan(&value)
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("get meta %s: %w", key, err)
+	}
+	return value, nil
+}
+
+func setMeta(ex execer, key, value string) error {
+	_, err := ex.Exec(`
+		INSERT INTO meta (key, value) VALUES (?, ?)
+		ON CONFLICT(key) DO UPDATE SET value = excluded.value
+	`, key, value)
+	if err != nil {
+		return fmt.Errorf("set meta %s: %w", key, err)
+	}
+	return nil
+}
+
 // Insert adds a new pearl to the database.
 func (d *DB) Insert(p *pearl.Pearl) error {
+	return insertPearl(d.db, p)
+}
+
+func insertPearl(ex execer, p *pearl.Pearl) error {
 	tags, err := json.Marshal(p.Tags)
 	if err != nil {
 		return fmt.Errorf("marshal tags: %w", err)
@@ -111,25 +125,45 @@ func (d *DB) Insert(p *pearl.Pearl) error {
 		}
 	}
 
-	_, err = d.db.Exec(`
-		INSERT INTO pearls (id, name, namespace, type, tags, globs, scopes, description, content_path, content_hash, refs, parent, connection, required, priority, created_at, updated_at, created_by, status)
-		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	owners, err := json.Marshal(p.Owners)
+	if err != nil {
+		return fmt.Errorf("marshal owners: %w", err)
+	}
+
+	extra, err := json.Marshal(p.Extra)
+	if err != nil {
+		return fmt.Errorf("marshal extra: %w", err)
+	}
+
+	var verifiedAt *string
+	if p.LastVerifiedAt != nil {
+		v := p.LastVerifiedAt.Format(time.RFC3339)
+		verifiedAt = &v
+	}
+
+	_, err = ex.Exec(`
+		INSERT INTO pearls (`+pearlColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
 	`,
 		p.ID, p.Name, p.Namespace, p.Type, tags, globs, scopes, p.Description,
 		p.ContentPath, p.ContentHash, refs, p.Parent, connJSON,
 		p.Required, p.Priority,
 		p.CreatedAt.Format(time.RFC3339), p.UpdatedAt.Format(time.RFC3339),
-		p.CreatedBy, p.Status,
+		p.CreatedBy, p.Status, extra, verifiedAt, p.VerifiedBy, owners,
 	)
 	if err != nil {
 		return fmt.Errorf("insert pearl: %w", err)
 	}
 
-	return nil
+	return indexGlobs(ex, p.ID, p.Globs)
 }
 
 // Update updates an existing pearl in the database.
 func (d *DB) Update(p *pearl.Pearl) error {
+	return updatePearl(d.db, p)
+}
+
+func updatePearl(ex execer, p *pearl.Pearl) error {
 	tags, err := json.Marshal(p.Tags)
 	if err != nil {
 		return fmt.Errorf("marshal tags: %w", err)
@@ -158,17 +192,35 @@ func (d *DB) Update(p *pearl.Pearl) error {
 		}
 	}
 
-	result, err := d.db.Exec(`
+	owners, err := json.Marshal(p.Owners)
+	if err != nil {
+		return fmt.Errorf("marshal owners: %w", err)
+	}
+
+	extra, err := json.Marshal(p.Extra)
+	if err != nil {
+		return fmt.Errorf("marshal extra: %w", err)
+	}
+
+	var verifiedAt *string
+	if p.LastVerifiedAt != nil {
+		v := p.LastVerifiedAt.Format(time.RFC3339)
+		verifiedAt = &v
+	}
+
+	result, err := ex.Exec(`
 		UPDATE pearls SET
 			name = ?, namespace = ?, type = ?, tags = ?, globs = ?, scopes = ?, description = ?,
 			content_path = ?, content_hash = ?, refs = ?, parent = ?,
-			connection = ?, required = ?, priority = ?, updated_at = ?, created_by = ?, status = ?
+			connection = ?, required = ?, priority = ?, updated_at = ?, created_by = ?, status = ?,
+			extra = ?, last_verified_at = ?, verified_by = ?, owners = ?
 		WHERE id = ?
 	`,
 		p.Name, p.Namespace, p.Type, tags, globs, scopes, p.Description,
 		p.ContentPath, p.ContentHash, refs, p.Parent, connJSON,
 		p.Required, p.Priority,
 		p.UpdatedAt.Format(time.RFC3339), p.CreatedBy, p.Status,
+		extra, verifiedAt, p.VerifiedBy, owners,
 		p.ID,
 	)
 	if err != nil {
@@ -183,12 +235,16 @@ func (d *DB) Update(p *pearl.Pearl) error {
 		return fmt.Errorf("pearl not found: %s", p.ID)
 	}
 
-	return nil
+	return indexGlobs(ex, p.ID, p.Globs)
 }
 
 // Delete removes a pearl from the database.
 func (d *DB) Delete(id string) error {
-	result, err := d.db.Exec("DELETE FROM pearls WHERE id = ?", id)
+	return deletePearl(d.db, id)
+}
+
+func deletePearl(ex execer, id string) error {
+	result, err := ex.Exec("DELETE FROM pearls WHERE id = ?", id)
 	if err != nil {
 		return fmt.Errorf("delete pearl: %w", err)
 	}
@@ -201,13 +257,17 @@ func (d *DB) Delete(id string) error {
 		return fmt.Errorf("pearl not found: %s", id)
 	}
 
-	return nil
+	return unindexGlobs(ex, id)
 }
 
 // Get retrieves a pearl by ID.
 func (d *DB) Get(id string) (*pearl.Pearl, error) {
-	row := d.db.QueryRow(`
-		SELECT id, name, namespace, type, tags, globs, scopes, description, content_path, content_hash, refs, parent, connection, required, priority, created_at, updated_at, created_by, status
+	return getPearl(d.db, id)
+}
+
+func getPearl(ex execer, id string) (*pearl.Pearl, error) {
+	row := ex.QueryRow(`
+		SELECT `+pearlColumns+`
 		FROM pearls WHERE id = ?
 	`, id)
 
@@ -216,44 +276,30 @@ func (d *DB) Get(id string) (*pearl.Pearl, error) {
 
 // List retrieves all pearls matching the given filters.
 func (d *DB) List(opts ListOptions) ([]*pearl.Pearl, error) {
-	query := "SELECT id, name, namespace, type, tags, globs, scopes, description, content_path, content_hash, refs, parent, connection, required, priority, created_at, updated_at, created_by, status FROM pearls WHERE 1=1"
-	args := []interface{}{}
+	return listPearls(d.db, opts)
+}
 
-	if opts.Namespace != "" {
-		query += " AND (namespace = ? OR namespace LIKE ?)"
-		args = append(args, opts.Namespace, opts.Namespace+".%")
-	}
-	if opts.Type != "" {
-		query += " AND type = ?"
-		args = append(args, opts.Type)
-	}
-	if opts.Status != "" {
-		query += " AND status = ?"
-		args = append(args, opts.Status)
-	}
-	if opts.Tag != "" {
-		query += " AND tags LIKE ?"
-		args = append(args, "%\""+opts.Tag+"\"%")
-	}
-	if opts.Scope != "" {
-		query += " AND scopes LIKE ?"
-		args = append(args, fmt.Sprintf(`%%"%s"%%`, opts.Scope))
-	}
-	if opts.Required != nil {
-		if *opts.Required {
-			query += " AND required = 1"
-		} else {
-			query += " AND required = 0"
-		}
+func listPearls(ex execer, opts ListOptions) ([]*pearl.Pearl, error) {
+	where, args := listWhere(opts)
+	after, afterArgs, err := cursorClause(opts.Sort, opts.Cursor)
+	if err != nil {
+		return nil, err
 	}
+	query := "SELECT " + pearlColumns + " FROM pearls WHERE 1=1" + where + after
+	args = append(args, afterArgs...)
 
-	query += " ORDER BY priority DESC, namespace, name"
+	query += orderBy(sortKeys(opts.Sort))
 
 	if opts.Limit > 0 {
 		query += fmt.Sprintf(" LIMIT %d", opts.Limit)
+	} else if opts.Offset > 0 {
+		query += " LIMIT -1"
+	}
+	if opts.Offset > 0 {
+		query += fmt.Sprintf(" OFFSET %d", opts.Offset)
 	}
 
-	rows, err := d.db.Query(query, args...)
+	rows, err := ex.Query(query, args...)
 	if err != nil {
 		return nil, fmt.Errorf("query pearls: %w", err)
 	}
@@ -271,15 +317,76 @@ func (d *DB) List(opts ListOptions) ([]*pearl.Pearl, error) {
 	return pearls, rows.Err()
 }
 
+// countPearls counts the pearls matching the filters, ignoring paging.
+func countPearls(ex execer, opts ListOptions) (int, error) {
+	where, args := listWhere(opts)
+	var n int
+	if err := ex.QueryRow("SELECT COUNT(*) FROM pearls WHERE 1=1"+where, args...).Scan(&n); err != nil {
+		return 0, fmt.Errorf("count pearls: %w", err)
+	}
+	return n, nil
+}
+
+// listWhere builds the conditions for the filters in opts.
+func listWhere(opts ListOptions) (string, []interface{}) {
+	var where string
+	args := []interface{}{}
+
+	if opts.Namespace != "" {
+		where += " AND (namespace = ? OR namespace LIKE ?)"
+		args = append(args, opts.Namespace, opts.Namespace+".%")
+	}
+	if opts.Type != "" {
+		where += " AND type = ?"
+		args = append(args, opts.Type)
+	}
+	if opts.Status != "" {
+		where += " AND status = ?"
+		args = append(args, opts.Status)
+	}
+	if opts.Tag != "" {
+		where += " AND tags LIKE ?"
+		args = append(args, "%\""+opts.Tag+"\"%")
+	}
+	if clause, scopeArgs := scopeClause(opts.Scopes); clause != "" {
+		where += clause
+		args = append(args, scopeArgs...)
+	}
+	if opts.Required != nil {
+		if *opts.Required {
+			where += " AND required = 1"
+		} else {
+			where += " AND required = 0"
+		}
+	}
+	if opts.Where != nil {
+		where += " AND (" + opts.Where.SQL + ")"
+		args = append(args, opts.Where.Args...)
+	}
+	return where, args
+}
+
 // ListOptions specifies filters for listing pearls.
 type ListOptions struct {
 	Namespace string
 	Type      string
 	Status    string
 	Tag       string
-	Scope     string
-	Required  *bool // Filter by required field (nil = no filter)
-	Limit     int
+	// Scopes filters by scope: a pearl must have a scope from every list.
+	// One list means any of its scopes; one list per scope means all.
+	Scopes   [][]string
+	Required *bool // Filter by required field (nil = no filter)
+	// Where is a compiled --query expression.
+	Where *query.Clause
+	// Sort orders results; nil means priority descending, then namespace
+	// and name. Ties are broken by ID.
+	Sort  []SortKey
+	Limit int
+	// Offset skips that many results.
+	Offset int
+	// Cursor resumes after the last pearl of a previous page (see
+	// Page.NextCursor). It must be used with the same Sort.
+	Cursor string
 }
 
 // Search performs a keyword search on pearls using LIKE.
@@ -291,7 +398,7 @@ func (d *DB) Search(query string, limit int) ([]*pearl.Pearl, error) {
 	// Simple LIKE-based search across searchable fields
 	pattern := "%" + query + "%"
 	rows, err := d.db.Query(`
-		SELECT id, name, namespace, type, tags, globs, scopes, description, content_path, content_hash, refs, parent, connection, required, priority, created_at, updated_at, created_by, status
+		SELECT `+pearlColumns+`
 		FROM pearls
 		WHERE id LIKE ? OR name LIKE ? OR namespace LIKE ? OR description LIKE ? OR tags LIKE ?
 		ORDER BY namespace, name
@@ -352,15 +459,21 @@ func (d *DB) FindReferencingPearls(targetID string) ([]string, error) {
 	return ids, rows.Err()
 }
 
-// FindByScope returns all pearls whose scopes JSON array contains the given scope string.
+// FindByScope returns all pearls whose scopes include the given scope.
 func (d *DB) FindByScope(scope string) ([]*pearl.Pearl, error) {
-	pattern := fmt.Sprintf(`%%"%s"%%`, scope)
+	return d.FindByScopes([][]string{{scope}})
+}
+
+// FindByScopes returns all pearls with a scope from every list, ordered
+// by namespace and name.
+func (d *DB) FindByScopes(scopes [][]string) ([]*pearl.Pearl, error) {
+	clause, args := scopeClause(scopes)
 	rows, err := d.db.Query(`
-		SELECT id, name, namespace, type, tags, globs, scopes, description, content_path, content_hash, refs, parent, connection, required, priority, created_at, updated_at, created_by, status
+		SELECT `+pearlColumns+`
 		FROM pearls
-		WHERE scopes LIKE ?
+		WHERE 1=1`+clause+`
 		ORDER BY namespace, name
-	`, pattern)
+	`, args...)
 	if err != nil {
 		return nil, fmt.Errorf("query pearls by scope: %w", err)
 	}
@@ -378,41 +491,29 @@ func (d *DB) FindByScope(scope string) ([]*pearl.Pearl, error) {
 	return pearls, rows.Err()
 }
 
-// FindByGlob returns all pearls that have a glob pattern matching the given path.
-// Since SQLite cannot evaluate doublestar glob patterns, this method loads all
-// pearls with non-empty globs and filters in Go.
-func (d *DB) FindByGlob(path string) ([]*pearl.Pearl, error) {
-	rows, err := d.db.Query(`
-		SELECT id, name, namespace, type, tags, globs, scopes, description, content_path, content_hash, refs, parent, connection, required, priority, created_at, updated_at, created_by, status
-		FROM pearls
-		WHERE globs != '[]' AND globs != '' AND globs != 'null'
-		ORDER BY namespace, name
-	`)
-	if err != nil {
-		return nil, fmt.Errorf("query pearls with globs: %w", err)
-	}
-	defer rows.Close()
-
-	var matched []*pearl.Pearl
-	for rows.Next() {
-		p, err := scanPearlRows(rows)
-		if err != nil {
-			return nil, err
+// scopeClause builds the WHERE conditions for a scope filter. Scopes are
+// compared exactly against the elements of the scopes JSON array.
+func scopeClause(scopes [][]string) (string, []interface{}) {
+	var clause string
+	var args []interface{}
+	for _, alternatives := range scopes {
+		if len(alternatives) == 0 {
+			continue
 		}
-
-		for _, g := range p.Globs {
-			ok, err := doublestar.Match(g, path)
-			if err != nil {
-				continue // skip invalid patterns
-			}
-			if ok {
-				matched = append(matched, p)
-				break
-			}
+		clause += " AND EXISTS (SELECT 1 FROM json_each(pearls.scopes) WHERE value IN (" + placeholders(len(alternatives)) + "))"
+		for _, s := range alternatives {
+			args = append(args, s)
 		}
 	}
+	return clause, args
+}
 
-	return matched, rows.Err()
+// execer is satisfied by both *sql.DB and *sql.Tx, so the same statements
+// can run inside or outside a transaction.
+type execer interface {
+	Exec(query string, args ...interface{}) (sql.Result, error)
+	Query(query string, args ...interface{}) (*sql.Rows, error)
+	QueryRow(query string, args ...interface{}) *sql.Row
 }
 
 // scanner interface for both sql.Row and sql.Rows
@@ -421,59 +522,33 @@ type scanner interface {
 }
 
 func scanPearl(row *sql.Row) (*pearl.Pearl, error) {
-	var p pearl.Pearl
-	var tags, globs, scopes, refs, connJSON []byte
-	var createdAt, updatedAt string
-
-	err := row.Scan(
-		&p.ID, &p.Name, &p.Namespace, &p.Type, &tags, &globs, &scopes, &p.Description,
-		&p.ContentPath, &p.ContentHash, &refs, &p.Parent, &connJSON,
-		&p.Required, &p.Priority,
-		&createdAt, &updatedAt, &p.CreatedBy, &p.Status,
-	)
+	p, err := scanPearlFrom(row)
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
-	if err != nil {
-		return nil, fmt.Errorf("scan pearl: %w", err)
-	}
-
-	if err := json.Unmarshal(tags, &p.Tags); err != nil {
-		return nil, fmt.Errorf("unmarshal tags: %w", err)
-	}
-	if err := json.Unmarshal(globs, &p.Globs); err != nil {
-		return nil, fmt.Errorf("unmarshal globs: %w", err)
-	}
-	if err := json.Unmarshal(scopes, &p.Scopes); err != nil {
-		return nil, fmt.Errorf("unmarshal scopes: %w", err)
-	}
-	if err := json.Unmarshal(refs, &p.References); err != nil {
-		return nil, fmt.Errorf("unmarshal refs: %w", err)
-	}
-	if len(connJSON) > 0 {
-		p.Connection = &pearl.ConnectionInfo{}
-		if err := json.Unmarshal(connJSON, p.Connection); err != nil {
-			return nil, fmt.Errorf("unmarshal connection: %w", err)
-		}
-	}
-
-	p.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
-	p.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
-
-	return &p, nil
+	return p, err
 }
 
 func scanPearlRows(rows *sql.Rows) (*pearl.Pearl, error) {
+	return scanPearlFrom(rows)
+}
+
+func scanPearlFrom(row scanner) (*pearl.Pearl, error) {
 	var p pearl.Pearl
-	var tags, globs, scopes, refs, connJSON []byte
+	var tags, globs, scopes, refs, connJSON, extra, owners []byte
 	var createdAt, updatedAt string
+	var verifiedAt sql.NullString
 
-	err := rows.Scan(
+	err := row.Scan(
 		&p.ID, &p.Name, &p.Namespace, &p.Type, &tags, &globs, &scopes, &p.Description,
 		&p.ContentPath, &p.ContentHash, &refs, &p.Parent, &connJSON,
 		&p.Required, &p.Priority,
-		&createdAt, &updatedAt, &p.CreatedBy, &p.Status,
+		&createdAt, &updatedAt, &p.CreatedBy, &p.Status, &extra,
+		&verifiedAt, &p.VerifiedBy, &owners,
 	)
+	if err == sql.ErrNoRows {
+		return nil, err
+	}
 	if err != nil {
 		return nil, fmt.Errorf("scan pearl: %w", err)
 	}
@@ -490,15 +565,29 @@ func scanPearlRows(rows *sql.Rows) (*pearl.Pearl, error) {
 	if err := json.Unmarshal(refs, &p.References); err != nil {
 		return nil, fmt.Errorf("unmarshal refs: %w", err)
 	}
+	if err := json.Unmarshal(owners, &p.Owners); err != nil {
+		return nil, fmt.Errorf("unmarshal owners: %w", err)
+	}
 	if len(connJSON) > 0 {
 		p.Connection = &pearl.ConnectionInfo{}
 		if err := json.Unmarshal(connJSON, p.Connection); err != nil {
 			return nil, fmt.Errorf("unmarshal connection: %w", err)
 		}
 	}
+	if err := json.Unmarshal(extra, &p.Extra); err != nil {
+		return nil, fmt.Errorf("unmarshal extra: %w", err)
+	}
+	if len(p.Extra) == 0 {
+		p.Extra = nil
+	}
 
 	p.CreatedAt, _ = time.Parse(time.RFC3339, createdAt)
 	p.UpdatedAt, _ = time.Parse(time.RFC3339, updatedAt)
+	if verifiedAt.Valid {
+		if t, err := time.Parse(time.RFC3339, verifiedAt.String); err == nil {
+			p.LastVerifiedAt = &t
+		}
+	}
 
 	return &p, nil
 }
@@ -3,10 +3,13 @@ package storage
 import (
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
 	"testing"
 	"time"
 
 	"github.com/justrnr500/pearls/internal/pearl"
+	"github.com/justrnr500/pearls/internal/query"
 )
 
 func TestDBCRUD(t *testing.T) {
@@ -577,6 +580,148 @@ func TestGlobsAndScopes(t *testing.T) {
 			t.Errorf("len(Scopes) after update = %d, want 3", len(got.Scopes))
 		}
 	})
+
+	// Test FindByGlob honors negated patterns in order
+	t.Run("FindByGlobNegated", func(t *testing.T) {
+		p := &pearl.Pearl{
+			ID: "conv.go-code", Name: "go-code", Namespace: "conv",
+			Type: pearl.TypeCustom, Status: pearl.StatusActive,
+			Globs:     []string{"cmd/**/*.go", "!**/*_test.go", "!cmd/gen/**", "cmd/gen/keep.go"},
+			CreatedAt: now, UpdatedAt: now,
+		}
+		if err := db.Insert(p); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+
+		tests := []struct {
+			path string
+			want int
+		}{
+			{"cmd/app/main.go", 1},
+			{"cmd/app/main_test.go", 0},
+			{"cmd/gen/types.go", 0},
+			{"cmd/gen/keep.go", 1},
+		}
+		for _, tt := range tests {
+			results, err := db.FindByGlob(tt.path)
+			if err != nil {
+				t.Fatalf("find by glob: %v", err)
+			}
+			if len(results) != tt.want {
+				t.Errorf("FindByGlob(%q) = %d matches, want %d", tt.path, len(results), tt.want)
+			}
+		}
+	})
+}
+
+func TestFindByScopes(t *testing.T) {
+	db, err := OpenDB(filepath.Join(t.TempDir(), "pearls.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Now().Truncate(time.Second)
+	for _, p := range []*pearl.Pearl{
+		{ID: "a.billing", Name: "billing", Namespace: "a", Scopes: []string{"payments", "pci"}},
+		{ID: "a.checkout", Name: "checkout", Namespace: "a", Scopes: []string{"payments"}},
+		{ID: "a.paywall", Name: "paywall", Namespace: "a", Scopes: []string{"pay"}},
+		{ID: "a.login", Name: "login", Namespace: "a", Scopes: []string{"auth"}},
+	} {
+		p.Type, p.Status, p.CreatedAt, p.UpdatedAt = pearl.TypeCustom, pearl.StatusActive, now, now
+		if err := db.Insert(p); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		scopes [][]string
+		want   []string
+	}{
+		{"exact match only", [][]string{{"pay"}}, []string{"a.paywall"}},
+		{"any", [][]string{{"pci", "auth"}}, []string{"a.billing", "a.login"}},
+		{"all", [][]string{{"payments"}, {"pci"}}, []string{"a.billing"}},
+		{"none", [][]string{{"payments"}, {"auth"}}, nil},
+	}
+	for _, tt := range tests {
+		results, err := db.FindByScopes(tt.scopes)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if got := pearlIDs(results); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	results, err := db.List(ListOptions{Scopes: [][]string{{"payments"}}})
+	if err != nil {
+		t.Fatalf("list: %v", err)
+	}
+	if got := pearlIDs(results); !reflect.DeepEqual(got, []string{"a.billing", "a.checkout"}) {
+		t.Errorf("list by scope = %v", got)
+	}
+}
+
+func TestListWhere(t *testing.T) {
+	db, err := OpenDB(filepath.Join(t.TempDir(), "pearls.db"))
+	if err != nil {
+		t.Fatalf("open db: %v", err)
+	}
+	defer db.Close()
+
+	now := time.Date(2025, 6, 30, 12, 0, 0, 0, time.UTC)
+	east := time.FixedZone("east", 9*60*60)
+	for _, p := range []*pearl.Pearl{
+		{ID: "db.users", Name: "users", Namespace: "db", Type: pearl.TypeTable, Tags: []string{"pii"}, UpdatedAt: now.AddDate(0, 0, -3)},
+		{ID: "db.pg.orders", Name: "orders", Namespace: "db.pg", Type: pearl.TypeTable, Tags: []string{"sensitive"}, UpdatedAt: now.AddDate(0, 0, -60)},
+		{ID: "db.pg.events", Name: "events", Namespace: "db.pg", Type: pearl.TypeTable, Tags: []string{"pii"}, Status: pearl.StatusArchived, UpdatedAt: now.AddDate(0, 0, -1)},
+		{ID: "dbx.logs", Name: "logs", Namespace: "dbx", Type: pearl.TypeTable, Tags: []string{"pii"}, UpdatedAt: now.AddDate(0, 0, -2).In(east)},
+		{ID: "api.auth", Name: "auth", Namespace: "api", Type: pearl.TypeAPI, Description: "Login 100% of users", Scopes: []string{"payments"}, UpdatedAt: now},
+	} {
+		p.CreatedAt = now.AddDate(-1, 0, 0)
+		if p.Status == "" {
+			p.Status = pearl.StatusActive
+		}
+		if err := db.Insert(p); err != nil {
+			t.Fatalf("insert: %v", err)
+		}
+	}
+
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"type:table (tag:pii OR tag:sensitive) updated:>30d -status:archived ns:db.*", []string{"db.users"}},
+		{"ns:db", []string{"db.pg.events", "db.pg.orders", "db.users"}},
+		{"ns:db* -ns:db.pg", []string{"db.users", "dbx.logs"}},
+		{"updated:<30d", []string{"db.pg.orders"}},
+		{"updated:>=2025-06-28T00:00:00Z updated:<2025-06-29T00:00:00Z", []string{"dbx.logs"}},
+		{"tag:p*", []string{"db.pg.events", "db.users", "dbx.logs"}},
+		{"users", []string{"api.auth", "db.users"}},
+		{`"100%"`, []string{"api.auth"}},
+		{"scope:backend", []string{"api.auth"}},
+		{"-has:tags", []string{"api.auth"}},
+		{"-has:verified type:api", []string{"api.auth"}},
+	}
+	for _, tt := range tests {
+		clause, err := query.ParseAndCompile(tt.query, query.Options{
+			Now:         now,
+			ExpandScope: func(s string) []string { return []string{s, "payments"} },
+		})
+		if err != nil {
+			t.Fatalf("%s: %v", tt.query, err)
+		}
+		results, err := db.List(ListOptions{Where: clause})
+		if err != nil {
+			t.Fatalf("%s: list: %v", tt.query, err)
+		}
+		got := pearlIDs(results)
+		sort.Strings(got)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
 }
 
 func TestEndToEndContextInjection(t *testing.T) {
@@ -975,3 +1120,106 @@ func TestRequiredAndPriority(t *testing.T) {
 		}
 	})
 }
+
+func TestStoreStaleness(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonlPath := filepath.Join(tmpDir, "pearls.jsonl")
+	store, err := NewStore(filepath.Join(tmpDir, "pearls.db"), jsonlPath, filepath.Join(tmpDir, "content"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now().Truncate(time.Second)
+	a := &pearl.Pearl{
+		ID: "db.a", Name: "a", Namespace: "db",
+		Type: pearl.TypeTable, Status: pearl.StatusActive,
+		CreatedAt: now, UpdatedAt: now,
+	}
+	if err := store.Create(a, "# a"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	stale, err := store.IsStale()
+	if err != nil {
+		t.Fatalf("is stale: %v", err)
+	}
+	if stale {
+		t.Error("store should be fresh after its own write")
+	}
+
+	// Simulate a git pull that adds a pearl to the JSONL only
+	b := &pearl.Pearl{
+		ID: "db.b", Name: "b", Namespace: "db",
+		Type: pearl.TypeTable, Status: pearl.StatusActive,
+		CreatedAt: now, UpdatedAt: now,
+	}
+	if err := NewJSONL(jsonlPath).Append(b); err != nil {
+		t.Fatalf("append: %v", err)
+	}
+
+	if stale, _ := store.IsStale(); !stale {
+		t.Error("store should be stale after JSONL changed underneath it")
+	}
+
+	rebuilt, err := store.EnsureFresh()
+	if err != nil {
+		t.Fatalf("ensure fresh: %v", err)
+	}
+	if !rebuilt {
+		t.Error("expected a rebuild")
+	}
+	if got, _ := store.Get("db.b"); got == nil {
+		t.Error("db.b should be present after rebuild")
+	}
+
+	rebuilt, _ = store.EnsureFresh()
+	if rebuilt {
+		t.Error("second EnsureFresh should be a no-op")
+	}
+}
+
+func TestVerificationFields(t *testing.T) {
+	tmpDir := t.TempDir()
+	jsonlPath := filepath.Join(tmpDir, "pearls.jsonl")
+	store, err := NewStore(filepath.Join(tmpDir, "pearls.db"), jsonlPath, filepath.Join(tmpDir, "content"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	now := time.Now().Truncate(time.Second)
+	p := &pearl.Pearl{
+		ID: "db.a", Name: "a", Namespace: "db",
+		Type: pearl.TypeTable, Status: pearl.StatusActive,
+		CreatedAt: now, UpdatedAt: now,
+	}
+	if err := store.Create(p, "# a"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	got, _ := store.Get("db.a")
+	if got.LastVerifiedAt != nil || !got.LastReviewed().Equal(now) {
+		t.Errorf("unverified pearl: LastVerifiedAt = %v, LastReviewed = %v", got.LastVerifiedAt, got.LastReviewed())
+	}
+
+	verified := now.Add(time.Hour)
+	got.LastVerifiedAt = &verified
+	got.VerifiedBy = "alice"
+	if err := store.Update(got, nil); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+
+	got, _ = store.Get("db.a")
+	if got.LastVerifiedAt == nil || !got.LastVerifiedAt.Equal(verified) || got.VerifiedBy != "alice" {
+		t.Errorf("db: verified = %v by %q", got.LastVerifiedAt, got.VerifiedBy)
+	}
+
+	records, err := NewJSONL(jsonlPath).ReadAll()
+	if err != nil {
+		t.Fatalf("read jsonl: %v", err)
+	}
+	if len(records) != 1 || records[0].LastVerifiedAt == nil || !records[0].LastVerifiedAt.Equal(verified) {
+		t.Errorf("jsonl: records = %+v", records)
+	}
+}
@@ -2,16 +2,32 @@ package storage
 
 import (
 	"fmt"
+	"os"
+	"strings"
+	"time"
 
+	"github.com/justrnr500/pearls/internal/history"
 	"github.com/justrnr500/pearls/internal/pearl"
 )
 
+// metaJSONLHash is the meta key holding the hash of the JSONL file as of the
+// last sync between the database and JSONL.
+const metaJSONLHash = "jsonl_hash"
+
 // Store provides a unified interface to pearl storage.
 // It syncs between SQLite (fast queries) and JSONL (git-tracked source of truth).
+//
+// When frontmatter mode is enabled, content files also carry their pearl's
+// metadata in a YAML block. Precedence is then frontmatter > JSONL > SQLite:
+// fields named in a content file's frontmatter win over the JSONL record, and
+// SQLite is always rebuilt from the result.
 type Store struct {
-	db      *DB
-	jsonl   *JSONL
-	content *Content
+	db          *DB
+	jsonl       *JSONL
+	content     *Content
+	frontmatter bool
+	history     *history.Log
+	recovered   bool // leftover temp files cleaned up
 }
 
 // NewStore creates a new store with the given paths.
@@ -48,6 +64,27 @@ func (s *Store) JSONL() *JSONL {
 	return s.jsonl
 }
 
+// SetFrontmatter enables or disables frontmatter mode.
+func (s *Store) SetFrontmatter(enabled bool) {
+	s.frontmatter = enabled
+}
+
+// Frontmatter reports whether frontmatter mode is enabled.
+func (s *Store) Frontmatter() bool {
+	return s.frontmatter
+}
+
+// SetHistory enables change history: every Create, Update, Delete, and
+// Restore appends a revision to log.
+func (s *Store) SetHistory(log *history.Log) {
+	s.history = log
+}
+
+// History returns the change history log, or nil if history is disabled.
+func (s *Store) History() *history.Log {
+	return s.history
+}
+
 // Create creates a new pearl with content.
 func (s *Store) Create(p *pearl.Pearl, content string) error {
 	// Generate content path if not set
@@ -55,28 +92,32 @@ func (s *Store) Create(p *pearl.Pearl, content string) error {
 		p.ContentPath = s.content.PathForPearl(p.Namespace, p.Name)
 	}
 
-	// Write content file
-	if content != "" {
-		if err := s.content.Write(p.ContentPath, content); err != nil {
-			return fmt.Errorf("write content: %w", err)
+	// In frontmatter mode, metadata in supplied content wins, then the
+	// complete block is written back so the file is authoritative.
+	if s.frontmatter && content != "" {
+		rendered, err := s.withFrontmatter(p, content)
+		if err != nil {
+			return err
 		}
-		p.ContentHash = HashString(content)
+		content = rendered
 	}
 
-	// Insert into database
-	if err := s.db.Insert(p); err != nil {
-		// Rollback content file on failure
-		s.content.Delete(p.ContentPath)
-		return fmt.Errorf("insert pearl: %w", err)
-	}
+	return s.mutate(func(m *mutation) error {
+		// Write content file
+		if content != "" {
+			if err := m.writeContent(p.ContentPath, content); err != nil {
+				return fmt.Errorf("write content: %w", err)
+			}
+			p.ContentHash = HashString(content)
+		}
 
-	// Append to JSONL
-	if err := s.jsonl.Append(p); err != nil {
-		// Note: DB insert succeeded, JSONL can be rebuilt from DB
-		return fmt.Errorf("append to jsonl: %w", err)
-	}
+		// Insert into database
+		if err := m.tx.Insert(p); err != nil {
+			return fmt.Errorf("insert pearl: %w", err)
+		}
 
-	return nil
+		return m.record(history.OpCreate, nil, p, "")
+	})
 }
 
 // Get retrieves a pearl by ID.
@@ -84,64 +125,130 @@ func (s *Store) Get(id string) (*pearl.Pearl, error) {
 	return s.db.Get(id)
 }
 
-// GetContent retrieves a pearl's markdown content.
+// GetContent retrieves a pearl's markdown content, without any frontmatter.
 func (s *Store) GetContent(p *pearl.Pearl) (string, error) {
 	if p.ContentPath == "" {
 		return "", nil
 	}
-	return s.content.Read(p.ContentPath)
+	content, err := s.content.Read(p.ContentPath)
+	if err != nil {
+		return "", err
+	}
+	return StripFrontmatter(content), nil
 }
 
 // Update updates a pearl and optionally its content.
 func (s *Store) Update(p *pearl.Pearl, content *string) error {
-	// Update content if provided
-	if content != nil && p.ContentPath != "" {
-		if err := s.content.Write(p.ContentPath, *content); err != nil {
-			return fmt.Errorf("write content: %w", err)
+	// In frontmatter mode, metadata changes must be mirrored into the file
+	// even when the body is unchanged.
+	if s.frontmatter && p.ContentPath != "" && (content != nil || s.content.Exists(p.ContentPath)) {
+		var body string
+		if content != nil {
+			body = *content
+		} else {
+			existing, err := s.content.Read(p.ContentPath)
+			if err != nil {
+				return fmt.Errorf("read content: %w", err)
+			}
+			body = existing
+		}
+		rendered, err := RenderFrontmatter(FrontmatterFor(p), StripFrontmatter(body))
+		if err != nil {
+			return err
 		}
-		p.ContentHash = HashString(*content)
+		content = &rendered
 	}
 
-	// Update in database
-	if err := s.db.Update(p); err != nil {
-		return fmt.Errorf("update pearl: %w", err)
-	}
+	return s.mutate(func(m *mutation) error {
+		before, err := m.tx.Get(p.ID)
+		if err != nil {
+			return fmt.Errorf("get pearl: %w", err)
+		}
+		hashBefore, err := m.saveContent(p.ContentPath)
+		if err != nil {
+			return err
+		}
 
-	// Rewrite JSONL (full rebuild for updates)
-	if err := s.syncToJSONL(); err != nil {
-		return fmt.Errorf("sync to jsonl: %w", err)
-	}
+		// Update content if provided
+		if content != nil && p.ContentPath != "" {
+			if err := m.writeContent(p.ContentPath, *content); err != nil {
+				return fmt.Errorf("write content: %w", err)
+			}
+			p.ContentHash = HashString(*content)
+		}
 
-	return nil
+		// Update in database
+		if err := m.tx.Update(p); err != nil {
+			return fmt.Errorf("update pearl: %w", err)
+		}
+
+		return m.record(history.OpUpdate, before, p, hashBefore)
+	})
+}
+
+// Restore returns a pearl to a past state: its metadata and, if content is
+// non-nil, its content. A deleted pearl is recreated.
+func (s *Store) Restore(p *pearl.Pearl, content *string) error {
+	return s.mutate(func(m *mutation) error {
+		before, err := m.tx.Get(p.ID)
+		if err != nil {
+			return fmt.Errorf("get pearl: %w", err)
+		}
+		hashBefore, err := m.saveContent(p.ContentPath)
+		if err != nil {
+			return err
+		}
+
+		if content != nil && p.ContentPath != "" {
+			if err := m.writeContent(p.ContentPath, *content); err != nil {
+				return fmt.Errorf("write content: %w", err)
+			}
+			p.ContentHash = HashString(*content)
+		}
+
+		if before == nil {
+			err = m.tx.Insert(p)
+		} else {
+			err = m.tx.Update(p)
+		}
+		if err != nil {
+			return fmt.Errorf("restore pearl: %w", err)
+		}
+
+		return m.record(history.OpRestore, before, p, hashBefore)
+	})
 }
 
 // Delete removes a pearl and its content.
 func (s *Store) Delete(id string) error {
-	// Get pearl first to find content path
-	p, err := s.db.Get(id)
-	if err != nil {
-		return fmt.Errorf("get pearl: %w", err)
-	}
-	if p == nil {
-		return fmt.Errorf("pearl not found: %s", id)
-	}
+	return s.mutate(func(m *mutation) error {
+		// Get pearl first to find content path
+		p, err := m.tx.Get(id)
+		if err != nil {
+			return fmt.Errorf("get pearl: %w", err)
+		}
+		if p == nil {
+			return fmt.Errorf("pearl not found: %s", id)
+		}
 
-	// Delete from database
-	if err := s.db.Delete(id); err != nil {
-		return fmt.Errorf("delete pearl: %w", err)
-	}
+		// Delete from database
+		if err := m.tx.Delete(id); err != nil {
+			return fmt.Errorf("delete pearl: %w", err)
+		}
 
-	// Delete content file
-	if p.ContentPath != "" {
-		s.content.Delete(p.ContentPath)
-	}
+		// Keep the last content so the pearl can be restored
+		hashBefore, err := m.saveContent(p.ContentPath)
+		if err != nil {
+			return err
+		}
 
-	// Rewrite JSONL
-	if err := s.syncToJSONL(); err != nil {
-		return fmt.Errorf("sync to jsonl: %w", err)
-	}
+		// Delete content file
+		if p.ContentPath != "" {
+			m.removeContent(p.ContentPath)
+		}
 
-	return nil
+		return m.record(history.OpDelete, p, nil, hashBefore)
+	})
 }
 
 // List retrieves pearls matching the given options.
@@ -149,6 +256,12 @@ func (s *Store) List(opts ListOptions) ([]*pearl.Pearl, error) {
 	return s.db.List(opts)
 }
 
+// ListPage lists one page of pearls with the total count and a cursor for
+// the next page.
+func (s *Store) ListPage(opts ListOptions) (*Page, error) {
+	return s.db.ListPage(opts)
+}
+
 // Search performs a full-text search.
 func (s *Store) Search(query string, limit int) ([]*pearl.Pearl, error) {
 	return s.db.Search(query, limit)
@@ -159,74 +272,233 @@ func (s *Store) FindByScope(scope string) ([]*pearl.Pearl, error) {
 	return s.db.FindByScope(scope)
 }
 
+// FindByScopes returns all pearls with a scope from every list.
+func (s *Store) FindByScopes(scopes [][]string) ([]*pearl.Pearl, error) {
+	return s.db.FindByScopes(scopes)
+}
+
 // FindByGlob returns all pearls whose glob patterns match the given file path.
 func (s *Store) FindByGlob(path string) ([]*pearl.Pearl, error) {
 	return s.db.FindByGlob(path)
 }
 
+// FindByGlobs matches many file paths in one call, returning the matching
+// pearls per path.
+func (s *Store) FindByGlobs(paths []string) (map[string][]*pearl.Pearl, error) {
+	return s.db.FindByGlobs(paths)
+}
+
 // SyncFromJSONL rebuilds the database from the JSONL file.
 // This is the "JSONL is source of truth" operation.
 func (s *Store) SyncFromJSONL() error {
+	return s.mutate(func(m *mutation) error {
+		m.writeJSONL = false
+		if m.reloaded {
+			return nil
+		}
+		return s.reload(m)
+	})
+}
+
+// reload replaces the database contents with the JSONL file within m.
+func (s *Store) reload(m *mutation) error {
 	pearls, err := s.jsonl.ReadAll()
 	if err != nil {
 		return fmt.Errorf("read jsonl: %w", err)
 	}
 
+	reconciled := false
+	if s.frontmatter {
+		pearls, reconciled, err = s.reconcileFrontmatter(pearls)
+		if err != nil {
+			return fmt.Errorf("reconcile frontmatter: %w", err)
+		}
+	}
+
 	// Clear existing database
-	if _, err := s.db.db.Exec("DELETE FROM pearls"); err != nil {
-		return fmt.Errorf("clear database: %w", err)
+	if err := m.tx.Clear(); err != nil {
+		return err
 	}
 
 	// Insert all pearls
 	for _, p := range pearls {
-		if err := s.db.Insert(p); err != nil {
+		if err := m.tx.Insert(p); err != nil {
 			return fmt.Errorf("insert pearl %s: %w", p.ID, err)
 		}
 	}
 
+	// Frontmatter edits flow back into the git-tracked JSONL
+	m.reconciled = m.reconciled || reconciled
+	m.reloaded = true
 	return nil
 }
 
-// syncToJSONL writes the database contents to the JSONL file.
-func (s *Store) syncToJSONL() error {
-	pearls, err := s.db.All()
+// IsStale reports whether the JSONL file has changed since the database was
+// last synced with it, e.g. after a git pull or checkout.
+func (s *Store) IsStale() (bool, error) {
+	current, err := s.jsonl.Hash()
 	if err != nil {
-		return fmt.Errorf("get all pearls: %w", err)
+		return false, err
 	}
-
-	return s.jsonl.WriteAll(pearls)
+	recorded, err := s.db.GetMeta(metaJSONLHash)
+	if err != nil {
+		return false, err
+	}
+	return current != recorded, nil
 }
 
-// SyncToJSONL exports the database to JSONL (public version).
-func (s *Store) SyncToJSONL() error {
-	return s.syncToJSONL()
+// EnsureFresh rebuilds the database from JSONL if it is stale.
+// It reports whether a rebuild happened.
+func (s *Store) EnsureFresh() (bool, error) {
+	stale, err := s.IsStale()
+	if err != nil {
+		return false, fmt.Errorf("check staleness: %w", err)
+	}
+	if !stale {
+		return false, nil
+	}
+
+	// Another writer may have synced while we waited for the lock;
+	// mutate rebuilds only if the database is still stale.
+	rebuilt := false
+	err = s.mutate(func(m *mutation) error {
+		rebuilt = m.reloaded
+		m.writeJSONL = false
+		return nil
+	})
+	if err != nil {
+		return false, err
+	}
+	return rebuilt, nil
 }
 
-// RefreshContentHashes updates content hashes for all pearls.
-func (s *Store) RefreshContentHashes() error {
-	pearls, err := s.db.All()
+// withFrontmatter applies any frontmatter already present in content to p and
+// returns the content with a complete, freshly rendered frontmatter block.
+func (s *Store) withFrontmatter(p *pearl.Pearl, content string) (string, error) {
+	fm, body, err := ParseFrontmatter(content)
 	if err != nil {
-		return fmt.Errorf("get all pearls: %w", err)
+		return "", fmt.Errorf("content for %s: %w", p.ID, err)
 	}
+	if fm != nil {
+		fm.Apply(p)
+	}
+	return RenderFrontmatter(FrontmatterFor(p), body)
+}
+
+// reconcileFrontmatter overlays content-file frontmatter onto the JSONL
+// pearls and adopts content files that carry frontmatter but have no JSONL
+// record. It reports whether anything changed.
+func (s *Store) reconcileFrontmatter(pearls []*pearl.Pearl) ([]*pearl.Pearl, bool, error) {
+	changed := false
+	now := time.Now()
+	known := make(map[string]bool)
 
 	for _, p := range pearls {
 		if p.ContentPath == "" {
 			continue
 		}
+		known[p.ContentPath] = true
+		if !s.content.Exists(p.ContentPath) {
+			continue
+		}
+
+		raw, err := s.content.Read(p.ContentPath)
+		if err != nil {
+			return nil, false, err
+		}
+		fm, _, err := ParseFrontmatter(raw)
+		if err != nil {
+			return nil, false, fmt.Errorf("%s: %w", p.ContentPath, err)
+		}
+		if fm != nil && fm.Apply(p) {
+			p.ContentHash = HashString(raw)
+			p.UpdatedAt = now
+			changed = true
+		}
+	}
+
+	files, err := s.content.ListFiles()
+	if err != nil {
+		return nil, false, fmt.Errorf("list content files: %w", err)
+	}
 
-		hash, err := s.content.Hash(p.ContentPath)
+	for _, f := range files {
+		if known[f] {
+			continue
+		}
+		raw, err := s.content.Read(f)
 		if err != nil {
-			continue // Skip missing files
+			return nil, false, err
+		}
+		fm, _, err := ParseFrontmatter(raw)
+		if err != nil || fm == nil || fm.Type == "" {
+			continue // not an authored pearl
 		}
 
-		if hash != p.ContentHash {
-			p.ContentHash = hash
-			if err := s.db.Update(p); err != nil {
-				return fmt.Errorf("update pearl %s: %w", p.ID, err)
-			}
+		id := IDForContentPath(f)
+		if pearl.ValidateNamespace(id) != nil || !pearl.AssetType(fm.Type).IsValid() {
+			continue
+		}
+
+		p := &pearl.Pearl{
+			ID:          id,
+			Name:        pearl.LastSegment(id),
+			Namespace:   pearl.ParentNamespace(id),
+			ContentPath: f,
+			ContentHash: HashString(raw),
+			Status:      pearl.StatusActive,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			CreatedBy:   "pearls-frontmatter",
 		}
+		fm.Apply(p)
+		pearls = append(pearls, p)
+		changed = true
 	}
 
-	return s.syncToJSONL()
+	return pearls, changed, nil
+}
+
+// IDForContentPath derives a pearl ID from a content file path.
+// Example: "db/postgres/users.md" -> "db.postgres.users"
+func IDForContentPath(path string) string {
+	path = strings.TrimSuffix(path, ".md")
+	return strings.ReplaceAll(path, string(os.PathSeparator), ".")
 }
 
+// SyncToJSONL exports the database to JSONL.
+func (s *Store) SyncToJSONL() error {
+	return s.mutate(func(m *mutation) error {
+		return nil
+	})
+}
+
+// RefreshContentHashes updates content hashes for all pearls.
+func (s *Store) RefreshContentHashes() error {
+	return s.mutate(func(m *mutation) error {
+		pearls, err := m.tx.All()
+		if err != nil {
+			return fmt.Errorf("get all pearls: %w", err)
+		}
+
+		for _, p := range pearls {
+			if p.ContentPath == "" {
+				continue
+			}
+
+			hash, err := s.content.Hash(p.ContentPath)
+			if err != nil {
+				continue // Skip missing files
+			}
+
+			if hash != p.ContentHash {
+				p.ContentHash = hash
+				if err := m.tx.Update(p); err != nil {
+					return fmt.Errorf("update pearl %s: %w", p.ID, err)
+				}
+			}
+		}
+
+		return nil
+	})
+}
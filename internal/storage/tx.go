@@ -0,0 +1,328 @@
+package storage
+
+import (
+	"database/sql"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/history"
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+// LockFile is the name of the advisory lock file that serializes writers.
+// It lives next to the JSONL file.
+const LockFile = "pearls.lock"
+
+// tmpSuffix marks files being written atomically (write temp, then rename).
+const tmpSuffix = ".tmp"
+
+// Tx is a database transaction over the pearls tables.
+type Tx struct {
+	tx *sql.Tx
+}
+
+// Begin starts a database transaction.
+func (d *DB) Begin() (*Tx, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("begin transaction: %w", err)
+	}
+	return &Tx{tx: tx}, nil
+}
+
+// Commit commits the transaction.
+func (t *Tx) Commit() error {
+	return t.tx.Commit()
+}
+
+// Rollback aborts the transaction.
+func (t *Tx) Rollback() error {
+	return t.tx.Rollback()
+}
+
+// Insert adds a new pearl within the transaction.
+func (t *Tx) Insert(p *pearl.Pearl) error {
+	return insertPearl(t.tx, p)
+}
+
+// Update updates an existing pearl within the transaction.
+func (t *Tx) Update(p *pearl.Pearl) error {
+	return updatePearl(t.tx, p)
+}
+
+// Delete removes a pearl within the transaction.
+func (t *Tx) Delete(id string) error {
+	return deletePearl(t.tx, id)
+}
+
+// Get retrieves a pearl by ID within the transaction.
+func (t *Tx) Get(id string) (*pearl.Pearl, error) {
+	return getPearl(t.tx, id)
+}
+
+// All retrieves all pearls within the transaction.
+func (t *Tx) All() ([]*pearl.Pearl, error) {
+	return listPearls(t.tx, ListOptions{})
+}
+
+// Clear deletes every pearl within the transaction.
+func (t *Tx) Clear() error {
+	if _, err := t.tx.Exec("DELETE FROM pearls; DELETE FROM glob_index"); err != nil {
+		return fmt.Errorf("clear database: %w", err)
+	}
+	return nil
+}
+
+// GetMeta returns a metadata value within the transaction.
+func (t *Tx) GetMeta(key string) (string, error) {
+	return getMeta(t.tx, key)
+}
+
+// SetMeta stores a metadata value within the transaction.
+func (t *Tx) SetMeta(key, value string) error {
+	return setMeta(t.tx, key, value)
+}
+
+// mutation is a unit of work across all three storage layers. Database
+// changes go through tx; content changes are recorded so they can be undone
+// if a later step fails.
+type mutation struct {
+	tx      *Tx
+	content *Content
+	undo    []func()
+
+	// writeJSONL controls whether the JSONL file is rewritten from the
+	// transaction's view on commit. Rebuilds from JSONL turn it off.
+	writeJSONL bool
+
+	// reloaded is set once the database has been rebuilt from JSONL
+	// within this mutation; reconciled if that rebuild picked up
+	// frontmatter edits, which must flow back into JSONL.
+	reloaded   bool
+	reconciled bool
+
+	// history receives revisions after commit; nil disables recording.
+	history   *history.Log
+	revisions []*history.Revision
+}
+
+// writeContent writes a content file, remembering its previous state.
+func (m *mutation) writeContent(relativePath, content string) error {
+	m.remember(relativePath)
+	return m.content.Write(relativePath, content)
+}
+
+// removeContent deletes a content file, remembering its previous state.
+func (m *mutation) removeContent(relativePath string) error {
+	m.remember(relativePath)
+	return m.content.Delete(relativePath)
+}
+
+func (m *mutation) remember(relativePath string) {
+	fullPath := m.content.FullPath(relativePath)
+	previous, err := os.ReadFile(fullPath)
+	existed := err == nil
+	m.undo = append(m.undo, func() {
+		if existed {
+			m.content.Write(relativePath, string(previous))
+		} else {
+			os.Remove(fullPath)
+		}
+	})
+}
+
+// saveContent copies a content file into the history object store and
+// returns its hash, or "" if history is disabled or the file is missing.
+func (m *mutation) saveContent(relativePath string) (string, error) {
+	if m.history == nil || relativePath == "" || !m.content.Exists(relativePath) {
+		return "", nil
+	}
+	content, err := m.content.Read(relativePath)
+	if err != nil {
+		return "", err
+	}
+	hash, err := m.history.PutObject(content)
+	if err != nil {
+		return "", fmt.Errorf("save history: %w", err)
+	}
+	return hash, nil
+}
+
+// record queues a revision for the change from before to after; either may
+// be nil for creates and deletes. hashBefore is the content hash saved
+// before the change.
+func (m *mutation) record(op history.Op, before, after *pearl.Pearl, hashBefore string) error {
+	if m.history == nil {
+		return nil
+	}
+
+	rev := &history.Revision{Op: op, At: time.Now(), HashBefore: hashBefore}
+	if after != nil {
+		hashAfter, err := m.saveContent(after.ContentPath)
+		if err != nil {
+			return err
+		}
+		// Snapshot the stored row, so values match what later reads return
+		stored, err := m.tx.Get(after.ID)
+		if err != nil {
+			return fmt.Errorf("get pearl: %w", err)
+		}
+		rev.PearlID, rev.Pearl, rev.HashAfter = after.ID, stored, hashAfter
+	} else {
+		rev.PearlID, rev.Pearl = before.ID, before
+	}
+
+	if before != nil && after != nil {
+		rev.Fields = ChangedFields(before, rev.Pearl)
+		if op == history.OpUpdate && len(rev.Fields) == 0 && rev.HashBefore == rev.HashAfter {
+			return nil // nothing but updated_at changed
+		}
+	}
+
+	m.revisions = append(m.revisions, rev)
+	return nil
+}
+
+// rollback restores content files in reverse order.
+func (m *mutation) rollback() {
+	for i := len(m.undo) - 1; i >= 0; i-- {
+		m.undo[i]()
+	}
+}
+
+// mutate runs fn as a single change across SQLite, JSONL, and content files.
+//
+// It holds the store's advisory lock for the duration, so concurrent writers
+// (other processes or other Store instances) are serialized. If the JSONL file
+// changed since the database last saw it, the database is first rebuilt from
+// JSONL so fn never works from a stale view. fn's database changes run in a
+// transaction; afterwards the JSONL file is rewritten
+// atomically from the transaction's view and the transaction is committed.
+// If anything fails before the JSONL rename, the transaction is rolled back
+// and content files are restored. If the commit itself fails after the
+// rename, the recorded JSONL hash no longer matches and the next open
+// rebuilds the database from JSONL.
+func (s *Store) mutate(fn func(m *mutation) error) error {
+	lock, err := acquireLock(s.lockPath())
+	if err != nil {
+		return err
+	}
+	defer lock.release()
+
+	if !s.recovered {
+		s.recoverTempFiles()
+		s.recovered = true
+	}
+
+	tx, err := s.db.Begin()
+	if err != nil {
+		return err
+	}
+
+	m := &mutation{tx: tx, content: s.content, writeJSONL: true, history: s.history}
+	fail := func(err error) error {
+		tx.Rollback()
+		m.rollback()
+		return err
+	}
+
+	current, err := s.jsonl.Hash()
+	if err != nil {
+		return fail(fmt.Errorf("hash jsonl: %w", err))
+	}
+	recorded, err := tx.GetMeta(metaJSONLHash)
+	if err != nil {
+		return fail(err)
+	}
+	if current != recorded {
+		if err := s.reload(m); err != nil {
+			return fail(err)
+		}
+	}
+
+	if err := fn(m); err != nil {
+		return fail(err)
+	}
+
+	if m.writeJSONL || m.reconciled {
+		pearls, err := tx.All()
+		if err != nil {
+			return fail(fmt.Errorf("get all pearls: %w", err))
+		}
+		if err := s.jsonl.WriteAll(pearls); err != nil {
+			return fail(fmt.Errorf("write jsonl: %w", err))
+		}
+	}
+
+	hash, err := s.jsonl.Hash()
+	if err == nil {
+		err = tx.SetMeta(metaJSONLHash, hash)
+	}
+	if err != nil {
+		tx.Rollback()
+		return fmt.Errorf("record jsonl hash: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("commit: %w", err)
+	}
+
+	if len(m.revisions) > 0 {
+		if err := s.history.Append(m.revisions...); err != nil {
+			return fmt.Errorf("record history: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func (s *Store) lockPath() string {
+	return filepath.Join(filepath.Dir(s.jsonl.Path()), LockFile)
+}
+
+// recoverTempFiles removes temp files left behind by a writer that crashed
+// between writing and renaming. The real files are intact because renames
+// are atomic, so the leftovers are safe to discard. Must hold the lock.
+func (s *Store) recoverTempFiles() {
+	os.Remove(s.jsonl.Path() + tmpSuffix)
+
+	filepath.WalkDir(s.content.BaseDir(), func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if !d.IsDir() && strings.HasSuffix(path, ".md"+tmpSuffix) {
+			os.Remove(path)
+		}
+		return nil
+	})
+}
+
+// fileLock is a held advisory lock on a file.
+type fileLock struct {
+	f *os.File
+}
+
+// acquireLock blocks until it holds an exclusive lock on path.
+func acquireLock(path string) (*fileLock, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("create lock directory: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("open lock file: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("acquire lock: %w", err)
+	}
+	return &fileLock{f: f}, nil
+}
+
+// release drops the lock.
+func (l *fileLock) release() {
+	unlockFile(l.f)
+	l.f.Close()
+}
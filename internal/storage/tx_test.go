@@ -0,0 +1,302 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/justrnr500/pearls/internal/history"
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+// writerEnv tells a re-executed test binary to act as a concurrent writer.
+const writerEnv = "PEARLS_TEST_WRITER"
+
+func testPearl(id string) *pearl.Pearl {
+	now := time.Now().Truncate(time.Second)
+	return &pearl.Pearl{
+		ID: id, Name: pearl.LastSegment(id), Namespace: pearl.ParentNamespace(id),
+		Type: pearl.TypeTable, Status: pearl.StatusActive,
+		CreatedAt: now, UpdatedAt: now,
+	}
+}
+
+// TestHelperWriter is not a real test. TestConcurrentWriters runs the test
+// binary again with writerEnv set, and this function does the writing.
+func TestHelperWriter(t *testing.T) {
+	writer := os.Getenv(writerEnv)
+	if writer == "" {
+		t.Skip("helper process only")
+	}
+	dir := os.Getenv("PEARLS_TEST_DIR")
+	count, _ := strconv.Atoi(os.Getenv("PEARLS_TEST_COUNT"))
+
+	store, err := NewStore(filepath.Join(dir, "pearls.db"), filepath.Join(dir, "pearls.jsonl"), filepath.Join(dir, "content"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	for i := 0; i < count; i++ {
+		p := testPearl(fmt.Sprintf("w%s.p%d", writer, i))
+		if err := store.Create(p, "# "+p.ID); err != nil {
+			t.Fatalf("create %s: %v", p.ID, err)
+		}
+		p.Description = "updated"
+		if err := store.Update(p, nil); err != nil {
+			t.Fatalf("update %s: %v", p.ID, err)
+		}
+	}
+}
+
+func TestConcurrentWriters(t *testing.T) {
+	if testing.Short() {
+		t.Skip("spawns processes")
+	}
+
+	const writers, perWriter = 4, 15
+	dir := t.TempDir()
+
+	// Initialize the schema before the writers race to open the database
+	store, err := NewStore(filepath.Join(dir, "pearls.db"), filepath.Join(dir, "pearls.jsonl"), filepath.Join(dir, "content"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	var cmds []*exec.Cmd
+	for w := 0; w < writers; w++ {
+		cmd := exec.Command(os.Args[0], "-test.run=^TestHelperWriter$")
+		cmd.Env = append(os.Environ(),
+			writerEnv+"="+strconv.Itoa(w),
+			"PEARLS_TEST_DIR="+dir,
+			"PEARLS_TEST_COUNT="+strconv.Itoa(perWriter),
+		)
+		if err := cmd.Start(); err != nil {
+			t.Fatalf("start writer %d: %v", w, err)
+		}
+		cmds = append(cmds, cmd)
+	}
+	for w, cmd := range cmds {
+		if err := cmd.Wait(); err != nil {
+			t.Errorf("writer %d failed: %v", w, err)
+		}
+	}
+	if t.Failed() {
+		return
+	}
+
+	fromJSONL, err := store.JSONL().ReadAll()
+	if err != nil {
+		t.Fatalf("read jsonl: %v", err)
+	}
+	fromDB, err := store.DB().All()
+	if err != nil {
+		t.Fatalf("read db: %v", err)
+	}
+
+	want := writers * perWriter
+	if len(fromJSONL) != want {
+		t.Errorf("jsonl has %d pearls, want %d", len(fromJSONL), want)
+	}
+	if len(fromDB) != want {
+		t.Errorf("db has %d pearls, want %d", len(fromDB), want)
+	}
+
+	inDB := make(map[string]*pearl.Pearl)
+	for _, p := range fromDB {
+		inDB[p.ID] = p
+	}
+	for _, p := range fromJSONL {
+		dbp, ok := inDB[p.ID]
+		if !ok {
+			t.Errorf("%s in jsonl but not db", p.ID)
+			continue
+		}
+		if p.Description != "updated" || dbp.Description != "updated" {
+			t.Errorf("%s lost its update: jsonl=%q db=%q", p.ID, p.Description, dbp.Description)
+		}
+	}
+
+	if stale, _ := store.IsStale(); stale {
+		t.Error("database should agree with jsonl after concurrent writes")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pearls.jsonl"+tmpSuffix)); !os.IsNotExist(err) {
+		t.Error("temp jsonl file left behind")
+	}
+}
+
+func TestMutateRollback(t *testing.T) {
+	dir := t.TempDir()
+	jsonlPath := filepath.Join(dir, "pearls.jsonl")
+	store, err := NewStore(filepath.Join(dir, "pearls.db"), jsonlPath, filepath.Join(dir, "content"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	a := testPearl("db.a")
+	if err := store.Create(a, "# a"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// A directory in the way of the temp file makes the JSONL write fail
+	blocker := jsonlPath + tmpSuffix
+	if err := os.MkdirAll(filepath.Join(blocker, "x"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	b := testPearl("db.b")
+	if err := store.Create(b, "# b"); err == nil {
+		t.Fatal("create should fail when jsonl cannot be written")
+	}
+	if got, _ := store.Get("db.b"); got != nil {
+		t.Error("db.b should be rolled back from the database")
+	}
+	if store.Content().Exists(b.ContentPath) {
+		t.Error("db.b content should be removed on rollback")
+	}
+
+	body := "# a, rewritten"
+	a.Description = "changed"
+	if err := store.Update(a, &body); err == nil {
+		t.Fatal("update should fail when jsonl cannot be written")
+	}
+	got, _ := store.Get("db.a")
+	if got == nil || got.Description != "" {
+		t.Errorf("db.a update should be rolled back, got %+v", got)
+	}
+	if content, _ := store.GetContent(got); content != "# a" {
+		t.Errorf("content = %q, want original restored", content)
+	}
+
+	if err := os.RemoveAll(blocker); err != nil {
+		t.Fatal(err)
+	}
+	if stale, _ := store.IsStale(); stale {
+		t.Error("failed writes should leave the database in sync with jsonl")
+	}
+}
+
+func TestRecoverTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	jsonlPath := filepath.Join(dir, "pearls.jsonl")
+	contentDir := filepath.Join(dir, "content")
+
+	// Leftovers from a writer that crashed before renaming
+	leftovers := []string{
+		jsonlPath + tmpSuffix,
+		filepath.Join(contentDir, "db", "users.md"+tmpSuffix),
+	}
+	for _, f := range leftovers {
+		if err := os.MkdirAll(filepath.Dir(f), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(f, []byte("partial"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	store, err := NewStore(filepath.Join(dir, "pearls.db"), jsonlPath, contentDir)
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	if err := store.Create(testPearl("db.a"), "# a"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	for _, f := range leftovers {
+		if _, err := os.Stat(f); !os.IsNotExist(err) {
+			t.Errorf("%s should have been removed", f)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, LockFile)); err != nil {
+		t.Errorf("lock file should exist: %v", err)
+	}
+}
+
+func TestStoreHistory(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewStore(filepath.Join(dir, "pearls.db"), filepath.Join(dir, "pearls.jsonl"), filepath.Join(dir, "content"))
+	if err != nil {
+		t.Fatalf("new store: %v", err)
+	}
+	defer store.Close()
+
+	log := history.NewLog(filepath.Join(dir, "history.jsonl"), filepath.Join(dir, "history"))
+	store.SetHistory(log)
+
+	p := testPearl("db.users")
+	p.Description = "v1"
+	if err := store.Create(p, "# v1"); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// An update that changes nothing but updated_at is not recorded
+	p.UpdatedAt = p.UpdatedAt.Add(time.Second)
+	if err := store.Update(p, nil); err != nil {
+		t.Fatalf("no-op update: %v", err)
+	}
+
+	p.Description = "v2"
+	body := "# v2"
+	if err := store.Update(p, &body); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := store.Delete("db.users"); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	revs, err := log.For("db.users")
+	if err != nil {
+		t.Fatalf("history: %v", err)
+	}
+	var ops []history.Op
+	for _, r := range revs {
+		ops = append(ops, r.Op)
+	}
+	wantOps := []history.Op{history.OpCreate, history.OpUpdate, history.OpDelete}
+	if !reflect.DeepEqual(ops, wantOps) {
+		t.Fatalf("ops = %v, want %v", ops, wantOps)
+	}
+
+	update := revs[1]
+	if !reflect.DeepEqual(update.Fields, []string{"description", "content_hash"}) {
+		t.Errorf("update fields = %v", update.Fields)
+	}
+	if update.HashBefore != HashString("# v1") || update.HashAfter != HashString("# v2") {
+		t.Errorf("update hashes = %s -> %s", update.HashBefore, update.HashAfter)
+	}
+	if revs[2].Pearl == nil || revs[2].Pearl.Description != "v2" {
+		t.Errorf("delete should keep the last state, got %+v", revs[2].Pearl)
+	}
+
+	// Restore the first revision, including its content
+	old, ok, err := log.Object(revs[0].HashAfter)
+	if err != nil || !ok {
+		t.Fatalf("content for r1 not saved: %v", err)
+	}
+	restored := *revs[0].Pearl
+	if err := store.Restore(&restored, &old); err != nil {
+		t.Fatalf("restore: %v", err)
+	}
+	got, _ := store.Get("db.users")
+	if got == nil || got.Description != "v1" {
+		t.Fatalf("restored pearl = %+v, want description v1", got)
+	}
+	if content, _ := store.GetContent(got); content != "# v1" {
+		t.Errorf("restored content = %q", content)
+	}
+
+	revs, _ = log.For("db.users")
+	if last := revs[len(revs)-1]; last.Op != history.OpRestore {
+		t.Errorf("last op = %s, want restore", last.Op)
+	}
+}
@@ -0,0 +1,168 @@
+package validate
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/markdown"
+)
+
+// rules is every rule, in reporting order.
+var rules = []Rule{
+	{
+		ID:          "description",
+		Description: "Pearls have a one-line description",
+		Severity:    SeverityWarning,
+		Check:       checkDescription,
+	},
+	{
+		ID:          "required-sections",
+		Description: "Content has the sections configured for the pearl's type",
+		Severity:    SeverityError,
+		Content:     true,
+		Check:       checkRequiredSections,
+	},
+	{
+		ID:          "unfilled-template",
+		Description: "Sections from the content template have been filled in",
+		Severity:    SeverityWarning,
+		Content:     true,
+		Check:       checkUnfilledTemplate,
+	},
+	{
+		ID:          "max-size",
+		Description: "Content is within the configured size limit",
+		Severity:    SeverityError,
+		Content:     true,
+		Check:       checkMaxSize,
+	},
+	{
+		ID:          "forbidden-phrases",
+		Description: "Content does not contain configured forbidden phrases",
+		Severity:    SeverityError,
+		Content:     true,
+		Check:       checkForbiddenPhrases,
+	},
+	{
+		ID:          "heading-structure",
+		Description: "Content starts with one H1, does not skip heading levels, and has no duplicate sibling headings",
+		Severity:    SeverityWarning,
+		Content:     true,
+		Check:       checkHeadingStructure,
+	},
+}
+
+func checkDescription(_ *config.ValidationConfig, t *Target) []Finding {
+	if strings.TrimSpace(t.Pearl.Description) == "" {
+		return []Finding{{Message: "description is empty"}}
+	}
+	return nil
+}
+
+func checkRequiredSections(cfg *config.ValidationConfig, t *Target) []Finding {
+	var findings []Finding
+	for _, want := range cfg.RequiredSections[string(t.Pearl.Type)] {
+		title := normalizeHeading(want)
+		if t.Doc().Find(title) == nil {
+			findings = append(findings, Finding{Message: fmt.Sprintf("missing required section %q for type %s", title, t.Pearl.Type)})
+		}
+	}
+	return findings
+}
+
+// checkUnfilledTemplate flags sections the template created that are still
+// empty or still hold the template's placeholder content.
+func checkUnfilledTemplate(_ *config.ValidationConfig, t *Target) []Finding {
+	if t.Template == "" {
+		return nil
+	}
+
+	placeholders := make(map[string]string)
+	markdown.Parse(t.Template, 1).Walk(func(s *markdown.Section) {
+		if s.Heading.Level > 1 {
+			placeholders[strings.ToLower(s.Title())] = s.Body()
+		}
+	})
+
+	var findings []Finding
+	t.Doc().Walk(func(s *markdown.Section) {
+		placeholder, ok := placeholders[strings.ToLower(s.Title())]
+		switch {
+		case !ok:
+		case s.Empty():
+			findings = append(findings, Finding{Line: s.Heading.Line, Message: fmt.Sprintf("section %q is empty", s.Title())})
+		case placeholder != "" && s.Body() == placeholder && len(s.Children) == 0:
+			findings = append(findings, Finding{Line: s.Heading.Line, Message: fmt.Sprintf("section %q still has template placeholder content", s.Title())})
+		}
+	})
+	return findings
+}
+
+func checkMaxSize(cfg *config.ValidationConfig, t *Target) []Finding {
+	if cfg.MaxContentBytes > 0 && len(t.Content) > cfg.MaxContentBytes {
+		return []Finding{{Message: fmt.Sprintf("content is %d bytes, over the %d byte limit", len(t.Content), cfg.MaxContentBytes)}}
+	}
+	return nil
+}
+
+func checkForbiddenPhrases(cfg *config.ValidationConfig, t *Target) []Finding {
+	if len(cfg.ForbiddenPhrases) == 0 {
+		return nil
+	}
+
+	var findings []Finding
+	for _, b := range t.Doc().Blocks {
+		if b.Kind == markdown.KindCode {
+			continue
+		}
+		for i, line := range strings.Split(b.Text, "\n") {
+			lower := strings.ToLower(line)
+			for _, phrase := range cfg.ForbiddenPhrases {
+				if strings.Contains(lower, strings.ToLower(phrase)) {
+					findings = append(findings, Finding{Line: b.Line + i, Message: fmt.Sprintf("contains forbidden phrase %q", phrase)})
+				}
+			}
+		}
+	}
+	return findings
+}
+
+func checkHeadingStructure(_ *config.ValidationConfig, t *Target) []Finding {
+	doc := t.Doc()
+	headings := doc.Headings()
+	if len(headings) == 0 {
+		return []Finding{{Line: t.BodyLine, Message: "content has no headings"}}
+	}
+
+	var findings []Finding
+	if headings[0].Level != 1 {
+		findings = append(findings, Finding{Line: headings[0].Line, Message: "content should start with an H1 title"})
+	}
+
+	prev := 0
+	for _, h := range headings {
+		if h.Level == 1 && prev > 0 {
+			findings = append(findings, Finding{Line: h.Line, Message: fmt.Sprintf("extra H1 %q; use one title per pearl", h.Text)})
+		}
+		if prev > 0 && h.Level > prev+1 {
+			findings = append(findings, Finding{Line: h.Line, Message: fmt.Sprintf("heading %q skips from H%d to H%d", h.Text, prev, h.Level)})
+		}
+		prev = h.Level
+	}
+
+	dupes := func(ss []*markdown.Section) {
+		seen := make(map[string]bool)
+		for _, s := range ss {
+			key := strings.ToLower(s.Title())
+			if seen[key] {
+				findings = append(findings, Finding{Line: s.Heading.Line, Message: fmt.Sprintf("duplicate heading %q", s.Title())})
+			}
+			seen[key] = true
+		}
+	}
+	dupes(doc.Sections)
+	doc.Walk(func(s *markdown.Section) { dupes(s.Children) })
+
+	return findings
+}
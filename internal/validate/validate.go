@@ -0,0 +1,163 @@
+// Package validate lints pearls: metadata and markdown content are checked
+// against rules configured in config.yaml.
+package validate
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/markdown"
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+// Severity is how serious a violation is.
+type Severity string
+
+const (
+	SeverityError   Severity = config.SeverityError
+	SeverityWarning Severity = config.SeverityWarning
+	SeverityNote    Severity = config.SeverityNote
+)
+
+// Target is a pearl under validation.
+type Target struct {
+	Pearl *pearl.Pearl
+	// HasContent is false if the pearl has no content file; content rules
+	// are then skipped.
+	HasContent bool
+	// Content is the markdown body, without frontmatter. BodyLine is the
+	// line of the file it starts on.
+	Content  string
+	BodyLine int
+	// Template is the content a new pearl of this type starts with.
+	Template string
+	// Path and MetadataPath locate the content file and the pearl's
+	// metadata record in reports, relative to the repository root.
+	Path         string
+	MetadataPath string
+
+	doc *markdown.Document
+}
+
+// Doc returns the parsed content.
+func (t *Target) Doc() *markdown.Document {
+	if t.doc == nil {
+		t.doc = markdown.Parse(t.Content, t.BodyLine)
+	}
+	return t.doc
+}
+
+// Finding is a rule's report about one problem. Line is 0 when the problem
+// is in metadata rather than content.
+type Finding struct {
+	Line    int
+	Message string
+}
+
+// Rule is a named check with a default severity.
+type Rule struct {
+	ID          string
+	Description string
+	Severity    Severity
+	// Content rules only run on pearls with content.
+	Content bool
+	Check   func(cfg *config.ValidationConfig, t *Target) []Finding
+}
+
+// Violation is a finding attributed to a rule and pearl.
+type Violation struct {
+	PearlID  string   `json:"id"`
+	Rule     string   `json:"rule"`
+	Severity Severity `json:"severity"`
+	Path     string   `json:"path,omitempty"`
+	Line     int      `json:"line,omitempty"`
+	Message  string   `json:"message"`
+}
+
+// Rules returns all rules this build knows.
+func Rules() []Rule {
+	return rules
+}
+
+// Engine applies the enabled rules with their configured severities.
+type Engine struct {
+	cfg   config.ValidationConfig
+	rules []Rule
+}
+
+// New returns an engine for cfg. It fails on unknown rule IDs.
+func New(cfg config.ValidationConfig) (*Engine, error) {
+	if err := cfg.Validate(); err != nil {
+		return nil, err
+	}
+
+	known := make(map[string]bool)
+	for _, r := range rules {
+		known[r.ID] = true
+	}
+	for id := range cfg.Rules {
+		if !known[id] {
+			return nil, fmt.Errorf("validation.rules: unknown rule %q", id)
+		}
+	}
+
+	e := &Engine{cfg: cfg}
+	for _, r := range rules {
+		switch sev := cfg.Rules[r.ID]; sev {
+		case config.SeverityOff:
+			continue
+		case "":
+		default:
+			r.Severity = Severity(sev)
+		}
+		e.rules = append(e.rules, r)
+	}
+	return e, nil
+}
+
+// Rules returns the enabled rules with their effective severities.
+func (e *Engine) Rules() []Rule {
+	return e.rules
+}
+
+// Check runs the enabled rules against t. Violations are ordered by line.
+func (e *Engine) Check(t *Target) []Violation {
+	var vs []Violation
+	for _, r := range e.rules {
+		if r.Content && !t.HasContent {
+			continue
+		}
+		path := t.MetadataPath
+		if r.Content {
+			path = t.Path
+		}
+		for _, f := range r.Check(&e.cfg, t) {
+			vs = append(vs, Violation{
+				PearlID:  t.Pearl.ID,
+				Rule:     r.ID,
+				Severity: r.Severity,
+				Path:     path,
+				Line:     f.Line,
+				Message:  f.Message,
+			})
+		}
+	}
+	sort.SliceStable(vs, func(i, j int) bool { return vs[i].Line < vs[j].Line })
+	return vs
+}
+
+// Count tallies violations by severity.
+func Count(vs []Violation) map[Severity]int {
+	counts := make(map[Severity]int)
+	for _, v := range vs {
+		counts[v.Severity]++
+	}
+	return counts
+}
+
+// normalizeHeading strips leading #'s so "## Columns" and "Columns" match.
+func normalizeHeading(h string) string {
+	return strings.TrimSpace(strings.TrimLeft(strings.TrimSpace(h), "#"))
+}
@@ -0,0 +1,127 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/justrnr500/pearls/internal/config"
+	"github.com/justrnr500/pearls/internal/pearl"
+)
+
+const tableTemplate = "# users\n\n## Schema\n\n| Column | Type |\n|--------|------|\n| id | |\n\n## Relationships\n\n## Notes\n\n"
+
+func target(p *pearl.Pearl, content string) *Target {
+	return &Target{Pearl: p, HasContent: true, Content: content, BodyLine: 1, Template: tableTemplate, Path: "content/db/users.md"}
+}
+
+func rulesHit(vs []Violation) map[string]int {
+	hit := make(map[string]int)
+	for _, v := range vs {
+		hit[v.Rule]++
+	}
+	return hit
+}
+
+func TestEngineRules(t *testing.T) {
+	cfg := config.ValidationConfig{
+		RequiredSections: map[string][]string{"table": {"## Schema", "Columns"}},
+		MaxContentBytes:  200,
+		ForbiddenPhrases: []string{"TBD"},
+	}
+	e, err := New(cfg)
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	users := &pearl.Pearl{ID: "db.users", Type: pearl.TypeTable}
+	content := "# users\n\n## Schema\n\n| Column | Type |\n|--------|------|\n| id | |\n\n## Relationships\n\nOwner is tbd.\n\n```\nTBD in code is fine\n```\n\n#### Deep\n\n## Notes\n\n"
+	vs := e.Check(target(users, content))
+
+	want := map[string]int{
+		"description":       1, // empty
+		"required-sections": 1, // Columns
+		"unfilled-template": 2, // Schema placeholder, Notes empty
+		"forbidden-phrases": 1, // prose only, not the code block
+		"heading-structure": 1, // H2 -> H4
+	}
+	got := rulesHit(vs)
+	for rule, n := range want {
+		if got[rule] != n {
+			t.Errorf("%s: %d violations, want %d (%v)", rule, got[rule], n, vs)
+		}
+	}
+	if got["max-size"] != 0 {
+		t.Errorf("max-size should not fire below the limit")
+	}
+
+	for _, v := range vs {
+		if v.Rule == "forbidden-phrases" && v.Line != 11 {
+			t.Errorf("forbidden phrase line = %d, want 11", v.Line)
+		}
+		if v.Rule == "description" && v.Path != "" {
+			t.Errorf("metadata violation path = %q, want metadata path", v.Path)
+		}
+	}
+
+	big := target(users, tableTemplate+strings.Repeat("x", 200))
+	if rulesHit(e.Check(big))["max-size"] != 1 {
+		t.Error("max-size should fire above the limit")
+	}
+
+	// Content rules are skipped without content
+	noContent := &Target{Pearl: &pearl.Pearl{ID: "x", Description: "X"}}
+	if vs := e.Check(noContent); len(vs) != 0 {
+		t.Errorf("pearl without content: %v", vs)
+	}
+}
+
+func TestEngineSeverities(t *testing.T) {
+	e, err := New(config.ValidationConfig{Rules: map[string]string{
+		"description":       "error",
+		"heading-structure": "off",
+	}})
+	if err != nil {
+		t.Fatalf("new: %v", err)
+	}
+
+	vs := e.Check(target(&pearl.Pearl{ID: "db.users"}, "no headings here\n"))
+	if len(vs) != 1 || vs[0].Rule != "description" || vs[0].Severity != SeverityError {
+		t.Errorf("violations = %+v", vs)
+	}
+	for _, r := range e.Rules() {
+		if r.ID == "heading-structure" {
+			t.Error("disabled rule should not be enabled")
+		}
+	}
+
+	if _, err := New(config.ValidationConfig{Rules: map[string]string{"no-such-rule": "error"}}); err == nil {
+		t.Error("expected error for unknown rule")
+	}
+	if _, err := New(config.ValidationConfig{Rules: map[string]string{"description": "fatal"}}); err == nil {
+		t.Error("expected error for unknown severity")
+	}
+}
+
+func TestSARIF(t *testing.T) {
+	e, _ := New(config.ValidationConfig{})
+	log := e.SARIF([]Violation{
+		{PearlID: "db.users", Rule: "heading-structure", Severity: SeverityWarning, Path: "content/db/users.md", Line: 3, Message: "m"},
+		{PearlID: "db.users", Rule: "description", Severity: SeverityWarning, Message: "d"},
+	})
+
+	run := log.Runs[0]
+	if log.Version != "2.1.0" || len(run.Tool.Driver.Rules) != len(Rules()) {
+		t.Fatalf("version %s, %d rules", log.Version, len(run.Tool.Driver.Rules))
+	}
+	r := run.Results[0]
+	if run.Tool.Driver.Rules[r.RuleIndex].ID != "heading-structure" {
+		t.Errorf("rule index %d does not point at heading-structure", r.RuleIndex)
+	}
+	loc := r.Locations[0].PhysicalLocation
+	if loc.ArtifactLocation.URI != "content/db/users.md" || loc.Region == nil || loc.Region.StartLine != 3 {
+		t.Errorf("location = %+v", loc)
+	}
+	if len(run.Results[1].Locations) != 0 {
+		t.Error("violation without a path should have no location")
+	}
+}